@@ -0,0 +1,107 @@
+// This applies, rolls back, and shows the status of the database migrations.
+//
+// The web server refuses to start while any migration is pending, so run "migrate up" before deploying a new version
+// (or set DB_MIGRATE=true for the web server to do it).
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/downballot/downballot/internal/appconfig"
+	"github.com/downballot/downballot/internal/database"
+	"github.com/downballot/downballot/internal/migrator"
+	"github.com/downballot/downballot/internal/schema/sqltype"
+	"github.com/joho/godotenv"
+)
+
+type StatusCommand struct{}
+
+type UpCommand struct {
+	To string `arg:"--to" help:"The ID of the last migration to apply; by default, every pending migration is applied."`
+}
+
+type DownCommand struct {
+	Steps int `arg:"-n, --steps" default:"1" help:"The number of migrations to roll back."`
+}
+
+type Args struct {
+	Config string         `arg:"-c, --config" default:"config.json" help:"The configuration file."`
+	Status *StatusCommand `arg:"subcommand:status" help:"Show which migrations have been applied."`
+	Up     *UpCommand     `arg:"subcommand:up" help:"Apply the pending migrations."`
+	Down   *DownCommand   `arg:"subcommand:down" help:"Roll back the most recently applied migrations."`
+}
+
+func main() {
+	ctx := context.Background()
+
+	godotenv.Load(".env")
+
+	var args Args
+	parser := arg.MustParse(&args)
+	if parser.Subcommand() == nil {
+		parser.Fail("missing command: status, up, or down")
+	}
+
+	var config appconfig.Config
+	{
+		contents, err := os.ReadFile(args.Config)
+		if err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Could not read %s: %v", args.Config, err))
+			os.Exit(1)
+		}
+		err = json.Unmarshal(contents, &config)
+		if err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Could not parse %s: %v", args.Config, err))
+			os.Exit(1)
+		}
+	}
+
+	err := config.SetEncryptionKeys()
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Could not load the encryption keys: %v", err))
+		os.Exit(1)
+	}
+	slog.InfoContext(ctx, fmt.Sprintf("Encryption key: %q", sqltype.ActiveEncryptionKeyID()))
+
+	db, err := database.New(ctx, config.DatabaseDriver, config.DatabaseString)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Could not connect to database: %v", err))
+		os.Exit(1)
+	}
+
+	switch {
+	case args.Status != nil:
+		statuses, err := migrator.GetStatus(db)
+		if err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Could not get the migration status: %v", err))
+			os.Exit(1)
+		}
+		for _, status := range statuses {
+			applied := "pending"
+			if status.Applied {
+				applied = "applied " + status.AppliedTimestamp.Format(time.RFC3339)
+			}
+			fmt.Printf("%s\t%s\t%s\n", status.Migration.ID, applied, status.Migration.Description)
+		}
+	case args.Up != nil:
+		migrations, err := migrator.Up(ctx, db, args.Up.To)
+		slog.InfoContext(ctx, fmt.Sprintf("Applied %d migration(s).", len(migrations)))
+		if err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Could not apply the migrations: %v", err))
+			os.Exit(1)
+		}
+	case args.Down != nil:
+		migrations, err := migrator.Down(ctx, db, args.Down.Steps)
+		slog.InfoContext(ctx, fmt.Sprintf("Rolled back %d migration(s).", len(migrations)))
+		if err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Could not roll back the migrations: %v", err))
+			os.Exit(1)
+		}
+	}
+}
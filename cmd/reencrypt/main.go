@@ -0,0 +1,71 @@
+// This re-encrypts the encrypted database columns with the active encryption key.
+//
+// To rotate the encryption key, add the new key to "encryption_keys" in config.json, make it the
+// "encryption_key_id", restart the web server, and then run this.  Once this has finished, the old
+// key can be removed.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/alexflint/go-arg"
+	"github.com/downballot/downballot/internal/appconfig"
+	"github.com/downballot/downballot/internal/database"
+	"github.com/downballot/downballot/internal/reencrypt"
+	"github.com/downballot/downballot/internal/schema/sqltype"
+	"github.com/joho/godotenv"
+)
+
+type Args struct {
+	Config    string `arg:"-c, --config" default:"config.json" help:"The configuration file."`
+	BatchSize int    `arg:"-b, --batch-size" default:"100" help:"The number of rows to re-encrypt in each transaction."`
+}
+
+func main() {
+	ctx := context.Background()
+
+	godotenv.Load(".env")
+
+	var args Args
+	arg.MustParse(&args)
+
+	var config appconfig.Config
+	{
+		contents, err := os.ReadFile(args.Config)
+		if err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Could not read %s: %v", args.Config, err))
+			os.Exit(1)
+		}
+		err = json.Unmarshal(contents, &config)
+		if err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Could not parse %s: %v", args.Config, err))
+			os.Exit(1)
+		}
+	}
+
+	err := config.SetEncryptionKeys()
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Could not load the encryption keys: %v", err))
+		os.Exit(1)
+	}
+	slog.InfoContext(ctx, fmt.Sprintf("Encryption key: %q", sqltype.ActiveEncryptionKeyID()))
+
+	db, err := database.New(ctx, config.DatabaseDriver, config.DatabaseString)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Could not connect to database: %v", err))
+		os.Exit(1)
+	}
+
+	results, err := reencrypt.Run(ctx, db, args.BatchSize)
+	for _, result := range results {
+		slog.InfoContext(ctx, fmt.Sprintf("%s.%s: %d rows re-encrypted (out of %d).", result.Column.Table, result.Column.Column, result.Reencrypted, result.Rows))
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Could not re-encrypt the database: %v", err))
+		os.Exit(1)
+	}
+}
@@ -3,15 +3,18 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"net/http/pprof"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"slices"
 	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/downballot/downballot/internal/api"
@@ -169,7 +172,12 @@ func main() {
 		}
 	}
 
-	sqltype.SetEncryptionKey(config.EncryptionKey)
+	err = config.SetEncryptionKeys()
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Could not load the encryption keys: %v", err))
+		os.Exit(1)
+	}
+	slog.InfoContext(ctx, fmt.Sprintf("Encryption key: %q", sqltype.ActiveEncryptionKeyID()))
 
 	db, err := database.New(ctx, config.DatabaseDriver, config.DatabaseString)
 	if err != nil {
@@ -180,23 +188,66 @@ func main() {
 	slog.InfoContext(ctx, fmt.Sprintf("DB_MIGRATE: %s", os.Getenv("DB_MIGRATE")))
 	if os.Getenv("DB_MIGRATE") == "true" {
 		slog.InfoContext(ctx, "Migrating the database...")
-		err = migrator.Migrate(db)
+		err = migrator.Migrate(ctx, db)
 		if err != nil {
 			slog.ErrorContext(ctx, fmt.Sprintf("Could not migrate database: %v", err))
 			os.Exit(1)
 		}
 	}
 
+	{
+		pendingMigrations, err := migrator.Pending(db)
+		if err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Could not check the database migrations: %v", err))
+			os.Exit(1)
+		}
+		if len(pendingMigrations) > 0 {
+			for _, migration := range pendingMigrations {
+				slog.ErrorContext(ctx, fmt.Sprintf("Pending migration %s: %s", migration.ID, migration.Description))
+			}
+			slog.ErrorContext(ctx, fmt.Sprintf("The database has %d pending migration(s); run \"migrate up\" (or set DB_MIGRATE=true) first.", len(pendingMigrations)))
+			os.Exit(1)
+		}
+	}
+
 	app := application.New(ctx, db)
 
 	apiInstance := api.New()
 	apiInstance.App = app
 	apiInstance.Config = api.Config{
-		JWTPrivateKey:  config.JWTPrivateKey,
-		JWTPublicKey:   config.JWTPublicKey,
-		JWTSecret:      config.JWTSecret,
-		MasterToken:    config.MasterToken,
-		SendGridAPIKey: config.SendGridAPIKey,
+		JWTPrivateKey:   config.JWTPrivateKey,
+		JWTPublicKey:    config.JWTPublicKey,
+		JWTSecret:       config.JWTSecret,
+		JWTSigningKeyID: config.JWTSigningKeyID,
+		Development:     config.Development,
+		MasterToken:     config.MasterToken,
+		SendGridAPIKey:  config.SendGridAPIKey,
+		BaseURL:         config.BaseURL,
+
+		OIDCIssuer:       config.OIDCIssuer,
+		OIDCClientID:     config.OIDCClientID,
+		OIDCClientSecret: config.OIDCClientSecret,
+	}
+
+	for _, key := range config.JWTKeys {
+		apiInstance.Config.JWTKeys = append(apiInstance.Config.JWTKeys, api.JWTKey{
+			ID:         key.ID,
+			Secret:     key.Secret,
+			PrivateKey: key.PrivateKey,
+			PublicKey:  key.PublicKey,
+		})
+	}
+
+	// Refuse to start if tokens can't be signed; an unsigned token can claim to be anyone.
+	if _, err := apiInstance.Config.Keyring(); err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Could not load the JWT keys: %v", err))
+		os.Exit(1)
+	}
+
+	err = apiInstance.FailInterruptedJobs(ctx)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Could not clean up the interrupted jobs: %v", err))
+		os.Exit(1)
 	}
 
 	apiContainer := apiInstance.Container(ctx)
@@ -229,6 +280,7 @@ func main() {
 		staticHandler.ServeHTTP(w, r)
 	})
 	myHandler.Handle("/api/", apiContainer)
+	myHandler.Handle("/.well-known/", apiContainer)
 
 	if profile {
 		//runtime.SetCPUProfileRate(50000)
@@ -246,9 +298,32 @@ func main() {
 		Handler: finalHandler,
 	}
 
+	// Stop cleanly when asked to, so that the background jobs can record where they got to.
+	signalContext, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-signalContext.Done()
+		slog.InfoContext(ctx, "Shutting down...")
+
+		shutdownContext, cancel := context.WithTimeout(ctx, 30*time.Second)
+		defer cancel()
+
+		err := httpServer.Shutdown(shutdownContext)
+		if err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Could not shut down the web server: %v", err))
+		}
+	}()
+
 	slog.InfoContext(ctx, fmt.Sprintf("Listening on: %s", httpServer.Addr))
 	err = httpServer.ListenAndServe()
-	if err != nil {
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		slog.ErrorContext(ctx, fmt.Sprintf("Error: %v", err))
 	}
+
+	shutdownContext, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	err = apiInstance.Shutdown(shutdownContext)
+	if err != nil {
+		slog.ErrorContext(ctx, fmt.Sprintf("Could not stop the background jobs: %v", err))
+	}
 }
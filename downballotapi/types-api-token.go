@@ -0,0 +1,31 @@
+package downballotapi
+
+import "github.com/downballot/downballot/internal/api/resttype"
+
+// CreateAPITokenRequest is the request to create a personal API token.
+type CreateAPITokenRequest struct {
+	Name           string   `json:"name"`
+	OrganizationID string   `json:"organization_id"` // The token can only access this organization.
+	Permissions    []string `json:"permissions"`     // These may use wildcards, such as "person:*".
+}
+
+// CreateAPITokenResponse is the response from creating a personal API token.
+type CreateAPITokenResponse struct {
+	APIToken *APIToken `json:"api_token"`
+	Token    string    `json:"token"` // This is the only time that the token itself is returned.
+}
+
+// ListAPITokensResponse is the response from listing the personal API tokens.
+type ListAPITokensResponse struct {
+	APITokens []*APIToken `json:"api_tokens"`
+}
+
+// APIToken is a long-lived personal API token, such as for an integration.
+type APIToken struct {
+	ID                  string             `json:"id"`
+	Name                string             `json:"name"`
+	OrganizationID      string             `json:"organization_id"`
+	Permissions         []string           `json:"permissions"`
+	CreatedTimestamp    resttype.DateTime  `json:"created_timestamp"`
+	ExpirationTimestamp *resttype.DateTime `json:"expiration_timestamp,omitempty"`
+}
@@ -1,5 +1,7 @@
 package downballotapi
 
+import "github.com/downballot/downballot/internal/api/resttype"
+
 // AuthenticationStatusResponse is the authentication status response.
 type AuthenticationStatusResponse struct {
 	User *AuthenticationStatusUser `json:"user"`
@@ -26,12 +28,16 @@ type EmailResponse struct {
 type LoginRequest struct {
 	Username string `json:"username" description:"(username/password) The username."`
 	Password string `json:"password" description:"(username/password) The password."`
+
+	AuthenticatorCode   string `json:"authenticator_code,omitempty" description:"The code from the user's authenticator app (or a recovery code); this is required if the user has enrolled an authenticator app."`
+	AuthenticatorSecret string `json:"authenticator_secret,omitempty" description:"The base32 secret of a new authenticator app to enroll along with a code from it; organization owners must do this if they have not enrolled one yet."`
 }
 
 // LoginResponse is the response from signing in with an account.
 type LoginResponse struct {
-	UserID string `json:"user_id"`
-	Token  string `json:"token"`
+	UserID        string   `json:"user_id"`
+	Token         string   `json:"token"`
+	RecoveryCodes []string `json:"recovery_codes,omitempty"` // If an authenticator app was enrolled while logging in, then these are its recovery codes; they are only returned once.
 }
 
 // ResetPasswordRequest is used to reset a user's password.
@@ -43,3 +49,57 @@ type ResetPasswordRequest struct {
 type ResetPasswordResponse struct {
 	Email string `json:"email"`
 }
+
+// ResetPasswordConfirmRequest is used to finish resetting a user's password.
+type ResetPasswordConfirmRequest struct {
+	Token string `json:"token"` // This is the token from the link in the password-reset e-mail.
+}
+
+// ResetPasswordConfirmResponse is the response from finishing resetting a user's password.
+type ResetPasswordConfirmResponse struct {
+	Email string `json:"email"`
+}
+
+// LogoutRequest is used to sign a user out everywhere.
+type LogoutRequest struct {
+}
+
+// LogoutResponse is the response from signing a user out everywhere.
+type LogoutResponse struct {
+	RevokedSessions int64 `json:"revoked_sessions"`
+}
+
+// ListSessionsResponse is the response from listing the current user's sessions.
+type ListSessionsResponse struct {
+	Sessions []*Session `json:"sessions"`
+}
+
+// Session is a token that was issued to a user.
+type Session struct {
+	ID                  string             `json:"id"`
+	CreatedTimestamp    resttype.DateTime  `json:"created_timestamp"`
+	ExpirationTimestamp *resttype.DateTime `json:"expiration_timestamp,omitempty"`
+	UserAgent           string             `json:"user_agent"`
+	SourceAddress       string             `json:"source_address"`
+	Current             bool               `json:"current"` // Whether this is the session of the token making the request.
+}
+
+// OIDCStartRequest is used to start logging in with the OpenID Connect identity provider.
+type OIDCStartRequest struct {
+	ClientNonce string `json:"client_nonce"` // This is a random value that the client keeps to itself (such as in session storage) until it finishes logging in.
+}
+
+// OIDCStartResponse is the response from starting to log in with the OpenID Connect identity provider.
+type OIDCStartResponse struct {
+	AuthorizationURL string `json:"authorization_url"` // Send the user here.
+}
+
+// OIDCLoginRequest is used to finish logging in with the OpenID Connect identity provider.
+type OIDCLoginRequest struct {
+	Code        string `json:"code"`         // This is the authorization code from the identity provider.
+	State       string `json:"state"`        // This is the state from the identity provider.
+	ClientNonce string `json:"client_nonce"` // This is the client nonce that was used to start logging in; only the client that started logging in can finish.
+
+	AuthenticatorCode   string `json:"authenticator_code,omitempty"`   // This is required if the user has enrolled an authenticator app.
+	AuthenticatorSecret string `json:"authenticator_secret,omitempty"` // This enrolls a new authenticator app along with a code from it; organization owners must do this if they have not enrolled one yet.
+}
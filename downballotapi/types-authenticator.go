@@ -0,0 +1,33 @@
+package downballotapi
+
+// AuthenticatorStatusResponse is the response from getting the status of the current user's authenticator app.
+type AuthenticatorStatusResponse struct {
+	Enrolled               bool `json:"enrolled"`                 // Whether the user has started enrolling an authenticator app.
+	Confirmed              bool `json:"confirmed"`                // Whether the authenticator app is required to log in.
+	RecoveryCodesRemaining int  `json:"recovery_codes_remaining"` // This is the number of unused recovery codes.
+}
+
+// EnrollAuthenticatorRequest is used to start enrolling an authenticator app.
+type EnrollAuthenticatorRequest struct {
+}
+
+// EnrollAuthenticatorResponse is the response from starting to enroll an authenticator app.
+type EnrollAuthenticatorResponse struct {
+	Secret          string `json:"secret"`           // This is the secret, for typing into the app by hand.
+	ProvisioningURI string `json:"provisioning_uri"` // This is the "otpauth://" URI, which is what goes in the QR code.
+}
+
+// ConfirmAuthenticatorRequest is used to finish enrolling an authenticator app.
+type ConfirmAuthenticatorRequest struct {
+	Code string `json:"code"` // This is a code from the authenticator app.
+}
+
+// RecoveryCodesRequest is used to replace the current user's recovery codes.
+type RecoveryCodesRequest struct {
+	Code string `json:"code"` // This is a code from the authenticator app.
+}
+
+// RecoveryCodesResponse has a new set of recovery codes.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"` // This is the only time that the codes are returned.
+}
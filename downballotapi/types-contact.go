@@ -0,0 +1,106 @@
+package downballotapi
+
+import (
+	"time"
+
+	"github.com/downballot/downballot/internal/api/restcsv"
+	"github.com/downballot/downballot/internal/api/resttype"
+)
+
+// ContactChannel is how a person was contacted.
+type ContactChannel string
+
+const (
+	ContactChannelDoor  ContactChannel = "door"
+	ContactChannelPhone ContactChannel = "phone"
+	ContactChannelText  ContactChannel = "text"
+)
+
+// CreateContactResultRequest is the request to create a contact result.
+type CreateContactResultRequest struct {
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// CreateContactResultResponse is the response from creating a contact result.
+type CreateContactResultResponse ContactResult
+
+// ListContactResultsResponse is the response from listing the contact results.
+type ListContactResultsResponse struct {
+	ContactResults []*ContactResult `json:"contact_results"`
+}
+
+// GetContactResultResponse is the response from getting a contact result.
+type GetContactResultResponse struct {
+	ContactResult *ContactResult `json:"contact_result"`
+}
+
+// ContactResult is one of the outcomes of a contact attempt that an organization records, such as "not_home".
+type ContactResult struct {
+	ID          string `json:"id"`
+	Code        string `json:"code"` // This is the lowercase code that filters use, such as "contact.last_result = not_home".
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// PatchContactResultRequest is the request for patching a contact result.
+type PatchContactResultRequest struct {
+	Code        *string `json:"code"`
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+}
+
+// PatchContactResultResponse is the response from patching a contact result.
+type PatchContactResultResponse struct {
+	ContactResult ContactResult `json:"contact_result"`
+}
+
+// CreateContactAttemptRequest is the request to log a contact attempt.
+type CreateContactAttemptRequest struct {
+	Timestamp *resttype.DateTime `json:"timestamp"` // If not set, then this is the current time.
+	Channel   ContactChannel     `json:"channel"`
+	Result    string             `json:"result"` // This is the code of the contact result.
+	Note      string             `json:"note"`
+}
+
+// CreateContactAttemptResponse is the response from logging a contact attempt.
+type CreateContactAttemptResponse ContactAttempt
+
+// ListContactAttemptsResponse is the response from listing the contact attempts.
+type ListContactAttemptsResponse struct {
+	ContactAttempts []*ContactAttempt `json:"contact_attempts"`
+}
+
+var _ CSVMarshaler = (*ListContactAttemptsResponse)(nil)
+
+func (r ListContactAttemptsResponse) MarshallCSV() (restcsv.Table, error) {
+	table := restcsv.Table{
+		Header: []string{"timestamp", "voter_id", "username", "channel", "result", "note"},
+		Rows:   make([][]string, 0, len(r.ContactAttempts)),
+	}
+
+	for _, contactAttempt := range r.ContactAttempts {
+		table.Rows = append(table.Rows, []string{
+			time.Time(contactAttempt.Timestamp).Format(time.RFC3339),
+			contactAttempt.VoterID,
+			contactAttempt.Username,
+			string(contactAttempt.Channel),
+			contactAttempt.Result,
+			contactAttempt.Note,
+		})
+	}
+	return table, nil
+}
+
+// ContactAttempt is a single attempt by a user to contact a person.
+type ContactAttempt struct {
+	ID        string            `json:"id"`
+	VoterID   string            `json:"voter_id"`
+	UserID    string            `json:"user_id"`
+	Username  string            `json:"username"`
+	Timestamp resttype.DateTime `json:"timestamp"`
+	Channel   ContactChannel    `json:"channel"`
+	Result    string            `json:"result"` // This is the code of the contact result.
+	Note      string            `json:"note"`
+}
@@ -0,0 +1,79 @@
+package downballotapi
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/downballot/downballot/internal/api/restcsv"
+)
+
+// ListHouseholdsResponse is the response from listing the households.
+type ListHouseholdsResponse struct {
+	Households    []*Household `json:"households"`
+	NextPageToken string       `json:"next_page_token,omitempty"` // If there are more households, then this is the token for the next page.
+}
+
+var _ CSVMarshaler = (*ListHouseholdsResponse)(nil)
+
+func (r ListHouseholdsResponse) MarshallCSV() (restcsv.Table, error) {
+	table := restcsv.Table{
+		Header: []string{"household_id", "address", "members", "voter_ids"},
+		Rows:   make([][]string, 0, len(r.Households)),
+	}
+
+	for _, household := range r.Households {
+		var voterIDs []string
+		for _, member := range household.Members {
+			voterIDs = append(voterIDs, member.VoterID)
+		}
+		table.Rows = append(table.Rows, []string{
+			household.ID,
+			household.Address,
+			strconv.Itoa(len(household.Members)),
+			strings.Join(voterIDs, ","),
+		})
+	}
+	return table, nil
+}
+
+// GetHouseholdResponse is the response from getting a household.
+type GetHouseholdResponse struct {
+	Household *Household `json:"household"`
+}
+
+// MergeHouseholdsRequest is the request to merge households into another household.
+type MergeHouseholdsRequest struct {
+	HouseholdIDs []string `json:"household_ids"` // These are the households whose members are moved; they are deleted afterward.
+}
+
+// MergeHouseholdsResponse is the response from merging households.
+type MergeHouseholdsResponse struct {
+	Household *Household `json:"household"`
+}
+
+// SplitHouseholdRequest is the request to split persons off of a household.
+type SplitHouseholdRequest struct {
+	VoterIDs []string `json:"voter_ids"` // These are the members who are moved into a new household.
+}
+
+// SplitHouseholdResponse is the response from splitting a household.
+type SplitHouseholdResponse struct {
+	Household *Household `json:"household"` // This is the new household.
+}
+
+// RebuildHouseholdsRequest is the request to rebuild the households.
+type RebuildHouseholdsRequest struct {
+	Reset bool `json:"reset"` // If true, then the persons who were merged or split by hand are also put back by their addresses.
+}
+
+// RebuildHouseholdsResponse is the response from rebuilding the households.
+type RebuildHouseholdsResponse struct {
+	Persons uint64 `json:"persons"` // This is the number of persons whose addresses were checked.
+}
+
+// Household is a group of persons who live at the same address.
+type Household struct {
+	ID      string    `json:"id"`
+	Address string    `json:"address"`
+	Members []*Person `json:"members,omitempty"`
+}
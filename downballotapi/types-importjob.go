@@ -0,0 +1,56 @@
+package downballotapi
+
+import (
+	"fmt"
+
+	"github.com/downballot/downballot/internal/api/restcsv"
+	"github.com/downballot/downballot/internal/api/resttype"
+)
+
+// GetImportJobResponse is the response from getting an import job.
+type GetImportJobResponse struct {
+	Job    *ImportJob        `json:"job"`
+	Errors []*ImportJobError `json:"errors"`
+}
+
+var _ CSVMarshaler = (*GetImportJobResponse)(nil)
+
+// MarshallCSV renders the per-row errors of the import job.
+func (r GetImportJobResponse) MarshallCSV() (restcsv.Table, error) {
+	table := restcsv.Table{
+		Header: []string{"row", "voter_id", "message"},
+		Rows:   make([][]string, 0, len(r.Errors)),
+	}
+
+	for _, importJobError := range r.Errors {
+		row := make([]string, len(table.Header))
+		row[0] = fmt.Sprintf("%d", importJobError.Row)
+		row[1] = importJobError.VoterID
+		row[2] = importJobError.Message
+		table.Rows = append(table.Rows, row)
+	}
+	return table, nil
+}
+
+// ImportJob is a background import of persons.
+type ImportJob struct {
+	ID                string             `json:"id"`
+	Status            string             `json:"status"`
+	DryRun            bool               `json:"dry_run"`
+	Message           string             `json:"message,omitempty"`
+	TotalRows         uint64             `json:"total_rows"`
+	ProcessedRows     uint64             `json:"processed_rows"`
+	CreatedRows       uint64             `json:"created_rows"`
+	UpdatedRows       uint64             `json:"updated_rows"`
+	UnchangedRows     uint64             `json:"unchanged_rows"`
+	FailedRows        uint64             `json:"failed_rows"`
+	CreatedTimestamp  resttype.DateTime  `json:"created_timestamp"`
+	FinishedTimestamp *resttype.DateTime `json:"finished_timestamp,omitempty"`
+}
+
+// ImportJobError is a row of an import job that could not be imported.
+type ImportJobError struct {
+	Row     uint64 `json:"row"` // This is the 1-based row number in the file, not counting the header.
+	VoterID string `json:"voter_id"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,70 @@
+package downballotapi
+
+// CreateImportProfileRequest is the request to create an import profile.
+type CreateImportProfileRequest struct {
+	Name        string                    `json:"name"`
+	Description string                    `json:"description"`
+	Columns     map[string]string         `json:"columns"`
+	Composites  []*ImportProfileComposite `json:"composites"`
+	Sets        []*ImportProfileSet       `json:"sets"`
+	Transforms  []*ImportProfileTransform `json:"transforms"`
+}
+
+// CreateImportProfileResponse is the response from creating an import profile.
+type CreateImportProfileResponse ImportProfile
+
+// ListImportProfilesResponse is the response from listing the import profiles.
+type ListImportProfilesResponse struct {
+	ImportProfiles []*ImportProfile `json:"import_profiles"`
+}
+
+// GetImportProfileResponse is the response from getting an import profile.
+type GetImportProfileResponse struct {
+	ImportProfile *ImportProfile `json:"import_profile"`
+}
+
+// ImportProfile is a named voter-file layout.
+type ImportProfile struct {
+	ID          string                    `json:"id"`
+	Name        string                    `json:"name"`
+	Description string                    `json:"description"`
+	Columns     map[string]string         `json:"columns"`    // This maps each (normalized) column to the field that it is copied into.
+	Composites  []*ImportProfileComposite `json:"composites"` // These build a single field out of multiple columns.
+	Sets        []*ImportProfileSet       `json:"sets"`       // These build a set field out of a family of columns.
+	Transforms  []*ImportProfileTransform `json:"transforms"` // These adjust the final value of a field.
+}
+
+// ImportProfileComposite builds a field from an expression, such as `name_first + ' ' + name_last`.
+type ImportProfileComposite struct {
+	Field      string `json:"field"`
+	Expression string `json:"expression"`
+}
+
+// ImportProfileSet builds a set field from every column that starts with a prefix.
+type ImportProfileSet struct {
+	Field        string `json:"field"`
+	ColumnPrefix string `json:"column_prefix"`
+}
+
+// ImportProfileTransform applies a series of operations, such as "upper" or "date:1/2/2006", to a field.
+type ImportProfileTransform struct {
+	Field      string   `json:"field"`
+	Operations []string `json:"operations"`
+}
+
+// PatchImportProfileRequest is the request for patching an import profile.
+//
+// Any of the definition parts that are present replace the existing part entirely.
+type PatchImportProfileRequest struct {
+	Name        *string                   `json:"name"`
+	Description *string                   `json:"description"`
+	Columns     map[string]string         `json:"columns"`
+	Composites  []*ImportProfileComposite `json:"composites"`
+	Sets        []*ImportProfileSet       `json:"sets"`
+	Transforms  []*ImportProfileTransform `json:"transforms"`
+}
+
+// PatchImportProfileResponse is the response from patching an import profile.
+type PatchImportProfileResponse struct {
+	ImportProfile ImportProfile `json:"import_profile"`
+}
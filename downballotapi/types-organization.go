@@ -1,5 +1,7 @@
 package downballotapi
 
+import "github.com/downballot/downballot/internal/api/resttype"
+
 // RegisterOrganizationRequest is the request to register an organization.
 type RegisterOrganizationRequest struct {
 	Name    string `json:"name"`
@@ -25,10 +27,57 @@ type GetOrganizationResponse struct {
 	Organization Organization `json:"organization"`
 }
 
+// PatchOrganizationRequest is the request for patching an organization.
+type PatchOrganizationRequest struct {
+	Name *string `json:"name"`
+}
+
+// PatchOrganizationResponse is the response from patching an organization.
+type PatchOrganizationResponse struct {
+	Organization Organization `json:"organization"`
+}
+
+// ArchiveOrganizationRequest is the request to archive an organization.
+type ArchiveOrganizationRequest struct {
+	Format string `json:"format"` // This is either "json" (the default) or "zip".
+}
+
+// OrganizationArchive is a full archive of an organization.
+//
+// The confirmation token is required in order to delete the organization.
+type OrganizationArchive struct {
+	ConfirmationToken string                          `json:"confirmation_token"`
+	ArchiveTimestamp  resttype.DateTime               `json:"archive_timestamp"`
+	Organization      Organization                    `json:"organization"`
+	PersonFields      []*PersonField                  `json:"person_fields"`
+	Persons           []*Person                       `json:"persons"`
+	Audits            []*PersonAudit                  `json:"audits"`
+	Groups            []*Group                        `json:"groups"`
+	Filters           []*Filter                       `json:"filters"`
+	Users             []*User                         `json:"users"`
+	GroupUsers        []*OrganizationArchiveGroupUser `json:"group_users"`
+	ImportProfiles    []*ImportProfile                `json:"import_profiles"`
+	Roles             []*Role                         `json:"roles"`
+	ContactResults    []*ContactResult                `json:"contact_results"`
+	ContactAttempts   []*ContactAttempt               `json:"contact_attempts"`
+	Households        []*Household                    `json:"households"` // The members of each household are given by the `household_id` of each person.
+	Turfs             []*Turf                         `json:"turfs"`      // The members of each turf are found from the coordinates of the persons.
+	Surveys           []*Survey                       `json:"surveys"`
+	SurveyResponses   []*SurveyResponse               `json:"survey_responses"`
+}
+
+// OrganizationArchiveGroupUser is the membership of a user in a group.
+type OrganizationArchiveGroupUser struct {
+	GroupID string `json:"group_id"`
+	UserID  string `json:"user_id"`
+	Owner   bool   `json:"owner"`
+}
+
 // AddUserToOrganizationRequest TODO:
 type AddUserToOrganizationRequest struct {
-	Username string `json:"username"`
-	Owner    bool   `json:"owner"`
+	Username string  `json:"username"`
+	Owner    bool    `json:"owner"`
+	RoleID   *string `json:"role_id"`
 }
 
 // AddUserToOrganizationResponse TODO:
@@ -8,13 +8,17 @@ import (
 )
 
 // ImportPersonResponse is the response from importing persons.
+//
+// The import itself runs in the background; use the job ID to check on its progress.
 type ImportPersonResponse struct {
+	JobID   string `json:"job_id"`
 	Records uint64 `json:"records"`
 }
 
 // ListPersonsResponse is the response from listing the persons.
 type ListPersonsResponse struct {
-	Persons []*Person `json:"persons"`
+	Persons       []*Person `json:"persons"`
+	NextPageToken string    `json:"next_page_token,omitempty"` // If there are more persons, then this is the token for the next page.
 }
 
 var _ CSVMarshaler = (*ListPersonsResponse)(nil)
@@ -31,15 +35,21 @@ func (r ListPersonsResponse) MarshallCSV() (restcsv.Table, error) {
 				headerSet[name] = true
 			}
 		}
-		table.Header = slices.Collect(maps.Keys(headerSet))
-		slices.Sort(table.Header)
+		delete(headerSet, "voter_id")
+		fieldNames := slices.Collect(maps.Keys(headerSet))
+		slices.Sort(fieldNames)
+
+		// The voter ID always comes first.
+		table.Header = append(table.Header, "voter_id")
+		table.Header = append(table.Header, fieldNames...)
 	}
 
 	for _, person := range r.Persons {
 		row := make([]string, len(table.Header))
+		row[0] = person.VoterID
 		if person.Fields != nil {
-			for i, name := range table.Header {
-				row[i] = person.Fields[name]
+			for i, name := range table.Header[1:] {
+				row[i+1] = person.Fields[name]
 			}
 		}
 		table.Rows = append(table.Rows, row)
@@ -70,7 +80,8 @@ type PostPersonUpdateResponse struct {
 
 // Person is an person.
 type Person struct {
-	ID      string            `json:"id"`
-	VoterID string            `json:"voter_id"`
-	Fields  map[string]string `json:"fields"`
+	ID          string            `json:"id"`
+	VoterID     string            `json:"voter_id"`
+	HouseholdID string            `json:"household_id,omitempty"` // If the person is in a household, then this is its ID.
+	Fields      map[string]string `json:"fields"`
 }
@@ -21,6 +21,7 @@ type CreatePersonFieldRequest struct {
 	AllowEmpty    bool                      `json:"allow_empty"`
 	AllowedValues []string                  `json:"allowed_values"`
 	AllowedRegex  string                    `json:"allowed_regex"`
+	Encrypted     bool                      `json:"encrypted"` // If set, then the values are encrypted; they can only be filtered with "=" and "!=", and they cannot be sorted.
 }
 
 // CreatePersonFieldResponse is the response from creating a person field.
@@ -45,11 +46,20 @@ type PatchPersonFieldRequest struct {
 	AllowEmpty    *bool                      `json:"allow_empty"`
 	AllowedValues []string                   `json:"allowed_values"`
 	AllowedRegex  *string                    `json:"allowed_regex"`
+	Encrypted     *bool                      `json:"encrypted"` // Changing this re-encodes every existing value (and its history).
 }
 
 // PatchPersonFieldResponse is the response from patching the person field.
 type PatchPersonFieldResponse struct {
-	PersonField PersonField `json:"person_field"`
+	PersonField   PersonField                `json:"person_field"`
+	InvalidValues []*PersonFieldInvalidValue `json:"invalid_values"` // These are the existing values that are not valid for the patched field; this is only set for a dry run.
+}
+
+// PersonFieldInvalidValue is an existing value that is not valid for a person field.
+type PersonFieldInvalidValue struct {
+	VoterID string `json:"voter_id"`
+	Value   string `json:"value"`
+	Error   string `json:"error"`
 }
 
 // PersonField is a person field.
@@ -60,4 +70,5 @@ type PersonField struct {
 	AllowEmpty    bool                      `json:"allow_empty"`
 	AllowedValues []string                  `json:"allowed_values"`
 	AllowedRegex  string                    `json:"allowed_regex"`
+	Encrypted     bool                      `json:"encrypted"`
 }
@@ -0,0 +1,41 @@
+package downballotapi
+
+// CreateRoleRequest is the request to create a role.
+type CreateRoleRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// CreateRoleResponse is the response from creating a role.
+type CreateRoleResponse Role
+
+// ListRolesResponse is the response from listing the roles.
+type ListRolesResponse struct {
+	Roles []*Role `json:"roles"`
+}
+
+// GetRoleResponse is the response from getting a role.
+type GetRoleResponse struct {
+	Role *Role `json:"role"`
+}
+
+// Role is a named set of permissions that can be given to the users of an organization.
+type Role struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"` // These may use wildcards, such as "person:*".
+}
+
+// PatchRoleRequest is the request for patching a role.
+type PatchRoleRequest struct {
+	Name        *string  `json:"name"`
+	Description *string  `json:"description"`
+	Permissions []string `json:"permissions"`
+}
+
+// PatchRoleResponse is the response from patching a role.
+type PatchRoleResponse struct {
+	Role Role `json:"role"`
+}
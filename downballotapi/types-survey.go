@@ -0,0 +1,155 @@
+package downballotapi
+
+import (
+	"strconv"
+
+	"github.com/downballot/downballot/internal/api/restcsv"
+	"github.com/downballot/downballot/internal/api/resttype"
+)
+
+// SurveyQuestionType is the kind of answer that a survey question takes.
+type SurveyQuestionType string
+
+const (
+	SurveyQuestionTypeChoice SurveyQuestionType = "choice" // The answer is the code of exactly one of the choices.
+	SurveyQuestionTypeText   SurveyQuestionType = "text"   // The answer is free-form.
+)
+
+// CreateSurveyRequest is the request to create a survey.
+type CreateSurveyRequest struct {
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Active      bool              `json:"active"` // If set, then this becomes the active survey.
+	Questions   []*SurveyQuestion `json:"questions"`
+}
+
+// CreateSurveyResponse is the response from creating a survey.
+type CreateSurveyResponse Survey
+
+// ListSurveysResponse is the response from listing the surveys.
+type ListSurveysResponse struct {
+	Surveys []*Survey `json:"surveys"`
+}
+
+// GetSurveyResponse is the response from getting a survey.
+type GetSurveyResponse struct {
+	Survey *Survey `json:"survey"`
+}
+
+// PatchSurveyRequest is the request for patching a survey.
+type PatchSurveyRequest struct {
+	Name        *string           `json:"name"`
+	Description *string           `json:"description"`
+	Active      *bool             `json:"active"`    // If true, then this becomes the active survey.
+	Questions   []*SurveyQuestion `json:"questions"` // If set, then these replace all of the questions; this is only allowed until the survey has a response.
+}
+
+// PatchSurveyResponse is the response from patching a survey.
+type PatchSurveyResponse struct {
+	Survey Survey `json:"survey"`
+}
+
+// Survey is a script of questions that canvassers ask the persons that they contact.
+type Survey struct {
+	ID          string            `json:"id"`
+	Name        string            `json:"name"`
+	Description string            `json:"description"`
+	Active      bool              `json:"active"`
+	Questions   []*SurveyQuestion `json:"questions"`
+}
+
+// SurveyQuestion is a single question in a survey.
+type SurveyQuestion struct {
+	ID      string             `json:"id"` // This is ignored when the questions are created.
+	Prompt  string             `json:"prompt"`
+	Type    SurveyQuestionType `json:"type"`
+	Field   string             `json:"field"` // If set, then answering the question also sets this person field.
+	Choices []*SurveyChoice    `json:"choices"`
+}
+
+// SurveyChoice is one of the answers to a "choice" question.
+type SurveyChoice struct {
+	Code  string `json:"code"` // This is the lowercase code that is given as the answer, such as "yes".
+	Label string `json:"label"`
+	Value string `json:"value"` // If the question has a field, then this is the value that the field is set to.
+}
+
+// CreateSurveyResponseRequest is the request to record a person's response to a survey.
+type CreateSurveyResponseRequest struct {
+	SurveyID         string             `json:"survey_id"`          // If not set, then this is the active survey.
+	ContactAttemptID string             `json:"contact_attempt_id"` // This is the contact attempt during which the person responded, if any.
+	Timestamp        *resttype.DateTime `json:"timestamp"`          // If not set, then this is the current time.
+	Answers          []*SurveyAnswer    `json:"answers"`
+}
+
+// CreateSurveyResponseResponse is the response from recording a response to a survey.
+type CreateSurveyResponseResponse SurveyResponse
+
+// ListSurveyResponsesResponse is the response from listing the survey responses.
+type ListSurveyResponsesResponse struct {
+	SurveyResponses []*SurveyResponse `json:"survey_responses"`
+}
+
+// SurveyResponse is the set of answers that a person gave to a survey.
+type SurveyResponse struct {
+	ID               string            `json:"id"`
+	SurveyID         string            `json:"survey_id"`
+	VoterID          string            `json:"voter_id"`
+	UserID           string            `json:"user_id"`
+	Username         string            `json:"username"`
+	Timestamp        resttype.DateTime `json:"timestamp"`
+	ContactAttemptID string            `json:"contact_attempt_id,omitempty"`
+	Answers          []*SurveyAnswer   `json:"answers"`
+}
+
+// SurveyAnswer is the answer to a single question.
+type SurveyAnswer struct {
+	QuestionID string `json:"question_id"`
+	Value      string `json:"value"` // For a "choice" question, this is the code of the choice.
+}
+
+// GetSurveyResultsResponse is the response from getting the results of a survey.
+type GetSurveyResultsResponse struct {
+	Groups []*SurveyGroupResult `json:"groups"`
+}
+
+var _ CSVMarshaler = (*GetSurveyResultsResponse)(nil)
+
+func (r GetSurveyResultsResponse) MarshallCSV() (restcsv.Table, error) {
+	table := restcsv.Table{
+		Header: []string{"group_id", "group", "question_id", "question", "answer", "count"},
+	}
+
+	for _, group := range r.Groups {
+		for _, question := range group.Questions {
+			table.Rows = append(table.Rows, []string{group.ID, group.Name, question.QuestionID, question.Prompt, "", strconv.FormatInt(question.Answers, 10)})
+			for _, choice := range question.Choices {
+				table.Rows = append(table.Rows, []string{group.ID, group.Name, question.QuestionID, question.Prompt, choice.Code, strconv.FormatInt(choice.Count, 10)})
+			}
+		}
+	}
+	return table, nil
+}
+
+// SurveyGroupResult is the results of a survey for the persons in a group.
+type SurveyGroupResult struct {
+	ID        string                  `json:"id"`
+	Name      string                  `json:"name"`
+	Responses int64                   `json:"responses"` // This is the number of persons who responded.
+	Questions []*SurveyQuestionResult `json:"questions"`
+}
+
+// SurveyQuestionResult is the results of a single question.
+type SurveyQuestionResult struct {
+	QuestionID string                `json:"question_id"`
+	Prompt     string                `json:"prompt"`
+	Answers    int64                 `json:"answers"` // This is the number of persons who answered the question.
+	Choices    []*SurveyChoiceResult `json:"choices"` // This is empty for a "text" question.
+}
+
+// SurveyChoiceResult is the number of persons who gave a particular answer.
+type SurveyChoiceResult struct {
+	Code  string `json:"code"`
+	Label string `json:"label"`
+	Count int64  `json:"count"`
+}
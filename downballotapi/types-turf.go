@@ -0,0 +1,112 @@
+package downballotapi
+
+import (
+	"encoding/json"
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/downballot/downballot/internal/api/restcsv"
+)
+
+// CreateTurfRequest is the request to create a turf.
+type CreateTurfRequest struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Geometry    json.RawMessage `json:"geometry"` // This is a GeoJSON "Polygon" or "MultiPolygon" (or a "Feature" of one).
+}
+
+// CreateTurfResponse is the response from creating a turf.
+type CreateTurfResponse Turf
+
+// ListTurfsResponse is the response from listing the turfs.
+type ListTurfsResponse struct {
+	Turfs []*Turf `json:"turfs"`
+}
+
+// GetTurfResponse is the response from getting a turf.
+type GetTurfResponse struct {
+	Turf *Turf `json:"turf"`
+}
+
+// PatchTurfRequest is the request for patching a turf.
+type PatchTurfRequest struct {
+	Name        *string         `json:"name"`
+	Description *string         `json:"description"`
+	Geometry    json.RawMessage `json:"geometry"` // If set, then the members of the turf are found again.
+}
+
+// PatchTurfResponse is the response from patching a turf.
+type PatchTurfResponse struct {
+	Turf Turf `json:"turf"`
+}
+
+// CutTurfsRequest is the request to cut the persons that match a filter into turfs.
+type CutTurfsRequest struct {
+	Filter     string `json:"filter"`      // Only the persons that match this filter are cut up; if empty, then every person is.
+	Count      int    `json:"count"`       // This is the number of turfs to create.
+	NamePrefix string `json:"name_prefix"` // The turfs are named "<name_prefix> 1", "<name_prefix> 2", and so on.
+}
+
+// CutTurfsResponse is the response from cutting turfs.
+type CutTurfsResponse struct {
+	Turfs []*Turf `json:"turfs"`
+}
+
+// Turf is an area that canvassers walk.
+type Turf struct {
+	ID          string          `json:"id"`
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Geometry    json.RawMessage `json:"geometry"`          // This is a GeoJSON "Polygon" or "MultiPolygon".
+	Members     *uint64         `json:"members,omitempty"` // This is the number of persons in the turf.
+}
+
+// GetWalkListResponse is the response from getting the walk list for a turf.
+type GetWalkListResponse struct {
+	Stops    []*WalkListStop `json:"stops"`
+	Distance float64         `json:"distance"` // This is the total distance of the route, in meters.
+}
+
+var _ CSVMarshaler = (*GetWalkListResponse)(nil)
+
+func (r GetWalkListResponse) MarshallCSV() (restcsv.Table, error) {
+	table := restcsv.Table{
+		Header: []string{"stop", "address", "voter_id"},
+	}
+
+	headerSet := map[string]bool{}
+	for _, stop := range r.Stops {
+		for _, person := range stop.Persons {
+			for name := range person.Fields {
+				headerSet[name] = true
+			}
+		}
+	}
+	delete(headerSet, "voter_id")
+	fieldNames := slices.Collect(maps.Keys(headerSet))
+	slices.Sort(fieldNames)
+	table.Header = append(table.Header, fieldNames...)
+
+	// There is one row for each person, so that the canvasser can check off each of them.
+	for _, stop := range r.Stops {
+		for _, person := range stop.Persons {
+			row := []string{strconv.Itoa(stop.Stop), stop.Address, person.VoterID}
+			for _, name := range fieldNames {
+				row = append(row, person.Fields[name])
+			}
+			table.Rows = append(table.Rows, row)
+		}
+	}
+	return table, nil
+}
+
+// WalkListStop is a single door on a walk list.
+type WalkListStop struct {
+	Stop        int       `json:"stop"`                   // This is the position of the door on the route, starting at 1.
+	HouseholdID string    `json:"household_id,omitempty"` // This is empty if the person is not in a household.
+	Address     string    `json:"address"`
+	Coordinates string    `json:"coordinates,omitempty"` // This is empty if the door has no coordinates; those doors come last.
+	Distance    float64   `json:"distance"`              // This is the distance from the previous door, in meters.
+	Persons     []*Person `json:"persons"`
+}
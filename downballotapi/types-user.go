@@ -9,6 +9,14 @@ type RegisterUserRequest struct {
 // RegisterUserResponse is the response from registering a user
 type RegisterUserResponse User
 
+// VerifyUserRequest is the request to verify a user's e-mail address.
+type VerifyUserRequest struct {
+	Token string `json:"token"` // This is the token from the link in the verification e-mail.
+}
+
+// VerifyUserResponse is the response from verifying a user's e-mail address.
+type VerifyUserResponse User
+
 // ListUsersResponse is the response from listing the users.
 type ListUsersResponse struct {
 	Users []*User `json:"users"`
@@ -21,7 +29,8 @@ type GetUserResponse struct {
 
 // PatchOrganizationUserRequest is the request for patching an organization user.
 type PatchOrganizationUserRequest struct {
-	Owner *bool `json:"owner"`
+	Owner  *bool   `json:"owner"`
+	RoleID *string `json:"role_id"` // Set this to "" to remove the user's role.
 }
 
 // PatchOrganizationUserResponse is the response from patching an organization user.
@@ -34,5 +43,6 @@ type User struct {
 	ID       string `json:"id"`
 	Username string `json:"username"`
 	Name     string `json:"name"`
-	Owner    bool   `json:"owner"` // Whether the user is an owner of the organization.
+	Owner    bool   `json:"owner"`             // Whether the user is an owner of the organization.
+	RoleID   string `json:"role_id,omitempty"` // The user's role in the organization, if any.
 }
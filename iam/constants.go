@@ -32,6 +32,31 @@ const (
 	IAMPersonFieldDefinitionDelete permissionset.Permission = "person-field-definition:delete"
 	IAMPersonFieldDefinitionRead   permissionset.Permission = "person-field-definition:read"
 	IAMPersonFieldDefinitionUpdate permissionset.Permission = "person-field-definition:update"
+	IAMImportProfileCreate         permissionset.Permission = "import-profile:create"
+	IAMImportProfileDelete         permissionset.Permission = "import-profile:delete"
+	IAMImportProfileRead           permissionset.Permission = "import-profile:read"
+	IAMImportProfileUpdate         permissionset.Permission = "import-profile:update"
+	IAMContactResultCreate         permissionset.Permission = "contact-result:create"
+	IAMContactResultDelete         permissionset.Permission = "contact-result:delete"
+	IAMContactResultRead           permissionset.Permission = "contact-result:read"
+	IAMContactResultUpdate         permissionset.Permission = "contact-result:update"
+	IAMContactAttemptCreate        permissionset.Permission = "contact-attempt:create"
+	IAMContactAttemptRead          permissionset.Permission = "contact-attempt:read"
+	IAMHouseholdUpdate             permissionset.Permission = "household:update"
+	IAMTurfCreate                  permissionset.Permission = "turf:create"
+	IAMTurfDelete                  permissionset.Permission = "turf:delete"
+	IAMTurfRead                    permissionset.Permission = "turf:read"
+	IAMTurfUpdate                  permissionset.Permission = "turf:update"
+	IAMSurveyCreate                permissionset.Permission = "survey:create"
+	IAMSurveyDelete                permissionset.Permission = "survey:delete"
+	IAMSurveyRead                  permissionset.Permission = "survey:read"
+	IAMSurveyUpdate                permissionset.Permission = "survey:update"
+	IAMSurveyResponseCreate        permissionset.Permission = "survey-response:create"
+	IAMSurveyResponseRead          permissionset.Permission = "survey-response:read"
+	IAMRoleCreate                  permissionset.Permission = "role:create"
+	IAMRoleDelete                  permissionset.Permission = "role:delete"
+	IAMRoleRead                    permissionset.Permission = "role:read"
+	IAMRoleUpdate                  permissionset.Permission = "role:update"
 )
 
 // Permissions is the definitive list of all valid permissions.
@@ -66,4 +91,52 @@ var Permissions = []permissionset.Permission{
 	IAMPersonFieldDefinitionDelete,
 	IAMPersonFieldDefinitionRead,
 	IAMPersonFieldDefinitionUpdate,
+	IAMImportProfileCreate,
+	IAMImportProfileDelete,
+	IAMImportProfileRead,
+	IAMImportProfileUpdate,
+	IAMContactResultCreate,
+	IAMContactResultDelete,
+	IAMContactResultRead,
+	IAMContactResultUpdate,
+	IAMContactAttemptCreate,
+	IAMContactAttemptRead,
+	IAMHouseholdUpdate,
+	IAMTurfCreate,
+	IAMTurfDelete,
+	IAMTurfRead,
+	IAMTurfUpdate,
+	IAMSurveyCreate,
+	IAMSurveyDelete,
+	IAMSurveyRead,
+	IAMSurveyUpdate,
+	IAMSurveyResponseCreate,
+	IAMSurveyResponseRead,
+	IAMRoleCreate,
+	IAMRoleDelete,
+	IAMRoleRead,
+	IAMRoleUpdate,
+}
+
+// GroupOwnerPermissions are the permissions that the owner of a group has.
+//
+// These apply to the group and to every group beneath it, so that a group owner can manage their part
+// of the organization without being an owner of the organization itself.
+var GroupOwnerPermissions = []permissionset.Permission{
+	IAMGroupCreate,
+	IAMGroupDelete,
+	IAMGroupRead,
+	IAMGroupUpdate,
+	IAMGroupUserCreate,
+	IAMGroupUserDelete,
+	IAMGroupUserRead,
+	IAMGroupUserUpdate,
+	IAMPersonRead,
+	IAMPersonUpdate,
+	IAMContactAttemptCreate,
+	IAMContactAttemptRead,
+	IAMTurfRead,
+	IAMSurveyRead,
+	IAMSurveyResponseCreate,
+	IAMSurveyResponseRead,
 }
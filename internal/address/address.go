@@ -0,0 +1,98 @@
+// Package address normalizes street addresses so that the same address, written differently, can be recognized.
+package address
+
+import (
+	"strings"
+	"unicode"
+)
+
+// abbreviations map the words in an address to their standard (USPS) abbreviations.
+var abbreviations = map[string]string{
+	"NORTH":     "N",
+	"SOUTH":     "S",
+	"EAST":      "E",
+	"WEST":      "W",
+	"NORTHEAST": "NE",
+	"NORTHWEST": "NW",
+	"SOUTHEAST": "SE",
+	"SOUTHWEST": "SW",
+	"ALLEY":     "ALY",
+	"AVENUE":    "AVE",
+	"BOULEVARD": "BLVD",
+	"CIRCLE":    "CIR",
+	"COURT":     "CT",
+	"DRIVE":     "DR",
+	"HIGHWAY":   "HWY",
+	"LANE":      "LN",
+	"PARKWAY":   "PKWY",
+	"PLACE":     "PL",
+	"ROAD":      "RD",
+	"SQUARE":    "SQ",
+	"STREET":    "ST",
+	"TERRACE":   "TER",
+	"TRAIL":     "TRL",
+	"APARTMENT": "APT",
+	"BUILDING":  "BLDG",
+	"FLOOR":     "FL",
+	"SUITE":     "STE",
+	"#":         "UNIT",
+}
+
+// Key returns the normalized form of an address.
+//
+// Two addresses that differ only in case, punctuation, spacing, abbreviations, or the ZIP+4 extension have the same
+// key.  The unit (such as an apartment number) is kept, since each unit is its own door.  If the address is blank,
+// then the key is empty.
+func Key(address string) string {
+	address = strings.ToUpper(address)
+
+	// Keep "#" as its own word, so that "APT #2" and "#2" both become a unit.
+	address = strings.ReplaceAll(address, "#", " # ")
+
+	words := strings.FieldsFunc(address, func(r rune) bool {
+		switch r {
+		case '#', '-':
+			return false
+		}
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	})
+
+	var output []string
+	for index, word := range words {
+		// Drop the ZIP+4 extension.
+		if zipCode, _, ok := strings.Cut(word, "-"); ok && index == len(words)-1 && isZIPCode(zipCode) {
+			word = zipCode
+		}
+		if abbreviation, ok := abbreviations[word]; ok {
+			word = abbreviation
+		}
+		// A unit that is given as "APT #2" is the same as "APT 2".
+		if word == "UNIT" && len(output) > 0 && isUnitType(output[len(output)-1]) {
+			continue
+		}
+		output = append(output, word)
+	}
+	return strings.Join(output, " ")
+}
+
+// isZIPCode returns true if the word is a five-digit ZIP code.
+func isZIPCode(word string) bool {
+	if len(word) != 5 {
+		return false
+	}
+	for _, r := range word {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isUnitType returns true if the word is a (normalized) unit type, such as "APT".
+func isUnitType(word string) bool {
+	switch word {
+	case "APT", "BLDG", "FL", "STE", "UNIT":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package address
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKey(t *testing.T) {
+	rows := []struct {
+		description string
+		input       string
+		output      string
+	}{
+		{"Empty", "", ""},
+		{"Blank", "  ,  ", ""},
+		{"Already normalized", "1 MAIN ST, NEWARK, DE 19711", "1 MAIN ST NEWARK DE 19711"},
+		{"Case and spacing", "1  Main st,Newark , de 19711", "1 MAIN ST NEWARK DE 19711"},
+		{"Abbreviations", "1 North Main Street, Newark, DE 19711", "1 N MAIN ST NEWARK DE 19711"},
+		{"Punctuation", "1 Main St., Newark, DE 19711", "1 MAIN ST NEWARK DE 19711"},
+		{"ZIP+4", "1 MAIN ST, NEWARK, DE 19711-1234", "1 MAIN ST NEWARK DE 19711"},
+		{"Apartment", "1 MAIN ST APARTMENT 2, NEWARK, DE 19711", "1 MAIN ST APT 2 NEWARK DE 19711"},
+		{"Apartment with a number sign", "1 MAIN ST APT #2, NEWARK, DE 19711", "1 MAIN ST APT 2 NEWARK DE 19711"},
+		{"Number sign alone", "1 MAIN ST #2, NEWARK, DE 19711", "1 MAIN ST UNIT 2 NEWARK DE 19711"},
+		{"Hyphenated house number", "12-14 MAIN ST, NEWARK, DE 19711", "12-14 MAIN ST NEWARK DE 19711"},
+	}
+	for rowIndex, row := range rows {
+		t.Run(fmt.Sprintf("%d/%s", rowIndex, row.description), func(t *testing.T) {
+			assert.Equal(t, row.output, Key(row.input))
+		})
+	}
+}
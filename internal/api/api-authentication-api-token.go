@@ -0,0 +1,178 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/apitoken"
+	"github.com/downballot/downballot/internal/durationparser"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/downballot/downballot/internal/schema/sqltype"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+type GetAuthenticationAPITokenMetadata struct {
+	restfulwrapper.HTTPMethodGET
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	_ string `api:"httppath:/authentication/api-token"`
+	_ string `api:"doc" description:"List the API tokens."`
+	_ string `api:"notes" description:"This lists the current user's personal API tokens that have neither expired nor been revoked.  The tokens themselves are never returned."`
+}
+
+func (a *API) GetAuthenticationAPIToken(ctx context.Context, meta GetAuthenticationAPITokenMetadata) (output downballotapi.Envelope[downballotapi.ListAPITokensResponse], err error) {
+	err = requireInteractiveUser(&meta.CurrentUser)
+	if err != nil {
+		return output, err
+	}
+
+	var apiTokens []*schema.APIToken
+	err = meta.DB.Session(&gorm.Session{}).
+		Where("user_id = ?", meta.CurrentUser.ID).
+		Where("revoked_timestamp IS NULL").
+		Where("expiration_timestamp IS NULL OR expiration_timestamp > ?", sqltype.DateTime(time.Now())).
+		Order("name ASC").
+		Order("id ASC").
+		Find(&apiTokens).
+		Error
+	if err != nil {
+		return output, fmt.Errorf("could not find API tokens: %w", err)
+	}
+
+	output.Message = "OK"
+	output.Success = true
+	output.Data.APITokens = []*downballotapi.APIToken{}
+	for _, apiToken := range apiTokens {
+		output.Data.APITokens = append(output.Data.APITokens, convertAPIToken(apiToken))
+	}
+	return output, nil
+}
+
+type PostAuthenticationAPITokenMetadata struct {
+	restfulwrapper.HTTPMethodPOST
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	_        string                              `api:"httppath:/authentication/api-token"`
+	_        string                              `api:"doc" description:"Create an API token."`
+	_        string                              `api:"notes" description:"This creates a personal API token for an integration.  The token acts as the current user, but only within the given organization and only with the given permissions (such as 'person:read'); it can always read the organization itself.  The token is only returned once."`
+	Body     downballotapi.CreateAPITokenRequest `api:"body"`
+	Lifetime *string                             `api:"query:lifetime"`
+}
+
+func (a *API) PostAuthenticationAPIToken(ctx context.Context, meta PostAuthenticationAPITokenMetadata) (output downballotapi.Envelope[downballotapi.CreateAPITokenResponse], err error) {
+	err = requireInteractiveUser(&meta.CurrentUser)
+	if err != nil {
+		return output, err
+	}
+	if meta.CurrentUser.SystemAdmin {
+		return output, restfulwrapper.NewAPIResponseError(http.StatusBadRequest, "The system user cannot create API tokens")
+	}
+
+	if meta.Body.Name == "" {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing name"))
+	}
+	if len(meta.Body.Permissions) == 0 {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing permissions"))
+	}
+	err = validatePermissions(meta.Body.Permissions)
+	if err != nil {
+		return output, restfulwrapper.NewAPIBodyError(err)
+	}
+
+	now := time.Now()
+	var expirationTimestamp *sqltype.DateTime
+	if meta.Lifetime != nil {
+		expirationDate, err := durationparser.Parse(now, *meta.Lifetime)
+		if err != nil {
+			return output, restfulwrapper.NewAPIQueryParameterError("lifetime", fmt.Errorf("could not parse 'lifetime' value %q: %v", *meta.Lifetime, err))
+		}
+		if expirationDate != nil {
+			t := sqltype.DateTime(*expirationDate)
+			expirationTimestamp = &t
+		}
+	}
+
+	// The organization must be one that the user can see.
+	var organizations []*schema.Organization
+	err = meta.DB.Session(&gorm.Session{}).
+		Where("id = ?", meta.Body.OrganizationID).
+		Find(&organizations).
+		Error
+	if err != nil {
+		return output, fmt.Errorf("could not find organization: %w", err)
+	}
+	if len(organizations) == 0 {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("invalid organization_id"))
+	}
+
+	token, err := apitoken.NewPersonalToken()
+	if err != nil {
+		return output, err
+	}
+
+	apiToken := schema.APIToken{
+		UserID:              meta.CurrentUser.ID,
+		OrganizationID:      organizations[0].ID,
+		Name:                meta.Body.Name,
+		Permissions:         meta.Body.Permissions,
+		TokenHash:           apitoken.HashPersonalToken(token),
+		CreatedTimestamp:    sqltype.DateTime(now),
+		ExpirationTimestamp: expirationTimestamp,
+	}
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		return tx.Session(&gorm.Session{NewDB: true}).
+			Create(&apiToken).
+			Error
+	})
+	if err != nil {
+		return output, fmt.Errorf("could not create API token: %w", err)
+	}
+
+	output.Message = "OK"
+	output.Success = true
+	output.Data.APIToken = convertAPIToken(&apiToken)
+	output.Data.Token = token
+	return output, nil
+}
+
+type DeleteAuthenticationAPITokenIDMetadata struct {
+	restfulwrapper.HTTPMethodDELETE
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	APITokenID string `api:"path:api_token_id" description:"The API token ID"`
+	_          string `api:"httppath:/authentication/api-token/{api_token_id}"`
+	_          string `api:"doc" description:"Revoke an API token."`
+	_          string `api:"notes" description:"This revokes one of the current user's personal API tokens."`
+}
+
+func (a *API) DeleteAuthenticationAPITokenID(ctx context.Context, meta DeleteAuthenticationAPITokenIDMetadata) error {
+	err := requireInteractiveUser(&meta.CurrentUser)
+	if err != nil {
+		return err
+	}
+
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Session(&gorm.Session{NewDB: true}).
+			Model(&schema.APIToken{}).
+			Where("id = ?", meta.APITokenID).
+			Where("user_id = ?", meta.CurrentUser.ID).
+			Where("revoked_timestamp IS NULL").
+			Update("revoked_timestamp", sqltype.DateTime(time.Now()))
+		if result.Error != nil {
+			return fmt.Errorf("could not revoke API token: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return restfulwrapper.NewAPIResponseError(http.StatusNotFound, "API token not found")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,304 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/downballot/downballot/internal/schema/sqltype"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+type GetAuthenticationAuthenticatorMetadata struct {
+	restfulwrapper.HTTPMethodGET
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	_ string `api:"httppath:/authentication/authenticator"`
+	_ string `api:"doc" description:"Get the authenticator app status."`
+	_ string `api:"notes" description:"This reports whether the current user has enrolled an authenticator app as a second factor and how many recovery codes are left."`
+}
+
+func (a *API) GetAuthenticationAuthenticator(ctx context.Context, meta GetAuthenticationAuthenticatorMetadata) (output downballotapi.Envelope[downballotapi.AuthenticatorStatusResponse], err error) {
+	err = requireInteractiveUser(&meta.CurrentUser)
+	if err != nil {
+		return output, err
+	}
+
+	authenticator, err := findAuthenticator(meta.DB, meta.CurrentUser.ID, false)
+	if err != nil {
+		return output, err
+	}
+
+	var recoveryCodesRemaining int64
+	err = meta.DB.Session(&gorm.Session{}).
+		Model(&schema.UserRecoveryCode{}).
+		Where("user_id = ?", meta.CurrentUser.ID).
+		Where("used_timestamp IS NULL").
+		Count(&recoveryCodesRemaining).
+		Error
+	if err != nil {
+		return output, fmt.Errorf("could not count recovery codes: %w", err)
+	}
+
+	output.Message = "OK"
+	output.Success = true
+	output.Data.Enrolled = authenticator != nil
+	output.Data.Confirmed = authenticator != nil && authenticator.ConfirmedTimestamp != nil
+	output.Data.RecoveryCodesRemaining = int(recoveryCodesRemaining)
+	return output, nil
+}
+
+type PostAuthenticationAuthenticatorMetadata struct {
+	restfulwrapper.HTTPMethodPOST
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	_    string                                   `api:"httppath:/authentication/authenticator"`
+	_    string                                   `api:"doc" description:"Start enrolling an authenticator app."`
+	_    string                                   `api:"notes" description:"This generates a new secret for an authenticator app and returns it along with the provisioning URI (for a QR code).  The app is not required to log in until it has been confirmed with a code from it.  This fails if the current user already has a confirmed authenticator app."`
+	Body downballotapi.EnrollAuthenticatorRequest `api:"body"`
+}
+
+func (a *API) PostAuthenticationAuthenticator(ctx context.Context, meta PostAuthenticationAuthenticatorMetadata) (output downballotapi.Envelope[downballotapi.EnrollAuthenticatorResponse], err error) {
+	err = requireInteractiveUser(&meta.CurrentUser)
+	if err != nil {
+		return output, err
+	}
+	if meta.CurrentUser.SystemAdmin {
+		return output, restfulwrapper.NewAPIResponseError(http.StatusBadRequest, "The system user cannot enroll an authenticator app")
+	}
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      authenticatorIssuer,
+		AccountName: meta.CurrentUser.EmailAddress,
+		Period:      uint(AuthenticatorPeriod),
+		Digits:      AuthenticatorDigits,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return output, fmt.Errorf("could not generate authenticator key: %w", err)
+	}
+
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		authenticator, err := findAuthenticator(tx, meta.CurrentUser.ID, false)
+		if err != nil {
+			return err
+		}
+		if authenticator != nil {
+			if authenticator.ConfirmedTimestamp != nil {
+				return restfulwrapper.NewAPIResponseError(http.StatusConflict, "An authenticator app is already enrolled")
+			}
+
+			// Start over with a new secret.
+			err = tx.Session(&gorm.Session{NewDB: true}).
+				Delete(authenticator).
+				Error
+			if err != nil {
+				return fmt.Errorf("could not delete authenticator: %w", err)
+			}
+		}
+
+		return tx.Session(&gorm.Session{NewDB: true}).
+			Create(&schema.UserAuthenticator{
+				UserID:           meta.CurrentUser.ID,
+				Secret:           sqltype.EncryptedString(key.Secret()),
+				CreatedTimestamp: sqltype.DateTime(time.Now()),
+			}).
+			Error
+	})
+	if err != nil {
+		return output, err
+	}
+
+	output.Message = "OK"
+	output.Success = true
+	output.Data.Secret = key.Secret()
+	output.Data.ProvisioningURI = key.URL()
+	return output, nil
+}
+
+type PostAuthenticationAuthenticatorConfirmMetadata struct {
+	restfulwrapper.HTTPMethodPOST
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	_    string                                    `api:"httppath:/authentication/authenticator/confirm"`
+	_    string                                    `api:"doc" description:"Finish enrolling an authenticator app."`
+	_    string                                    `api:"notes" description:"This confirms the authenticator app with a code from it.  From then on, a code from the app (or a recovery code) is required to log in.  The recovery codes are only returned once."`
+	Body downballotapi.ConfirmAuthenticatorRequest `api:"body"`
+}
+
+func (a *API) PostAuthenticationAuthenticatorConfirm(ctx context.Context, meta PostAuthenticationAuthenticatorConfirmMetadata) (output downballotapi.Envelope[downballotapi.RecoveryCodesResponse], err error) {
+	err = requireInteractiveUser(&meta.CurrentUser)
+	if err != nil {
+		return output, err
+	}
+	if meta.Body.Code == "" {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing code"))
+	}
+
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		authenticator, err := findAuthenticator(tx, meta.CurrentUser.ID, false)
+		if err != nil {
+			return err
+		}
+		if authenticator == nil {
+			return restfulwrapper.NewAPIResponseError(http.StatusNotFound, "No authenticator app is being enrolled")
+		}
+		if authenticator.ConfirmedTimestamp != nil {
+			return restfulwrapper.NewAPIResponseError(http.StatusConflict, "The authenticator app has already been confirmed")
+		}
+
+		err = useAuthenticatorCode(tx, authenticator, meta.Body.Code)
+		if err != nil {
+			if errors.Is(err, errInvalidAuthenticatorCode) {
+				return restfulwrapper.NewAPIBodyError(err)
+			}
+			return err
+		}
+
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Model(&schema.UserAuthenticator{}).
+			Where("id = ?", authenticator.ID).
+			Update("confirmed_timestamp", sqltype.DateTime(time.Now())).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not update authenticator: %w", err)
+		}
+
+		output.Data.RecoveryCodes, err = newRecoveryCodes(tx, meta.CurrentUser.ID)
+		return err
+	})
+	if err != nil {
+		return output, err
+	}
+
+	output.Message = "OK"
+	output.Success = true
+	return output, nil
+}
+
+type PostAuthenticationAuthenticatorRecoveryCodesMetadata struct {
+	restfulwrapper.HTTPMethodPOST
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	_    string                             `api:"httppath:/authentication/authenticator/recovery-codes"`
+	_    string                             `api:"doc" description:"Replace the recovery codes."`
+	_    string                             `api:"notes" description:"This replaces the current user's recovery codes with new ones; the old ones stop working.  A code from the authenticator app is required; after too many invalid codes, the user is locked out for a while.  The recovery codes are only returned once."`
+	Body downballotapi.RecoveryCodesRequest `api:"body"`
+}
+
+func (a *API) PostAuthenticationAuthenticatorRecoveryCodes(ctx context.Context, meta PostAuthenticationAuthenticatorRecoveryCodesMetadata) (output downballotapi.Envelope[downballotapi.RecoveryCodesResponse], err error) {
+	err = requireInteractiveUser(&meta.CurrentUser)
+	if err != nil {
+		return output, err
+	}
+	if meta.Body.Code == "" {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing code"))
+	}
+
+	err = reserveAuthenticatorAttempt(meta.DB, meta.CurrentUser.ID)
+	if err != nil {
+		return output, err
+	}
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		authenticator, err := findAuthenticator(tx, meta.CurrentUser.ID, true)
+		if err != nil {
+			return err
+		}
+		if authenticator == nil {
+			return restfulwrapper.NewAPIResponseError(http.StatusNotFound, "No authenticator app is enrolled")
+		}
+
+		err = useAuthenticatorCode(tx, authenticator, meta.Body.Code)
+		if err != nil {
+			return err
+		}
+
+		output.Data.RecoveryCodes, err = newRecoveryCodes(tx, meta.CurrentUser.ID)
+		return err
+	})
+	finishErr := finishAuthenticatorAttempt(meta.DB, meta.CurrentUser.ID, err)
+	if finishErr != nil {
+		return output, finishErr
+	}
+	if err != nil {
+		if errors.Is(err, errInvalidAuthenticatorCode) {
+			return output, restfulwrapper.NewAPIBodyError(err)
+		}
+		return output, err
+	}
+
+	output.Message = "OK"
+	output.Success = true
+	return output, nil
+}
+
+type DeleteAuthenticationAuthenticatorMetadata struct {
+	restfulwrapper.HTTPMethodDELETE
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	_    string `api:"httppath:/authentication/authenticator"`
+	_    string `api:"doc" description:"Remove the authenticator app."`
+	_    string `api:"notes" description:"This removes the current user's authenticator app and recovery codes, so that only the e-mailed one-time password is required to log in.  Once the app has been confirmed, a code from it (or a recovery code) is required; after too many invalid codes, the user is locked out for a while."`
+	Code string `api:"query:code" description:"A code from the authenticator app or a recovery code."`
+}
+
+func (a *API) DeleteAuthenticationAuthenticator(ctx context.Context, meta DeleteAuthenticationAuthenticatorMetadata) error {
+	err := requireInteractiveUser(&meta.CurrentUser)
+	if err != nil {
+		return err
+	}
+
+	err = reserveAuthenticatorAttempt(meta.DB, meta.CurrentUser.ID)
+	if err != nil {
+		return err
+	}
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		authenticator, err := findAuthenticator(tx, meta.CurrentUser.ID, false)
+		if err != nil {
+			return err
+		}
+		if authenticator == nil {
+			return restfulwrapper.NewAPIResponseError(http.StatusNotFound, "No authenticator app is enrolled")
+		}
+
+		// Otherwise, a stolen token would be enough to take away the second factor.
+		err = verifySecondFactor(tx, meta.CurrentUser.ID, meta.Code)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Delete(authenticator).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not delete authenticator: %w", err)
+		}
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Where("user_id = ?", meta.CurrentUser.ID).
+			Delete(&schema.UserRecoveryCode{}).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not delete recovery codes: %w", err)
+		}
+		return nil
+	})
+	finishErr := finishAuthenticatorAttempt(meta.DB, meta.CurrentUser.ID, err)
+	if finishErr != nil {
+		return finishErr
+	}
+	if err != nil {
+		if errors.Is(err, errAuthenticatorCodeRequired) || errors.Is(err, errInvalidAuthenticatorCode) {
+			return restfulwrapper.NewAPIQueryParameterError("code", err)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/apitoken"
+	"github.com/downballot/downballot/internal/durationparser"
+	"github.com/downballot/downballot/internal/httpextra"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+// OIDC start does not accept authentication, since it is what creates authentication.
+type PostAuthenticationOIDCStartMetadata struct {
+	restfulwrapper.HTTPMethodPOST
+	_    string                         `api:"httppath:/authentication/oidc/start"`
+	_    string                         `api:"doc" description:"Start logging in with the identity provider."`
+	_    string                         `api:"notes" description:"This starts an OpenID Connect login.  Send the user to the authorization URL; the identity provider will send them back to the application with a code and the state, which are then used to finish logging in along with the same client nonce."`
+	Body downballotapi.OIDCStartRequest `api:"body"`
+}
+
+func (a *API) PostAuthenticationOIDCStart(ctx context.Context, meta PostAuthenticationOIDCStartMetadata) (output downballotapi.Envelope[downballotapi.OIDCStartResponse], err error) {
+	if a.oidc == nil {
+		return output, restfulwrapper.NewAPIResponseError(http.StatusNotFound, "OpenID Connect is not configured")
+	}
+
+	state, nonce, err := a.newOIDCState(meta.Body.ClientNonce)
+	if err != nil {
+		if errors.Is(err, errInvalidOIDCClientNonce) {
+			return output, restfulwrapper.NewAPIBodyError(err)
+		}
+		return output, err
+	}
+	authorizationURL, err := a.oidc.AuthorizationURL(ctx, state, nonce)
+	if err != nil {
+		return output, err
+	}
+
+	output.Message = "OK"
+	output.Success = true
+	output.Data.AuthorizationURL = authorizationURL
+	return output, nil
+}
+
+// OIDC login does not accept authentication, since it is what creates authentication.
+type PostAuthenticationOIDCLoginMetadata struct {
+	restfulwrapper.HTTPMethodPOST
+	downballotwrapper.UseDatabase
+	_           string                         `api:"httppath:/authentication/oidc/login"`
+	_           string                         `api:"doc" description:"Finish logging in with the identity provider."`
+	_           string                         `api:"notes" description:"This finishes an OpenID Connect login.  The identity provider must have verified the user's e-mail address, which must belong to an existing, verified user.  The client nonce must be the one that was used to start logging in.  If the user has enrolled an authenticator app, then a code from it (or a recovery code) is also required.  Organization owners must have an authenticator app; one that has not enrolled yet can do so here by giving the app's secret along with a code from it, and the recovery codes are returned.  Upon completion, this will provide the user with an API token that can be used in subsequent calls."`
+	Body        downballotapi.OIDCLoginRequest `api:"body"`
+	Lifetime    *string                        `api:"query:lifetime"`
+	HTTPRequest *http.Request                  `api:"httprequest"`
+}
+
+func (a *API) PostAuthenticationOIDCLogin(ctx context.Context, meta PostAuthenticationOIDCLoginMetadata) (output downballotapi.Envelope[downballotapi.LoginResponse], err error) {
+	if a.oidc == nil {
+		return output, restfulwrapper.NewAPIResponseError(http.StatusNotFound, "OpenID Connect is not configured")
+	}
+	if meta.Body.Code == "" {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing code"))
+	}
+
+	nonce, err := a.parseOIDCState(meta.Body.State, meta.Body.ClientNonce)
+	if err != nil {
+		if errors.Is(err, errInvalidOIDCState) {
+			return output, restfulwrapper.NewAPIBodyError(err)
+		}
+		return output, err
+	}
+
+	claims := apitoken.TokenClaims{}
+	if meta.Lifetime != nil {
+		expirationDate, err := durationparser.Parse(time.Now(), *meta.Lifetime)
+		if err != nil {
+			return output, restfulwrapper.NewAPIQueryParameterError("lifetime", fmt.Errorf("could not parse 'lifetime' value %q: %v", *meta.Lifetime, err))
+		}
+		if expirationDate != nil {
+			claims.ExpiresAt = expirationDate.Unix()
+		}
+	}
+
+	identity, err := a.oidc.Exchange(ctx, meta.Body.Code, nonce)
+	if err != nil {
+		slog.WarnContext(ctx, fmt.Sprintf("Could not log in with the identity provider: %v", err))
+		return output, restfulwrapper.NewAPIResponseError(http.StatusUnauthorized, "Could not log in with the identity provider")
+	}
+	if identity.Email == "" || !identity.EmailVerified {
+		return output, restfulwrapper.NewAPIResponseError(http.StatusUnauthorized, "The identity provider has not verified the e-mail address")
+	}
+
+	var users []*schema.User
+	err = meta.DB.Session(&gorm.Session{}).
+		Where("username = ?", identity.Email).
+		Find(&users).
+		Error
+	if err != nil {
+		return output, err
+	}
+	if len(users) == 0 {
+		return output, restfulwrapper.NewAPIResponseError(http.StatusUnauthorized, "No user has this e-mail address")
+	}
+	user := users[0]
+
+	// Anyone can register an unverified user with someone else's address, so the user has to be verified the usual way.
+	if !user.Verified {
+		return output, restfulwrapper.NewAPIResponseError(http.StatusUnauthorized, "The user has not been verified")
+	}
+
+	// The identity provider only replaces the e-mailed password, not the authenticator app.
+	var recoveryCodes []string
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		recoveryCodes, err = verifyLoginSecondFactor(tx, user.ID, meta.Body.AuthenticatorCode, meta.Body.AuthenticatorSecret)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, errAuthenticatorCodeRequired) {
+			return output, restfulwrapper.NewAPIResponseError(http.StatusUnauthorized, "Authenticator code required")
+		}
+		if errors.Is(err, errAuthenticatorEnrollmentRequired) {
+			return output, restfulwrapper.NewAPIResponseError(http.StatusUnauthorized, "Authenticator enrollment required")
+		}
+		if errors.Is(err, errInvalidAuthenticatorSecret) {
+			return output, restfulwrapper.NewAPIBodyError(err)
+		}
+		if errors.Is(err, errInvalidAuthenticatorCode) {
+			return output, restfulwrapper.NewAPIResponseError(http.StatusUnauthorized, "Invalid authenticator code")
+		}
+		return output, err
+	}
+
+	claims.Subject = user.Username
+	claims.SessionIdentifier = user.SessionIdentifier
+
+	sourceAddress, err := httpextra.GetRequestSource(meta.HTTPRequest)
+	if err != nil {
+		slog.WarnContext(ctx, fmt.Sprintf("Could not determine the source of the request: %v", err))
+	}
+	tokenString, err := a.issueSessionToken(meta.DB, user.ID, claims, meta.HTTPRequest.UserAgent(), sourceAddress)
+	if err != nil {
+		return output, err
+	}
+
+	output.Message = "OK"
+	output.Success = true
+	output.Data.UserID = user.Username
+	output.Data.Token = tokenString
+	output.Data.RecoveryCodes = recoveryCodes
+	return output, nil
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/api/resttype"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/downballot/downballot/internal/schema/sqltype"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+type GetAuthenticationSessionMetadata struct {
+	restfulwrapper.HTTPMethodGET
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	_ string `api:"httppath:/authentication/session"`
+	_ string `api:"doc" description:"List the sessions."`
+	_ string `api:"notes" description:"This lists the current user's active sessions; that is, the tokens that have neither expired nor been revoked."`
+}
+
+func (a *API) GetAuthenticationSession(ctx context.Context, meta GetAuthenticationSessionMetadata) (output downballotapi.Envelope[downballotapi.ListSessionsResponse], err error) {
+	err = requireInteractiveUser(&meta.CurrentUser)
+	if err != nil {
+		return output, err
+	}
+
+	now := sqltype.DateTime(time.Now())
+
+	var sessions []*schema.Session
+	err = meta.DB.Session(&gorm.Session{}).
+		Where("user_id = ?", meta.CurrentUser.ID).
+		Where("revoked_timestamp IS NULL").
+		Where("expiration_timestamp IS NULL OR expiration_timestamp > ?", now).
+		Order("created_timestamp DESC").
+		Order("id DESC").
+		Find(&sessions).
+		Error
+	if err != nil {
+		return output, fmt.Errorf("could not find sessions: %w", err)
+	}
+
+	output.Message = "OK"
+	output.Success = true
+	output.Data.Sessions = []*downballotapi.Session{}
+	for _, session := range sessions {
+		s := &downballotapi.Session{
+			ID:               fmt.Sprintf("%d", session.ID),
+			CreatedTimestamp: resttype.DateTime(session.CreatedTimestamp),
+			UserAgent:        session.UserAgent,
+			SourceAddress:    session.SourceAddress,
+			Current:          session.ID == meta.CurrentUser.SessionID,
+		}
+		if session.ExpirationTimestamp != nil {
+			expirationTimestamp := resttype.DateTime(*session.ExpirationTimestamp)
+			s.ExpirationTimestamp = &expirationTimestamp
+		}
+		output.Data.Sessions = append(output.Data.Sessions, s)
+	}
+	return output, nil
+}
+
+type DeleteAuthenticationSessionIDMetadata struct {
+	restfulwrapper.HTTPMethodDELETE
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	SessionID string `api:"path:session_id" description:"The session ID"`
+	_         string `api:"httppath:/authentication/session/{session_id}"`
+	_         string `api:"doc" description:"Revoke a session."`
+	_         string `api:"notes" description:"This revokes one of the current user's sessions.  The token for that session stops working; the user's other sessions are unaffected."`
+}
+
+func (a *API) DeleteAuthenticationSessionID(ctx context.Context, meta DeleteAuthenticationSessionIDMetadata) error {
+	err := requireInteractiveUser(&meta.CurrentUser)
+	if err != nil {
+		return err
+	}
+
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Session(&gorm.Session{NewDB: true}).
+			Model(&schema.Session{}).
+			Where("id = ?", meta.SessionID).
+			Where("user_id = ?", meta.CurrentUser.ID).
+			Where("revoked_timestamp IS NULL").
+			Update("revoked_timestamp", sqltype.DateTime(time.Now()))
+		if result.Error != nil {
+			return fmt.Errorf("could not revoke session: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return restfulwrapper.NewAPIResponseError(http.StatusNotFound, "Session not found")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -2,22 +2,21 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
-	"net/mail"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/downballot/downballot/downballotapi"
 	"github.com/downballot/downballot/internal/api/downballotwrapper"
 	"github.com/downballot/downballot/internal/apitoken"
 	"github.com/downballot/downballot/internal/durationparser"
+	"github.com/downballot/downballot/internal/httpextra"
 	"github.com/downballot/downballot/internal/schema"
 	"github.com/downballot/downballot/internal/schema/sqltype"
 	"github.com/pquerna/otp"
 	"github.com/pquerna/otp/totp"
-	"github.com/tekkamanendless/go-mailer"
 	"github.com/tekkamanendless/restfulwrapper"
 	"gorm.io/gorm"
 )
@@ -40,6 +39,14 @@ type PostAuthenticationEmailMetadata struct {
 func (a *API) PostAuthenticationEmail(ctx context.Context, meta PostAuthenticationEmailMetadata) (output downballotapi.Envelope[downballotapi.EmailResponse], err error) {
 	slog.InfoContext(ctx, fmt.Sprintf("Email: %s", meta.Body.Email))
 
+	// Count the e-mail whether or not the address belongs to a user so that the response doesn't reveal which users exist.
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		return throttleEmail(tx, meta.Body.Email)
+	})
+	if err != nil {
+		return output, err
+	}
+
 	var users []*schema.User
 	err = meta.DB.Session(&gorm.Session{}).
 		Where("username = ?", meta.Body.Email).
@@ -49,8 +56,8 @@ func (a *API) PostAuthenticationEmail(ctx context.Context, meta PostAuthenticati
 		return output, err
 	}
 
-	if len(users) == 0 {
-		// This isn't a valid user.
+	if len(users) == 0 || !users[0].Verified {
+		// This isn't a valid user (or it hasn't been verified yet).
 
 		// Don't do anything different; send the same message.
 	} else {
@@ -122,20 +129,9 @@ func (a *API) PostAuthenticationEmail(ctx context.Context, meta PostAuthenticati
 		slog.InfoContext(ctx, fmt.Sprintf("One-time password: %s", oneTimePassword))
 
 		// Send the e-mail.
-		if a.mailer == nil {
-			slog.WarnContext(ctx, "No mailer configured; could not send e-mail.")
-		} else {
-			err = a.mailer.SendMail(ctx, mailer.Message{
-				From: mail.Address{
-					Name:    "Downballot",
-					Address: "noreply@app.downballot.io",
-				},
-				To: mail.Address{
-					Address: meta.Body.Email,
-				},
-				Subject:       "Your Downballot one-time password",
-				BodyPlainText: fmt.Sprintf("Your one-time password is:\n\n%s", oneTimePassword),
-			})
+		err = a.sendEmail(ctx, meta.Body.Email, "Your Downballot one-time password", fmt.Sprintf("Your one-time password is:\n\n%s", oneTimePassword))
+		if err != nil {
+			slog.WarnContext(ctx, fmt.Sprintf("Could not send e-mail: %v", err))
 		}
 	}
 
@@ -151,11 +147,12 @@ type PostAuthenticationLoginMetadata struct {
 	restfulwrapper.HTTPMethodPOST
 	downballotwrapper.MayHaveAuthenticatedUser
 	downballotwrapper.UseDatabase
-	_        string                     `api:"httppath:/authentication/login"`
-	_        string                     `api:"doc" description:"Log in."`
-	_        string                     `api:"notes" description:"This attempts to log in the user with a username and password.  Upon completion, this will provide the user with an API token that can be used in subsequent calls."`
-	Body     downballotapi.LoginRequest `api:"body"`
-	Lifetime *string                    `api:"query:lifetime"`
+	_           string                     `api:"httppath:/authentication/login"`
+	_           string                     `api:"doc" description:"Log in."`
+	_           string                     `api:"notes" description:"This attempts to log in the user with a username and password.  If the user has enrolled an authenticator app, then a code from it (or a recovery code) is also required.  Organization owners must have an authenticator app; one that has not enrolled yet can do so here by giving the app's secret along with a code from it, and the recovery codes are returned.  Upon completion, this will provide the user with an API token that can be used in subsequent calls."`
+	Body        downballotapi.LoginRequest `api:"body"`
+	Lifetime    *string                    `api:"query:lifetime"`
+	HTTPRequest *http.Request              `api:"httprequest"`
 }
 
 func (a *API) PostAuthenticationLogin(ctx context.Context, meta PostAuthenticationLoginMetadata) (output downballotapi.Envelope[downballotapi.LoginResponse], err error) {
@@ -178,12 +175,68 @@ func (a *API) PostAuthenticationLogin(ctx context.Context, meta PostAuthenticati
 	}
 	slog.InfoContext(ctx, fmt.Sprintf("Expiration date: %v", claims.ExpiresAt))
 
+	sourceAddress, err := httpextra.GetRequestSource(meta.HTTPRequest)
+	if err != nil {
+		slog.WarnContext(ctx, fmt.Sprintf("Could not determine the source of the request: %v", err))
+	}
+
+	var userID uint64
+	var recoveryCodes []string // These are only set if an authenticator app was enrolled while logging in.
 	if meta.CurrentUser != nil {
-		claims.Subject = meta.CurrentUser.EmailAddress
-		claims.SessionIdentifier = meta.CurrentUser.ID
+		err = requireInteractiveUser(meta.CurrentUser)
+		if err != nil {
+			return output, err
+		}
+
+		var users []schema.User
+		err = meta.DB.Session(&gorm.Session{}).
+			Where("id = ?", meta.CurrentUser.ID).
+			Find(&users).
+			Error
+		if err != nil {
+			return output, err
+		}
+		if len(users) == 0 {
+			// This is the system user, which cannot have a token.
+			return output, restfulwrapper.NewAPIResponseError(http.StatusUnauthorized, "")
+		}
+
+		userID = users[0].ID
+		claims.Subject = users[0].Username
+		claims.SessionIdentifier = users[0].SessionIdentifier
 
 		slog.InfoContext(ctx, fmt.Sprintf("This request is already authenticated as: %s", claims.Subject))
 	} else if meta.Body.Username != "" && meta.Body.Password != "" {
+		// Count the attempt as a failure before even looking at the password, so that parallel guesses cannot get past
+		// the lockout.  An attempt that turns out not to be a failure is taken back.
+		if sourceAddress != "" {
+			err = reserveLoginAttempt(meta.DB, throttleKindLoginSource, sourceAddress, loginSourceMaxFailures)
+			if err != nil {
+				return output, err
+			}
+		}
+
+		// releaseSource takes back the attempt for the source address.
+		releaseSource := func() error {
+			if sourceAddress == "" {
+				return nil
+			}
+			return releaseLoginAttempt(meta.DB, throttleKindLoginSource, sourceAddress, loginSourceMaxFailures)
+		}
+
+		err = reserveLoginAttempt(meta.DB, throttleKindLoginUsername, meta.Body.Username, loginUsernameMaxFailures)
+		if err != nil {
+			// The password was never checked, so this attempt must not count against the source address either.
+			releaseErr := releaseSource()
+			if releaseErr != nil {
+				return output, releaseErr
+			}
+			return output, err
+		}
+
+		// Every failure looks the same, so that the response does not reveal whether the user exists or is verified.
+		loginFailed := restfulwrapper.NewAPIResponseError(http.StatusUnauthorized, "Invalid username or password")
+
 		var users []schema.User
 		err = meta.DB.Session(&gorm.Session{}).
 			Where("username = ?", meta.Body.Username).
@@ -194,7 +247,7 @@ func (a *API) PostAuthenticationLogin(ctx context.Context, meta PostAuthenticati
 		}
 
 		if len(users) == 0 {
-			return output, restfulwrapper.NewAPIResponseError(http.StatusUnauthorized, "Invalid username or password")
+			return output, loginFailed
 		}
 
 		if len(users) > 1 {
@@ -214,7 +267,7 @@ func (a *API) PostAuthenticationLogin(ctx context.Context, meta PostAuthenticati
 				return output, err
 			}
 			if len(userTOTPs) == 0 {
-				return output, restfulwrapper.NewAPIResponseError(http.StatusUnauthorized, "User does not have a TOTP")
+				return output, loginFailed
 			}
 			userTOTP = userTOTPs[0]
 		}
@@ -236,10 +289,59 @@ func (a *API) PostAuthenticationLogin(ctx context.Context, meta PostAuthenticati
 			}
 
 			if oneTimePassword != meta.Body.Password {
-				return output, restfulwrapper.NewAPIResponseError(http.StatusUnauthorized, "Invalid password")
+				return output, loginFailed
+			}
+		}
+
+		// This is only checked after the password so that it does not reveal anything to someone without it.
+		if !user.Verified {
+			return output, loginFailed
+		}
+
+		// An authenticator app is a second factor on top of the e-mailed password, since the e-mail account may be compromised.
+		err = meta.DB.Transaction(func(tx *gorm.DB) error {
+			var err error
+			recoveryCodes, err = verifyLoginSecondFactor(tx, user.ID, meta.Body.AuthenticatorCode, meta.Body.AuthenticatorSecret)
+			return err
+		})
+		if err != nil {
+			if errors.Is(err, errAuthenticatorCodeRequired) || errors.Is(err, errAuthenticatorEnrollmentRequired) {
+				// This is not a failure; the client has to ask the user for the code (or to enroll an authenticator app).
+				message := "Authenticator code required"
+				if errors.Is(err, errAuthenticatorEnrollmentRequired) {
+					message = "Authenticator enrollment required"
+				}
+				err = releaseLoginAttempt(meta.DB, throttleKindLoginUsername, meta.Body.Username, loginUsernameMaxFailures)
+				if err != nil {
+					return output, err
+				}
+				err = releaseSource()
+				if err != nil {
+					return output, err
+				}
+				return output, restfulwrapper.NewAPIResponseError(http.StatusUnauthorized, message)
+			}
+			if errors.Is(err, errInvalidAuthenticatorSecret) {
+				return output, restfulwrapper.NewAPIBodyError(err)
+			}
+			if errors.Is(err, errInvalidAuthenticatorCode) {
+				return output, restfulwrapper.NewAPIResponseError(http.StatusUnauthorized, "Invalid authenticator code")
 			}
+			return output, err
 		}
 
+		err = meta.DB.Transaction(func(tx *gorm.DB) error {
+			return clearLoginFailures(tx, throttleKindLoginUsername, meta.Body.Username)
+		})
+		if err != nil {
+			return output, err
+		}
+		err = releaseSource()
+		if err != nil {
+			return output, err
+		}
+
+		userID = user.ID
 		claims.Subject = meta.Body.Username
 		claims.SessionIdentifier = user.SessionIdentifier
 	} else {
@@ -252,22 +354,7 @@ func (a *API) PostAuthenticationLogin(ctx context.Context, meta PostAuthenticati
 	}
 
 	// Generate a token for the user.
-
-	var signingMethod jwt.SigningMethod
-	var signingKey any
-
-	if a.jwtSecret != nil {
-		signingMethod = jwt.SigningMethodHS512
-		signingKey = a.jwtSecret
-	} else if a.jwtPrivateKey != nil {
-		signingMethod = jwt.SigningMethodRS256
-		signingKey = a.jwtPrivateKey
-	} else {
-		signingMethod = jwt.SigningMethodNone
-		signingKey = jwt.UnsafeAllowNoneSignatureType
-	}
-	token := jwt.NewWithClaims(signingMethod, claims)
-	tokenString, err := token.SignedString(signingKey)
+	tokenString, err := a.issueSessionToken(meta.DB, userID, claims, meta.HTTPRequest.UserAgent(), sourceAddress)
 	if err != nil {
 		return output, err
 	}
@@ -276,22 +363,61 @@ func (a *API) PostAuthenticationLogin(ctx context.Context, meta PostAuthenticati
 	output.Success = true
 	output.Data.UserID = meta.Body.Username
 	output.Data.Token = tokenString
+	output.Data.RecoveryCodes = recoveryCodes
 	return output, nil
 }
 
 // Reset-password does not accept authentication, since you're only resetting your password if you can't log in.
 type PostAuthenticationResetPasswordMetadata struct {
 	restfulwrapper.HTTPMethodPOST
+	downballotwrapper.UseDatabase
 	_    string                             `api:"httppath:/authentication/reset-password"`
 	_    string                             `api:"doc" description:"Reset a user's password."`
-	_    string                             `api:"notes" description:"This attempts to reset a user's password.  This will send the user an e-mail with a link to click on to reset her password."`
+	_    string                             `api:"notes" description:"This attempts to reset a user's password.  This will send the user an e-mail with a link to click on to reset her password.  The response is the same whether or not the user exists."`
 	Body downballotapi.ResetPasswordRequest `api:"body"`
 }
 
 func (a *API) PostAuthenticationResetPassword(ctx context.Context, meta PostAuthenticationResetPasswordMetadata) (output downballotapi.Envelope[downballotapi.ResetPasswordResponse], err error) {
 	slog.InfoContext(ctx, fmt.Sprintf("Username: %s", meta.Body.Username))
 
-	// TODO: ATTEMPT TO RESET THE PASSWORD
+	// Count the e-mail whether or not the address belongs to a user so that the response doesn't reveal which users exist.
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		return throttleEmail(tx, meta.Body.Username)
+	})
+	if err != nil {
+		return output, err
+	}
+
+	var users []*schema.User
+	err = meta.DB.Session(&gorm.Session{}).
+		Where("username = ?", meta.Body.Username).
+		Find(&users).
+		Error
+	if err != nil {
+		return output, err
+	}
+
+	if len(users) == 0 {
+		// This isn't a valid user.
+
+		// Don't do anything different; send the same message.
+	} else {
+		user := users[0]
+
+		var token string
+		err = meta.DB.Transaction(func(tx *gorm.DB) error {
+			token, err = a.newUserLink(tx, user, userLinkPurposeResetPassword, userLinkResetPasswordLifetime)
+			return err
+		})
+		if err != nil {
+			return output, err
+		}
+
+		err = a.sendEmail(ctx, user.Username, "Reset your Downballot password", fmt.Sprintf("Someone asked to reset the password for your Downballot account.  To do so, visit this link:\n\n%s\n\nThis will sign you out everywhere.  If you did not ask for this, then you can ignore this e-mail.", a.userLinkURL("/ui/reset-password", token)))
+		if err != nil {
+			slog.WarnContext(ctx, fmt.Sprintf("Could not send e-mail: %v", err))
+		}
+	}
 
 	output.Message = "OK"
 	output.Success = true
@@ -299,6 +425,65 @@ func (a *API) PostAuthenticationResetPassword(ctx context.Context, meta PostAuth
 	return output, nil
 }
 
+// Reset-password confirmation does not accept authentication, since the link in the e-mail is the proof of identity.
+type PostAuthenticationResetPasswordConfirmMetadata struct {
+	restfulwrapper.HTTPMethodPOST
+	downballotwrapper.UseDatabase
+	_    string                                    `api:"httppath:/authentication/reset-password/confirm"`
+	_    string                                    `api:"doc" description:"Finish resetting a user's password."`
+	_    string                                    `api:"notes" description:"This uses the token from a password-reset e-mail.  The user's one-time-password secret is replaced and all of the user's existing tokens stop working.  Since the e-mail proves that the user owns the address, this also verifies the user."`
+	Body downballotapi.ResetPasswordConfirmRequest `api:"body"`
+}
+
+func (a *API) PostAuthenticationResetPasswordConfirm(ctx context.Context, meta PostAuthenticationResetPasswordConfirmMetadata) (output downballotapi.Envelope[downballotapi.ResetPasswordConfirmResponse], err error) {
+	if meta.Body.Token == "" {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing token"))
+	}
+
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		user, err := a.useUserLink(tx, meta.Body.Token, userLinkPurposeResetPassword)
+		if err != nil {
+			return err
+		}
+
+		// A new secret will be generated the next time that a one-time password is requested.
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Where("user_id = ?", user.ID).
+			Delete(&schema.UserTOTP{}).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not delete TOTP: %w", err)
+		}
+
+		_, err = revokeUserSessions(tx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Model(&schema.User{}).
+			Where("id = ?", user.ID).
+			Update("verified", true).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not update user: %w", err)
+		}
+
+		output.Data.Email = user.Username
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, errInvalidUserLink) {
+			return output, restfulwrapper.NewAPIBodyError(err)
+		}
+		return output, err
+	}
+
+	output.Message = "OK"
+	output.Success = true
+	return output, nil
+}
+
 // Status does not require authentication for historical reasons.  If no user is logged in, then the "user" field will be null.
 type GetAuthenticationStatusMetadata struct {
 	restfulwrapper.HTTPMethodGET
@@ -325,3 +510,41 @@ func (a *API) GetAuthenticationStatus(ctx context.Context, meta GetAuthenticatio
 
 	return output, nil
 }
+
+type PostAuthenticationLogoutMetadata struct {
+	restfulwrapper.HTTPMethodPOST
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	_    string                      `api:"httppath:/authentication/logout"`
+	_    string                      `api:"doc" description:"Log out everywhere."`
+	_    string                      `api:"notes" description:"This revokes all of the current user's sessions, including the one making the request.  Every token that was issued to the user (including the user's API tokens) stops working."`
+	Body downballotapi.LogoutRequest `api:"body"`
+}
+
+func (a *API) PostAuthenticationLogout(ctx context.Context, meta PostAuthenticationLogoutMetadata) (output downballotapi.Envelope[downballotapi.LogoutResponse], err error) {
+	err = requireInteractiveUser(&meta.CurrentUser)
+	if err != nil {
+		return output, err
+	}
+	if meta.CurrentUser.SystemAdmin {
+		return output, restfulwrapper.NewAPIResponseError(http.StatusBadRequest, "The system user cannot log out")
+	}
+
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		revokedSessions, err := revokeUserSessions(tx, meta.CurrentUser.ID)
+		if err != nil {
+			return err
+		}
+		output.Data.RevokedSessions = revokedSessions
+		return nil
+	})
+	if err != nil {
+		return output, err
+	}
+
+	slog.InfoContext(ctx, fmt.Sprintf("Revoked %d sessions for user %d.", output.Data.RevokedSessions, meta.CurrentUser.ID))
+
+	output.Message = "OK"
+	output.Success = true
+	return output, nil
+}
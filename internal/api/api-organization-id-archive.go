@@ -0,0 +1,68 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/downballot/downballot/internal/schema/sqltype"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+type PostOrganizationIDArchiveMetadata struct {
+	restfulwrapper.HTTPMethodPOST
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionOrganizationDelete
+	_    string                                   `api:"httppath:/organization/{organization_id}/archive"`
+	_    string                                   `api:"doc" description:"Archive the organization."`
+	_    string                                   `api:"notes" description:"This produces a full archive of the organization (persons, fields, audits, groups, filters, and memberships) for download.  The archive includes a confirmation token (also sent in the X-Confirmation-Token header) that is required to delete the organization."`
+	_    string                                   `api:"produces:application/json,application/zip"`
+	Body downballotapi.ArchiveOrganizationRequest `api:"body"`
+}
+
+func (a *API) PostOrganizationIDArchive(ctx context.Context, meta PostOrganizationIDArchiveMetadata) (output *organizationArchive, err error) {
+	format := meta.Body.Format
+	switch format {
+	case "":
+		format = organizationArchiveFormatJSON
+	case organizationArchiveFormatJSON, organizationArchiveFormatZIP:
+	default:
+		return nil, restfulwrapper.NewAPIBodyError(fmt.Errorf("unsupported format: %s", format))
+	}
+
+	token, tokenHash, err := newOrganizationDeletionToken()
+	if err != nil {
+		return nil, fmt.Errorf("could not create confirmation token: %w", err)
+	}
+
+	now := time.Now()
+	archive := schema.OrganizationArchive{
+		OrganizationID:      meta.Organization.ID,
+		UserID:              meta.CurrentUser.ID,
+		TokenHash:           tokenHash,
+		CreatedTimestamp:    sqltype.DateTime(now),
+		ExpirationTimestamp: sqltype.DateTime(now.Add(organizationDeletionTokenLifetime)),
+	}
+	err = meta.DB.Session(&gorm.Session{NewDB: true}).
+		Create(&archive).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("could not record organization archive: %w", err)
+	}
+
+	output = &organizationArchive{
+		ctx:               ctx,
+		db:                meta.DB,
+		organization:      meta.Organization,
+		format:            format,
+		confirmationToken: token,
+		timestamp:         now,
+	}
+	return output, nil
+}
@@ -0,0 +1,157 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+type hasContactResult struct {
+	ContactResultID string               `api:"path:contact_result_id" description:"The contact result ID"`
+	ContactResult   schema.ContactResult `api:"database.query:where:id = ? AND organization_id = ?,ContactResultID,OrganizationID"`
+}
+
+type DeleteOrganizationIDContactResultIDMetadata struct {
+	restfulwrapper.HTTPMethodDELETE
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionContactResultDelete
+	hasContactResult
+	_ string `api:"httppath:/organization/{organization_id}/contact-result/{contact_result_id}"`
+	_ string `api:"doc" description:"Delete the contact result."`
+	_ string `api:"notes" description:"This deletes the contact result.  A result that has been recorded for any contact attempt cannot be deleted."`
+}
+
+func (a *API) DeleteOrganizationIDContactResultID(ctx context.Context, meta DeleteOrganizationIDContactResultIDMetadata) error {
+	err := meta.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err := tx.Session(&gorm.Session{NewDB: true}).
+			Model(&schema.ContactAttempt{}).
+			Where("contact_result_id = ?", meta.ContactResult.ID).
+			Count(&count).
+			Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return restfulwrapper.NewAPIResponseError(http.StatusConflict, fmt.Sprintf("Contact result %q has been recorded for %d contact attempt(s)", meta.ContactResult.Code, count))
+		}
+
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Where("id = ?", meta.ContactResult.ID).
+			Delete(&schema.ContactResult{}).
+			Error
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type GetOrganizationIDContactResultIDMetadata struct {
+	restfulwrapper.HTTPMethodGET
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionContactResultRead
+	hasContactResult
+	_ string `api:"httppath:/organization/{organization_id}/contact-result/{contact_result_id}"`
+	_ string `api:"doc" description:"Get the contact result."`
+	_ string `api:"notes" description:"This gets the contact result."`
+}
+
+func (a *API) GetOrganizationIDContactResultID(ctx context.Context, meta GetOrganizationIDContactResultIDMetadata) (output downballotapi.Envelope[downballotapi.GetContactResultResponse], err error) {
+	output.Message = "OK"
+	output.Success = true
+	output.Data.ContactResult = convertContactResult(&meta.ContactResult)
+	return output, nil
+}
+
+type PatchOrganizationIDContactResultIDMetadata struct {
+	restfulwrapper.HTTPMethodPATCH
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionContactResultUpdate
+	hasContactResult
+	_    string                                  `api:"httppath:/organization/{organization_id}/contact-result/{contact_result_id}"`
+	_    string                                  `api:"doc" description:"Patch the contact result."`
+	_    string                                  `api:"notes" description:"This patches the contact result.  Changing the code changes it for every contact attempt that recorded it."`
+	Body downballotapi.PatchContactResultRequest `api:"body"`
+}
+
+func (a *API) PatchOrganizationIDContactResultID(ctx context.Context, meta PatchOrganizationIDContactResultIDMetadata) (output downballotapi.Envelope[downballotapi.PatchContactResultResponse], err error) {
+	updateMap := map[string]any{}
+	if meta.Body.Code != nil {
+		code, err := normalizeCode(*meta.Body.Code)
+		if err != nil {
+			return output, restfulwrapper.NewAPIBodyError(err)
+		}
+		updateMap["code"] = code
+	}
+	if meta.Body.Name != nil {
+		if *meta.Body.Name == "" {
+			return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing name"))
+		}
+		updateMap["name"] = *meta.Body.Name
+	}
+	if meta.Body.Description != nil {
+		updateMap["description"] = *meta.Body.Description
+	}
+
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		if code, ok := updateMap["code"]; ok {
+			var count int64
+			err = tx.Session(&gorm.Session{NewDB: true}).
+				Model(&schema.ContactResult{}).
+				Where("organization_id = ?", meta.Organization.ID).
+				Where("code = ?", code).
+				Where("id <> ?", meta.ContactResult.ID).
+				Count(&count).
+				Error
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return restfulwrapper.NewAPIBodyError(fmt.Errorf("duplicate code: %s", code))
+			}
+		}
+
+		if len(updateMap) > 0 {
+			err = tx.Session(&gorm.Session{NewDB: true}).
+				Model(&schema.ContactResult{}).
+				Where("id = ?", meta.ContactResult.ID).
+				Updates(updateMap).
+				Error
+			if err != nil {
+				return err
+			}
+		}
+
+		var contactResult schema.ContactResult
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Where("id = ?", meta.ContactResult.ID).
+			First(&contactResult).
+			Error
+		if err != nil {
+			return err
+		}
+
+		output.Message = "OK"
+		output.Success = true
+		output.Data.ContactResult = *convertContactResult(&contactResult)
+		return nil
+	})
+	if err != nil {
+		return output, err
+	}
+	return output, nil
+}
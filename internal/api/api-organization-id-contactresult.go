@@ -0,0 +1,105 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+type GetOrganizationIDContactResultMetadata struct {
+	restfulwrapper.HTTPMethodGET
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionContactResultRead
+	_ string `api:"httppath:/organization/{organization_id}/contact-result"`
+	_ string `api:"doc" description:"List the contact results."`
+	_ string `api:"notes" description:"This lists the results that can be recorded for a contact attempt."`
+}
+
+func (a *API) GetOrganizationIDContactResult(ctx context.Context, meta GetOrganizationIDContactResultMetadata) (output downballotapi.Envelope[downballotapi.ListContactResultsResponse], err error) {
+	var contactResults []*schema.ContactResult
+	err = meta.DB.Session(&gorm.Session{}).
+		Where("organization_id = ?", meta.Organization.ID).
+		Order("code ASC").
+		Find(&contactResults).
+		Error
+	if err != nil {
+		return output, fmt.Errorf("could not find contact results: %w", err)
+	}
+
+	output.Message = "OK"
+	output.Success = true
+	output.Data.ContactResults = []*downballotapi.ContactResult{}
+	for _, contactResult := range contactResults {
+		output.Data.ContactResults = append(output.Data.ContactResults, convertContactResult(contactResult))
+	}
+	return output, nil
+}
+
+type PostOrganizationIDContactResultMetadata struct {
+	restfulwrapper.HTTPMethodPOST
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionContactResultCreate
+	_    string                                   `api:"httppath:/organization/{organization_id}/contact-result"`
+	_    string                                   `api:"doc" description:"Create a contact result."`
+	_    string                                   `api:"notes" description:"This creates a result that can be recorded for a contact attempt, such as 'not_home'.  The code is lowercased."`
+	Body downballotapi.CreateContactResultRequest `api:"body"`
+}
+
+func (a *API) PostOrganizationIDContactResult(ctx context.Context, meta PostOrganizationIDContactResultMetadata) (output downballotapi.Envelope[downballotapi.CreateContactResultResponse], err error) {
+	code, err := normalizeCode(meta.Body.Code)
+	if err != nil {
+		return output, restfulwrapper.NewAPIBodyError(err)
+	}
+	if meta.Body.Name == "" {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing name"))
+	}
+
+	contactResult := schema.ContactResult{
+		OrganizationID: meta.Organization.ID,
+		Code:           code,
+		Name:           meta.Body.Name,
+		Description:    meta.Body.Description,
+	}
+
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Model(&schema.ContactResult{}).
+			Where("organization_id = ?", meta.Organization.ID).
+			Where("code = ?", contactResult.Code).
+			Count(&count).
+			Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return restfulwrapper.NewAPIBodyError(fmt.Errorf("duplicate code: %s", contactResult.Code))
+		}
+
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Create(&contactResult).
+			Error
+		if err != nil {
+			return err
+		}
+
+		output.Message = "OK"
+		output.Success = true
+		output.Data = downballotapi.CreateContactResultResponse(*convertContactResult(&contactResult))
+		return nil
+	})
+	if err != nil {
+		return output, err
+	}
+
+	return output, nil
+}
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"context"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/api/resttype"
+	"github.com/tekkamanendless/restfulwrapper"
+)
+
+type GetOrganizationIDGroupIDHouseholdMetadata struct {
+	restfulwrapper.HTTPMethodGET
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	hasGroup
+	downballotwrapper.RequirePermissionGroupRead
+	downballotwrapper.RequirePermissionPersonRead
+	_         string               `api:"httppath:/organization/{organization_id}/group/{group_id}/household"`
+	_         string               `api:"produces:application/json,text/csv"`
+	_         string               `api:"doc" description:"Get the households in the group."`
+	_         string               `api:"notes" description:"This gets the households that have a person in the group who matches the filter, one row per household.  Each household lists its members in the group who match the filter."`
+	Filter    *string              `api:"query:filter"`
+	Fields    *resttype.StringList `api:"query:fields"`
+	PageToken *string              `api:"query:page_token" description:"The next_page_token from the previous page."`
+	Limit     int                  `api:"query:limit" default:"25" description:"The maximum number of households to return (at most 10000)."`
+}
+
+func (a *API) GetOrganizationIDGroupIDHousehold(ctx context.Context, meta GetOrganizationIDGroupIDHouseholdMetadata) (output downballotapi.Envelope[downballotapi.ListHouseholdsResponse], err error) {
+	err = checkPageLimit(meta.Limit)
+	if err != nil {
+		return output, err
+	}
+
+	households, nextPageToken, err := filterHouseholdsPage(ctx, meta.DB, meta.CurrentUser.ID, meta.Organization.ID, &meta.Group.ID, meta.Filter, (*[]string)(meta.Fields), meta.PageToken, meta.Limit)
+	if err != nil {
+		return output, err
+	}
+	output.Message = "OK"
+	output.Success = true
+	output.Data.Households = households
+	output.Data.NextPageToken = nextPageToken
+	return output, nil
+}
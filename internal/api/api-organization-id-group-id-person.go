@@ -15,16 +15,19 @@ type GetOrganizationIDGroupIDPersonMetadata struct {
 	downballotwrapper.RequireAuthenticatedUser
 	downballotwrapper.UseDatabase
 	hasOrganization
+	hasGroup
 	downballotwrapper.RequirePermissionGroupRead
 	downballotwrapper.RequirePermissionPersonRead
-	hasGroup
-	_      string               `api:"httppath:/organization/{organization_id}/group/{group_id}/person"`
-	_      string               `api:"produces:application/json,text/csv"`
-	_      string               `api:"doc" description:"Get the people in the group."`
-	_      string               `api:"notes" description:"This gets the people in the group."`
-	Filter *string              `api:"query:filter"`
-	Fields *resttype.StringList `api:"query:fields"`
-	Limit  int                  `api:"query:limit" default:"1000"`
+	_            string               `api:"httppath:/organization/{organization_id}/group/{group_id}/person"`
+	_            string               `api:"produces:application/json,text/csv"`
+	_            string               `api:"doc" description:"Get the people in the group."`
+	_            string               `api:"notes" description:"This gets the people in the group."`
+	Filter       *string              `api:"query:filter"`
+	Fields       *resttype.StringList `api:"query:fields"`
+	Sort         *resttype.StringList `api:"query:sort" description:"A comma-separated list of fields to sort by; prefix a field with '-' to sort it in descending order."`
+	DistanceFrom *string              `api:"query:distance_from" description:"A point (\"latitude,longitude\") to measure the distance pseudo-field from, in meters; it can be returned and sorted like any other field."`
+	PageToken    *string              `api:"query:page_token" description:"The next_page_token from the previous page."`
+	Limit        int                  `api:"query:limit" default:"1000" description:"The maximum number of persons to return (at most 10000)."`
 }
 
 func (a *API) GetOrganizationIDGroupIDPerson(ctx context.Context, meta GetOrganizationIDGroupIDPersonMetadata) (output downballotapi.Envelope[downballotapi.ListPersonsResponse], err error) {
@@ -35,11 +38,25 @@ func (a *API) GetOrganizationIDGroupIDPerson(ctx context.Context, meta GetOrgani
 		}
 	}
 
-	persons, err := filterPersons(ctx, meta.DB, meta.CurrentUser.ID, meta.Organization.ID, &meta.Group.ID, meta.Filter, (*[]string)(meta.Fields), meta.Limit)
+	err = checkPageLimit(meta.Limit)
+	if err != nil {
+		return output, err
+	}
+	var sort []string
+	if meta.Sort != nil {
+		sort = *meta.Sort
+	}
+	distanceFrom, err := parseDistanceFrom(meta.DistanceFrom)
+	if err != nil {
+		return output, err
+	}
+
+	persons, nextPageToken, err := filterPersonsPage(ctx, meta.DB, meta.CurrentUser.ID, meta.Organization.ID, &meta.Group.ID, meta.Filter, (*[]string)(meta.Fields), sort, distanceFrom, meta.PageToken, meta.Limit)
 	if err != nil {
 		return output, err
 	}
 
 	output.Data.Persons = persons
+	output.Data.NextPageToken = nextPageToken
 	return output, nil
 }
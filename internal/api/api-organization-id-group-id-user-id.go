@@ -22,9 +22,9 @@ type GetOrganizationIDGroupIDUserIDMetadata struct {
 	downballotwrapper.RequireAuthenticatedUser
 	downballotwrapper.UseDatabase
 	hasOrganization
+	hasGroup
 	downballotwrapper.RequirePermissionGroupRead
 	downballotwrapper.RequirePermissionGroupUserRead
-	hasGroup
 	hasGroupUser
 	_ string `api:"httppath:/organization/{organization_id}/group/{group_id}/user/{user_id}"`
 	_ string `api:"doc" description:"Get the user in the group."`
@@ -50,9 +50,9 @@ type PatchOrganizationIDGroupIDUserIDMetadata struct {
 	downballotwrapper.RequireAuthenticatedUser
 	downballotwrapper.UseDatabase
 	hasOrganization
+	hasGroup
 	downballotwrapper.RequirePermissionGroupRead
 	downballotwrapper.RequirePermissionGroupUserUpdate
-	hasGroup
 	hasGroupUser
 	_    string                              `api:"httppath:/organization/{organization_id}/group/{group_id}/user/{user_id}"`
 	_    string                              `api:"doc" description:"Patch the user in the group."`
@@ -114,9 +114,9 @@ type DeleteOrganizationIDGroupIDUserIDMetadata struct {
 	downballotwrapper.RequireAuthenticatedUser
 	downballotwrapper.UseDatabase
 	hasOrganization
+	hasGroup
 	downballotwrapper.RequirePermissionGroupRead
 	downballotwrapper.RequirePermissionGroupUserDelete
-	hasGroup
 	hasGroupUser
 	_ string `api:"httppath:/organization/{organization_id}/group/{group_id}/user/{user_id}"`
 	_ string `api:"doc" description:"Delete the user from the group."`
@@ -16,9 +16,9 @@ type GetOrganizationIDGroupIDUserMetadata struct {
 	downballotwrapper.RequireAuthenticatedUser
 	downballotwrapper.UseDatabase
 	hasOrganization
+	hasGroup
 	downballotwrapper.RequirePermissionGroupRead
 	downballotwrapper.RequirePermissionGroupUserRead
-	hasGroup
 	_ string `api:"httppath:/organization/{organization_id}/group/{group_id}/user"`
 	_ string `api:"doc" description:"Get the users in the group."`
 	_ string `api:"notes" description:"This gets the users in the group."`
@@ -5,15 +5,20 @@ import (
 	"fmt"
 
 	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/iam"
 	"github.com/downballot/downballot/internal/api/downballotwrapper"
 	"github.com/downballot/downballot/internal/schema"
 	"github.com/tekkamanendless/restfulwrapper"
 	"gorm.io/gorm"
 )
 
+// hasGroup loads the group from the path.
+//
+// This must come before any required permissions so that they are checked against the group.
 type hasGroup struct {
 	GroupID string       `api:"path:group_id" description:"The group ID"`
 	Group   schema.Group `api:"database.query:where:id = ? AND organization_id IN (SELECT id FROM organization),GroupID"`
+	_       any          `api:"attribute:downballotwrapper.groupID:Group.ID"`
 }
 
 type DeleteOrganizationIDGroupIDMetadata struct {
@@ -21,15 +26,21 @@ type DeleteOrganizationIDGroupIDMetadata struct {
 	downballotwrapper.RequireAuthenticatedUser
 	downballotwrapper.UseDatabase
 	hasOrganization
-	downballotwrapper.RequirePermissionGroupDelete
 	hasGroup
+	downballotwrapper.RequirePermissionGroupDelete
 	_ string `api:"httppath:/organization/{organization_id}/group/{group_id}"`
 	_ string `api:"doc" description:"Delete the group."`
 	_ string `api:"notes" description:"This deletes the group."`
 }
 
 func (a *API) DeleteOrganizationIDGroupID(ctx context.Context, meta DeleteOrganizationIDGroupIDMetadata) error {
-	err := meta.DB.Transaction(func(tx *gorm.DB) error {
+	// A group owner may not delete their own group; that is up to whoever manages its parent.
+	err := requireGroupPermission(meta.CurrentUser, meta.Organization.ID, parentGroupID(meta.Group), iam.IAMGroupDelete)
+	if err != nil {
+		return err
+	}
+
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
 		err := tx.Session(&gorm.Session{NewDB: true}).
 			Where("id = ?", meta.Group.ID).
 			Delete(&schema.Group{}).
@@ -47,8 +58,8 @@ type GetOrganizationIDGroupIDMetadata struct {
 	downballotwrapper.RequireAuthenticatedUser
 	downballotwrapper.UseDatabase
 	hasOrganization
-	downballotwrapper.RequirePermissionGroupRead
 	hasGroup
+	downballotwrapper.RequirePermissionGroupRead
 	_ string `api:"httppath:/organization/{organization_id}/group/{group_id}"`
 	_ string `api:"doc" description:"Get the group."`
 	_ string `api:"notes" description:"This gets the group."`
@@ -74,8 +85,8 @@ type PatchOrganizationIDGroupIDMetadata struct {
 	downballotwrapper.RequireAuthenticatedUser
 	downballotwrapper.UseDatabase
 	hasOrganization
-	downballotwrapper.RequirePermissionGroupUpdate
 	hasGroup
+	downballotwrapper.RequirePermissionGroupUpdate
 	_    string                          `api:"httppath:/organization/{organization_id}/group/{group_id}"`
 	_    string                          `api:"doc" description:"Patch the group."`
 	_    string                          `api:"notes" description:"This patches the group."`
@@ -83,6 +94,12 @@ type PatchOrganizationIDGroupIDMetadata struct {
 }
 
 func (a *API) PatchOrganizationIDGroupID(ctx context.Context, meta PatchOrganizationIDGroupIDMetadata) (output downballotapi.Envelope[downballotapi.GetGroupResponse], err error) {
+	// A group owner may not change their own group (such as widening its filter); that is up to whoever manages its parent.
+	err = requireGroupPermission(meta.CurrentUser, meta.Organization.ID, parentGroupID(meta.Group), iam.IAMGroupUpdate)
+	if err != nil {
+		return output, err
+	}
+
 	updateMap := map[string]any{}
 	if meta.Body.Name != nil {
 		updateMap["name"] = *meta.Body.Name
@@ -109,6 +126,10 @@ func (a *API) PatchOrganizationIDGroupID(ctx context.Context, meta PatchOrganiza
 		if parentGroup == nil {
 			return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("invalid parent_id"))
 		}
+		err = requireGroupPermission(meta.CurrentUser, meta.Organization.ID, parentGroup.ID, iam.IAMGroupCreate)
+		if err != nil {
+			return output, err
+		}
 
 		groupMap := map[uint64]bool{}
 		for _, g := range groups {
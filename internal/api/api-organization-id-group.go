@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/iam"
 	"github.com/downballot/downballot/internal/api/downballotwrapper"
 	"github.com/downballot/downballot/internal/schema"
 	"github.com/tekkamanendless/restfulwrapper"
@@ -17,7 +18,7 @@ type PostOrganizationIDGroupMetadata struct {
 	downballotwrapper.RequireAuthenticatedUser
 	downballotwrapper.UseDatabase
 	hasOrganization
-	downballotwrapper.RequirePermissionGroupCreate
+	downballotwrapper.RequirePermissionGroupCreateOnAnyGroup
 	_    string                           `api:"httppath:/organization/{organization_id}/group"`
 	_    string                           `api:"doc" description:"Create a new group."`
 	_    string                           `api:"notes" description:"This creates a new group."`
@@ -46,6 +47,10 @@ func (a *API) PostOrganizationIDGroup(ctx context.Context, meta PostOrganization
 	if parentGroup == nil {
 		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("invalid parent_id"))
 	}
+	err = requireGroupPermission(meta.CurrentUser, meta.Organization.ID, parentGroup.ID, iam.IAMGroupCreate)
+	if err != nil {
+		return output, err
+	}
 
 	var owner schema.User
 	err = meta.DB.Session(&gorm.Session{}).
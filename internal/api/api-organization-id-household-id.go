@@ -0,0 +1,192 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+type hasHousehold struct {
+	HouseholdID string           `api:"path:household_id" description:"The household ID"`
+	Household   schema.Household `api:"database.query:where:id = ? AND organization_id = ?,HouseholdID,OrganizationID"`
+}
+
+type GetOrganizationIDHouseholdIDMetadata struct {
+	restfulwrapper.HTTPMethodGET
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionPersonRead
+	hasHousehold
+	_ string `api:"httppath:/organization/{organization_id}/household/{household_id}"`
+	_ string `api:"doc" description:"Get the household."`
+	_ string `api:"notes" description:"This gets the household with the members that the user can see."`
+}
+
+func (a *API) GetOrganizationIDHouseholdID(ctx context.Context, meta GetOrganizationIDHouseholdIDMetadata) (output downballotapi.Envelope[downballotapi.GetHouseholdResponse], err error) {
+	household, err := getHousehold(ctx, meta.DB, meta.CurrentUser.ID, meta.Organization.ID, &meta.Household)
+	if err != nil {
+		return output, err
+	}
+	if household == nil {
+		return output, restfulwrapper.NewAPIResponseError(http.StatusNotFound, "")
+	}
+
+	output.Message = "OK"
+	output.Success = true
+	output.Data.Household = household
+	return output, nil
+}
+
+type PostOrganizationIDHouseholdIDMergeMetadata struct {
+	restfulwrapper.HTTPMethodPOST
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionHouseholdUpdate
+	hasHousehold
+	_    string                               `api:"httppath:/organization/{organization_id}/household/{household_id}/merge"`
+	_    string                               `api:"doc" description:"Merge households into the household."`
+	_    string                               `api:"notes" description:"This moves every member of the other households into this one and deletes the other households.  The members stay here even if their addresses change."`
+	Body downballotapi.MergeHouseholdsRequest `api:"body"`
+}
+
+func (a *API) PostOrganizationIDHouseholdIDMerge(ctx context.Context, meta PostOrganizationIDHouseholdIDMergeMetadata) (output downballotapi.Envelope[downballotapi.MergeHouseholdsResponse], err error) {
+	householdIDs, err := parseHouseholdIDs(meta.Body.HouseholdIDs)
+	if err != nil {
+		return output, restfulwrapper.NewAPIBodyError(err)
+	}
+	if len(householdIDs) == 0 {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing household IDs"))
+	}
+	if slices.Contains(householdIDs, meta.Household.ID) {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("a household cannot be merged into itself"))
+	}
+
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		var households []*schema.Household
+		err := tx.Session(&gorm.Session{NewDB: true}).
+			Where("organization_id = ?", meta.Organization.ID).
+			Where("id IN (?)", householdIDs).
+			Find(&households).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not find households: %w", err)
+		}
+		for _, householdID := range householdIDs {
+			if !slices.ContainsFunc(households, func(household *schema.Household) bool { return household.ID == householdID }) {
+				return restfulwrapper.NewAPIBodyError(fmt.Errorf("household not found: %d", householdID))
+			}
+		}
+
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Model(&schema.HouseholdMember{}).
+			Where("household_id IN (?)", append(householdIDs, meta.Household.ID)).
+			Updates(map[string]any{"household_id": meta.Household.ID, "manual": true}).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not update household members: %w", err)
+		}
+
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Where("id IN (?)", householdIDs).
+			Delete(&schema.Household{}).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not delete households: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return output, err
+	}
+
+	output.Data.Household, err = getHousehold(ctx, meta.DB, meta.CurrentUser.ID, meta.Organization.ID, &meta.Household)
+	if err != nil {
+		return output, err
+	}
+	output.Message = "OK"
+	output.Success = true
+	return output, nil
+}
+
+type PostOrganizationIDHouseholdIDSplitMetadata struct {
+	restfulwrapper.HTTPMethodPOST
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionHouseholdUpdate
+	hasHousehold
+	_    string                              `api:"httppath:/organization/{organization_id}/household/{household_id}/split"`
+	_    string                              `api:"doc" description:"Split persons off of the household."`
+	_    string                              `api:"notes" description:"This moves the given members of the household into a new household at the same address.  The moved members stay in the new household even if their addresses change."`
+	Body downballotapi.SplitHouseholdRequest `api:"body"`
+}
+
+func (a *API) PostOrganizationIDHouseholdIDSplit(ctx context.Context, meta PostOrganizationIDHouseholdIDSplitMetadata) (output downballotapi.Envelope[downballotapi.SplitHouseholdResponse], err error) {
+	if len(meta.Body.VoterIDs) == 0 {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing voter IDs"))
+	}
+
+	household := schema.Household{
+		OrganizationID: meta.Organization.ID,
+		Address:        meta.Household.Address,
+	}
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		var persons []*schema.Person
+		err := tx.Session(&gorm.Session{NewDB: true}).
+			Where("organization_id = ?", meta.Organization.ID).
+			Where("voter_id IN (?)", meta.Body.VoterIDs).
+			Where("id IN (SELECT person_id FROM household_member WHERE household_id = ?)", meta.Household.ID).
+			Find(&persons).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not find persons: %w", err)
+		}
+		for _, voterID := range meta.Body.VoterIDs {
+			if !slices.ContainsFunc(persons, func(person *schema.Person) bool { return person.VoterID == voterID }) {
+				return restfulwrapper.NewAPIBodyError(fmt.Errorf("voter ID is not in the household: %s", voterID))
+			}
+		}
+
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Create(&household).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not create household: %w", err)
+		}
+
+		var personIDs []uint64
+		for _, person := range persons {
+			personIDs = append(personIDs, person.ID)
+		}
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Model(&schema.HouseholdMember{}).
+			Where("person_id IN (?)", personIDs).
+			Updates(map[string]any{"household_id": household.ID, "manual": true}).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not update household members: %w", err)
+		}
+
+		return deleteEmptyHouseholds(tx, []uint64{meta.Household.ID})
+	})
+	if err != nil {
+		return output, err
+	}
+
+	output.Data.Household, err = getHousehold(ctx, meta.DB, meta.CurrentUser.ID, meta.Organization.ID, &household)
+	if err != nil {
+		return output, err
+	}
+	output.Message = "OK"
+	output.Success = true
+	return output, nil
+}
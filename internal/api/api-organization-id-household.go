@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/api/resttype"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+type GetOrganizationIDHouseholdMetadata struct {
+	restfulwrapper.HTTPMethodGET
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionPersonRead
+	_         string               `api:"httppath:/organization/{organization_id}/household"`
+	_         string               `api:"produces:application/json,text/csv"`
+	_         string               `api:"doc" description:"List the households."`
+	_         string               `api:"notes" description:"This lists the households that have a person who matches the filter, one row per household.  Each household lists its members who match the filter."`
+	Filter    *string              `api:"query:filter"`
+	Fields    *resttype.StringList `api:"query:fields"`
+	PageToken *string              `api:"query:page_token" description:"The next_page_token from the previous page."`
+	Limit     int                  `api:"query:limit" default:"25" description:"The maximum number of households to return (at most 10000)."`
+}
+
+func (a *API) GetOrganizationIDHousehold(ctx context.Context, meta GetOrganizationIDHouseholdMetadata) (output downballotapi.Envelope[downballotapi.ListHouseholdsResponse], err error) {
+	err = checkPageLimit(meta.Limit)
+	if err != nil {
+		return output, err
+	}
+
+	households, nextPageToken, err := filterHouseholdsPage(ctx, meta.DB, meta.CurrentUser.ID, meta.Organization.ID, nil /*no group ID*/, meta.Filter, (*[]string)(meta.Fields), meta.PageToken, meta.Limit)
+	if err != nil {
+		return output, err
+	}
+	output.Message = "OK"
+	output.Success = true
+	output.Data.Households = households
+	output.Data.NextPageToken = nextPageToken
+	return output, nil
+}
+
+type PostOrganizationIDHouseholdRebuildMetadata struct {
+	restfulwrapper.HTTPMethodPOST
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionHouseholdUpdate
+	_    string                                 `api:"httppath:/organization/{organization_id}/household/rebuild"`
+	_    string                                 `api:"doc" description:"Rebuild the households."`
+	_    string                                 `api:"notes" description:"This puts every person into the household for their residential address.  Unless the households are reset, persons who were merged or split by hand are left alone."`
+	Body downballotapi.RebuildHouseholdsRequest `api:"body"`
+}
+
+func (a *API) PostOrganizationIDHouseholdRebuild(ctx context.Context, meta PostOrganizationIDHouseholdRebuildMetadata) (output downballotapi.Envelope[downballotapi.RebuildHouseholdsResponse], err error) {
+	var lastID uint64
+	for {
+		var personIDs []uint64
+		err = meta.DB.Session(&gorm.Session{}).
+			Model(&schema.Person{}).
+			Where("organization_id = ?", meta.Organization.ID).
+			Where("id > ?", lastID).
+			Order("id").
+			Limit(2000).
+			Pluck("id", &personIDs).
+			Error
+		if err != nil {
+			return output, fmt.Errorf("could not find persons: %w", err)
+		}
+		if len(personIDs) == 0 {
+			break
+		}
+		lastID = personIDs[len(personIDs)-1]
+
+		err = meta.DB.Transaction(func(tx *gorm.DB) error {
+			if meta.Body.Reset {
+				err := tx.Session(&gorm.Session{NewDB: true}).
+					Model(&schema.HouseholdMember{}).
+					Where("person_id IN (?)", personIDs).
+					Update("manual", false).
+					Error
+				if err != nil {
+					return fmt.Errorf("could not update household members: %w", err)
+				}
+			}
+
+			personIDToAddressMap, err := personFieldValues(tx, meta.Organization.ID, householdAddressField, personIDs)
+			if err != nil {
+				return err
+			}
+			return assignHouseholds(tx, meta.Organization.ID, personIDToAddressMap)
+		})
+		if err != nil {
+			return output, err
+		}
+		output.Data.Persons += uint64(len(personIDs))
+	}
+
+	if meta.Body.Reset {
+		// The households that were split off by hand are only deleted once they are empty.
+		err = meta.DB.Session(&gorm.Session{}).
+			Where("organization_id = ?", meta.Organization.ID).
+			Where("id NOT IN (SELECT household_id FROM household_member)").
+			Delete(&schema.Household{}).
+			Error
+		if err != nil {
+			return output, fmt.Errorf("could not delete empty households: %w", err)
+		}
+	}
+
+	output.Message = "OK"
+	output.Success = true
+	return output, nil
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/importprofile"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+type hasImportProfile struct {
+	ImportProfileID string               `api:"path:import_profile_id" description:"The import profile ID"`
+	ImportProfile   schema.ImportProfile `api:"database.query:where:id = ? AND organization_id = ?,ImportProfileID,OrganizationID"`
+}
+
+type DeleteOrganizationIDImportProfileIDMetadata struct {
+	restfulwrapper.HTTPMethodDELETE
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionImportProfileDelete
+	hasImportProfile
+	_ string `api:"httppath:/organization/{organization_id}/import-profile/{import_profile_id}"`
+	_ string `api:"doc" description:"Delete the import profile."`
+	_ string `api:"notes" description:"This deletes the import profile."`
+}
+
+func (a *API) DeleteOrganizationIDImportProfileID(ctx context.Context, meta DeleteOrganizationIDImportProfileIDMetadata) error {
+	err := meta.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Session(&gorm.Session{NewDB: true}).
+			Where("id = ?", meta.ImportProfile.ID).
+			Delete(&schema.ImportProfile{}).
+			Error
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type GetOrganizationIDImportProfileIDMetadata struct {
+	restfulwrapper.HTTPMethodGET
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionImportProfileRead
+	hasImportProfile
+	_ string `api:"httppath:/organization/{organization_id}/import-profile/{import_profile_id}"`
+	_ string `api:"doc" description:"Get the import profile."`
+	_ string `api:"notes" description:"This gets the import profile."`
+}
+
+func (a *API) GetOrganizationIDImportProfileID(ctx context.Context, meta GetOrganizationIDImportProfileIDMetadata) (output downballotapi.Envelope[downballotapi.GetImportProfileResponse], err error) {
+	o, err := convertImportProfile(&meta.ImportProfile)
+	if err != nil {
+		return output, err
+	}
+
+	output.Message = "OK"
+	output.Success = true
+	output.Data.ImportProfile = o
+	return output, nil
+}
+
+type PatchOrganizationIDImportProfileIDMetadata struct {
+	restfulwrapper.HTTPMethodPATCH
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionImportProfileUpdate
+	hasImportProfile
+	_    string                                  `api:"httppath:/organization/{organization_id}/import-profile/{import_profile_id}"`
+	_    string                                  `api:"doc" description:"Patch the import profile."`
+	_    string                                  `api:"notes" description:"This patches the import profile.  Any of the columns, composites, sets, or transforms that are given replace the existing ones entirely."`
+	Body downballotapi.PatchImportProfileRequest `api:"body"`
+}
+
+func (a *API) PatchOrganizationIDImportProfileID(ctx context.Context, meta PatchOrganizationIDImportProfileIDMetadata) (output downballotapi.Envelope[downballotapi.PatchImportProfileResponse], err error) {
+	updateMap := map[string]any{}
+	if meta.Body.Name != nil {
+		if *meta.Body.Name == "" {
+			return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing name"))
+		}
+		updateMap["name"] = *meta.Body.Name
+	}
+	if meta.Body.Description != nil {
+		updateMap["description"] = *meta.Body.Description
+	}
+	if meta.Body.Columns != nil || meta.Body.Composites != nil || meta.Body.Sets != nil || meta.Body.Transforms != nil {
+		var profile importprofile.Profile
+		err = json.Unmarshal([]byte(meta.ImportProfile.Definition), &profile)
+		if err != nil {
+			return output, fmt.Errorf("could not decode import profile: %w", err)
+		}
+
+		patch := newImportProfile(meta.Body.Columns, meta.Body.Composites, meta.Body.Sets, meta.Body.Transforms)
+		if meta.Body.Columns != nil {
+			profile.Columns = patch.Columns
+		}
+		if meta.Body.Composites != nil {
+			profile.Composites = patch.Composites
+		}
+		if meta.Body.Sets != nil {
+			profile.Sets = patch.Sets
+		}
+		if meta.Body.Transforms != nil {
+			profile.Transforms = patch.Transforms
+		}
+
+		err = validateImportProfile(meta.DB, meta.Organization.ID, profile)
+		if err != nil {
+			return output, restfulwrapper.NewAPIBodyError(err)
+		}
+		definition, err := json.Marshal(profile)
+		if err != nil {
+			return output, fmt.Errorf("could not encode import profile: %w", err)
+		}
+		updateMap["definition"] = string(definition)
+	}
+
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		if name, ok := updateMap["name"]; ok {
+			var count int64
+			err = tx.Session(&gorm.Session{NewDB: true}).
+				Model(&schema.ImportProfile{}).
+				Where("organization_id = ?", meta.Organization.ID).
+				Where("name = ?", name).
+				Where("id <> ?", meta.ImportProfile.ID).
+				Count(&count).
+				Error
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return restfulwrapper.NewAPIBodyError(fmt.Errorf("duplicate name: %s", name))
+			}
+		}
+
+		if len(updateMap) > 0 {
+			err = tx.Session(&gorm.Session{NewDB: true}).
+				Model(&schema.ImportProfile{}).
+				Where("id = ?", meta.ImportProfile.ID).
+				Updates(updateMap).
+				Error
+			if err != nil {
+				return err
+			}
+		}
+
+		var importProfile schema.ImportProfile
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Where("id = ?", meta.ImportProfile.ID).
+			First(&importProfile).
+			Error
+		if err != nil {
+			return err
+		}
+
+		o, err := convertImportProfile(&importProfile)
+		if err != nil {
+			return err
+		}
+
+		output.Message = "OK"
+		output.Success = true
+		output.Data.ImportProfile = *o
+		return nil
+	})
+	if err != nil {
+		return output, err
+	}
+	return output, nil
+}
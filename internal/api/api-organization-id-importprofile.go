@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+type GetOrganizationIDImportProfileMetadata struct {
+	restfulwrapper.HTTPMethodGET
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionImportProfileRead
+	_ string `api:"httppath:/organization/{organization_id}/import-profile"`
+	_ string `api:"doc" description:"List the import profiles."`
+	_ string `api:"notes" description:"This lists the import profiles.  The built-in 'default' profile is not included."`
+}
+
+func (a *API) GetOrganizationIDImportProfile(ctx context.Context, meta GetOrganizationIDImportProfileMetadata) (output downballotapi.Envelope[downballotapi.ListImportProfilesResponse], err error) {
+	var importProfiles []*schema.ImportProfile
+	err = meta.DB.Session(&gorm.Session{}).
+		Where("organization_id = ?", meta.Organization.ID).
+		Order("name ASC").
+		Find(&importProfiles).
+		Error
+	if err != nil {
+		return output, fmt.Errorf("could not find import profiles: %w", err)
+	}
+
+	output.Message = "OK"
+	output.Success = true
+	output.Data.ImportProfiles = []*downballotapi.ImportProfile{}
+	for _, importProfile := range importProfiles {
+		o, err := convertImportProfile(importProfile)
+		if err != nil {
+			return output, err
+		}
+		output.Data.ImportProfiles = append(output.Data.ImportProfiles, o)
+	}
+	return output, nil
+}
+
+type PostOrganizationIDImportProfileMetadata struct {
+	restfulwrapper.HTTPMethodPOST
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionImportProfileCreate
+	_    string                                   `api:"httppath:/organization/{organization_id}/import-profile"`
+	_    string                                   `api:"doc" description:"Create an import profile."`
+	_    string                                   `api:"notes" description:"This creates an import profile, which describes how the columns of a voter file turn into person fields."`
+	Body downballotapi.CreateImportProfileRequest `api:"body"`
+}
+
+func (a *API) PostOrganizationIDImportProfile(ctx context.Context, meta PostOrganizationIDImportProfileMetadata) (output downballotapi.Envelope[downballotapi.CreateImportProfileResponse], err error) {
+	if meta.Body.Name == "" {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing name"))
+	}
+
+	profile := newImportProfile(meta.Body.Columns, meta.Body.Composites, meta.Body.Sets, meta.Body.Transforms)
+	err = validateImportProfile(meta.DB, meta.Organization.ID, profile)
+	if err != nil {
+		return output, restfulwrapper.NewAPIBodyError(err)
+	}
+	definition, err := json.Marshal(profile)
+	if err != nil {
+		return output, fmt.Errorf("could not encode import profile: %w", err)
+	}
+
+	importProfile := schema.ImportProfile{
+		OrganizationID: meta.Organization.ID,
+		Name:           meta.Body.Name,
+		Description:    meta.Body.Description,
+		Definition:     string(definition),
+	}
+
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Model(&schema.ImportProfile{}).
+			Where("organization_id = ?", meta.Organization.ID).
+			Where("name = ?", importProfile.Name).
+			Count(&count).
+			Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return restfulwrapper.NewAPIBodyError(fmt.Errorf("duplicate name: %s", importProfile.Name))
+		}
+
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Create(&importProfile).
+			Error
+		if err != nil {
+			return err
+		}
+
+		o, err := convertImportProfile(&importProfile)
+		if err != nil {
+			return err
+		}
+
+		output.Message = "OK"
+		output.Success = true
+		output.Data = downballotapi.CreateImportProfileResponse(*o)
+		return nil
+	})
+	if err != nil {
+		return output, err
+	}
+
+	return output, nil
+}
@@ -2,8 +2,11 @@ package api
 
 import (
 	"context"
+	"slices"
+	"strconv"
 
 	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/iam"
 	"github.com/downballot/downballot/internal/api/downballotwrapper"
 	"github.com/tekkamanendless/restfulwrapper"
 )
@@ -13,19 +16,30 @@ type GetOrganizationIDPermissionMetadata struct {
 	downballotwrapper.RequireAuthenticatedUser
 	downballotwrapper.UseDatabase
 	hasOrganization
-	_ string `api:"httppath:/organization/{organization_id}/permission"`
-	_ string `api:"doc" description:"List the user's permissions."`
-	_ string `api:"notes" description:"This lists the user's permissions."`
+	_       string  `api:"httppath:/organization/{organization_id}/permission"`
+	_       string  `api:"doc" description:"List the user's permissions."`
+	_       string  `api:"notes" description:"This lists the user's permissions.  Any wildcard permissions (such as those from a role) are expanded into the concrete permissions that they grant.  If a group is given, then this includes the permissions that the user has on that group (such as by owning it or one of its parents)."`
+	GroupID *string `api:"query:group_id" description:"The group ID"`
 }
 
 func (a *API) GetOrganizationIDPermission(ctx context.Context, meta GetOrganizationIDPermissionMetadata) (output downballotapi.Envelope[downballotapi.ListPermissionsResponse], err error) {
 	permissionSet := meta.CurrentUser.PermissionSetForOrganization(meta.Organization.ID)
+	if meta.GroupID != nil && *meta.GroupID != "" {
+		groupID, err := strconv.ParseUint(*meta.GroupID, 10, 64)
+		if err != nil {
+			return output, restfulwrapper.NewAPIQueryParameterError("group_id", err)
+		}
+		permissionSet = meta.CurrentUser.PermissionSetForGroup(meta.Organization.ID, groupID)
+	}
 
 	output.Message = "OK"
 	output.Success = true
 	output.Data.Permissions = []string{}
-	for _, permission := range permissionSet.Permissions() {
-		output.Data.Permissions = append(output.Data.Permissions, string(permission))
+	for _, permission := range iam.Permissions {
+		if permissionSet.Match(permission) {
+			output.Data.Permissions = append(output.Data.Permissions, string(permission))
+		}
 	}
+	slices.Sort(output.Data.Permissions)
 	return output, nil
 }
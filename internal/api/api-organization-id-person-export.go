@@ -0,0 +1,28 @@
+package api
+
+import (
+	"context"
+
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/api/resttype"
+	"github.com/tekkamanendless/restfulwrapper"
+)
+
+type GetOrganizationIDPersonExportMetadata struct {
+	restfulwrapper.HTTPMethodGET
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionPersonRead
+	_      string               `api:"httppath:/organization/{organization_id}/person/export"`
+	_      string               `api:"produces:application/json,text/csv,application/x-ndjson"`
+	_      string               `api:"doc" description:"Export the persons."`
+	_      string               `api:"notes" description:"This streams every matching person as CSV or newline-delimited JSON.  The columns are in the order given by 'fields', with the voter ID first unless it is listed explicitly."`
+	Filter *string              `api:"query:filter"`
+	Fields *resttype.StringList `api:"query:fields"`
+	Format string               `api:"query:format" default:"csv" description:"The output format; this is either 'csv' or 'ndjson'."`
+}
+
+func (a *API) GetOrganizationIDPersonExport(ctx context.Context, meta GetOrganizationIDPersonExportMetadata) (output *personExport, err error) {
+	return newPersonExport(ctx, meta.DB, meta.CurrentUser.ID, meta.Organization.ID, nil /*no group ID*/, meta.Filter, (*[]string)(meta.Fields), meta.Format)
+}
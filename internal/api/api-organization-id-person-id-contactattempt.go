@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/iam"
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/downballot/downballot/internal/schema/sqltype"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+type GetOrganizationIDPersonIDContactAttemptMetadata struct {
+	restfulwrapper.HTTPMethodGET
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionContactAttemptReadOnAnyGroup
+	VoterID string `api:"path:voter_id"`
+	_       string `api:"httppath:/organization/{organization_id}/person/{voter_id}/contact-attempt"`
+	_       string `api:"doc" description:"List the contact attempts for the person."`
+	_       string `api:"notes" description:"This lists the contact attempts for the person with the given voter ID, most recent first."`
+}
+
+func (a *API) GetOrganizationIDPersonIDContactAttempt(ctx context.Context, meta GetOrganizationIDPersonIDContactAttemptMetadata) (output downballotapi.Envelope[downballotapi.ListContactAttemptsResponse], err error) {
+	filter := "voter_id = " + meta.VoterID
+	limit := 1
+	persons, err := filterPersonsWithPermission(ctx, meta.DB, meta.CurrentUser, meta.Organization.ID, iam.IAMContactAttemptRead, &filter, nil /*no fields*/, limit)
+	if err != nil {
+		return output, err
+	}
+	if len(persons) == 0 {
+		return output, restfulwrapper.NewAPIResponseError(http.StatusNotFound, "")
+	}
+
+	var contactAttempts []*schema.ContactAttempt
+	err = meta.DB.Session(&gorm.Session{}).
+		Where("person_id = ?", persons[0].ID).
+		Order("timestamp DESC, id DESC").
+		Find(&contactAttempts).
+		Error
+	if err != nil {
+		return output, fmt.Errorf("could not find contact attempts: %w", err)
+	}
+
+	output.Data.ContactAttempts, err = convertContactAttempts(meta.DB, contactAttempts)
+	if err != nil {
+		return output, err
+	}
+	output.Message = "OK"
+	output.Success = true
+	return output, nil
+}
+
+type PostOrganizationIDPersonIDContactAttemptMetadata struct {
+	restfulwrapper.HTTPMethodPOST
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionContactAttemptCreateOnAnyGroup
+	VoterID string                                    `api:"path:voter_id"`
+	_       string                                    `api:"httppath:/organization/{organization_id}/person/{voter_id}/contact-attempt"`
+	_       string                                    `api:"doc" description:"Log a contact attempt for the person."`
+	_       string                                    `api:"notes" description:"This logs an attempt by the current user to contact the person with the given voter ID.  The result must be the code of one of the organization's contact results."`
+	Body    downballotapi.CreateContactAttemptRequest `api:"body"`
+}
+
+func (a *API) PostOrganizationIDPersonIDContactAttempt(ctx context.Context, meta PostOrganizationIDPersonIDContactAttemptMetadata) (output downballotapi.Envelope[downballotapi.CreateContactAttemptResponse], err error) {
+	switch schema.ContactChannel(meta.Body.Channel) {
+	case schema.ContactChannelDoor:
+	case schema.ContactChannelPhone:
+	case schema.ContactChannelText:
+	default:
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("unknown channel: %q", meta.Body.Channel))
+	}
+
+	code, err := normalizeCode(meta.Body.Result)
+	if err != nil {
+		return output, restfulwrapper.NewAPIBodyError(err)
+	}
+	var contactResults []*schema.ContactResult
+	err = meta.DB.Session(&gorm.Session{}).
+		Where("organization_id = ?", meta.Organization.ID).
+		Where("code = ?", code).
+		Find(&contactResults).
+		Error
+	if err != nil {
+		return output, fmt.Errorf("could not find contact result: %w", err)
+	}
+	if len(contactResults) == 0 {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("unknown result: %q", code))
+	}
+
+	filter := "voter_id = " + meta.VoterID
+	limit := 1
+	persons, err := filterPersonsWithPermission(ctx, meta.DB, meta.CurrentUser, meta.Organization.ID, iam.IAMContactAttemptCreate, &filter, nil /*no fields*/, limit)
+	if err != nil {
+		return output, err
+	}
+	if len(persons) == 0 {
+		return output, restfulwrapper.NewAPIResponseError(http.StatusNotFound, "")
+	}
+
+	personID, err := strconv.ParseUint(persons[0].ID, 10, 64)
+	if err != nil {
+		return output, err
+	}
+
+	timestamp := time.Now()
+	if meta.Body.Timestamp != nil {
+		timestamp = time.Time(*meta.Body.Timestamp)
+	}
+
+	contactAttempt := schema.ContactAttempt{
+		PersonID:        personID,
+		UserID:          meta.CurrentUser.ID,
+		Timestamp:       sqltype.DateTime(timestamp),
+		Channel:         schema.ContactChannel(meta.Body.Channel),
+		ContactResultID: contactResults[0].ID,
+		Note:            meta.Body.Note,
+	}
+	err = meta.DB.Session(&gorm.Session{}).
+		Create(&contactAttempt).
+		Error
+	if err != nil {
+		return output, fmt.Errorf("could not create contact attempt: %w", err)
+	}
+
+	o, err := convertContactAttempts(meta.DB, []*schema.ContactAttempt{&contactAttempt})
+	if err != nil {
+		return output, err
+	}
+
+	output.Message = "OK"
+	output.Success = true
+	output.Data = downballotapi.CreateContactAttemptResponse(*o[0])
+	return output, nil
+}
@@ -0,0 +1,261 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/iam"
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/downballot/downballot/internal/schema/sqltype"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+type GetOrganizationIDPersonIDSurveyResponseMetadata struct {
+	restfulwrapper.HTTPMethodGET
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionSurveyResponseReadOnAnyGroup
+	VoterID string `api:"path:voter_id"`
+	_       string `api:"httppath:/organization/{organization_id}/person/{voter_id}/survey-response"`
+	_       string `api:"doc" description:"List the survey responses for the person."`
+	_       string `api:"notes" description:"This lists the survey responses for the person with the given voter ID, most recent first."`
+}
+
+func (a *API) GetOrganizationIDPersonIDSurveyResponse(ctx context.Context, meta GetOrganizationIDPersonIDSurveyResponseMetadata) (output downballotapi.Envelope[downballotapi.ListSurveyResponsesResponse], err error) {
+	filter := "voter_id = " + meta.VoterID
+	limit := 1
+	persons, err := filterPersonsWithPermission(ctx, meta.DB, meta.CurrentUser, meta.Organization.ID, iam.IAMSurveyResponseRead, &filter, nil /*no fields*/, limit)
+	if err != nil {
+		return output, err
+	}
+	if len(persons) == 0 {
+		return output, restfulwrapper.NewAPIResponseError(http.StatusNotFound, "")
+	}
+
+	var surveyResponses []*schema.SurveyResponse
+	err = meta.DB.Session(&gorm.Session{}).
+		Where("person_id = ?", persons[0].ID).
+		Order("timestamp DESC, id DESC").
+		Find(&surveyResponses).
+		Error
+	if err != nil {
+		return output, fmt.Errorf("could not find survey responses: %w", err)
+	}
+
+	output.Data.SurveyResponses, err = convertSurveyResponses(meta.DB, surveyResponses)
+	if err != nil {
+		return output, err
+	}
+	output.Message = "OK"
+	output.Success = true
+	return output, nil
+}
+
+type PostOrganizationIDPersonIDSurveyResponseMetadata struct {
+	restfulwrapper.HTTPMethodPOST
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionSurveyResponseCreateOnAnyGroup
+	VoterID string                                    `api:"path:voter_id"`
+	_       string                                    `api:"httppath:/organization/{organization_id}/person/{voter_id}/survey-response"`
+	_       string                                    `api:"doc" description:"Record the person's response to a survey."`
+	_       string                                    `api:"notes" description:"This records the answers that the person with the given voter ID gave to the survey (the active survey, unless another one is given).  Questions may be left unanswered.  The answer to a 'choice' question is the code of one of its choices.  Answering a question that is linked to a person field also sets that field, and the change is audited."`
+	Body    downballotapi.CreateSurveyResponseRequest `api:"body"`
+}
+
+func (a *API) PostOrganizationIDPersonIDSurveyResponse(ctx context.Context, meta PostOrganizationIDPersonIDSurveyResponseMetadata) (output downballotapi.Envelope[downballotapi.CreateSurveyResponseResponse], err error) {
+	var surveys []*schema.Survey
+	{
+		query := meta.DB.Session(&gorm.Session{}).
+			Where("organization_id = ?", meta.Organization.ID)
+		if meta.Body.SurveyID != "" {
+			query = query.Where("id = ?", meta.Body.SurveyID)
+		} else {
+			query = query.Where("active = ?", true)
+		}
+		err = query.
+			Limit(1).
+			Find(&surveys).
+			Error
+		if err != nil {
+			return output, fmt.Errorf("could not find survey: %w", err)
+		}
+		if len(surveys) == 0 {
+			if meta.Body.SurveyID != "" {
+				return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("unknown survey: %s", meta.Body.SurveyID))
+			}
+			return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("there is no active survey"))
+		}
+	}
+	survey := surveys[0]
+
+	filter := "voter_id = " + meta.VoterID
+	limit := 1
+	persons, err := filterPersonsWithPermission(ctx, meta.DB, meta.CurrentUser, meta.Organization.ID, iam.IAMSurveyResponseCreate, &filter, nil /*all fields*/, limit)
+	if err != nil {
+		return output, err
+	}
+	if len(persons) == 0 {
+		return output, restfulwrapper.NewAPIResponseError(http.StatusNotFound, "")
+	}
+	person := persons[0]
+
+	personID, err := strconv.ParseUint(person.ID, 10, 64)
+	if err != nil {
+		return output, err
+	}
+
+	surveyResponse := schema.SurveyResponse{
+		SurveyID:  survey.ID,
+		PersonID:  personID,
+		UserID:    meta.CurrentUser.ID,
+		Timestamp: sqltype.DateTime(time.Now()),
+	}
+	if meta.Body.Timestamp != nil {
+		surveyResponse.Timestamp = sqltype.DateTime(*meta.Body.Timestamp)
+	}
+	if meta.Body.ContactAttemptID != "" {
+		var contactAttempts []*schema.ContactAttempt
+		err = meta.DB.Session(&gorm.Session{}).
+			Where("id = ?", meta.Body.ContactAttemptID).
+			Where("person_id = ?", personID).
+			Find(&contactAttempts).
+			Error
+		if err != nil {
+			return output, fmt.Errorf("could not find contact attempt: %w", err)
+		}
+		if len(contactAttempts) == 0 {
+			return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("unknown contact attempt: %s", meta.Body.ContactAttemptID))
+		}
+		surveyResponse.ContactAttemptID = &contactAttempts[0].ID
+	}
+
+	surveyIDToQuestionsMap, err := loadSurveyQuestions(meta.DB, []uint64{survey.ID})
+	if err != nil {
+		return output, err
+	}
+	questions := surveyIDToQuestionsMap[survey.ID]
+
+	// Check every answer, and work out the fields that the answers set.
+	var answers []*schema.SurveyAnswer
+	fieldValueMap := map[*schema.PersonFieldDefinition]string{}
+	{
+		questionIDToQuestionMap := map[string]*schema.SurveyQuestion{}
+		for _, question := range questions.Questions {
+			questionIDToQuestionMap[fmt.Sprintf("%d", question.ID)] = question
+		}
+
+		answeredMap := map[uint64]bool{}
+		for _, answer := range meta.Body.Answers {
+			if answer == nil {
+				return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing answer"))
+			}
+			question := questionIDToQuestionMap[answer.QuestionID]
+			if question == nil {
+				return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("unknown question: %s", answer.QuestionID))
+			}
+			if answeredMap[question.ID] {
+				return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("duplicate answer for question: %s", answer.QuestionID))
+			}
+			answeredMap[question.ID] = true
+
+			value := answer.Value
+			fieldValue := answer.Value
+			switch question.Type {
+			case schema.SurveyQuestionTypeChoice:
+				value = strings.ToLower(strings.TrimSpace(answer.Value))
+				var choice *schema.SurveyChoice
+				for _, c := range questions.QuestionIDToChoicesMap[question.ID] {
+					if c.Code == value {
+						choice = c
+						break
+					}
+				}
+				if choice == nil {
+					return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("unknown choice for question %s: %q", answer.QuestionID, answer.Value))
+				}
+				fieldValue = choice.Value
+			case schema.SurveyQuestionTypeText:
+				if strings.TrimSpace(answer.Value) == "" {
+					return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing answer for question: %s", answer.QuestionID))
+				}
+			}
+
+			if fieldDefinition := questions.Field(question); fieldDefinition != nil {
+				err = fieldDefinition.Validate(fieldValue)
+				if err != nil {
+					return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("invalid value for field %s: %w", fieldDefinition.Name, err))
+				}
+				if _, ok := fieldValueMap[fieldDefinition]; ok {
+					return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("more than one answer sets field: %s", fieldDefinition.Name))
+				}
+				fieldValueMap[fieldDefinition] = fieldValue
+			}
+
+			answers = append(answers, &schema.SurveyAnswer{
+				SurveyQuestionID: question.ID,
+				Value:            value,
+			})
+		}
+	}
+	if len(answers) == 0 {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing answers"))
+	}
+
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Session(&gorm.Session{NewDB: true}).
+			Create(&surveyResponse).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not create survey response: %w", err)
+		}
+		for _, answer := range answers {
+			answer.SurveyResponseID = surveyResponse.ID
+			err = tx.Session(&gorm.Session{NewDB: true}).
+				Create(answer).
+				Error
+			if err != nil {
+				return fmt.Errorf("could not create survey answer: %w", err)
+			}
+		}
+
+		changedFields := map[string]string{}
+		for fieldDefinition, value := range fieldValueMap {
+			var oldValue *string
+			if v, ok := person.Fields[fieldDefinition.Name]; ok {
+				oldValue = &v
+			}
+			err = setPersonField(tx, meta.CurrentUser.ID, personID, fieldDefinition, oldValue, value)
+			if err != nil {
+				return err
+			}
+			changedFields[fieldDefinition.Name] = value
+		}
+		err = updatePersonMemberships(tx, meta.Organization.ID, map[uint64]map[string]string{personID: changedFields})
+		if err != nil {
+			return err
+		}
+
+		o, err := convertSurveyResponses(tx, []*schema.SurveyResponse{&surveyResponse})
+		if err != nil {
+			return err
+		}
+		output.Message = "OK"
+		output.Success = true
+		output.Data = downballotapi.CreateSurveyResponseResponse(*o[0])
+		return nil
+	})
+	if err != nil {
+		return output, err
+	}
+	return output, nil
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/iam"
 	"github.com/downballot/downballot/internal/api/downballotwrapper"
 	"github.com/downballot/downballot/internal/api/resttype"
 	"github.com/downballot/downballot/internal/schema"
@@ -95,7 +96,7 @@ type PatchOrganizationIDPersonIDMetadata struct {
 	downballotwrapper.RequireAuthenticatedUser
 	downballotwrapper.UseDatabase
 	hasOrganization
-	downballotwrapper.RequirePermissionPersonUpdate
+	downballotwrapper.RequirePermissionPersonUpdateOnAnyGroup
 	VoterID string                           `api:"path:voter_id"`
 	_       string                           `api:"httppath:/organization/{organization_id}/person/{voter_id}"`
 	_       string                           `api:"doc" description:"Update the person."`
@@ -106,7 +107,7 @@ type PatchOrganizationIDPersonIDMetadata struct {
 func (a *API) PatchOrganizationIDPersonID(ctx context.Context, meta PatchOrganizationIDPersonIDMetadata) (output downballotapi.Envelope[downballotapi.GetPersonResponse], err error) {
 	filter := "voter_id = " + meta.VoterID
 	limit := 1
-	persons, err := filterPersons(ctx, meta.DB, meta.CurrentUser.ID, meta.Organization.ID, nil /*no group ID*/, &filter, nil /*no fields*/, limit)
+	persons, err := filterPersonsWithPermission(ctx, meta.DB, meta.CurrentUser, meta.Organization.ID, iam.IAMPersonUpdate, &filter, nil /*no fields*/, limit)
 	if err != nil {
 		return output, err
 	}
@@ -197,8 +198,13 @@ func (a *API) PatchOrganizationIDPersonID(ctx context.Context, meta PatchOrganiz
 						return fmt.Errorf("could not delete field: %w", err)
 					}
 				} else {
+					storedValue, blindIndex, err := fieldDefinition.EncodeValue(*value)
+					if err != nil {
+						return err
+					}
+
 					var fields []*schema.PersonField
-					err := tx.Session(&gorm.Session{}).
+					err = tx.Session(&gorm.Session{}).
 						Where("person_id = ?", personID).
 						Where("person_field_definition_id = ?", fieldDefinition.ID).
 						Find(&fields).
@@ -211,7 +217,8 @@ func (a *API) PatchOrganizationIDPersonID(ctx context.Context, meta PatchOrganiz
 						field := schema.PersonField{
 							PersonID:                personID,
 							PersonFieldDefinitionID: fieldDefinition.ID,
-							Value:                   *value,
+							Value:                   storedValue,
+							BlindIndex:              blindIndex,
 						}
 						err := tx.Session(&gorm.Session{}).
 							Create(&field).
@@ -224,7 +231,7 @@ func (a *API) PatchOrganizationIDPersonID(ctx context.Context, meta PatchOrganiz
 						err := tx.Session(&gorm.Session{}).
 							Model(&schema.PersonField{}).
 							Where("id = ?", field.ID).
-							Update("value", *value).
+							Updates(map[string]any{"value": storedValue, "blind_index": blindIndex}).
 							Error
 						if err != nil {
 							return fmt.Errorf("could not update field: %w", err)
@@ -232,13 +239,28 @@ func (a *API) PatchOrganizationIDPersonID(ctx context.Context, meta PatchOrganiz
 					}
 				}
 
-				err := tx.Session(&gorm.Session{}).
+				err := audit.Encode(*fieldDefinition)
+				if err != nil {
+					return err
+				}
+				err = tx.Session(&gorm.Session{}).
 					Create(&audit).
 					Error
 				if err != nil {
 					return fmt.Errorf("could not create audit: %w", err)
 				}
 			}
+			changedFields := map[string]string{}
+			for field, value := range meta.Body.Fields {
+				changedFields[field] = ""
+				if value != nil {
+					changedFields[field] = *value
+				}
+			}
+			err := updatePersonMemberships(tx, meta.Organization.ID, map[uint64]map[string]string{personID: changedFields})
+			if err != nil {
+				return err
+			}
 			return nil
 		})
 		if err != nil {
@@ -352,6 +374,10 @@ func (a *API) GetOrganizationIDPersonIDAudit(ctx context.Context, meta GetOrgani
 		if fieldDefinition == nil {
 			return output, fmt.Errorf("unknown field definition: %d", audit.PersonFieldDefinitionID)
 		}
+		err = audit.Decode(*fieldDefinition)
+		if err != nil {
+			return output, err
+		}
 
 		output.Data.Audits = append(output.Data.Audits, &downballotapi.PersonAudit{
 			ID:        fmt.Sprintf("%d", audit.ID),
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/api/resttype"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+type hasImportJob struct {
+	ImportJobID string           `api:"path:import_job_id" description:"The import job ID"`
+	ImportJob   schema.ImportJob `api:"database.query:where:id = ? AND organization_id = ?,ImportJobID,OrganizationID"`
+}
+
+type GetOrganizationIDPersonImportIDMetadata struct {
+	restfulwrapper.HTTPMethodGET
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionPersonCreate
+	hasImportJob
+	_ string `api:"httppath:/organization/{organization_id}/person/import/{import_job_id}"`
+	_ string `api:"doc" description:"Get an import job."`
+	_ string `api:"notes" description:"This gets the progress of an import job along with the rows that could not be imported.  As CSV, only the rows that could not be imported are returned."`
+	_ string `api:"produces:application/json,text/csv"`
+}
+
+func (a *API) GetOrganizationIDPersonImportID(ctx context.Context, meta GetOrganizationIDPersonImportIDMetadata) (output downballotapi.Envelope[downballotapi.GetImportJobResponse], err error) {
+	var importJobErrors []*schema.ImportJobError
+	err = meta.DB.Session(&gorm.Session{}).
+		Where("import_job_id = ?", meta.ImportJob.ID).
+		Order("row_num").
+		Find(&importJobErrors).
+		Error
+	if err != nil {
+		return output, fmt.Errorf("could not find import job errors: %w", err)
+	}
+
+	output.Message = "OK"
+	output.Success = true
+	output.Data.Job = &downballotapi.ImportJob{
+		ID:               fmt.Sprintf("%d", meta.ImportJob.ID),
+		Status:           string(meta.ImportJob.Status),
+		DryRun:           meta.ImportJob.DryRun,
+		Message:          meta.ImportJob.Message,
+		TotalRows:        meta.ImportJob.TotalRows,
+		ProcessedRows:    meta.ImportJob.ProcessedRows,
+		CreatedRows:      meta.ImportJob.CreatedRows,
+		UpdatedRows:      meta.ImportJob.UpdatedRows,
+		UnchangedRows:    meta.ImportJob.UnchangedRows,
+		FailedRows:       meta.ImportJob.FailedRows,
+		CreatedTimestamp: resttype.DateTime(meta.ImportJob.CreatedTimestamp),
+	}
+	if meta.ImportJob.FinishedTimestamp != nil {
+		finishedTimestamp := resttype.DateTime(*meta.ImportJob.FinishedTimestamp)
+		output.Data.Job.FinishedTimestamp = &finishedTimestamp
+	}
+	output.Data.Errors = []*downballotapi.ImportJobError{}
+	for _, importJobError := range importJobErrors {
+		output.Data.Errors = append(output.Data.Errors, &downballotapi.ImportJobError{
+			Row:     importJobError.RowNumber,
+			VoterID: importJobError.VoterID,
+			Message: importJobError.Message,
+		})
+	}
+	return output, nil
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/iam"
 	"github.com/downballot/downballot/internal/api/downballotwrapper"
 	"github.com/downballot/downballot/internal/filter"
 	"github.com/downballot/downballot/internal/schema"
@@ -22,7 +23,7 @@ type PostOrganizationIDPersonUpdateMetadata struct {
 	downballotwrapper.RequireAuthenticatedUser
 	downballotwrapper.UseDatabase
 	hasOrganization
-	downballotwrapper.RequirePermissionPersonUpdate
+	downballotwrapper.RequirePermissionPersonUpdateOnAnyGroup
 	_    string                                `api:"httppath:/organization/{organization_id}/person/update"`
 	_    string                                `api:"doc" description:"Update the persons."`
 	_    string                                `api:"notes" description:"This updates the persons."`
@@ -46,7 +47,7 @@ func (a *API) PostOrganizationIDPersonUpdate(ctx context.Context, meta PostOrgan
 		filterString = "voter_id = (" + strings.Join(voterIDs, ", ") + ")"
 	}
 	limit := len(meta.Body.VoterIDs)
-	persons, err := filterPersons(ctx, meta.DB, meta.CurrentUser.ID, meta.Organization.ID, nil /*no group ID*/, &filterString, nil /*no fields*/, limit)
+	persons, err := filterPersonsWithPermission(ctx, meta.DB, meta.CurrentUser, meta.Organization.ID, iam.IAMPersonUpdate, &filterString, nil /*no fields*/, limit)
 	if err != nil {
 		return output, err
 	}
@@ -108,6 +109,7 @@ func (a *API) PostOrganizationIDPersonUpdate(ctx context.Context, meta PostOrgan
 
 	{
 		err = meta.DB.Transaction(func(tx *gorm.DB) error {
+			personIDToFieldsMap := map[uint64]map[string]string{} // These are the fields that were given for each person.
 			for _, person := range persons {
 				personID, err := strconv.ParseUint(person.ID, 10, 64)
 				if err != nil {
@@ -157,8 +159,13 @@ func (a *API) PostOrganizationIDPersonUpdate(ctx context.Context, meta PostOrgan
 							return fmt.Errorf("could not delete field: %w", err)
 						}
 					} else {
+						storedValue, blindIndex, err := fieldDefinition.EncodeValue(*value)
+						if err != nil {
+							return err
+						}
+
 						var fields []*schema.PersonField
-						err := tx.Session(&gorm.Session{}).
+						err = tx.Session(&gorm.Session{}).
 							Where("person_id = ?", personID).
 							Where("person_field_definition_id = ?", fieldDefinition.ID).
 							Find(&fields).
@@ -171,7 +178,8 @@ func (a *API) PostOrganizationIDPersonUpdate(ctx context.Context, meta PostOrgan
 							field := schema.PersonField{
 								PersonID:                personID,
 								PersonFieldDefinitionID: fieldDefinition.ID,
-								Value:                   *value,
+								Value:                   storedValue,
+								BlindIndex:              blindIndex,
 							}
 							err := tx.Session(&gorm.Session{}).
 								Create(&field).
@@ -184,7 +192,7 @@ func (a *API) PostOrganizationIDPersonUpdate(ctx context.Context, meta PostOrgan
 							err := tx.Session(&gorm.Session{}).
 								Model(&schema.PersonField{}).
 								Where("id = ?", field.ID).
-								Update("value", *value).
+								Updates(map[string]any{"value": storedValue, "blind_index": blindIndex}).
 								Error
 							if err != nil {
 								return fmt.Errorf("could not update field: %w", err)
@@ -192,13 +200,29 @@ func (a *API) PostOrganizationIDPersonUpdate(ctx context.Context, meta PostOrgan
 						}
 					}
 
-					err := tx.Session(&gorm.Session{}).
+					err := audit.Encode(*fieldDefinition)
+					if err != nil {
+						return err
+					}
+					err = tx.Session(&gorm.Session{}).
 						Create(&audit).
 						Error
 					if err != nil {
 						return fmt.Errorf("could not create audit: %w", err)
 					}
 				}
+				personIDToFieldsMap[personID] = map[string]string{}
+				for field, value := range meta.Body.Fields {
+					personIDToFieldsMap[personID][field] = ""
+					if value != nil {
+						personIDToFieldsMap[personID][field] = *value
+					}
+				}
+			}
+
+			err := updatePersonMemberships(tx, meta.Organization.ID, personIDToFieldsMap)
+			if err != nil {
+				return err
 			}
 			return nil
 		})
@@ -3,6 +3,8 @@ package api
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"strings"
 
 	"github.com/downballot/downballot/downballotapi"
 	"github.com/downballot/downballot/internal/api/downballotwrapper"
@@ -39,6 +41,7 @@ func (a *API) GetOrganizationIDPersonFieldID(ctx context.Context, meta GetOrgani
 		AllowEmpty:    meta.PersonField.AllowEmpty,
 		AllowedValues: meta.PersonField.AllowedValues,
 		AllowedRegex:  meta.PersonField.AllowedRegex,
+		Encrypted:     meta.PersonField.Encrypted,
 	}
 	return output, nil
 }
@@ -50,28 +53,132 @@ type PatchOrganizationIDPersonFieldIDMetadata struct {
 	hasOrganization
 	downballotwrapper.RequirePermissionPersonFieldDefinitionUpdate
 	hasPersonField
-	_    string                                `api:"httppath:/organization/{organization_id}/person-field/{person_field_id}"`
-	_    string                                `api:"doc" description:"Add a person field."`
-	_    string                                `api:"notes" description:"This adds a person field."`
-	Body downballotapi.PatchPersonFieldRequest `api:"body"`
+	_      string                                `api:"httppath:/organization/{organization_id}/person-field/{person_field_id}"`
+	_      string                                `api:"doc" description:"Update a person field."`
+	_      string                                `api:"notes" description:"This updates a person field.  Renaming the field also renames it in every group, filter, and import profile that refers to it.  Changing how the field is validated (such as its type) fails if any existing value would no longer be valid; use 'dry_run' to list those values first.  Encrypting (or decrypting) the field re-encodes every existing value and its history."`
+	DryRun bool                                  `api:"query:dry_run" description:"If true, then nothing is changed; the response lists the existing values that would no longer be valid."`
+	Body   downballotapi.PatchPersonFieldRequest `api:"body"`
 }
 
 func (a *API) PostOrganizationIDPersonFieldID(ctx context.Context, meta PatchOrganizationIDPersonFieldIDMetadata) (output downballotapi.Envelope[downballotapi.PatchPersonFieldResponse], err error) {
+	personField := meta.PersonField
+	revalidate := false
+
 	updateMap := map[string]any{}
 	if meta.Body.Name != nil {
+		if *meta.Body.Name == "" {
+			return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing name"))
+		}
+		if strings.HasPrefix(*meta.Body.Name, contactFilterFieldPrefix) {
+			return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("reserved name: %s", *meta.Body.Name))
+		}
 		updateMap["name"] = *meta.Body.Name
+		personField.Name = *meta.Body.Name
 	}
 	if meta.Body.Type != nil {
+		switch schema.PersonFieldDefinitionType(*meta.Body.Type) {
+		case schema.PersonFieldDefinitionTypeBoolean:
+		case schema.PersonFieldDefinitionTypeCoordinates:
+		case schema.PersonFieldDefinitionTypeDate:
+		case schema.PersonFieldDefinitionTypeEnum:
+		case schema.PersonFieldDefinitionTypeInteger:
+		case schema.PersonFieldDefinitionTypeSet:
+		case schema.PersonFieldDefinitionTypeString:
+		default:
+			return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("unknown type: %q", *meta.Body.Type))
+		}
 		updateMap["type"] = *meta.Body.Type
+		personField.Type = schema.PersonFieldDefinitionType(*meta.Body.Type)
+		revalidate = true
 	}
 	if meta.Body.AllowEmpty != nil {
 		updateMap["allow_empty"] = *meta.Body.AllowEmpty
+		personField.AllowEmpty = *meta.Body.AllowEmpty
+		revalidate = true
 	}
 	if meta.Body.AllowedValues != nil {
 		updateMap["allowed_values"] = sqltype.StringArray(meta.Body.AllowedValues)
+		personField.AllowedValues = meta.Body.AllowedValues
+		revalidate = true
 	}
 	if meta.Body.AllowedRegex != nil {
 		updateMap["allowed_regex"] = *meta.Body.AllowedRegex
+		personField.AllowedRegex = *meta.Body.AllowedRegex
+		revalidate = true
+	}
+
+	if meta.Body.Encrypted != nil {
+		if *meta.Body.Encrypted {
+			err = sqltype.CheckEncryption()
+			if err != nil {
+				return output, restfulwrapper.NewAPIResponseError(http.StatusBadRequest, fmt.Sprintf("Encrypted fields are not available: %v", err))
+			}
+		}
+		updateMap["encrypted"] = *meta.Body.Encrypted
+		personField.Encrypted = *meta.Body.Encrypted
+	}
+
+	renamed := personField.Name != meta.PersonField.Name
+	reencode := personField.Encrypted != meta.PersonField.Encrypted
+	if renamed {
+		var count int64
+		err = meta.DB.Session(&gorm.Session{}).
+			Model(&schema.PersonFieldDefinition{}).
+			Where("organization_id = ?", meta.Organization.ID).
+			Where("name = ?", personField.Name).
+			Where("id != ?", meta.PersonField.ID).
+			Count(&count).
+			Error
+		if err != nil {
+			return output, fmt.Errorf("could not check person field name: %w", err)
+		}
+		if count > 0 {
+			return output, restfulwrapper.NewAPIResponseError(http.StatusConflict, fmt.Sprintf("person field already exists: %s", personField.Name))
+		}
+	}
+
+	invalidValues := []*downballotapi.PersonFieldInvalidValue{}
+	if revalidate {
+		invalidValues, err = findInvalidPersonFieldValues(meta.DB, meta.PersonField, personField)
+		if err != nil {
+			return output, err
+		}
+	}
+
+	if meta.DryRun {
+		output.Message = "OK"
+		output.Success = true
+		output.Data.PersonField = downballotapi.PersonField{
+			ID:            fmt.Sprintf("%d", personField.ID),
+			Name:          personField.Name,
+			Type:          downballotapi.PersonFieldDefinitionType(personField.Type),
+			AllowEmpty:    personField.AllowEmpty,
+			AllowedValues: personField.AllowedValues,
+			AllowedRegex:  personField.AllowedRegex,
+			Encrypted:     personField.Encrypted,
+		}
+		output.Data.InvalidValues = invalidValues
+		return output, nil
+	}
+
+	if len(invalidValues) > 0 {
+		var voterIDs []string
+		for _, invalidValue := range invalidValues {
+			if len(voterIDs) == 10 {
+				voterIDs = append(voterIDs, "...")
+				break
+			}
+			voterIDs = append(voterIDs, invalidValue.VoterID)
+		}
+		return output, restfulwrapper.NewAPIResponseError(http.StatusConflict, fmt.Sprintf("%d existing values are not valid (voter IDs: %s)", len(invalidValues), strings.Join(voterIDs, ", ")))
+	}
+
+	var references personFieldReferences
+	if renamed {
+		references, err = findPersonFieldReferences(ctx, meta.DB, meta.Organization.ID, meta.PersonField.Name)
+		if err != nil {
+			return output, err
+		}
 	}
 
 	err = meta.DB.Transaction(func(tx *gorm.DB) error {
@@ -84,6 +191,20 @@ func (a *API) PostOrganizationIDPersonFieldID(ctx context.Context, meta PatchOrg
 			return err
 		}
 
+		if renamed {
+			err = renamePersonFieldReferences(ctx, tx, references, meta.PersonField.Name, personField.Name)
+			if err != nil {
+				return err
+			}
+		}
+
+		if reencode {
+			err = reencodePersonFieldValues(tx, meta.PersonField, personField)
+			if err != nil {
+				return err
+			}
+		}
+
 		var personField schema.PersonFieldDefinition
 		err = tx.Session(&gorm.Session{}).
 			Where("id = ?", meta.PersonField.ID).
@@ -102,6 +223,7 @@ func (a *API) PostOrganizationIDPersonFieldID(ctx context.Context, meta PatchOrg
 			AllowEmpty:    personField.AllowEmpty,
 			AllowedValues: personField.AllowedValues,
 			AllowedRegex:  personField.AllowedRegex,
+			Encrypted:     personField.Encrypted,
 		}
 
 		return nil
@@ -112,3 +234,68 @@ func (a *API) PostOrganizationIDPersonFieldID(ctx context.Context, meta PatchOrg
 
 	return output, nil
 }
+
+type DeleteOrganizationIDPersonFieldIDMetadata struct {
+	restfulwrapper.HTTPMethodDELETE
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionPersonFieldDefinitionDelete
+	hasPersonField
+	_     string `api:"httppath:/organization/{organization_id}/person-field/{person_field_id}"`
+	_     string `api:"doc" description:"Delete a person field."`
+	_     string `api:"notes" description:"This deletes a person field along with every person's value for it.  If any group, filter, import profile, or survey question refers to the field, then this fails unless 'force' is set; those references are left as-is, except that the survey questions no longer set the field."`
+	Force bool   `api:"query:force" description:"If true, then delete the field even if it is still referenced."`
+}
+
+func (a *API) DeleteOrganizationIDPersonFieldID(ctx context.Context, meta DeleteOrganizationIDPersonFieldIDMetadata) error {
+	if !meta.Force {
+		references, err := findPersonFieldReferences(ctx, meta.DB, meta.Organization.ID, meta.PersonField.Name)
+		if err != nil {
+			return err
+		}
+		if !references.Empty() {
+			return restfulwrapper.NewAPIResponseError(http.StatusConflict, fmt.Sprintf("person field is referenced by: %s", references))
+		}
+	}
+
+	err := meta.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Session(&gorm.Session{NewDB: true}).
+			Model(&schema.SurveyQuestion{}).
+			Where("person_field_definition_id = ?", meta.PersonField.ID).
+			Update("person_field_definition_id", nil).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not unlink survey questions: %w", err)
+		}
+
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Where("person_field_definition_id = ?", meta.PersonField.ID).
+			Delete(&schema.PersonAudit{}).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not delete person audits: %w", err)
+		}
+
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Where("person_field_definition_id = ?", meta.PersonField.ID).
+			Delete(&schema.PersonField{}).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not delete person fields: %w", err)
+		}
+
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Where("id = ?", meta.PersonField.ID).
+			Delete(&schema.PersonFieldDefinition{}).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not delete person field: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
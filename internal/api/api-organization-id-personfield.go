@@ -3,10 +3,13 @@ package api
 import (
 	"context"
 	"fmt"
+	"net/http"
+	"strings"
 
 	"github.com/downballot/downballot/downballotapi"
 	"github.com/downballot/downballot/internal/api/downballotwrapper"
 	"github.com/downballot/downballot/internal/schema"
+	"github.com/downballot/downballot/internal/schema/sqltype"
 	"github.com/tekkamanendless/restfulwrapper"
 	"gorm.io/gorm"
 )
@@ -43,6 +46,7 @@ func (a *API) GetOrganizationIDPersonField(ctx context.Context, meta GetOrganiza
 			AllowEmpty:    personField.AllowEmpty,
 			AllowedValues: personField.AllowedValues,
 			AllowedRegex:  personField.AllowedRegex,
+			Encrypted:     personField.Encrypted,
 		}
 		output.Data.PersonFields = append(output.Data.PersonFields, u)
 	}
@@ -65,6 +69,9 @@ func (a *API) PostOrganizationIDPersonField(ctx context.Context, meta PostOrgani
 	if meta.Body.Name == "" {
 		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing name"))
 	}
+	if strings.HasPrefix(meta.Body.Name, contactFilterFieldPrefix) {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("reserved name: %s", meta.Body.Name))
+	}
 
 	switch schema.PersonFieldDefinitionType(meta.Body.Type) {
 	case schema.PersonFieldDefinitionTypeBoolean:
@@ -78,6 +85,13 @@ func (a *API) PostOrganizationIDPersonField(ctx context.Context, meta PostOrgani
 		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("unknown type: %q", meta.Body.Type))
 	}
 
+	if meta.Body.Encrypted {
+		err = sqltype.CheckEncryption()
+		if err != nil {
+			return output, restfulwrapper.NewAPIResponseError(http.StatusBadRequest, fmt.Sprintf("Encrypted fields are not available: %v", err))
+		}
+	}
+
 	personField := schema.PersonFieldDefinition{
 		OrganizationID: meta.Organization.ID,
 		Name:           meta.Body.Name,
@@ -85,6 +99,7 @@ func (a *API) PostOrganizationIDPersonField(ctx context.Context, meta PostOrgani
 		AllowEmpty:     meta.Body.AllowEmpty,
 		AllowedValues:  meta.Body.AllowedValues,
 		AllowedRegex:   meta.Body.AllowedRegex,
+		Encrypted:      meta.Body.Encrypted,
 	}
 
 	err = meta.DB.Transaction(func(tx *gorm.DB) error {
@@ -104,6 +119,7 @@ func (a *API) PostOrganizationIDPersonField(ctx context.Context, meta PostOrgani
 			AllowEmpty:    personField.AllowEmpty,
 			AllowedValues: personField.AllowedValues,
 			AllowedRegex:  personField.AllowedRegex,
+			Encrypted:     personField.Encrypted,
 		}
 
 		return nil
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/downballot/downballot/internal/schema/sqltype"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+type hasRole struct {
+	RoleID string      `api:"path:role_id" description:"The role ID"`
+	Role   schema.Role `api:"database.query:where:id = ? AND organization_id = ?,RoleID,OrganizationID"`
+}
+
+type DeleteOrganizationIDRoleIDMetadata struct {
+	restfulwrapper.HTTPMethodDELETE
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionRoleDelete
+	hasRole
+	_ string `api:"httppath:/organization/{organization_id}/role/{role_id}"`
+	_ string `api:"doc" description:"Delete the role."`
+	_ string `api:"notes" description:"This deletes the role.  Any users with the role are left with the read-only permissions."`
+}
+
+func (a *API) DeleteOrganizationIDRoleID(ctx context.Context, meta DeleteOrganizationIDRoleIDMetadata) error {
+	err := meta.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Session(&gorm.Session{NewDB: true}).
+			Model(&schema.UserOrganizationMap{}).
+			Where("organization_id = ?", meta.Organization.ID).
+			Where("role_id = ?", meta.Role.ID).
+			Update("role_id", nil).
+			Error
+		if err != nil {
+			return err
+		}
+
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Where("id = ?", meta.Role.ID).
+			Delete(&schema.Role{}).
+			Error
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type GetOrganizationIDRoleIDMetadata struct {
+	restfulwrapper.HTTPMethodGET
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionRoleRead
+	hasRole
+	_ string `api:"httppath:/organization/{organization_id}/role/{role_id}"`
+	_ string `api:"doc" description:"Get the role."`
+	_ string `api:"notes" description:"This gets the role."`
+}
+
+func (a *API) GetOrganizationIDRoleID(ctx context.Context, meta GetOrganizationIDRoleIDMetadata) (output downballotapi.Envelope[downballotapi.GetRoleResponse], err error) {
+	output.Message = "OK"
+	output.Success = true
+	output.Data.Role = convertRole(&meta.Role)
+	return output, nil
+}
+
+type PatchOrganizationIDRoleIDMetadata struct {
+	restfulwrapper.HTTPMethodPATCH
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionRoleUpdate
+	hasRole
+	_    string                         `api:"httppath:/organization/{organization_id}/role/{role_id}"`
+	_    string                         `api:"doc" description:"Patch the role."`
+	_    string                         `api:"notes" description:"This patches the role.  If the permissions are given, then they replace the existing ones entirely; a user can only grant the permissions that they have themselves."`
+	Body downballotapi.PatchRoleRequest `api:"body"`
+}
+
+func (a *API) PatchOrganizationIDRoleID(ctx context.Context, meta PatchOrganizationIDRoleIDMetadata) (output downballotapi.Envelope[downballotapi.PatchRoleResponse], err error) {
+	updateMap := map[string]any{}
+	if meta.Body.Name != nil {
+		if *meta.Body.Name == "" {
+			return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing name"))
+		}
+		updateMap["name"] = *meta.Body.Name
+	}
+	if meta.Body.Description != nil {
+		updateMap["description"] = *meta.Body.Description
+	}
+	if meta.Body.Permissions != nil {
+		err = validatePermissions(meta.Body.Permissions)
+		if err != nil {
+			return output, restfulwrapper.NewAPIBodyError(err)
+		}
+		err = checkGrantablePermissions(meta.CurrentUser.PermissionSetForOrganization(meta.Organization.ID), meta.Body.Permissions)
+		if err != nil {
+			return output, err
+		}
+		updateMap["permissions"] = sqltype.StringArray(meta.Body.Permissions)
+	}
+
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		if name, ok := updateMap["name"]; ok {
+			var count int64
+			err = tx.Session(&gorm.Session{NewDB: true}).
+				Model(&schema.Role{}).
+				Where("organization_id = ?", meta.Organization.ID).
+				Where("name = ?", name).
+				Where("id <> ?", meta.Role.ID).
+				Count(&count).
+				Error
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return restfulwrapper.NewAPIBodyError(fmt.Errorf("duplicate name: %s", name))
+			}
+		}
+
+		if len(updateMap) > 0 {
+			err = tx.Session(&gorm.Session{NewDB: true}).
+				Model(&schema.Role{}).
+				Where("id = ?", meta.Role.ID).
+				Updates(updateMap).
+				Error
+			if err != nil {
+				return err
+			}
+		}
+
+		var role schema.Role
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Where("id = ?", meta.Role.ID).
+			First(&role).
+			Error
+		if err != nil {
+			return err
+		}
+
+		output.Message = "OK"
+		output.Success = true
+		output.Data.Role = *convertRole(&role)
+		return nil
+	})
+	if err != nil {
+		return output, err
+	}
+	return output, nil
+}
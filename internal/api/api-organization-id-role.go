@@ -0,0 +1,109 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+type GetOrganizationIDRoleMetadata struct {
+	restfulwrapper.HTTPMethodGET
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionRoleRead
+	_ string `api:"httppath:/organization/{organization_id}/role"`
+	_ string `api:"doc" description:"List the roles."`
+	_ string `api:"notes" description:"This lists the roles."`
+}
+
+func (a *API) GetOrganizationIDRole(ctx context.Context, meta GetOrganizationIDRoleMetadata) (output downballotapi.Envelope[downballotapi.ListRolesResponse], err error) {
+	var roles []*schema.Role
+	err = meta.DB.Session(&gorm.Session{}).
+		Where("organization_id = ?", meta.Organization.ID).
+		Order("name ASC").
+		Find(&roles).
+		Error
+	if err != nil {
+		return output, fmt.Errorf("could not find roles: %w", err)
+	}
+
+	output.Message = "OK"
+	output.Success = true
+	output.Data.Roles = []*downballotapi.Role{}
+	for _, role := range roles {
+		output.Data.Roles = append(output.Data.Roles, convertRole(role))
+	}
+	return output, nil
+}
+
+type PostOrganizationIDRoleMetadata struct {
+	restfulwrapper.HTTPMethodPOST
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionRoleCreate
+	_    string                          `api:"httppath:/organization/{organization_id}/role"`
+	_    string                          `api:"doc" description:"Create a role."`
+	_    string                          `api:"notes" description:"This creates a role, which is a named set of permissions (such as 'person:read' or 'person:*') that can be given to the users of the organization.  A user can only grant the permissions that they have themselves."`
+	Body downballotapi.CreateRoleRequest `api:"body"`
+}
+
+func (a *API) PostOrganizationIDRole(ctx context.Context, meta PostOrganizationIDRoleMetadata) (output downballotapi.Envelope[downballotapi.CreateRoleResponse], err error) {
+	if meta.Body.Name == "" {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing name"))
+	}
+	err = validatePermissions(meta.Body.Permissions)
+	if err != nil {
+		return output, restfulwrapper.NewAPIBodyError(err)
+	}
+	err = checkGrantablePermissions(meta.CurrentUser.PermissionSetForOrganization(meta.Organization.ID), meta.Body.Permissions)
+	if err != nil {
+		return output, err
+	}
+
+	role := schema.Role{
+		OrganizationID: meta.Organization.ID,
+		Name:           meta.Body.Name,
+		Description:    meta.Body.Description,
+		Permissions:    meta.Body.Permissions,
+	}
+
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Model(&schema.Role{}).
+			Where("organization_id = ?", meta.Organization.ID).
+			Where("name = ?", role.Name).
+			Count(&count).
+			Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return restfulwrapper.NewAPIBodyError(fmt.Errorf("duplicate name: %s", role.Name))
+		}
+
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Create(&role).
+			Error
+		if err != nil {
+			return err
+		}
+
+		output.Message = "OK"
+		output.Success = true
+		output.Data = downballotapi.CreateRoleResponse(*convertRole(&role))
+		return nil
+	})
+	if err != nil {
+		return output, err
+	}
+
+	return output, nil
+}
@@ -0,0 +1,257 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/iam"
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/api/resttype"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+type hasSurvey struct {
+	SurveyID string        `api:"path:survey_id" description:"The survey ID"`
+	Survey   schema.Survey `api:"database.query:where:id = ? AND organization_id = ?,SurveyID,OrganizationID"`
+}
+
+type DeleteOrganizationIDSurveyIDMetadata struct {
+	restfulwrapper.HTTPMethodDELETE
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionSurveyDelete
+	hasSurvey
+	_ string `api:"httppath:/organization/{organization_id}/survey/{survey_id}"`
+	_ string `api:"doc" description:"Delete the survey."`
+	_ string `api:"notes" description:"This deletes the survey.  A survey that anyone has responded to cannot be deleted; deactivate it instead."`
+}
+
+func (a *API) DeleteOrganizationIDSurveyID(ctx context.Context, meta DeleteOrganizationIDSurveyIDMetadata) error {
+	err := meta.DB.Transaction(func(tx *gorm.DB) error {
+		count, err := countSurveyResponses(tx, meta.Survey.ID)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return restfulwrapper.NewAPIResponseError(http.StatusConflict, fmt.Sprintf("Survey %q has %d response(s)", meta.Survey.Name, count))
+		}
+
+		err = setSurveyQuestions(tx, meta.Organization.ID, meta.Survey.ID, nil /*no questions*/)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Where("id = ?", meta.Survey.ID).
+			Delete(&schema.Survey{}).
+			Error
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type GetOrganizationIDSurveyIDMetadata struct {
+	restfulwrapper.HTTPMethodGET
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionSurveyReadOnAnyGroup
+	hasSurvey
+	_ string `api:"httppath:/organization/{organization_id}/survey/{survey_id}"`
+	_ string `api:"doc" description:"Get the survey."`
+	_ string `api:"notes" description:"This gets the survey, with its questions."`
+}
+
+func (a *API) GetOrganizationIDSurveyID(ctx context.Context, meta GetOrganizationIDSurveyIDMetadata) (output downballotapi.Envelope[downballotapi.GetSurveyResponse], err error) {
+	o, err := convertSurveys(meta.DB, []*schema.Survey{&meta.Survey})
+	if err != nil {
+		return output, err
+	}
+	output.Message = "OK"
+	output.Success = true
+	output.Data.Survey = o[0]
+	return output, nil
+}
+
+type PatchOrganizationIDSurveyIDMetadata struct {
+	restfulwrapper.HTTPMethodPATCH
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionSurveyUpdate
+	hasSurvey
+	_    string                           `api:"httppath:/organization/{organization_id}/survey/{survey_id}"`
+	_    string                           `api:"doc" description:"Patch the survey."`
+	_    string                           `api:"notes" description:"This patches the survey.  Activating it deactivates every other survey.  The questions can only be replaced until someone has responded to the survey; after that, create a new survey instead."`
+	Body downballotapi.PatchSurveyRequest `api:"body"`
+}
+
+func (a *API) PatchOrganizationIDSurveyID(ctx context.Context, meta PatchOrganizationIDSurveyIDMetadata) (output downballotapi.Envelope[downballotapi.PatchSurveyResponse], err error) {
+	survey := meta.Survey
+	updateMap := map[string]any{}
+	if meta.Body.Name != nil {
+		if *meta.Body.Name == "" {
+			return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing name"))
+		}
+		updateMap["name"] = *meta.Body.Name
+	}
+	if meta.Body.Description != nil {
+		updateMap["description"] = *meta.Body.Description
+	}
+	if meta.Body.Questions != nil && len(meta.Body.Questions) == 0 {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing questions"))
+	}
+
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		if name, ok := updateMap["name"]; ok {
+			var count int64
+			err = tx.Session(&gorm.Session{NewDB: true}).
+				Model(&schema.Survey{}).
+				Where("organization_id = ?", meta.Organization.ID).
+				Where("name = ?", name).
+				Where("id <> ?", meta.Survey.ID).
+				Count(&count).
+				Error
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return restfulwrapper.NewAPIBodyError(fmt.Errorf("duplicate name: %s", name))
+			}
+		}
+
+		if len(updateMap) > 0 {
+			err = tx.Session(&gorm.Session{NewDB: true}).
+				Model(&schema.Survey{}).
+				Where("id = ?", meta.Survey.ID).
+				Updates(updateMap).
+				Error
+			if err != nil {
+				return err
+			}
+		}
+
+		if meta.Body.Questions != nil {
+			count, err := countSurveyResponses(tx, meta.Survey.ID)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return restfulwrapper.NewAPIResponseError(http.StatusConflict, fmt.Sprintf("Survey %q has %d response(s), so its questions cannot be changed", meta.Survey.Name, count))
+			}
+
+			err = setSurveyQuestions(tx, meta.Organization.ID, meta.Survey.ID, meta.Body.Questions)
+			if err != nil {
+				return err
+			}
+		}
+
+		if meta.Body.Active != nil {
+			if *meta.Body.Active {
+				err = activateSurvey(tx, meta.Organization.ID, meta.Survey.ID)
+			} else {
+				err = tx.Session(&gorm.Session{NewDB: true}).
+					Model(&schema.Survey{}).
+					Where("id = ?", meta.Survey.ID).
+					Update("active", false).
+					Error
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Where("id = ?", meta.Survey.ID).
+			First(&survey).
+			Error
+		if err != nil {
+			return err
+		}
+
+		o, err := convertSurveys(tx, []*schema.Survey{&survey})
+		if err != nil {
+			return err
+		}
+
+		output.Message = "OK"
+		output.Success = true
+		output.Data.Survey = *o[0]
+		return nil
+	})
+	if err != nil {
+		return output, err
+	}
+	return output, nil
+}
+
+type GetOrganizationIDSurveyIDResultMetadata struct {
+	restfulwrapper.HTTPMethodGET
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionGroupRead
+	downballotwrapper.RequirePermissionSurveyResponseReadOnAnyGroup
+	hasSurvey
+	_        string              `api:"httppath:/organization/{organization_id}/survey/{survey_id}/result"`
+	_        string              `api:"produces:application/json,text/csv"`
+	_        string              `api:"doc" description:"Get the results of the survey."`
+	_        string              `api:"notes" description:"This counts the answers to each question by the persons in each group.  Only the most recent response by each person is counted, and only the groups in which the user may read the responses are counted."`
+	GroupIDs resttype.StringList `api:"query:group_ids" description:"The groups to count; if not set, then every group that the user can see is counted."`
+}
+
+func (a *API) GetOrganizationIDSurveyIDResult(ctx context.Context, meta GetOrganizationIDSurveyIDResultMetadata) (output downballotapi.Envelope[downballotapi.GetSurveyResultsResponse], err error) {
+	groups, err := getGroupsForUser(meta.DB, meta.CurrentUser.ID, meta.OrganizationID)
+	if err != nil {
+		return output, err
+	}
+	if len(meta.GroupIDs) > 0 {
+		var selectedGroups []*schema.Group
+		for _, groupID := range meta.GroupIDs {
+			index := slices.IndexFunc(groups, func(g *schema.Group) bool {
+				return fmt.Sprintf("%v", g.ID) == groupID
+			})
+			if index < 0 {
+				return output, restfulwrapper.NewAPIQueryParameterError("group_ids", fmt.Errorf("invalid group_id: %s", groupID))
+			}
+			err = requireGroupPermission(meta.CurrentUser, meta.Organization.ID, groups[index].ID, iam.IAMSurveyResponseRead)
+			if err != nil {
+				return output, err
+			}
+			selectedGroups = append(selectedGroups, groups[index])
+		}
+		groups = selectedGroups
+	} else {
+		// Only count the groups whose responses the user may read.
+		groups = slices.DeleteFunc(groups, func(g *schema.Group) bool {
+			permissionSet := meta.CurrentUser.PermissionSetForGroup(meta.Organization.ID, g.ID)
+			return !permissionSet.Match(iam.IAMSurveyResponseRead)
+		})
+	}
+
+	surveyIDToQuestionsMap, err := loadSurveyQuestions(meta.DB, []uint64{meta.Survey.ID})
+	if err != nil {
+		return output, err
+	}
+
+	output.Data.Groups = []*downballotapi.SurveyGroupResult{}
+	for _, group := range groups {
+		result, err := surveyGroupResult(ctx, meta.DB, meta.CurrentUser.ID, meta.Organization.ID, group, meta.Survey.ID, surveyIDToQuestionsMap[meta.Survey.ID])
+		if err != nil {
+			return output, err
+		}
+		output.Data.Groups = append(output.Data.Groups, result)
+	}
+	output.Message = "OK"
+	output.Success = true
+	return output, nil
+}
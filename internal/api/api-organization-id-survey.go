@@ -0,0 +1,157 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+type GetOrganizationIDSurveyMetadata struct {
+	restfulwrapper.HTTPMethodGET
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionSurveyReadOnAnyGroup
+	_ string `api:"httppath:/organization/{organization_id}/survey"`
+	_ string `api:"doc" description:"List the surveys."`
+	_ string `api:"notes" description:"This lists the surveys, with their questions."`
+}
+
+func (a *API) GetOrganizationIDSurvey(ctx context.Context, meta GetOrganizationIDSurveyMetadata) (output downballotapi.Envelope[downballotapi.ListSurveysResponse], err error) {
+	var surveys []*schema.Survey
+	err = meta.DB.Session(&gorm.Session{}).
+		Where("organization_id = ?", meta.Organization.ID).
+		Order("name ASC").
+		Find(&surveys).
+		Error
+	if err != nil {
+		return output, fmt.Errorf("could not find surveys: %w", err)
+	}
+
+	output.Data.Surveys, err = convertSurveys(meta.DB, surveys)
+	if err != nil {
+		return output, err
+	}
+	output.Message = "OK"
+	output.Success = true
+	return output, nil
+}
+
+type PostOrganizationIDSurveyMetadata struct {
+	restfulwrapper.HTTPMethodPOST
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionSurveyCreate
+	_    string                            `api:"httppath:/organization/{organization_id}/survey"`
+	_    string                            `api:"doc" description:"Create a survey."`
+	_    string                            `api:"notes" description:"This creates a survey with the given questions, in order.  A question that is linked to a person field sets that field whenever it is answered.  If the survey is active, then every other survey is deactivated."`
+	Body downballotapi.CreateSurveyRequest `api:"body"`
+}
+
+func (a *API) PostOrganizationIDSurvey(ctx context.Context, meta PostOrganizationIDSurveyMetadata) (output downballotapi.Envelope[downballotapi.CreateSurveyResponse], err error) {
+	if meta.Body.Name == "" {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing name"))
+	}
+	if len(meta.Body.Questions) == 0 {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing questions"))
+	}
+
+	survey := schema.Survey{
+		OrganizationID: meta.Organization.ID,
+		Name:           meta.Body.Name,
+		Description:    meta.Body.Description,
+	}
+
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Model(&schema.Survey{}).
+			Where("organization_id = ?", meta.Organization.ID).
+			Where("name = ?", survey.Name).
+			Count(&count).
+			Error
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			return restfulwrapper.NewAPIBodyError(fmt.Errorf("duplicate name: %s", survey.Name))
+		}
+
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Create(&survey).
+			Error
+		if err != nil {
+			return err
+		}
+
+		err = setSurveyQuestions(tx, meta.Organization.ID, survey.ID, meta.Body.Questions)
+		if err != nil {
+			return err
+		}
+
+		if meta.Body.Active {
+			err = activateSurvey(tx, meta.Organization.ID, survey.ID)
+			if err != nil {
+				return err
+			}
+			survey.Active = true
+		}
+
+		o, err := convertSurveys(tx, []*schema.Survey{&survey})
+		if err != nil {
+			return err
+		}
+
+		output.Message = "OK"
+		output.Success = true
+		output.Data = downballotapi.CreateSurveyResponse(*o[0])
+		return nil
+	})
+	if err != nil {
+		return output, err
+	}
+	return output, nil
+}
+
+type GetOrganizationIDSurveyActiveMetadata struct {
+	restfulwrapper.HTTPMethodGET
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionSurveyReadOnAnyGroup
+	_ string `api:"httppath:/organization/{organization_id}/survey/active"`
+	_ string `api:"doc" description:"Get the active survey."`
+	_ string `api:"notes" description:"This gets the survey that canvassers should ask, with its questions.  If there is no active survey, then this is not found."`
+}
+
+func (a *API) GetOrganizationIDSurveyActive(ctx context.Context, meta GetOrganizationIDSurveyActiveMetadata) (output downballotapi.Envelope[downballotapi.GetSurveyResponse], err error) {
+	var surveys []*schema.Survey
+	err = meta.DB.Session(&gorm.Session{}).
+		Where("organization_id = ?", meta.Organization.ID).
+		Where("active = ?", true).
+		Limit(1).
+		Find(&surveys).
+		Error
+	if err != nil {
+		return output, fmt.Errorf("could not find survey: %w", err)
+	}
+	if len(surveys) == 0 {
+		return output, restfulwrapper.NewAPIResponseError(http.StatusNotFound, "There is no active survey")
+	}
+
+	o, err := convertSurveys(meta.DB, surveys)
+	if err != nil {
+		return output, err
+	}
+	output.Message = "OK"
+	output.Success = true
+	output.Data.Survey = o[0]
+	return output, nil
+}
@@ -0,0 +1,296 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/api/resttype"
+	"github.com/downballot/downballot/internal/geo"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+type hasTurf struct {
+	TurfID string      `api:"path:turf_id" description:"The turf ID"`
+	Turf   schema.Turf `api:"database.query:where:id = ? AND organization_id = ?,TurfID,OrganizationID"`
+}
+
+type DeleteOrganizationIDTurfIDMetadata struct {
+	restfulwrapper.HTTPMethodDELETE
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionTurfDelete
+	hasTurf
+	_ string `api:"httppath:/organization/{organization_id}/turf/{turf_id}"`
+	_ string `api:"doc" description:"Delete the turf."`
+	_ string `api:"notes" description:"This deletes the turf.  The persons in it are not affected."`
+}
+
+func (a *API) DeleteOrganizationIDTurfID(ctx context.Context, meta DeleteOrganizationIDTurfIDMetadata) error {
+	err := meta.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Session(&gorm.Session{NewDB: true}).
+			Where("turf_id = ?", meta.Turf.ID).
+			Delete(&schema.TurfMember{}).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not delete turf members: %w", err)
+		}
+
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Where("id = ?", meta.Turf.ID).
+			Delete(&schema.Turf{}).
+			Error
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+type GetOrganizationIDTurfIDMetadata struct {
+	restfulwrapper.HTTPMethodGET
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionTurfReadOnAnyGroup
+	hasTurf
+	_ string `api:"httppath:/organization/{organization_id}/turf/{turf_id}"`
+	_ string `api:"doc" description:"Get the turf."`
+	_ string `api:"notes" description:"This gets the turf, with the number of persons in it."`
+}
+
+func (a *API) GetOrganizationIDTurfID(ctx context.Context, meta GetOrganizationIDTurfIDMetadata) (output downballotapi.Envelope[downballotapi.GetTurfResponse], err error) {
+	turfIDToCountMap, err := countTurfMembers(meta.DB, []uint64{meta.Turf.ID})
+	if err != nil {
+		return output, err
+	}
+	count := turfIDToCountMap[meta.Turf.ID]
+
+	output.Message = "OK"
+	output.Success = true
+	output.Data.Turf = convertTurf(&meta.Turf, &count)
+	return output, nil
+}
+
+type PatchOrganizationIDTurfIDMetadata struct {
+	restfulwrapper.HTTPMethodPATCH
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionTurfUpdate
+	hasTurf
+	_    string                         `api:"httppath:/organization/{organization_id}/turf/{turf_id}"`
+	_    string                         `api:"doc" description:"Patch the turf."`
+	_    string                         `api:"notes" description:"This patches the turf.  Changing the geometry finds the members of the turf again."`
+	Body downballotapi.PatchTurfRequest `api:"body"`
+}
+
+func (a *API) PatchOrganizationIDTurfID(ctx context.Context, meta PatchOrganizationIDTurfIDMetadata) (output downballotapi.Envelope[downballotapi.PatchTurfResponse], err error) {
+	turf := meta.Turf
+	updateMap := map[string]any{}
+	if meta.Body.Name != nil {
+		if *meta.Body.Name == "" {
+			return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing name"))
+		}
+		updateMap["name"] = *meta.Body.Name
+	}
+	if meta.Body.Description != nil {
+		updateMap["description"] = *meta.Body.Description
+	}
+	if len(meta.Body.Geometry) > 0 {
+		err = setTurfGeometry(&turf, meta.Body.Geometry)
+		if err != nil {
+			return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("invalid geometry: %w", err))
+		}
+		updateMap["geometry"] = turf.Geometry
+		updateMap["min_latitude"] = turf.MinLatitude
+		updateMap["min_longitude"] = turf.MinLongitude
+		updateMap["max_latitude"] = turf.MaxLatitude
+		updateMap["max_longitude"] = turf.MaxLongitude
+	}
+
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		if name, ok := updateMap["name"]; ok {
+			var count int64
+			err = tx.Session(&gorm.Session{NewDB: true}).
+				Model(&schema.Turf{}).
+				Where("organization_id = ?", meta.Organization.ID).
+				Where("name = ?", name).
+				Where("id <> ?", meta.Turf.ID).
+				Count(&count).
+				Error
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return restfulwrapper.NewAPIBodyError(fmt.Errorf("duplicate name: %s", name))
+			}
+		}
+
+		if len(updateMap) > 0 {
+			err = tx.Session(&gorm.Session{NewDB: true}).
+				Model(&schema.Turf{}).
+				Where("id = ?", meta.Turf.ID).
+				Updates(updateMap).
+				Error
+			if err != nil {
+				return err
+			}
+		}
+
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Where("id = ?", meta.Turf.ID).
+			First(&turf).
+			Error
+		if err != nil {
+			return err
+		}
+
+		if len(meta.Body.Geometry) > 0 {
+			err = refreshTurfMembers(tx, &turf)
+			if err != nil {
+				return err
+			}
+		}
+
+		turfIDToCountMap, err := countTurfMembers(tx, []uint64{turf.ID})
+		if err != nil {
+			return err
+		}
+		count := turfIDToCountMap[turf.ID]
+
+		output.Message = "OK"
+		output.Success = true
+		output.Data.Turf = *convertTurf(&turf, &count)
+		return nil
+	})
+	if err != nil {
+		return output, err
+	}
+	return output, nil
+}
+
+type GetOrganizationIDTurfIDWalkListMetadata struct {
+	restfulwrapper.HTTPMethodGET
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionTurfReadOnAnyGroup
+	downballotwrapper.RequirePermissionPersonRead
+	hasTurf
+	_      string               `api:"httppath:/organization/{organization_id}/turf/{turf_id}/walk-list"`
+	_      string               `api:"produces:application/json,text/csv"`
+	_      string               `api:"doc" description:"Get the walk list for the turf."`
+	_      string               `api:"notes" description:"This lists the doors in the turf that have a person who matches the filter, in the order in which to knock on them.  The route always goes to the closest door that hasn't been visited yet, starting from the door closest to the start (or the westernmost door).  Doors without coordinates come last.  The CSV has one row per person."`
+	Filter *string              `api:"query:filter"`
+	Fields *resttype.StringList `api:"query:fields"`
+	Start  *string              `api:"query:start" description:"The coordinates from which to start, such as '43.61,-116.20'."`
+}
+
+func (a *API) GetOrganizationIDTurfIDWalkList(ctx context.Context, meta GetOrganizationIDTurfIDWalkListMetadata) (output downballotapi.Envelope[downballotapi.GetWalkListResponse], err error) {
+	var start *geo.Point
+	if meta.Start != nil && strings.TrimSpace(*meta.Start) != "" {
+		point, err := geo.ParsePoint(*meta.Start)
+		if err != nil {
+			return output, restfulwrapper.NewAPIQueryParameterError("start", err)
+		}
+		start = &point
+	}
+
+	query, fieldDefinitionByIDMap, fieldDefinitionByNameMap, err := preparePersonQuery(ctx, meta.DB, meta.CurrentUser.ID, meta.Organization.ID, nil /*no group ID*/, meta.Filter)
+	if err != nil {
+		return output, err
+	}
+	var personIDs []uint64
+	err = query.
+		Where("person.id IN (SELECT person_id FROM turf_member WHERE turf_id = ?)", meta.Turf.ID).
+		Distinct().
+		Order("person.id ASC").
+		Pluck("person.id", &personIDs).
+		Error
+	if err != nil {
+		return output, err
+	}
+
+	doors, err := findTurfDoors(meta.DB, meta.Organization.ID, personIDs)
+	if err != nil {
+		return output, err
+	}
+
+	// Plan the route through the doors that have coordinates.
+	var points []geo.Point
+	var pointDoors []*turfDoor
+	var otherDoors []*turfDoor
+	for _, door := range doors {
+		if door.Point == nil {
+			otherDoors = append(otherDoors, door)
+			continue
+		}
+		points = append(points, *door.Point)
+		pointDoors = append(pointDoors, door)
+	}
+	startIndex := 0
+	for i, point := range points {
+		if start != nil {
+			if geo.Distance(*start, point) < geo.Distance(*start, points[startIndex]) {
+				startIndex = i
+			}
+		} else if point.Longitude < points[startIndex].Longitude {
+			startIndex = i
+		}
+	}
+	var route []*turfDoor
+	for _, index := range geo.NearestNeighborRoute(points, startIndex) {
+		route = append(route, pointDoors[index])
+	}
+	route = append(route, otherDoors...)
+
+	personIDToPersonMap := map[string]*downballotapi.Person{}
+	for start := 0; start < len(personIDs); start += 2000 {
+		persons, err := loadPersons(meta.DB, personIDs[start:min(start+2000, len(personIDs))], (*[]string)(meta.Fields), fieldDefinitionByIDMap, fieldDefinitionByNameMap)
+		if err != nil {
+			return output, err
+		}
+		for _, person := range persons {
+			personIDToPersonMap[person.ID] = person
+		}
+	}
+
+	output.Data.Stops = []*downballotapi.WalkListStop{}
+	previous := start
+	for i, door := range route {
+		stop := &downballotapi.WalkListStop{
+			Stop:    i + 1,
+			Address: door.Address,
+			Persons: []*downballotapi.Person{},
+		}
+		if door.HouseholdID != 0 {
+			stop.HouseholdID = fmt.Sprintf("%d", door.HouseholdID)
+		}
+		if door.Point != nil {
+			stop.Coordinates = door.Point.String()
+			if previous != nil {
+				stop.Distance = math.Round(geo.Distance(*previous, *door.Point))
+			}
+			previous = door.Point
+		}
+		for _, personID := range door.PersonIDs {
+			if person := personIDToPersonMap[fmt.Sprintf("%d", personID)]; person != nil {
+				stop.Persons = append(stop.Persons, person)
+			}
+		}
+		output.Data.Distance += stop.Distance
+		output.Data.Stops = append(output.Data.Stops, stop)
+	}
+
+	output.Message = "OK"
+	output.Success = true
+	return output, nil
+}
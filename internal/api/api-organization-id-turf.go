@@ -0,0 +1,210 @@
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/geo"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+// maxTurfCutCount is the largest number of turfs that can be cut at once.
+const maxTurfCutCount = 1000
+
+type GetOrganizationIDTurfMetadata struct {
+	restfulwrapper.HTTPMethodGET
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionTurfReadOnAnyGroup
+	_ string `api:"httppath:/organization/{organization_id}/turf"`
+	_ string `api:"doc" description:"List the turfs."`
+	_ string `api:"notes" description:"This lists the turfs, with the number of persons in each."`
+}
+
+func (a *API) GetOrganizationIDTurf(ctx context.Context, meta GetOrganizationIDTurfMetadata) (output downballotapi.Envelope[downballotapi.ListTurfsResponse], err error) {
+	var turfs []*schema.Turf
+	err = meta.DB.Session(&gorm.Session{}).
+		Where("organization_id = ?", meta.Organization.ID).
+		Order("name ASC").
+		Find(&turfs).
+		Error
+	if err != nil {
+		return output, fmt.Errorf("could not find turfs: %w", err)
+	}
+
+	var turfIDs []uint64
+	for _, turf := range turfs {
+		turfIDs = append(turfIDs, turf.ID)
+	}
+	turfIDToCountMap, err := countTurfMembers(meta.DB, turfIDs)
+	if err != nil {
+		return output, err
+	}
+
+	output.Message = "OK"
+	output.Success = true
+	output.Data.Turfs = []*downballotapi.Turf{}
+	for _, turf := range turfs {
+		count := turfIDToCountMap[turf.ID]
+		output.Data.Turfs = append(output.Data.Turfs, convertTurf(turf, &count))
+	}
+	return output, nil
+}
+
+type PostOrganizationIDTurfMetadata struct {
+	restfulwrapper.HTTPMethodPOST
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionTurfCreate
+	_    string                          `api:"httppath:/organization/{organization_id}/turf"`
+	_    string                          `api:"doc" description:"Create a turf."`
+	_    string                          `api:"notes" description:"This creates a turf from a GeoJSON polygon.  Every person whose coordinates are inside of it becomes a member."`
+	Body downballotapi.CreateTurfRequest `api:"body"`
+}
+
+func (a *API) PostOrganizationIDTurf(ctx context.Context, meta PostOrganizationIDTurfMetadata) (output downballotapi.Envelope[downballotapi.CreateTurfResponse], err error) {
+	if meta.Body.Name == "" {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing name"))
+	}
+	if len(meta.Body.Geometry) == 0 {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing geometry"))
+	}
+
+	turf := schema.Turf{
+		OrganizationID: meta.Organization.ID,
+		Name:           meta.Body.Name,
+		Description:    meta.Body.Description,
+	}
+	err = setTurfGeometry(&turf, meta.Body.Geometry)
+	if err != nil {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("invalid geometry: %w", err))
+	}
+
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		err := createTurfs(tx, meta.Organization.ID, []*schema.Turf{&turf})
+		if err != nil {
+			return err
+		}
+
+		turfIDToCountMap, err := countTurfMembers(tx, []uint64{turf.ID})
+		if err != nil {
+			return err
+		}
+		count := turfIDToCountMap[turf.ID]
+
+		output.Message = "OK"
+		output.Success = true
+		output.Data = downballotapi.CreateTurfResponse(*convertTurf(&turf, &count))
+		return nil
+	})
+	if err != nil {
+		return output, err
+	}
+	return output, nil
+}
+
+type PostOrganizationIDTurfCutMetadata struct {
+	restfulwrapper.HTTPMethodPOST
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionTurfCreate
+	downballotwrapper.RequirePermissionPersonRead
+	_    string                        `api:"httppath:/organization/{organization_id}/turf/cut"`
+	_    string                        `api:"doc" description:"Cut turfs."`
+	_    string                        `api:"notes" description:"This splits the persons who match the filter into turfs with roughly the same number of doors in each.  A household is one door; a person who is not in a household is a door of their own.  Persons without coordinates are left out.  Each turf is a rectangle, so it also includes any other persons inside of it."`
+	Body downballotapi.CutTurfsRequest `api:"body"`
+}
+
+func (a *API) PostOrganizationIDTurfCut(ctx context.Context, meta PostOrganizationIDTurfCutMetadata) (output downballotapi.Envelope[downballotapi.CutTurfsResponse], err error) {
+	if meta.Body.Count <= 0 || meta.Body.Count > maxTurfCutCount {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("the count must be between 1 and %d", maxTurfCutCount))
+	}
+	if meta.Body.NamePrefix == "" {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing name prefix"))
+	}
+
+	query, _, _, err := preparePersonQuery(ctx, meta.DB, meta.CurrentUser.ID, meta.Organization.ID, nil /*no group ID*/, &meta.Body.Filter)
+	if err != nil {
+		return output, err
+	}
+	var personIDs []uint64
+	err = query.
+		Distinct().
+		Order("person.id ASC").
+		Pluck("person.id", &personIDs).
+		Error
+	if err != nil {
+		return output, err
+	}
+
+	doors, err := findTurfDoors(meta.DB, meta.Organization.ID, personIDs)
+	if err != nil {
+		return output, err
+	}
+	var points []geo.Point
+	for _, door := range doors {
+		if door.Point != nil {
+			points = append(points, *door.Point)
+		}
+	}
+	if len(points) == 0 {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("none of the persons who match the filter have coordinates"))
+	}
+
+	var turfs []*schema.Turf
+	for i, cell := range geo.Cut(points, meta.Body.Count) {
+		turf := &schema.Turf{
+			OrganizationID: meta.Organization.ID,
+			Name:           fmt.Sprintf("%s %d", meta.Body.NamePrefix, i+1),
+			Description:    fmt.Sprintf("Cut from %d door(s)", len(cell.Indexes)),
+		}
+		if meta.Body.Filter != "" {
+			turf.Description += " matching: " + meta.Body.Filter
+		}
+		contents, err := geo.Geometry{cell.Box.Polygon()}.GeoJSON()
+		if err != nil {
+			return output, err
+		}
+		err = setTurfGeometry(turf, contents)
+		if err != nil {
+			return output, err
+		}
+		turfs = append(turfs, turf)
+	}
+
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		err := createTurfs(tx, meta.Organization.ID, turfs)
+		if err != nil {
+			return err
+		}
+
+		var turfIDs []uint64
+		for _, turf := range turfs {
+			turfIDs = append(turfIDs, turf.ID)
+		}
+		turfIDToCountMap, err := countTurfMembers(tx, turfIDs)
+		if err != nil {
+			return err
+		}
+
+		output.Message = "OK"
+		output.Success = true
+		output.Data.Turfs = []*downballotapi.Turf{}
+		for _, turf := range turfs {
+			count := turfIDToCountMap[turf.ID]
+			output.Data.Turfs = append(output.Data.Turfs, convertTurf(turf, &count))
+		}
+		return nil
+	})
+	if err != nil {
+		return output, err
+	}
+	return output, nil
+}
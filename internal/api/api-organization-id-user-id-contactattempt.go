@@ -0,0 +1,54 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/iam"
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+type GetOrganizationIDUserIDContactAttemptMetadata struct {
+	restfulwrapper.HTTPMethodGET
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionContactAttemptReadOnAnyGroup
+	hasUser
+	_ string `api:"httppath:/organization/{organization_id}/user/{user_id}/contact-attempt"`
+	_ string `api:"doc" description:"List the contact attempts by the user."`
+	_ string `api:"notes" description:"This lists the contact attempts that the user logged in the organization, most recent first.  Any user can list their own; listing another user's requires the permission for the whole organization."`
+}
+
+func (a *API) GetOrganizationIDUserIDContactAttempt(ctx context.Context, meta GetOrganizationIDUserIDContactAttemptMetadata) (output downballotapi.Envelope[downballotapi.ListContactAttemptsResponse], err error) {
+	if meta.User.ID != meta.CurrentUser.ID {
+		permissionSet := meta.CurrentUser.PermissionSetForOrganization(meta.Organization.ID)
+		if !permissionSet.Match(iam.IAMContactAttemptRead) {
+			return output, restfulwrapper.NewAPIResponseError(http.StatusForbidden, fmt.Sprintf("Forbidden: missing permission: %s", iam.IAMContactAttemptRead))
+		}
+	}
+
+	var contactAttempts []*schema.ContactAttempt
+	err = meta.DB.Session(&gorm.Session{}).
+		Where("user_id = ?", meta.User.ID).
+		Where("person_id IN (SELECT id FROM person WHERE organization_id = ?)", meta.Organization.ID).
+		Order("timestamp DESC, id DESC").
+		Find(&contactAttempts).
+		Error
+	if err != nil {
+		return output, fmt.Errorf("could not find contact attempts: %w", err)
+	}
+
+	output.Data.ContactAttempts, err = convertContactAttempts(meta.DB, contactAttempts)
+	if err != nil {
+		return output, err
+	}
+	output.Message = "OK"
+	output.Success = true
+	return output, nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/iam"
 	"github.com/downballot/downballot/internal/api/downballotwrapper"
 	"github.com/downballot/downballot/internal/schema"
 	"github.com/tekkamanendless/restfulwrapper"
@@ -71,7 +72,7 @@ type PostOrganizationIDUserIDGroupMetadata struct {
 	downballotwrapper.RequireAuthenticatedUser
 	downballotwrapper.UseDatabase
 	hasOrganization
-	downballotwrapper.RequirePermissionOrganizationUserUpdate
+	downballotwrapper.RequirePermissionGroupUserCreateOnAnyGroup
 	hasUser
 	_    string                              `api:"httppath:/organization/{organization_id}/user/{user_id}/group"`
 	_    string                              `api:"doc" description:"Add a user to a group."`
@@ -100,8 +101,15 @@ func (a *API) PostOrganizationIDUserIDGroup(ctx context.Context, meta PostOrgani
 		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("invalid group_id"))
 	}
 
+	err = requireGroupPermission(meta.CurrentUser, meta.Organization.ID, group.ID, iam.IAMGroupUserCreate)
+	if err != nil {
+		return output, err
+	}
 	if meta.Body.Owner {
-		// TODO: Make sure that this user can add the user as an owner.
+		err = requireGroupPermission(meta.CurrentUser, meta.Organization.ID, group.ID, iam.IAMGroupUserUpdate)
+		if err != nil {
+			return output, err
+		}
 	}
 
 	userGroupMapping := schema.UserGroupMap{
@@ -37,6 +37,7 @@ func (a *API) GetOrganizationIDUserID(ctx context.Context, meta GetOrganizationI
 		Name:     meta.User.Name,
 		Username: meta.User.Username,
 		Owner:    meta.UserOrganizationMap.Owner,
+		RoleID:   convertRoleID(meta.UserOrganizationMap.RoleID),
 	}
 	return output, nil
 }
@@ -50,12 +51,27 @@ type DeleteOrganizationIDUserIDMetadata struct {
 	hasUser
 	_ string `api:"httppath:/organization/{organization_id}/user/{user_id}"`
 	_ string `api:"doc" description:"Delete the user from the organization."`
-	_ string `api:"notes" description:"This deletes the user from the organization."`
+	_ string `api:"notes" description:"This deletes the user from the organization.  Only a user who has every permission of the user's role (or of an owner) can do this."`
 }
 
 func (a *API) DeleteOrganizationIDUserID(ctx context.Context, meta DeleteOrganizationIDUserIDMetadata) error {
 	// TODO: Ensure that the user cannot delete herself from the organization.
 
+	// Removing the user takes away everything that their membership gave them.
+	permissionSet := meta.CurrentUser.PermissionSetForOrganization(meta.Organization.ID)
+	if meta.UserOrganizationMap.Owner {
+		err := checkGrantableOwner(permissionSet)
+		if err != nil {
+			return err
+		}
+	}
+	if meta.UserOrganizationMap.RoleID != nil {
+		err := checkRevocableRole(meta.DB, permissionSet, *meta.UserOrganizationMap.RoleID)
+		if err != nil {
+			return err
+		}
+	}
+
 	err := meta.DB.Transaction(func(tx *gorm.DB) error {
 		err := tx.Session(&gorm.Session{NewDB: true}).
 			Where("user_id = ?", meta.User.ID).
@@ -82,15 +98,48 @@ type PatchOrganizationIDUserIDMetadata struct {
 	hasUser
 	_    string                                     `api:"httppath:/organization/{organization_id}/user/{user_id}"`
 	_    string                                     `api:"doc" description:"Patch the user."`
-	_    string                                     `api:"notes" description:"This patches the user."`
+	_    string                                     `api:"notes" description:"This patches the user.  Only a user who has every permission of the role (or of an owner) can give it to someone or take it away."`
 	Body downballotapi.PatchOrganizationUserRequest `api:"body"`
 }
 
 func (a *API) PatchOrganizationIDUserID(ctx context.Context, meta PatchOrganizationIDUserIDMetadata) (output downballotapi.Envelope[downballotapi.PatchOrganizationUserResponse], err error) {
+	permissionSet := meta.CurrentUser.PermissionSetForOrganization(meta.Organization.ID)
+
 	updateMap := map[string]any{}
 	if meta.Body.Owner != nil {
+		if *meta.Body.Owner || meta.UserOrganizationMap.Owner {
+			err = checkGrantableOwner(permissionSet)
+			if err != nil {
+				return output, err
+			}
+		}
 		updateMap["owner"] = *meta.Body.Owner
 	}
+	if meta.Body.RoleID != nil {
+		var roleID *uint64
+		if *meta.Body.RoleID != "" {
+			role, err := findRole(meta.DB, meta.Organization.ID, *meta.Body.RoleID)
+			if err != nil {
+				return output, restfulwrapper.NewAPIBodyError(err)
+			}
+			err = checkGrantablePermissions(permissionSet, role.Permissions)
+			if err != nil {
+				return output, err
+			}
+			roleID = &role.ID
+		}
+		if currentRoleID := meta.UserOrganizationMap.RoleID; currentRoleID != nil && (roleID == nil || *roleID != *currentRoleID) {
+			err = checkRevocableRole(meta.DB, permissionSet, *currentRoleID)
+			if err != nil {
+				return output, err
+			}
+		}
+		if roleID == nil {
+			updateMap["role_id"] = nil
+		} else {
+			updateMap["role_id"] = *roleID
+		}
+	}
 
 	err = meta.DB.Transaction(func(tx *gorm.DB) error {
 		err = tx.Session(&gorm.Session{NewDB: true}).
@@ -120,6 +169,7 @@ func (a *API) PatchOrganizationIDUserID(ctx context.Context, meta PatchOrganizat
 			Name:     meta.User.Name,
 			Username: meta.User.Username,
 			Owner:    userOrganizationMap.Owner,
+			RoleID:   convertRoleID(userOrganizationMap.RoleID),
 		}
 
 		return nil
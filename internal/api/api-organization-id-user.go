@@ -56,6 +56,7 @@ func (a *API) GetOrganizationIDUser(ctx context.Context, meta GetOrganizationIDU
 			Name:     user.Name,
 			Username: user.Username,
 			Owner:    userIDToUserOrganizationMapMap[user.ID].Owner,
+			RoleID:   convertRoleID(userIDToUserOrganizationMapMap[user.ID].RoleID),
 		}
 		output.Data.Users = append(output.Data.Users, u)
 	}
@@ -70,7 +71,7 @@ type PostOrganizationIDUserMetadata struct {
 	downballotwrapper.RequirePermissionOrganizationUserCreate
 	_    string                                     `api:"httppath:/organization/{organization_id}/user"`
 	_    string                                     `api:"doc" description:"Add a user to an organization."`
-	_    string                                     `api:"notes" description:"This adds a user to an organization."`
+	_    string                                     `api:"notes" description:"This adds a user to an organization.  Only a user who has every permission of the role (or of an owner) can give it to someone."`
 	Body downballotapi.AddUserToOrganizationRequest `api:"body"`
 }
 
@@ -91,12 +92,34 @@ func (a *API) PostOrganizationIDUser(ctx context.Context, meta PostOrganizationI
 		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("invalid username"))
 	}
 	user := users[0]
+	if !user.Verified {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("user has not been verified"))
+	}
+
+	permissionSet := meta.CurrentUser.PermissionSetForOrganization(meta.Organization.ID)
 
 	userOrganizationMapping := schema.UserOrganizationMap{
 		UserID:         user.ID,
 		OrganizationID: meta.Organization.ID,
 		Owner:          meta.Body.Owner,
 	}
+	if meta.Body.Owner {
+		err = checkGrantableOwner(permissionSet)
+		if err != nil {
+			return output, err
+		}
+	}
+	if meta.Body.RoleID != nil && *meta.Body.RoleID != "" {
+		role, err := findRole(meta.DB, meta.Organization.ID, *meta.Body.RoleID)
+		if err != nil {
+			return output, restfulwrapper.NewAPIBodyError(err)
+		}
+		err = checkGrantablePermissions(permissionSet, role.Permissions)
+		if err != nil {
+			return output, err
+		}
+		userOrganizationMapping.RoleID = &role.ID
+	}
 
 	output.Message = "OK"
 	output.Success = true
@@ -3,12 +3,16 @@ package api
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"time"
 
 	"github.com/downballot/downballot/downballotapi"
 	"github.com/downballot/downballot/internal/api/downballotwrapper"
 	"github.com/downballot/downballot/internal/schema"
+	"github.com/downballot/downballot/internal/schema/sqltype"
 	"github.com/downballot/downballot/permissionset"
 	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
 )
 
 type hasOrganization struct {
@@ -38,3 +42,152 @@ func (a *API) GetOrganizationID(ctx context.Context, meta GetOrganizationIDMetad
 	output.Data.Organization = o
 	return output, nil
 }
+
+type PatchOrganizationIDMetadata struct {
+	restfulwrapper.HTTPMethodPATCH
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionOrganizationUpdate
+	_    string                                 `api:"httppath:/organization/{organization_id}"`
+	_    string                                 `api:"doc" description:"Patch the organization."`
+	_    string                                 `api:"notes" description:"This patches the organization."`
+	Body downballotapi.PatchOrganizationRequest `api:"body"`
+}
+
+func (a *API) PatchOrganizationID(ctx context.Context, meta PatchOrganizationIDMetadata) (output downballotapi.Envelope[downballotapi.PatchOrganizationResponse], err error) {
+	updateMap := map[string]any{}
+	if meta.Body.Name != nil {
+		if *meta.Body.Name == "" {
+			return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing name"))
+		}
+		updateMap["name"] = *meta.Body.Name
+	}
+
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		if len(updateMap) > 0 {
+			err = tx.Session(&gorm.Session{NewDB: true}).
+				Model(&schema.Organization{}).
+				Where("id = ?", meta.Organization.ID).
+				Updates(updateMap).
+				Error
+			if err != nil {
+				return err
+			}
+		}
+
+		var organization schema.Organization
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Where("id = ?", meta.Organization.ID).
+			First(&organization).
+			Error
+		if err != nil {
+			return err
+		}
+
+		output.Message = "OK"
+		output.Success = true
+		output.Data.Organization = downballotapi.Organization{
+			ID:   fmt.Sprintf("%d", organization.ID),
+			Name: organization.Name,
+		}
+		return nil
+	})
+	if err != nil {
+		return output, err
+	}
+	return output, nil
+}
+
+type DeleteOrganizationIDMetadata struct {
+	restfulwrapper.HTTPMethodDELETE
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	hasOrganization
+	downballotwrapper.RequirePermissionOrganizationDelete
+	_                 string `api:"httppath:/organization/{organization_id}"`
+	_                 string `api:"doc" description:"Delete the organization."`
+	_                 string `api:"notes" description:"This deletes the organization and purges all of its data.  An archive of the organization must have been produced first; its confirmation token is required."`
+	ConfirmationToken string `api:"query:confirmation_token" description:"The confirmation token from a recent archive of the organization."`
+}
+
+func (a *API) DeleteOrganizationID(ctx context.Context, meta DeleteOrganizationIDMetadata) error {
+	if meta.ConfirmationToken == "" {
+		return restfulwrapper.NewAPIQueryParameterError("confirmation_token", fmt.Errorf("missing confirmation token; archive the organization first"))
+	}
+
+	var archives []*schema.OrganizationArchive
+	err := meta.DB.Session(&gorm.Session{}).
+		Where("organization_id = ?", meta.Organization.ID).
+		Where("token_hash = ?", hashOrganizationDeletionToken(meta.ConfirmationToken)).
+		Where("expiration_timestamp > ?", sqltype.DateTime(time.Now())).
+		Limit(1).
+		Find(&archives).
+		Error
+	if err != nil {
+		return fmt.Errorf("could not find organization archive: %w", err)
+	}
+	if len(archives) == 0 {
+		return restfulwrapper.NewAPIQueryParameterError("confirmation_token", fmt.Errorf("invalid or expired confirmation token"))
+	}
+
+	// Delete everything explicitly (children first) rather than relying on the database to cascade,
+	// so that the voter data is purged regardless of how foreign keys are enforced.
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		organizationID := meta.Organization.ID
+		personSubquery := tx.Session(&gorm.Session{NewDB: true}).Model(&schema.Person{}).Select("id").Where("organization_id = ?", organizationID)
+		groupSubquery := tx.Session(&gorm.Session{NewDB: true}).Model(&schema.Group{}).Select("id").Where("organization_id = ?", organizationID)
+		importJobSubquery := tx.Session(&gorm.Session{NewDB: true}).Model(&schema.ImportJob{}).Select("id").Where("organization_id = ?", organizationID)
+		surveySubquery := tx.Session(&gorm.Session{NewDB: true}).Model(&schema.Survey{}).Select("id").Where("organization_id = ?", organizationID)
+		surveyQuestionSubquery := tx.Session(&gorm.Session{NewDB: true}).Model(&schema.SurveyQuestion{}).Select("id").Where("survey_id IN (?)", surveySubquery)
+		surveyResponseSubquery := tx.Session(&gorm.Session{NewDB: true}).Model(&schema.SurveyResponse{}).Select("id").Where("survey_id IN (?)", surveySubquery)
+
+		steps := []struct {
+			description string
+			model       any
+			query       string
+			args        []any
+		}{
+			{"person audits", &schema.PersonAudit{}, "person_id IN (?)", []any{personSubquery}},
+			{"person fields", &schema.PersonField{}, "person_id IN (?)", []any{personSubquery}},
+			{"survey answers", &schema.SurveyAnswer{}, "survey_response_id IN (?)", []any{surveyResponseSubquery}},
+			{"survey responses", &schema.SurveyResponse{}, "survey_id IN (?)", []any{surveySubquery}},
+			{"survey choices", &schema.SurveyChoice{}, "survey_question_id IN (?)", []any{surveyQuestionSubquery}},
+			{"survey questions", &schema.SurveyQuestion{}, "survey_id IN (?)", []any{surveySubquery}},
+			{"surveys", &schema.Survey{}, "organization_id = ?", []any{organizationID}},
+			{"contact attempts", &schema.ContactAttempt{}, "person_id IN (?)", []any{personSubquery}},
+			{"household members", &schema.HouseholdMember{}, "person_id IN (?)", []any{personSubquery}},
+			{"households", &schema.Household{}, "organization_id = ?", []any{organizationID}},
+			{"turf members", &schema.TurfMember{}, "person_id IN (?)", []any{personSubquery}},
+			{"turfs", &schema.Turf{}, "organization_id = ?", []any{organizationID}},
+			{"persons", &schema.Person{}, "organization_id = ?", []any{organizationID}},
+			{"person field definitions", &schema.PersonFieldDefinition{}, "organization_id = ?", []any{organizationID}},
+			{"group users", &schema.UserGroupMap{}, "group_id IN (?)", []any{groupSubquery}},
+			{"groups", &schema.Group{}, "organization_id = ?", []any{organizationID}},
+			{"filters", &schema.Filter{}, "organization_id = ?", []any{organizationID}},
+			{"import job errors", &schema.ImportJobError{}, "import_job_id IN (?)", []any{importJobSubquery}},
+			{"import jobs", &schema.ImportJob{}, "organization_id = ?", []any{organizationID}},
+			{"import profiles", &schema.ImportProfile{}, "organization_id = ?", []any{organizationID}},
+			{"contact results", &schema.ContactResult{}, "organization_id = ?", []any{organizationID}},
+			{"organization users", &schema.UserOrganizationMap{}, "organization_id = ?", []any{organizationID}},
+			{"roles", &schema.Role{}, "organization_id = ?", []any{organizationID}},
+			{"organization archives", &schema.OrganizationArchive{}, "organization_id = ?", []any{organizationID}},
+			{"API tokens", &schema.APIToken{}, "organization_id = ?", []any{organizationID}},
+			{"organization", &schema.Organization{}, "id = ?", []any{organizationID}},
+		}
+		for _, step := range steps {
+			result := tx.Session(&gorm.Session{NewDB: true}).
+				Where(step.query, step.args...).
+				Delete(step.model)
+			if result.Error != nil {
+				return fmt.Errorf("could not delete %s: %w", step.description, result.Error)
+			}
+			slog.InfoContext(ctx, fmt.Sprintf("Deleted %d %s from organization %d.", result.RowsAffected, step.description, organizationID))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -57,6 +57,10 @@ type PostOrganizationMetadata struct {
 }
 
 func (a *API) PostOrganization(ctx context.Context, meta PostOrganizationMetadata) (output downballotapi.Envelope[downballotapi.RegisterOrganizationResponse], err error) {
+	err = requireInteractiveUser(&meta.CurrentUser)
+	if err != nil {
+		return output, err
+	}
 	if meta.Body.Name == "" {
 		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing name"))
 	}
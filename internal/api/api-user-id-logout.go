@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+type PostUserIDLogoutMetadata struct {
+	restfulwrapper.HTTPMethodPOST
+	downballotwrapper.RequireAuthenticatedUser
+	downballotwrapper.UseDatabase
+	UserID string                      `api:"path:user_id" description:"The user ID"`
+	_      string                      `api:"httppath:/user/{user_id}/logout"`
+	_      string                      `api:"doc" description:"Log a user out everywhere."`
+	_      string                      `api:"notes" description:"This revokes all of the user's sessions and API tokens.  Only a system administrator may do this."`
+	Body   downballotapi.LogoutRequest `api:"body"`
+}
+
+func (a *API) PostUserIDLogout(ctx context.Context, meta PostUserIDLogoutMetadata) (output downballotapi.Envelope[downballotapi.LogoutResponse], err error) {
+	if !meta.CurrentUser.SystemAdmin {
+		return output, restfulwrapper.NewAPIResponseError(http.StatusForbidden, "Only a system administrator may log out another user")
+	}
+
+	// Look the user up only after the permission check so that the response doesn't reveal which users exist.
+	var users []*schema.User
+	err = meta.DB.Session(&gorm.Session{}).
+		Where("id = ?", meta.UserID).
+		Find(&users).
+		Error
+	if err != nil {
+		return output, fmt.Errorf("could not find user: %w", err)
+	}
+	if len(users) == 0 {
+		return output, restfulwrapper.NewAPIResponseError(http.StatusNotFound, "User not found")
+	}
+	user := users[0]
+
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		revokedSessions, err := revokeUserSessions(tx, user.ID)
+		if err != nil {
+			return err
+		}
+		output.Data.RevokedSessions = revokedSessions
+		return nil
+	})
+	if err != nil {
+		return output, err
+	}
+
+	slog.InfoContext(ctx, fmt.Sprintf("Revoked %d sessions for user %d.", output.Data.RevokedSessions, user.ID))
+
+	output.Message = "OK"
+	output.Success = true
+	return output, nil
+}
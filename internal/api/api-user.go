@@ -2,7 +2,9 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 
 	"github.com/downballot/downballot/downballotapi"
@@ -18,7 +20,7 @@ type PostUserMetadata struct {
 	downballotwrapper.UseDatabase
 	_    string                            `api:"httppath:/user"`
 	_    string                            `api:"doc" description:"Register a new user."`
-	_    string                            `api:"notes" description:"This registers a new user."`
+	_    string                            `api:"notes" description:"This registers a new user.  The user cannot log in until they use the link in the verification e-mail.  Registering an unverified username again replaces it."`
 	Body downballotapi.RegisterUserRequest `api:"body"`
 }
 
@@ -30,6 +32,7 @@ func (a *API) PostUser(ctx context.Context, meta PostUserMetadata) (output downb
 		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing username"))
 	}
 
+	var existingUser *schema.User
 	{
 		var testUsers []*schema.User
 		err = meta.DB.Session(&gorm.Session{}).
@@ -40,36 +43,113 @@ func (a *API) PostUser(ctx context.Context, meta PostUserMetadata) (output downb
 			return output, fmt.Errorf("could not search for existing users: %w", err)
 		}
 		if len(testUsers) > 0 {
-			// TODO: Don't fail if the user is unverified; just treat it like a new user.
-			return output, restfulwrapper.NewAPIResponseError(http.StatusConflict, "Username already taken")
+			if testUsers[0].Verified {
+				return output, restfulwrapper.NewAPIResponseError(http.StatusConflict, "Username already taken")
+			}
+			// Nobody has proven that they own this address yet, so treat this like a new user.
+			existingUser = testUsers[0]
 		}
 	}
 
+	var token string
 	output.Message = "OK"
 	output.Success = true
 	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		err = throttleEmail(tx, meta.Body.Username)
+		if err != nil {
+			return err
+		}
+
 		user := schema.User{
 			Name:     meta.Body.Name,
 			Username: meta.Body.Username,
 		}
 
+		if existingUser == nil {
+			err = tx.Session(&gorm.Session{NewDB: true}).
+				Create(&user).
+				Error
+			if err != nil {
+				return fmt.Errorf("could not create user: %w", err)
+			}
+		} else {
+			user.ID = existingUser.ID
+			err = tx.Session(&gorm.Session{NewDB: true}).
+				Model(&schema.User{}).
+				Where("id = ?", user.ID).
+				Update("name", user.Name).
+				Error
+			if err != nil {
+				return fmt.Errorf("could not update user: %w", err)
+			}
+		}
+
+		// The user stays unverified until they use the link in the verification e-mail.
+		token, err = a.newUserLink(tx, &user, userLinkPurposeVerify, userLinkVerifyLifetime)
+		if err != nil {
+			return err
+		}
+
+		output.Data.ID = fmt.Sprintf("%d", user.ID)
+		output.Data.Name = user.Name
+		output.Data.Username = user.Username
+		return nil
+	})
+	if err != nil {
+		return output, fmt.Errorf("could not execute transaction: %w", err)
+	}
+
+	err = a.sendEmail(ctx, meta.Body.Username, "Verify your Downballot account", fmt.Sprintf("Welcome to Downballot!  To verify your e-mail address, visit this link:\n\n%s\n\nIf you did not sign up for Downballot, then you can ignore this e-mail.", a.userLinkURL("/ui/verify", token)))
+	if err != nil {
+		slog.WarnContext(ctx, fmt.Sprintf("Could not send e-mail: %v", err))
+	}
+
+	return output, nil
+}
+
+// VerifyUser does not accept authentication, since the link in the e-mail is the proof of identity.
+type PostUserVerifyMetadata struct {
+	restfulwrapper.HTTPMethodPOST
+	downballotwrapper.UseDatabase
+	_    string                          `api:"httppath:/user/verify"`
+	_    string                          `api:"doc" description:"Verify a user's e-mail address."`
+	_    string                          `api:"notes" description:"This uses the token from a verification e-mail to verify the user's e-mail address."`
+	Body downballotapi.VerifyUserRequest `api:"body"`
+}
+
+func (a *API) PostUserVerify(ctx context.Context, meta PostUserVerifyMetadata) (output downballotapi.Envelope[downballotapi.VerifyUserResponse], err error) {
+	if meta.Body.Token == "" {
+		return output, restfulwrapper.NewAPIBodyError(fmt.Errorf("missing token"))
+	}
+
+	err = meta.DB.Transaction(func(tx *gorm.DB) error {
+		user, err := a.useUserLink(tx, meta.Body.Token, userLinkPurposeVerify)
+		if err != nil {
+			return err
+		}
+
 		err = tx.Session(&gorm.Session{NewDB: true}).
-			Create(&user).
+			Model(&schema.User{}).
+			Where("id = ?", user.ID).
+			Update("verified", true).
 			Error
 		if err != nil {
-			return fmt.Errorf("could not create user: %w", err)
+			return fmt.Errorf("could not update user: %w", err)
 		}
 
-		// TODO: Mark the user as unverified and send a verification e-mail.
-
 		output.Data.ID = fmt.Sprintf("%d", user.ID)
 		output.Data.Name = user.Name
 		output.Data.Username = user.Username
 		return nil
 	})
 	if err != nil {
-		return output, fmt.Errorf("could not execute transaction: %w", err)
+		if errors.Is(err, errInvalidUserLink) {
+			return output, restfulwrapper.NewAPIBodyError(err)
+		}
+		return output, err
 	}
 
+	output.Message = "OK"
+	output.Success = true
 	return output, nil
 }
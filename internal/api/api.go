@@ -2,13 +2,14 @@ package api
 
 import (
 	"context"
-	"crypto/rsa"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"runtime/debug"
+	"strings"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/apitoken"
 	"github.com/downballot/downballot/internal/application"
 	restfulspec "github.com/emicklei/go-restful-openapi/v2"
 	"github.com/emicklei/go-restful/v3"
@@ -19,57 +20,57 @@ import (
 )
 
 type API struct {
-	jwtSecret     []byte          // This is the JWT secret, if any.
-	jwtPublicKey  *rsa.PublicKey  // This is the JWT public key, if any.
-	jwtPrivateKey *rsa.PrivateKey // This is the JWT private key, if any.
-	mailer        *mailer.Mailer  // This is the mailer.
+	keyring *apitoken.Keyring // This is the keyring for signing and verifying tokens.
+	mailer  *mailer.Mailer    // This is the mailer.
+	baseURL string            // This is the public URL of the application.
+	oidc    *oidcClient       // This is the OpenID Connect client, if any.
+	jobs    *backgroundJobs   // These are the jobs that outlive their requests.
 }
 
 // DefaultPageSize is the default page size for paginated things.
 const DefaultPageSize = 25
 
+// MaxPageSize is the largest page size that can be asked for.
+const MaxPageSize = 10000
+
 // Instance contains the local data for the API.
 type Instance struct {
 	App    *application.App // This is the application.
 	Config Config           // This is the full configuration.
 
-	jwtSecret     []byte          // This is the JWT secret, if any.
-	jwtPublicKey  *rsa.PublicKey  // This is the JWT public key, if any.
-	jwtPrivateKey *rsa.PrivateKey // This is the JWT private key, if any.
+	jobs *backgroundJobs // These are the jobs that outlive their requests.
 }
 
 // New returns a new API instance.
 func New() *Instance {
 	instance := new(Instance)
+	instance.jobs = newBackgroundJobs()
 	return instance
 }
 
+// FailInterruptedJobs marks the jobs that a previous run of the server left unfinished as failed.
+//
+// This should be called once at startup, before any requests are served.
+func (i *Instance) FailInterruptedJobs(ctx context.Context) error {
+	return failInterruptedImportJobs(ctx, i.App.DB())
+}
+
+// Shutdown stops the jobs that are running in the background and waits for them to finish, or until the context is done.
+func (i *Instance) Shutdown(ctx context.Context) error {
+	return i.jobs.Shutdown(ctx)
+}
+
 // Container creates a new `restful` container.
 //
 // If `Debug` is set, then the debug endpoints will be added to it.
 func (i *Instance) Container(ctx context.Context) *restful.Container {
-	if i.Config.JWTSecret != "" {
-		i.jwtSecret = []byte(i.Config.JWTSecret)
-	}
-	if i.Config.JWTPublicKey != "" {
-		publicKey, err := jwt.ParseRSAPublicKeyFromPEM([]byte(i.Config.JWTPublicKey))
-		if err != nil {
-			slog.WarnContext(ctx, fmt.Sprintf("Could not parse PEM public key: %v", err))
-		} else {
-			i.jwtPublicKey = publicKey
-		}
+	keyring, err := i.Config.Keyring()
+	if err != nil {
+		// Without a keyring, no token can be issued or accepted.
+		slog.ErrorContext(ctx, fmt.Sprintf("Could not load the JWT keys: %v", err))
+		keyring = apitoken.NewKeyring(false)
 	}
-	if i.Config.JWTPrivateKey != "" {
-		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(i.Config.JWTPrivateKey))
-		if err != nil {
-			slog.WarnContext(ctx, fmt.Sprintf("Could not parse PEM private key: %v", err))
-		} else {
-			i.jwtPrivateKey = privateKey
-		}
-	}
-	//slog.DebugContext(ctx, fmt.Sprintf("JWT secret: %v", i.jwtSecret))
-	//slog.DebugContext(ctx, fmt.Sprintf("JWT public key: %v", i.jwtPublicKey))
-	//slog.DebugContext(ctx, fmt.Sprintf("JWT private key: %v", i.jwtPrivateKey))
+	slog.InfoContext(ctx, fmt.Sprintf("JWT keys: %v (signing key: %q)", keyring.KeyIDs(), keyring.SigningKeyID()))
 
 	container := restful.NewContainer()
 
@@ -115,25 +116,29 @@ func (i *Instance) Container(ctx context.Context) *restful.Container {
 		{
 			middlewareConfig := downballotwrapper.Config{
 				DB:          i.App.DB(),
+				Keyring:     keyring,
 				SystemToken: i.Config.MasterToken,
 			}
-			if i.Config.JWTSecret != "" {
-				middlewareConfig.JWTSecret = []byte(i.Config.JWTSecret)
-			}
 
 			var mailerInstance *mailer.Mailer
 			if i.Config.SendGridAPIKey != "" {
 				mailerInstance = mailer.New(mailer.TypeSendgrid, mailer.WithAPIKey(i.Config.SendGridAPIKey))
 			}
 
+			var oidcInstance *oidcClient
+			if i.Config.OIDCIssuer != "" {
+				oidcInstance = newOIDCClient(i.Config.OIDCIssuer, i.Config.OIDCClientID, i.Config.OIDCClientSecret, strings.TrimSuffix(i.Config.BaseURL, "/")+"/ui/oidc-callback")
+			}
+
 			session := webService.Session().
 				Attributes(middlewareConfig.Attributes()).
 				Do(middlewareConfig.Do())
 			session.Register(ctx, "/", &API{
-				jwtSecret:     i.jwtSecret,
-				jwtPublicKey:  i.jwtPublicKey,
-				jwtPrivateKey: i.jwtPrivateKey,
-				mailer:        mailerInstance,
+				keyring: keyring,
+				mailer:  mailerInstance,
+				baseURL: strings.TrimSuffix(i.Config.BaseURL, "/"),
+				oidc:    oidcInstance,
+				jobs:    i.jobs,
 			})
 		}
 
@@ -189,5 +194,17 @@ func (i *Instance) Container(ctx context.Context) *restful.Container {
 	}
 	container.Add(restfulspec.NewOpenAPIService(config))
 
+	// Publish the public keys so that other services can verify our tokens.
+	// This is a standard document, so it doesn't use our envelope.
+	{
+		webService := new(restful.WebService).
+			Path("/.well-known").
+			Produces(restful.MIME_JSON)
+		webService.Route(webService.GET("/jwks.json").To(func(req *restful.Request, resp *restful.Response) {
+			resp.WriteHeaderAndEntity(http.StatusOK, keyring.JWKS())
+		}))
+		container.Add(webService)
+	}
+
 	return container
 }
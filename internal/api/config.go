@@ -1,10 +1,99 @@
 package api
 
+import (
+	"fmt"
+
+	"github.com/downballot/downballot/internal/apitoken"
+)
+
 type Config struct {
-	JWTSecret     string
-	JWTPublicKey  string
-	JWTPrivateKey string
+	JWTSecret     string // This is the legacy JWT secret; it has the key ID "default".
+	JWTPublicKey  string // This is the legacy JWT public key; it has the key ID "default".
+	JWTPrivateKey string // This is the legacy JWT private key; it has the key ID "default".
+
+	JWTKeys         []JWTKey // These are the JWT keys, which may be rotated.
+	JWTSigningKeyID string   // This is the ID of the key that signs new tokens; every other key only verifies tokens.
+	Development     bool     // If set, then tokens are not signed when there are no JWT keys.  Never use this in production.
 
 	MasterToken    string // This is the master token for full system authentication.
 	SendGridAPIKey string // This is the SendGrid API key for sending emails.
+	BaseURL        string // This is the public URL of the application, which is used to build the links in emails.
+
+	OIDCIssuer       string // This is the OpenID Connect issuer URL; if set, then users may log in with the identity provider.
+	OIDCClientID     string // This is the OpenID Connect client ID.
+	OIDCClientSecret string // This is the OpenID Connect client secret.
+}
+
+// JWTKey is a key for signing and verifying tokens.
+//
+// Exactly one of the secret, the private key, or the public key must be set.  A key with only a public key can only
+// verify tokens.
+type JWTKey struct {
+	ID         string // This is the key ID, which is put in the "kid" header of every token that the key signs.
+	Secret     string // This is a shared secret (HMAC).
+	PrivateKey string // This is a PEM-encoded RSA, ECDSA, or Ed25519 private key.
+	PublicKey  string // This is a PEM-encoded RSA, ECDSA, or Ed25519 public key.
+}
+
+// Keyring returns the keyring for signing and verifying tokens.
+//
+// This fails if no key can sign tokens, unless this is development.
+func (c Config) Keyring() (*apitoken.Keyring, error) {
+	keyring := apitoken.NewKeyring(c.Development)
+
+	keys := []JWTKey{}
+	if c.JWTSecret != "" || c.JWTPrivateKey != "" || c.JWTPublicKey != "" {
+		// The secret always took precedence over the key pair, so keep it that way.
+		legacyKey := JWTKey{
+			ID: apitoken.LegacyKeyID,
+		}
+		switch {
+		case c.JWTSecret != "":
+			legacyKey.Secret = c.JWTSecret
+		case c.JWTPrivateKey != "":
+			legacyKey.PrivateKey = c.JWTPrivateKey
+		default:
+			legacyKey.PublicKey = c.JWTPublicKey
+		}
+		keys = append(keys, legacyKey)
+	}
+	keys = append(keys, c.JWTKeys...)
+
+	var signingKeyIDs []string
+	for _, key := range keys {
+		var err error
+		switch {
+		case key.Secret != "":
+			err = keyring.AddSecret(key.ID, []byte(key.Secret))
+			signingKeyIDs = append(signingKeyIDs, key.ID)
+		case key.PrivateKey != "":
+			err = keyring.AddPrivateKeyPEM(key.ID, key.PrivateKey)
+			signingKeyIDs = append(signingKeyIDs, key.ID)
+		case key.PublicKey != "":
+			err = keyring.AddPublicKeyPEM(key.ID, key.PublicKey)
+		default:
+			err = fmt.Errorf("key %q: missing secret, private key, or public key", key.ID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT key: %w", err)
+		}
+	}
+
+	signingKeyID := c.JWTSigningKeyID
+	if signingKeyID == "" && len(signingKeyIDs) == 1 {
+		signingKeyID = signingKeyIDs[0]
+	}
+	if signingKeyID != "" {
+		err := keyring.SetSigningKey(signingKeyID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JWT signing key: %w", err)
+		}
+	} else if len(signingKeyIDs) > 1 {
+		return nil, fmt.Errorf("missing JWT signing key ID")
+	}
+
+	if !keyring.CanSign() {
+		return nil, fmt.Errorf("no JWT signing key is configured")
+	}
+	return keyring, nil
 }
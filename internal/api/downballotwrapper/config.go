@@ -1,19 +1,16 @@
 package downballotwrapper
 
 import (
-	"crypto/rsa"
-
+	"github.com/downballot/downballot/internal/apitoken"
 	"github.com/emicklei/go-restful/v3"
 	"gorm.io/gorm"
 )
 
 // Config is the configuration for the middleware.
 type Config struct {
-	DB            *gorm.DB
-	JWTPrivateKey *rsa.PrivateKey // This is the JWT private key, if any.
-	JWTPublicKey  *rsa.PublicKey  // This is the JWT public key, if any.
-	JWTSecret     []byte
-	SystemToken   string
+	DB          *gorm.DB
+	Keyring     *apitoken.Keyring // This is the keyring for verifying tokens.
+	SystemToken string
 }
 
 // Attributes returns the attributes for the middleware.
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/downballot/downballot/downballotapi"
 	"github.com/emicklei/go-restful/v3"
@@ -18,6 +20,34 @@ func Error(err error) error {
 	}
 }
 
+// NewTooManyRequestsError returns an error for a request that was rate limited.
+// The response will tell the client how long to wait before trying again.
+func NewTooManyRequestsError(retryAfter time.Duration, message string) error {
+	if message == "" {
+		message = http.StatusText(http.StatusTooManyRequests)
+	}
+	return &tooManyRequestsError{
+		retryAfter: retryAfter,
+		message:    message,
+	}
+}
+
+// tooManyRequestsError is an error for a request that was rate limited.
+type tooManyRequestsError struct {
+	retryAfter time.Duration
+	message    string
+}
+
+var _ error = (*tooManyRequestsError)(nil)
+
+func (e *tooManyRequestsError) Error() string {
+	return e.message
+}
+
+func (e *tooManyRequestsError) Unwrap() error {
+	return httperror.ErrStatusTooManyRequests
+}
+
 // wrappedError is an error
 type wrappedError struct {
 	err error
@@ -43,6 +73,18 @@ func (e *wrappedError) WriteError(resp *restful.Response) {
 		}
 	}
 
+	{
+		var errTooManyRequests *tooManyRequestsError
+		if errors.As(e.err, &errTooManyRequests) {
+			// The header is in whole seconds; always round up so that the client doesn't retry too early.
+			seconds := int64((errTooManyRequests.retryAfter + time.Second - 1) / time.Second)
+			if seconds < 1 {
+				seconds = 1
+			}
+			resp.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+		}
+	}
+
 	content := downballotapi.Envelope[Output]{
 		Message: e.err.Error(),
 		Success: false,
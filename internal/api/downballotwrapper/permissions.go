@@ -84,3 +84,78 @@ type RequirePermissionPersonFieldDefinitionRead struct {
 type RequirePermissionPersonFieldDefinitionUpdate struct {
 	_ string `api:"downballot.permission:person-field-definition:update"`
 }
+type RequirePermissionImportProfileCreate struct {
+	_ string `api:"downballot.permission:import-profile:create"`
+}
+type RequirePermissionImportProfileDelete struct {
+	_ string `api:"downballot.permission:import-profile:delete"`
+}
+type RequirePermissionImportProfileRead struct {
+	_ string `api:"downballot.permission:import-profile:read"`
+}
+type RequirePermissionImportProfileUpdate struct {
+	_ string `api:"downballot.permission:import-profile:update"`
+}
+type RequirePermissionContactResultCreate struct {
+	_ string `api:"downballot.permission:contact-result:create"`
+}
+type RequirePermissionContactResultDelete struct {
+	_ string `api:"downballot.permission:contact-result:delete"`
+}
+type RequirePermissionContactResultRead struct {
+	_ string `api:"downballot.permission:contact-result:read"`
+}
+type RequirePermissionContactResultUpdate struct {
+	_ string `api:"downballot.permission:contact-result:update"`
+}
+type RequirePermissionContactAttemptCreate struct {
+	_ string `api:"downballot.permission:contact-attempt:create"`
+}
+type RequirePermissionContactAttemptRead struct {
+	_ string `api:"downballot.permission:contact-attempt:read"`
+}
+type RequirePermissionHouseholdUpdate struct {
+	_ string `api:"downballot.permission:household:update"`
+}
+type RequirePermissionTurfCreate struct {
+	_ string `api:"downballot.permission:turf:create"`
+}
+type RequirePermissionTurfDelete struct {
+	_ string `api:"downballot.permission:turf:delete"`
+}
+type RequirePermissionTurfRead struct {
+	_ string `api:"downballot.permission:turf:read"`
+}
+type RequirePermissionTurfUpdate struct {
+	_ string `api:"downballot.permission:turf:update"`
+}
+type RequirePermissionSurveyCreate struct {
+	_ string `api:"downballot.permission:survey:create"`
+}
+type RequirePermissionSurveyDelete struct {
+	_ string `api:"downballot.permission:survey:delete"`
+}
+type RequirePermissionSurveyRead struct {
+	_ string `api:"downballot.permission:survey:read"`
+}
+type RequirePermissionSurveyUpdate struct {
+	_ string `api:"downballot.permission:survey:update"`
+}
+type RequirePermissionSurveyResponseCreate struct {
+	_ string `api:"downballot.permission:survey-response:create"`
+}
+type RequirePermissionSurveyResponseRead struct {
+	_ string `api:"downballot.permission:survey-response:read"`
+}
+type RequirePermissionRoleCreate struct {
+	_ string `api:"downballot.permission:role:create"`
+}
+type RequirePermissionRoleDelete struct {
+	_ string `api:"downballot.permission:role:delete"`
+}
+type RequirePermissionRoleRead struct {
+	_ string `api:"downballot.permission:role:read"`
+}
+type RequirePermissionRoleUpdate struct {
+	_ string `api:"downballot.permission:role:update"`
+}
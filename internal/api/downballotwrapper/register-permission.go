@@ -11,8 +11,49 @@ import (
 	"github.com/tekkamanendless/restfulwrapper"
 )
 
+// These are for endpoints that act on groups that are not in their paths (such as a group in the body or every group
+// that a person is in), so their permissions are met if the user has them on any group in the organization.  The
+// handler must then check the permission against each group that it acts on (see `User.PermissionSetForGroup`), unless
+// what it acts on does not belong to any group (such as a survey).
+type RequirePermissionContactAttemptCreateOnAnyGroup struct {
+	_ string `api:"downballot.permissionOnAnyGroup:contact-attempt:create"`
+}
+type RequirePermissionContactAttemptReadOnAnyGroup struct {
+	_ string `api:"downballot.permissionOnAnyGroup:contact-attempt:read"`
+}
+type RequirePermissionGroupCreateOnAnyGroup struct {
+	_ string `api:"downballot.permissionOnAnyGroup:group:create"`
+}
+type RequirePermissionGroupUserCreateOnAnyGroup struct {
+	_ string `api:"downballot.permissionOnAnyGroup:group.user:create"`
+}
+type RequirePermissionPersonUpdateOnAnyGroup struct {
+	_ string `api:"downballot.permissionOnAnyGroup:person:update"`
+}
+type RequirePermissionSurveyReadOnAnyGroup struct {
+	_ string `api:"downballot.permissionOnAnyGroup:survey:read"`
+}
+type RequirePermissionSurveyResponseCreateOnAnyGroup struct {
+	_ string `api:"downballot.permissionOnAnyGroup:survey-response:create"`
+}
+type RequirePermissionSurveyResponseReadOnAnyGroup struct {
+	_ string `api:"downballot.permissionOnAnyGroup:survey-response:read"`
+}
+type RequirePermissionTurfReadOnAnyGroup struct {
+	_ string `api:"downballot.permissionOnAnyGroup:turf:read"`
+}
+
 func init() {
-	restfulwrapper.Register("downballot.permission", func(apiTagValue string, field reflect.StructField, info *restfulwrapper.RestfulFunctionInfo) (restfulwrapper.InputFieldFunction, error) {
+	registerPermission("downballot.permission", false)
+	registerPermission("downballot.permissionOnAnyGroup", true)
+}
+
+// registerPermission registers the tag for requiring a permission.
+//
+// If `anyGroup` is set and the request does not refer to a group, then a permission that the user was granted on any
+// group in the organization is enough.
+func registerPermission(tagName string, anyGroup bool) {
+	restfulwrapper.Register(tagName, func(apiTagValue string, field reflect.StructField, info *restfulwrapper.RestfulFunctionInfo) (restfulwrapper.InputFieldFunction, error) {
 		requireAuthentication := false
 		switch field.Type.String() {
 		case "string":
@@ -48,7 +89,13 @@ func init() {
 			if !ok {
 				return fmt.Errorf("organization ID value is not a uint64: %T", organizationIDInterface)
 			}
-			permissionSet := user.PermissionSetForOrganization(organizationID)
+			permissionSet, err := permissionSetForRequest(user, req, organizationID)
+			if err != nil {
+				return err
+			}
+			if anyGroup && req.Attribute(attributeGroupID) == nil {
+				permissionSet = user.permissionSetForAnyGroup(organizationID)
+			}
 			slog.DebugContext(ctx, fmt.Sprintf("Permission set for organization.id=%d: %v", organizationID, permissionSet.Permissions()))
 
 			matched := permissionSet.Match(permission)
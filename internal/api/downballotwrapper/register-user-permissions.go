@@ -11,7 +11,26 @@ import (
 	"github.com/tekkamanendless/restfulwrapper"
 )
 
-const attributeOrganizationID = "downballotwrapper.organizationID"
+const (
+	attributeOrganizationID = "downballotwrapper.organizationID"
+	attributeGroupID        = "downballotwrapper.groupID"
+)
+
+// permissionSetForRequest returns the permission set that applies to the request.
+//
+// If the request refers to a group, then this is the user's permission set for that group.  Otherwise, this is only the
+// user's permission set for the organization, so that a permission granted on one group never applies to the others.
+func permissionSetForRequest(user *User, req *restful.Request, organizationID uint64) (permissionset.PermissionSet, error) {
+	groupIDValue := req.Attribute(attributeGroupID)
+	if groupIDValue == nil {
+		return user.PermissionSetForOrganization(organizationID), nil
+	}
+	groupID, ok := groupIDValue.(uint64)
+	if !ok {
+		return permissionset.PermissionSet{}, fmt.Errorf("group ID value is not a uint64: %T", groupIDValue)
+	}
+	return user.PermissionSetForGroup(organizationID, groupID), nil
+}
 
 func init() {
 	restfulwrapper.Register("downballot.organizationPermissionSet", func(apiTagValue string, field reflect.StructField, info *restfulwrapper.RestfulFunctionInfo) (restfulwrapper.InputFieldFunction, error) {
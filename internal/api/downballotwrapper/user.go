@@ -4,11 +4,16 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/WinterYukky/gorm-extra-clause-plugin/exclause"
 	"github.com/downballot/downballot/iam"
+	"github.com/downballot/downballot/internal/apitoken"
 	"github.com/downballot/downballot/internal/schema"
+	"github.com/downballot/downballot/internal/schema/sqltype"
 	"github.com/downballot/downballot/permissionset"
 	"github.com/emicklei/go-restful/v3"
 	"github.com/tekkamanendless/httperror"
@@ -21,7 +26,18 @@ type User struct {
 	EmailAddress                   string                                 // The user's email address.  This will be "@system" if the system token is used.
 	Name                           string                                 // The user's name.  This will be "System User" if the system token is used.
 	SystemAdmin                    bool                                   // Whether the user is a system administrator.  This is only true if the system token is used.
+	SessionID                      uint64                                 // The session that the token belongs to.  This will be "0" if the token predates sessions or if the system token is used.
+	APITokenID                     uint64                                 // The personal API token that was used.  This will be "0" unless a personal API token was used.
+	apiTokenOrganizationID         uint64                                 // The only organization that the personal API token can access.
 	organizationToPermissionSetMap map[uint64]permissionset.PermissionSet // The user's permission set for each organization.
+	groupMap                       map[uint64]userGroup                   // The groups in each organization in which the user has been granted permissions on a group.
+}
+
+// userGroup is a group, as far as the user's permissions are concerned.
+type userGroup struct {
+	OrganizationID uint64                      // The organization that the group belongs to.
+	ParentID       uint64                      // The parent group ID.  This will be "0" for the root group.
+	PermissionSet  permissionset.PermissionSet // The permissions granted directly on this group (such as by owning it).
 }
 
 // PermissionSetForOrganization returns the user's permission set for an organization.
@@ -35,6 +51,57 @@ func (u *User) PermissionSetForOrganization(organizationID uint64) permissionset
 	return *permissionset.NewPermissionSet()
 }
 
+// PermissionSetForGroup returns the user's permission set for a group.
+//
+// This is the user's permission set for the organization plus any permissions that were granted on the group
+// or on any of its parents.
+func (u *User) PermissionSetForGroup(organizationID uint64, groupID uint64) permissionset.PermissionSet {
+	organizationPermissionSet := u.PermissionSetForOrganization(organizationID)
+	permissionSet := permissionset.NewPermissionSet(organizationPermissionSet.Permissions()...)
+	for groupID != 0 {
+		group, ok := u.groupMap[groupID]
+		if !ok || group.OrganizationID != organizationID {
+			break
+		}
+		permissionSet.AddPermission(group.PermissionSet.Permissions()...)
+		groupID = group.ParentID
+	}
+	return *permissionSet
+}
+
+// GroupIDsWithPermission returns the IDs of the groups in the organization on which the user was directly granted the permission.
+//
+// The permission also applies to every group beneath the ones returned.
+func (u *User) GroupIDsWithPermission(organizationID uint64, permission permissionset.Permission) []uint64 {
+	var output []uint64
+	for groupID, group := range u.groupMap {
+		if group.OrganizationID != organizationID {
+			continue
+		}
+		if group.PermissionSet.Match(permission) {
+			output = append(output, groupID)
+		}
+	}
+	slices.Sort(output)
+	return output
+}
+
+// permissionSetForAnyGroup returns the user's permission set for the organization plus every permission that
+// the user was granted on any group in it.
+//
+// This is only good enough to know whether the user might be able to do something; the handler must then
+// check the permission against the specific group.
+func (u *User) permissionSetForAnyGroup(organizationID uint64) permissionset.PermissionSet {
+	organizationPermissionSet := u.PermissionSetForOrganization(organizationID)
+	permissionSet := permissionset.NewPermissionSet(organizationPermissionSet.Permissions()...)
+	for _, group := range u.groupMap {
+		if group.OrganizationID == organizationID {
+			permissionSet.AddPermission(group.PermissionSet.Permissions()...)
+		}
+	}
+	return *permissionSet
+}
+
 // RequireAuthenticatedUser requires an authenticated user.
 type RequireAuthenticatedUser struct {
 	CurrentUser User `api:"downballot.currentUser"`
@@ -105,7 +172,7 @@ func (c Config) filterAppendUserInformation(req *restful.Request, resp *restful.
 		{
 			// This is the name of the real "organization" table.
 			//
-			// MySQL is smart enough to know that we're not referring to a table that we haven't created yet, but SQLite is not.
+			// MySQL and PostgreSQL are smart enough to know that we're not referring to a table that we haven't created yet, but SQLite is not.
 			//
 			// SQLite will fail with this error: SQL logic error: circular reference: organization (1)
 			// So, to work around that, we're going to insert the schema name, which is "main", so that SQLite doesn't get confused.
@@ -128,6 +195,9 @@ func (c Config) filterAppendUserInformation(req *restful.Request, resp *restful.
 			} else {
 				subQuery = subQuery.Where("user_organization_map.user_id = ?", user.ID)
 			}
+			if user.apiTokenOrganizationID != 0 {
+				subQuery = subQuery.Where("organization.id = ?", user.apiTokenOrganizationID)
+			}
 
 			withClause := exclause.With{
 				Recursive: false,
@@ -165,10 +235,17 @@ func (c Config) findUserInformationFromToken(db *gorm.DB, tokenString string) (*
 		}, nil
 	}
 
+	if apitoken.IsPersonalToken(tokenString) {
+		return findUserInformationFromPersonalToken(db, tokenString)
+	}
+
 	claims, err := c.ValidateToken(tokenString)
 	if err != nil {
 		return nil, fmt.Errorf("invalid token: %w", err)
 	}
+	if claims.Purpose != "" {
+		return nil, fmt.Errorf("token cannot be used for authentication: %s", claims.Purpose)
+	}
 
 	// Validate the user.
 	var users []*schema.User
@@ -185,6 +262,105 @@ func (c Config) findUserInformationFromToken(db *gorm.DB, tokenString string) (*
 	}
 	user := users[0]
 
+	// Tokens that were issued for a session only work as long as the session has not been revoked.
+	var sessionID uint64
+	if claims.Id != "" {
+		sessionID, err = strconv.ParseUint(claims.Id, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid session ID %q: %w", claims.Id, err)
+		}
+		var sessions []*schema.Session
+		err = db.Session(&gorm.Session{}).
+			Where("id = ?", sessionID).
+			Where("user_id = ?", user.ID).
+			Where("revoked_timestamp IS NULL").
+			Limit(1).
+			Find(&sessions).
+			Error
+		if err != nil {
+			return nil, fmt.Errorf("could not query for session: %w", err)
+		}
+		if len(sessions) == 0 {
+			return nil, fmt.Errorf("session %d has been revoked", sessionID)
+		}
+	}
+
+	userInformation, err := loadUserInformation(db, user)
+	if err != nil {
+		return nil, err
+	}
+	userInformation.SessionID = sessionID
+	return userInformation, nil
+}
+
+// findUserInformationFromPersonalToken finds the user for a personal API token.
+//
+// The user's permissions are limited to the token's organization and to the token's permissions.
+func findUserInformationFromPersonalToken(db *gorm.DB, tokenString string) (*User, error) {
+	var apiTokens []*schema.APIToken
+	err := db.Session(&gorm.Session{}).
+		Where("token_hash = ?", apitoken.HashPersonalToken(tokenString)).
+		Where("revoked_timestamp IS NULL").
+		Where("expiration_timestamp IS NULL OR expiration_timestamp > ?", sqltype.DateTime(time.Now())).
+		Preload("User").
+		Limit(1).
+		Find(&apiTokens).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("could not query for API token: %w", err)
+	}
+	if len(apiTokens) == 0 || apiTokens[0].User == nil {
+		return nil, fmt.Errorf("invalid API token")
+	}
+	apiToken := apiTokens[0]
+
+	userInformation, err := loadUserInformation(db, apiToken.User)
+	if err != nil {
+		return nil, err
+	}
+	userInformation.APITokenID = apiToken.ID
+	userInformation.apiTokenOrganizationID = apiToken.OrganizationID
+
+	// Every token can read its own organization; otherwise, it couldn't do anything in it.
+	tokenPermissionSet := permissionset.NewPermissionSet(permissionset.Permission(iam.IAMOrganizationRead))
+	for _, permission := range apiToken.Permissions {
+		tokenPermissionSet.AddPermission(permissionset.Permission(permission))
+	}
+
+	// restrict limits a permission set to the concrete permissions that the token also has.
+	restrict := func(permissionSet permissionset.PermissionSet) permissionset.PermissionSet {
+		output := permissionset.NewPermissionSet()
+		for _, permission := range iam.Permissions {
+			if permissionSet.Match(permission) && tokenPermissionSet.Match(permission) {
+				output.AddPermission(permission)
+			}
+		}
+		return *output
+	}
+
+	organizationToPermissionSetMap := map[uint64]permissionset.PermissionSet{}
+	if permissionSet, ok := userInformation.organizationToPermissionSetMap[apiToken.OrganizationID]; ok {
+		organizationToPermissionSetMap[apiToken.OrganizationID] = restrict(permissionSet)
+	}
+	userInformation.organizationToPermissionSetMap = organizationToPermissionSetMap
+
+	groupMap := map[uint64]userGroup{}
+	for groupID, group := range userInformation.groupMap {
+		if group.OrganizationID != apiToken.OrganizationID {
+			continue
+		}
+		group.PermissionSet = restrict(group.PermissionSet)
+		groupMap[groupID] = group
+	}
+	userInformation.groupMap = groupMap
+
+	return userInformation, nil
+}
+
+// loadUserInformation loads the user's permissions.
+func loadUserInformation(db *gorm.DB, user *schema.User) (*User, error) {
+	var err error
+
 	organizationToPermissionSetMap := map[uint64]permissionset.PermissionSet{}
 	{
 		var userOrganizationMaps []*schema.UserOrganizationMap
@@ -195,6 +371,30 @@ func (c Config) findUserInformationFromToken(db *gorm.DB, tokenString string) (*
 		if err != nil {
 			return nil, fmt.Errorf("could not query for user group maps: %w", err)
 		}
+
+		roleIDToRoleMap := map[uint64]*schema.Role{}
+		{
+			var roleIDs []uint64
+			for _, userOrganizationMap := range userOrganizationMaps {
+				if userOrganizationMap.RoleID != nil {
+					roleIDs = append(roleIDs, *userOrganizationMap.RoleID)
+				}
+			}
+			if len(roleIDs) > 0 {
+				var roles []*schema.Role
+				err = db.Session(&gorm.Session{}).
+					Where("id IN (?)", roleIDs).
+					Find(&roles).
+					Error
+				if err != nil {
+					return nil, fmt.Errorf("could not query for roles: %w", err)
+				}
+				for _, role := range roles {
+					roleIDToRoleMap[role.ID] = role
+				}
+			}
+		}
+
 		for _, userOrganizationMap := range userOrganizationMaps {
 			permissionSet := permissionset.PermissionSet{}
 			if userOrganizationMap.Owner {
@@ -205,17 +405,78 @@ func (c Config) findUserInformationFromToken(db *gorm.DB, tokenString string) (*
 				permissionSet.AddPermission(permissionset.Permission(iam.IAMOrganizationRead))
 				permissionSet.AddPermission(permissionset.Permission(iam.IAMPersonRead))
 				permissionSet.AddPermission(permissionset.Permission(iam.IAMPersonFieldDefinitionRead))
+
+				// A role adds to the read-only permissions that every member has.
+				if userOrganizationMap.RoleID != nil {
+					if role := roleIDToRoleMap[*userOrganizationMap.RoleID]; role != nil {
+						for _, permission := range role.Permissions {
+							permissionSet.AddPermission(permissionset.Permission(permission))
+						}
+					}
+				}
 			}
 			organizationToPermissionSetMap[userOrganizationMap.OrganizationID] = permissionSet
 		}
 	}
 
+	groupMap := map[uint64]userGroup{}
+	{
+		var userGroupMaps []*schema.UserGroupMap
+		err = db.Session(&gorm.Session{}).
+			Where("user_id = ?", user.ID).
+			Where("owner = ?", true).
+			Preload("Group").
+			Find(&userGroupMaps).
+			Error
+		if err != nil {
+			return nil, fmt.Errorf("could not query for user group maps: %w", err)
+		}
+
+		// We need the whole hierarchy of every organization in which the user owns a group so that the permissions
+		// can be inherited by the groups beneath it.
+		var organizationIDs []uint64
+		for _, userGroupMap := range userGroupMaps {
+			if userGroupMap.Group != nil {
+				organizationIDs = append(organizationIDs, userGroupMap.Group.OrganizationID)
+			}
+		}
+		if len(organizationIDs) > 0 {
+			var groups []*schema.Group
+			err = db.Session(&gorm.Session{}).
+				Where("organization_id IN (?)", organizationIDs).
+				Find(&groups).
+				Error
+			if err != nil {
+				return nil, fmt.Errorf("could not query for groups: %w", err)
+			}
+			for _, group := range groups {
+				g := userGroup{
+					OrganizationID: group.OrganizationID,
+				}
+				if group.ParentID != nil {
+					g.ParentID = *group.ParentID
+				}
+				groupMap[group.ID] = g
+			}
+		}
+
+		for _, userGroupMap := range userGroupMaps {
+			g, ok := groupMap[userGroupMap.GroupID]
+			if !ok {
+				continue
+			}
+			g.PermissionSet.AddPermission(iam.GroupOwnerPermissions...)
+			groupMap[userGroupMap.GroupID] = g
+		}
+	}
+
 	return &User{
 		ID:                             user.ID,
 		EmailAddress:                   user.Username,
 		Name:                           user.Name,
 		SystemAdmin:                    false,
 		organizationToPermissionSetMap: organizationToPermissionSetMap,
+		groupMap:                       groupMap,
 	}, nil
 }
 
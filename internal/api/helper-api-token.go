@@ -0,0 +1,39 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/api/resttype"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/tekkamanendless/restfulwrapper"
+)
+
+// requireInteractiveUser fails if the request was made with a personal API token.
+//
+// A personal API token is limited to one organization, so it may not be used to manage the user's account
+// (such as to get a new token with all of the user's permissions).
+func requireInteractiveUser(user *downballotwrapper.User) error {
+	if user != nil && user.APITokenID != 0 {
+		return restfulwrapper.NewAPIResponseError(http.StatusForbidden, "This cannot be done with an API token")
+	}
+	return nil
+}
+
+// convertAPIToken converts a personal API token into its API form.
+func convertAPIToken(apiToken *schema.APIToken) *downballotapi.APIToken {
+	output := &downballotapi.APIToken{
+		ID:               fmt.Sprintf("%d", apiToken.ID),
+		Name:             apiToken.Name,
+		OrganizationID:   fmt.Sprintf("%d", apiToken.OrganizationID),
+		Permissions:      append([]string{}, apiToken.Permissions...),
+		CreatedTimestamp: resttype.DateTime(apiToken.CreatedTimestamp),
+	}
+	if apiToken.ExpirationTimestamp != nil {
+		expirationTimestamp := resttype.DateTime(*apiToken.ExpirationTimestamp)
+		output.ExpirationTimestamp = &expirationTimestamp
+	}
+	return output
+}
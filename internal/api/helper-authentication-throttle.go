@@ -0,0 +1,206 @@
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/downballot/downballot/internal/schema/sqltype"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Authentication throttle kinds.
+const (
+	throttleKindLoginUsername = "login-username" // Failed logins for a username.
+	throttleKindLoginSource   = "login-source"   // Failed logins from a source address.
+	throttleKindEmail         = "email"          // E-mails sent to an address.
+
+	throttleKindAuthenticatorUser = "authenticator-user" // Failed authenticator codes from a user who is already logged in.
+)
+
+// Limits for failed logins.
+//
+// Once the maximum number of failures has been reached, further attempts are locked out, and the lockout doubles with
+// each additional failure.
+const (
+	loginUsernameMaxFailures = 5                // This is the number of failures allowed for a username before it is locked out.
+	loginSourceMaxFailures   = 20               // This is higher than for a username because many users may share an address.
+	loginLockoutBase         = 30 * time.Second // This is the first lockout.
+	loginLockoutMax          = 1 * time.Hour    // The lockout never grows beyond this.
+	loginFailureWindow       = 24 * time.Hour   // Failures older than this are forgotten.
+)
+
+// authenticatorMaxFailures is the number of failed authenticator codes allowed for a user who is already logged in
+// before they are locked out; the lockout grows just like it does for logins.
+const authenticatorMaxFailures = 5
+
+// Limits for e-mails sent to an address.
+const (
+	emailLimit  = 5
+	emailWindow = 1 * time.Hour
+)
+
+// authenticationThrottleRetries is the number of times that a throttle update is tried when parallel requests keep changing it.
+const authenticationThrottleRetries = 10
+
+// loadAuthenticationThrottle loads (or creates) the throttle for the kind and key.
+//
+// If the throttle's window is older than the given window, then it starts over.  The count as it was stored is also
+// returned so that the throttle can be saved with saveAuthenticationThrottle.
+func loadAuthenticationThrottle(tx *gorm.DB, kind string, key string, now time.Time, window time.Duration) (*schema.AuthenticationThrottle, int, error) {
+	err := tx.Session(&gorm.Session{NewDB: true}).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&schema.AuthenticationThrottle{
+			Kind:                 kind,
+			Key:                  key,
+			WindowStartTimestamp: sqltype.DateTime(now),
+		}).
+		Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not create authentication throttle: %w", err)
+	}
+
+	var throttle schema.AuthenticationThrottle
+	err = tx.Session(&gorm.Session{NewDB: true}).
+		Where("kind = ?", kind).
+		Where("throttle_key = ?", key).
+		First(&throttle).
+		Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not find authentication throttle: %w", err)
+	}
+	storedCount := throttle.Count
+
+	if time.Time(throttle.WindowStartTimestamp).Add(window).Before(now) {
+		throttle.Count = 0
+		throttle.WindowStartTimestamp = sqltype.DateTime(now)
+		throttle.LockedUntilTimestamp = nil
+	}
+	return &throttle, storedCount, nil
+}
+
+// saveAuthenticationThrottle saves the throttle, but only if its count hasn't changed since it was loaded.
+//
+// Every change to a throttle changes its count, so this returns false if a parallel request got there first; the
+// caller must then load the throttle and try again.
+func saveAuthenticationThrottle(tx *gorm.DB, throttle *schema.AuthenticationThrottle, storedCount int) (bool, error) {
+	result := tx.Session(&gorm.Session{NewDB: true}).
+		Model(&schema.AuthenticationThrottle{}).
+		Where("id = ?", throttle.ID).
+		Where("count = ?", storedCount).
+		Updates(map[string]any{
+			"count":                  throttle.Count,
+			"window_start_timestamp": throttle.WindowStartTimestamp,
+			"locked_until_timestamp": throttle.LockedUntilTimestamp,
+		})
+	if result.Error != nil {
+		return false, fmt.Errorf("could not update authentication throttle: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// reserveLoginAttempt counts a login attempt for the kind and key as a failure before the password is checked.
+//
+// This fails with a "429" error if logins are locked out.  Counting the attempt up front means that parallel guesses
+// cannot all get past the lockout before any of them has failed; a successful login takes the attempt back with
+// clearLoginFailures or releaseLoginAttempt.
+func reserveLoginAttempt(db *gorm.DB, kind string, key string, maxFailures int) error {
+	for range authenticationThrottleRetries {
+		now := time.Now()
+		throttle, storedCount, err := loadAuthenticationThrottle(db, kind, key, now, loginFailureWindow)
+		if err != nil {
+			return err
+		}
+		if throttle.LockedUntilTimestamp != nil && time.Time(*throttle.LockedUntilTimestamp).After(now) {
+			return downballotwrapper.NewTooManyRequestsError(time.Time(*throttle.LockedUntilTimestamp).Sub(now), "Too many failed attempts; try again later")
+		}
+
+		throttle.Count++
+		if throttle.Count >= maxFailures {
+			lockout := loginLockoutMax
+			if shift := throttle.Count - maxFailures; shift < 16 {
+				lockout = min(loginLockoutBase<<shift, loginLockoutMax)
+			}
+			lockedUntilTimestamp := sqltype.DateTime(now.Add(lockout))
+			throttle.LockedUntilTimestamp = &lockedUntilTimestamp
+		}
+
+		saved, err := saveAuthenticationThrottle(db, throttle, storedCount)
+		if err != nil {
+			return err
+		}
+		if saved {
+			return nil
+		}
+	}
+	return fmt.Errorf("could not update authentication throttle: too many parallel attempts")
+}
+
+// releaseLoginAttempt takes back an attempt that was reserved with reserveLoginAttempt, since it did not fail after all.
+func releaseLoginAttempt(db *gorm.DB, kind string, key string, maxFailures int) error {
+	for range authenticationThrottleRetries {
+		throttle, storedCount, err := loadAuthenticationThrottle(db, kind, key, time.Now(), loginFailureWindow)
+		if err != nil {
+			return err
+		}
+		if throttle.Count == 0 {
+			return nil
+		}
+
+		throttle.Count--
+		if throttle.Count < maxFailures {
+			throttle.LockedUntilTimestamp = nil
+		}
+
+		saved, err := saveAuthenticationThrottle(db, throttle, storedCount)
+		if err != nil {
+			return err
+		}
+		if saved {
+			return nil
+		}
+	}
+	return fmt.Errorf("could not update authentication throttle: too many parallel attempts")
+}
+
+// clearLoginFailures forgets the failed logins for the kind and key.
+func clearLoginFailures(tx *gorm.DB, kind string, key string) error {
+	err := tx.Session(&gorm.Session{NewDB: true}).
+		Where("kind = ?", kind).
+		Where("throttle_key = ?", key).
+		Delete(&schema.AuthenticationThrottle{}).
+		Error
+	if err != nil {
+		return fmt.Errorf("could not delete authentication throttle: %w", err)
+	}
+	return nil
+}
+
+// throttleEmail counts an e-mail that is about to be sent to the address.
+//
+// This fails with a "429" error if too many e-mails have been sent to the address recently.
+func throttleEmail(tx *gorm.DB, address string) error {
+	for range authenticationThrottleRetries {
+		now := time.Now()
+		throttle, storedCount, err := loadAuthenticationThrottle(tx, throttleKindEmail, address, now, emailWindow)
+		if err != nil {
+			return err
+		}
+		if throttle.Count >= emailLimit {
+			retryAfter := time.Time(throttle.WindowStartTimestamp).Add(emailWindow).Sub(now)
+			return downballotwrapper.NewTooManyRequestsError(retryAfter, "Too many e-mails have been sent to this address; try again later")
+		}
+
+		throttle.Count++
+		saved, err := saveAuthenticationThrottle(tx, throttle, storedCount)
+		if err != nil {
+			return err
+		}
+		if saved {
+			return nil
+		}
+	}
+	return fmt.Errorf("could not update authentication throttle: too many parallel attempts")
+}
@@ -0,0 +1,338 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/downballot/downballot/internal/schema/sqltype"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
+	"gorm.io/gorm"
+)
+
+// These are the settings that every authenticator app understands.
+const AuthenticatorPeriod = 30               // 30 seconds.
+const AuthenticatorDigits = otp.DigitsSix    // 6 digits.
+const AuthenticatorSkew = 1                  // Allow for the app's clock to be off by one time period in either direction.
+const authenticatorIssuer = "Downballot"     // This is the name that the app shows for the account.
+const authenticatorRecoveryCodeCount = 10    // This is the number of recovery codes that a user gets at a time.
+const authenticatorRecoveryCodeLength = 10   // This is the number of random bytes in a recovery code.
+const authenticatorRecoveryCodeGroupSize = 4 // Recovery codes are broken up into groups of this many characters.
+const authenticatorSecretMinLength = 20      // This is the minimum number of bytes in a secret that a user brings to log in.
+const authenticatorSecretMaxLength = 64      // This is the maximum number of bytes in a secret that a user brings to log in.
+
+// errAuthenticatorCodeRequired is returned when the user has an authenticator app but no code was given.
+var errAuthenticatorCodeRequired = errors.New("authenticator code required")
+
+// errAuthenticatorEnrollmentRequired is returned when the user must have an authenticator app (such as an organization
+// owner) but has not enrolled one.
+var errAuthenticatorEnrollmentRequired = errors.New("authenticator enrollment required")
+
+// errInvalidAuthenticatorSecret is returned when the secret for a new authenticator app is invalid.
+var errInvalidAuthenticatorSecret = errors.New("invalid authenticator secret")
+
+// errInvalidAuthenticatorCode is returned when a code from an authenticator app (or a recovery code) is invalid.
+var errInvalidAuthenticatorCode = errors.New("invalid authenticator code")
+
+// findAuthenticator returns the user's authenticator app, if any.
+//
+// If `confirmed` is set, then an authenticator that has not been confirmed yet is ignored.
+func findAuthenticator(db *gorm.DB, userID uint64, confirmed bool) (*schema.UserAuthenticator, error) {
+	query := db.Session(&gorm.Session{NewDB: true}).
+		Where("user_id = ?", userID)
+	if confirmed {
+		query = query.Where("confirmed_timestamp IS NOT NULL")
+	}
+	var authenticators []*schema.UserAuthenticator
+	err := query.
+		Find(&authenticators).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("could not find authenticator: %w", err)
+	}
+	if len(authenticators) == 0 {
+		return nil, nil
+	}
+	return authenticators[0], nil
+}
+
+// useAuthenticatorCode checks a code from the authenticator app and marks it as used.
+//
+// A code can only be used once, even within its time period, so that a code that was seen over someone's shoulder
+// is worthless.
+func useAuthenticatorCode(tx *gorm.DB, authenticator *schema.UserAuthenticator, code string) error {
+	counter, ok := authenticatorCodeCounter(string(authenticator.Secret), code, time.Now())
+	if !ok || counter <= authenticator.LastUsedCounter {
+		return errInvalidAuthenticatorCode
+	}
+
+	// Only update the counter if it is still older than the code, so that parallel requests cannot both use it.
+	result := tx.Session(&gorm.Session{NewDB: true}).
+		Model(&schema.UserAuthenticator{}).
+		Where("id = ?", authenticator.ID).
+		Where("last_used_counter < ?", counter).
+		Update("last_used_counter", counter)
+	if result.Error != nil {
+		return fmt.Errorf("could not update authenticator: %w", result.Error)
+	}
+	if result.RowsAffected != 1 {
+		return errInvalidAuthenticatorCode
+	}
+	authenticator.LastUsedCounter = counter
+	return nil
+}
+
+// authenticatorCodeCounter returns the time step that the code belongs to, if it is valid at all.
+func authenticatorCodeCounter(secret string, code string, now time.Time) (uint64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != AuthenticatorDigits.Length() {
+		return 0, false
+	}
+
+	currentCounter := now.Unix() / AuthenticatorPeriod
+	for offset := -AuthenticatorSkew; offset <= AuthenticatorSkew; offset++ {
+		counter := currentCounter + int64(offset)
+		expectedCode, err := totp.GenerateCodeCustom(secret, time.Unix(counter*AuthenticatorPeriod, 0), authenticatorValidateOpts())
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expectedCode), []byte(code)) == 1 {
+			return uint64(counter), true
+		}
+	}
+	return 0, false
+}
+
+// authenticatorValidateOpts returns the options for generating and validating authenticator app codes.
+func authenticatorValidateOpts() totp.ValidateOpts {
+	return totp.ValidateOpts{
+		Period:    uint(AuthenticatorPeriod),
+		Skew:      AuthenticatorSkew,
+		Digits:    AuthenticatorDigits,
+		Algorithm: otp.AlgorithmSHA1,
+	}
+}
+
+// verifySecondFactor checks the code from the user's authenticator app (or one of the user's recovery codes).
+//
+// If the user has not enrolled an authenticator app, then there is nothing to check.
+func verifySecondFactor(tx *gorm.DB, userID uint64, code string) error {
+	authenticator, err := findAuthenticator(tx, userID, true)
+	if err != nil {
+		return err
+	}
+	if authenticator == nil {
+		return nil
+	}
+	if strings.TrimSpace(code) == "" {
+		return errAuthenticatorCodeRequired
+	}
+
+	err = useAuthenticatorCode(tx, authenticator, code)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, errInvalidAuthenticatorCode) {
+		return err
+	}
+
+	// Someone who has lost their phone can use a recovery code instead.
+	result := tx.Session(&gorm.Session{NewDB: true}).
+		Model(&schema.UserRecoveryCode{}).
+		Where("user_id = ?", userID).
+		Where("code_hash = ?", hashRecoveryCode(code)).
+		Where("used_timestamp IS NULL").
+		Update("used_timestamp", sqltype.DateTime(time.Now()))
+	if result.Error != nil {
+		return fmt.Errorf("could not use recovery code: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return errInvalidAuthenticatorCode
+	}
+	return nil
+}
+
+// requiresAuthenticator returns whether the user must have an authenticator app to log in.
+//
+// This is the case for anyone who owns an organization or has a role with every permission, since they can do anything
+// with the organization's voter data.
+func requiresAuthenticator(tx *gorm.DB, userID uint64) (bool, error) {
+	var userOrganizationMaps []*schema.UserOrganizationMap
+	err := tx.Session(&gorm.Session{NewDB: true}).
+		Where("user_id = ?", userID).
+		Preload("Role").
+		Find(&userOrganizationMaps).
+		Error
+	if err != nil {
+		return false, fmt.Errorf("could not find user organization maps: %w", err)
+	}
+	for _, userOrganizationMap := range userOrganizationMaps {
+		if userOrganizationMap.Owner {
+			return true, nil
+		}
+		if userOrganizationMap.Role != nil && slices.Contains(userOrganizationMap.Role.Permissions, "*") {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// verifyLoginSecondFactor checks the second factor when the user logs in.
+//
+// A user without a confirmed authenticator app can enroll one by giving its secret along with a code from it; in that
+// case, the new recovery codes are returned.  Otherwise, a user who must have an authenticator app cannot log in
+// without one.
+func verifyLoginSecondFactor(tx *gorm.DB, userID uint64, code string, secret string) ([]string, error) {
+	authenticator, err := findAuthenticator(tx, userID, true)
+	if err != nil {
+		return nil, err
+	}
+	if authenticator != nil {
+		return nil, verifySecondFactor(tx, userID, code)
+	}
+	if secret != "" {
+		return enrollAuthenticator(tx, userID, secret, code)
+	}
+
+	required, err := requiresAuthenticator(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if required {
+		return nil, errAuthenticatorEnrollmentRequired
+	}
+	return nil, nil
+}
+
+// enrollAuthenticator enrolls and confirms an authenticator app with the given secret in one step and returns the new
+// recovery codes.
+//
+// This replaces any authenticator app that was not confirmed yet.
+func enrollAuthenticator(tx *gorm.DB, userID uint64, secret string, code string) ([]string, error) {
+	secret = strings.ToUpper(strings.TrimRight(strings.ReplaceAll(secret, " ", ""), "="))
+	secretBytes, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil || len(secretBytes) < authenticatorSecretMinLength || len(secretBytes) > authenticatorSecretMaxLength {
+		return nil, errInvalidAuthenticatorSecret
+	}
+	if strings.TrimSpace(code) == "" {
+		return nil, errAuthenticatorCodeRequired
+	}
+	counter, ok := authenticatorCodeCounter(secret, code, time.Now())
+	if !ok {
+		return nil, errInvalidAuthenticatorCode
+	}
+
+	err = tx.Session(&gorm.Session{NewDB: true}).
+		Where("user_id = ?", userID).
+		Delete(&schema.UserAuthenticator{}).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("could not delete authenticator: %w", err)
+	}
+
+	now := sqltype.DateTime(time.Now())
+	err = tx.Session(&gorm.Session{NewDB: true}).
+		Create(&schema.UserAuthenticator{
+			UserID:             userID,
+			Secret:             sqltype.EncryptedString(secret),
+			CreatedTimestamp:   now,
+			ConfirmedTimestamp: &now,
+			LastUsedCounter:    counter,
+		}).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("could not create authenticator: %w", err)
+	}
+
+	return newRecoveryCodes(tx, userID)
+}
+
+// newRecoveryCodes replaces the user's recovery codes and returns the new ones.
+//
+// Only the hashes are stored, so this is the only time that the codes are available.
+func newRecoveryCodes(tx *gorm.DB, userID uint64) ([]string, error) {
+	err := tx.Session(&gorm.Session{NewDB: true}).
+		Where("user_id = ?", userID).
+		Delete(&schema.UserRecoveryCode{}).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("could not delete recovery codes: %w", err)
+	}
+
+	var output []string
+	for range authenticatorRecoveryCodeCount {
+		codeBytes := make([]byte, authenticatorRecoveryCodeLength)
+		_, err := rand.Read(codeBytes)
+		if err != nil {
+			return nil, fmt.Errorf("could not generate recovery code: %w", err)
+		}
+		encoded := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(codeBytes))
+
+		var groups []string
+		for len(encoded) > authenticatorRecoveryCodeGroupSize {
+			groups = append(groups, encoded[:authenticatorRecoveryCodeGroupSize])
+			encoded = encoded[authenticatorRecoveryCodeGroupSize:]
+		}
+		groups = append(groups, encoded)
+		code := strings.Join(groups, "-")
+
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Create(&schema.UserRecoveryCode{
+				UserID:   userID,
+				CodeHash: hashRecoveryCode(code),
+			}).
+			Error
+		if err != nil {
+			return nil, fmt.Errorf("could not create recovery code: %w", err)
+		}
+		output = append(output, code)
+	}
+	return output, nil
+}
+
+// hashRecoveryCode returns the hash of a recovery code.
+//
+// The dashes and the case don't matter, since people will type the code in by hand.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	hash := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(hash[:])
+}
+
+// reserveAuthenticatorAttempt counts an attempt to check a code for a user who is already logged in as a failure
+// before the code is checked.
+//
+// This fails with a "429" error if the user is locked out, so that a stolen token cannot be used to guess codes.  The
+// attempt must be settled with finishAuthenticatorAttempt.
+func reserveAuthenticatorAttempt(db *gorm.DB, userID uint64) error {
+	return reserveLoginAttempt(db, throttleKindAuthenticatorUser, strconv.FormatUint(userID, 10), authenticatorMaxFailures)
+}
+
+// finishAuthenticatorAttempt settles an attempt that was reserved with reserveAuthenticatorAttempt.
+//
+// A correct code forgets the user's failures, and an invalid one stays counted; anything else did not check a code, so
+// the attempt is taken back.
+func finishAuthenticatorAttempt(db *gorm.DB, userID uint64, attemptErr error) error {
+	key := strconv.FormatUint(userID, 10)
+	switch {
+	case attemptErr == nil:
+		return db.Transaction(func(tx *gorm.DB) error {
+			return clearLoginFailures(tx, throttleKindAuthenticatorUser, key)
+		})
+	case errors.Is(attemptErr, errInvalidAuthenticatorCode):
+		return nil
+	default:
+		return releaseLoginAttempt(db, throttleKindAuthenticatorUser, key, authenticatorMaxFailures)
+	}
+}
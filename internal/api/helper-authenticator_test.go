@@ -0,0 +1,59 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/downballot/downballot/internal/databasetest"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/downballot/downballot/internal/schema/sqltype"
+	"github.com/downballot/downballot/internal/testutils"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUseAuthenticatorCode(t *testing.T) {
+	testutils.Setup(t)
+
+	ctx := t.Context()
+
+	db, err := databasetest.New(ctx)
+	require.NoError(t, err)
+
+	user := schema.User{Username: "use-authenticator-code@example.com"}
+	require.NoError(t, db.Create(&user).Error)
+
+	key, err := totp.Generate(totp.GenerateOpts{
+		Issuer:      "Downballot",
+		AccountName: user.Username,
+	})
+	require.NoError(t, err)
+	authenticator := schema.UserAuthenticator{
+		UserID:           user.ID,
+		Secret:           sqltype.EncryptedString(key.Secret()),
+		CreatedTimestamp: sqltype.DateTime(time.Now()),
+	}
+	require.NoError(t, db.Create(&authenticator).Error)
+
+	code, err := totp.GenerateCodeCustom(key.Secret(), time.Now(), authenticatorValidateOpts())
+	require.NoError(t, err)
+
+	// Two requests load the authenticator before either of them has used the code.
+	first := authenticator
+	second := authenticator
+
+	err = useAuthenticatorCode(db, &first, code)
+	require.NoError(t, err)
+
+	err = useAuthenticatorCode(db, &second, code)
+	require.ErrorIs(t, err, errInvalidAuthenticatorCode)
+
+	err = useAuthenticatorCode(db, &first, code)
+	require.ErrorIs(t, err, errInvalidAuthenticatorCode)
+
+	var stored schema.UserAuthenticator
+	require.NoError(t, db.Where("id = ?", authenticator.ID).First(&stored).Error)
+	assert.Equal(t, first.LastUsedCounter, stored.LastUsedCounter)
+	assert.Equal(t, authenticator.LastUsedCounter, second.LastUsedCounter)
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"sync"
+)
+
+// backgroundJobs keeps track of the work that outlives the request that started it (such as an import job),
+// so that shutting down can stop that work and wait for it.
+type backgroundJobs struct {
+	ctx    context.Context    // This is canceled when the server shuts down.
+	cancel context.CancelFunc // This cancels ctx.
+	wg     sync.WaitGroup     // This waits for every running job.
+}
+
+// newBackgroundJobs returns a new, empty set of background jobs.
+func newBackgroundJobs() *backgroundJobs {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &backgroundJobs{
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Go runs the function in the background.
+//
+// The function's context keeps the values of the given context, but it is only canceled when the server shuts down.
+func (b *backgroundJobs) Go(ctx context.Context, f func(ctx context.Context)) {
+	jobContext, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(b.ctx, cancel)
+	b.wg.Go(func() {
+		defer stop()
+		defer cancel()
+
+		f(jobContext)
+	})
+}
+
+// Shutdown cancels every background job and waits for them to return, or until the context is done.
+func (b *backgroundJobs) Shutdown(ctx context.Context) error {
+	b.cancel()
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
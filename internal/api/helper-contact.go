@@ -0,0 +1,216 @@
+package api
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/api/resttype"
+	"github.com/downballot/downballot/internal/filter"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+// codeRegexp matches a valid code, such as the contact result "not_home" or the survey choice "strong_yes".
+var codeRegexp = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// normalizeCode lowercases a code (for a contact result or a survey choice) and makes sure that it is valid.
+func normalizeCode(code string) (string, error) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if code == "" {
+		return "", fmt.Errorf("missing code")
+	}
+	if !codeRegexp.MatchString(code) {
+		return "", fmt.Errorf("invalid code: %q (only letters, digits, and underscores are allowed)", code)
+	}
+	return code, nil
+}
+
+// convertContactResult converts a contact result into its API form.
+func convertContactResult(contactResult *schema.ContactResult) *downballotapi.ContactResult {
+	return &downballotapi.ContactResult{
+		ID:          fmt.Sprintf("%d", contactResult.ID),
+		Code:        contactResult.Code,
+		Name:        contactResult.Name,
+		Description: contactResult.Description,
+	}
+}
+
+// convertContactAttempts converts the contact attempts into their API form.
+//
+// This looks up the voter IDs, the usernames, and the result codes that the attempts refer to.
+func convertContactAttempts(db *gorm.DB, contactAttempts []*schema.ContactAttempt) ([]*downballotapi.ContactAttempt, error) {
+	output := []*downballotapi.ContactAttempt{}
+	if len(contactAttempts) == 0 {
+		return output, nil
+	}
+
+	personIDMap := map[uint64]bool{}
+	userIDMap := map[uint64]bool{}
+	contactResultIDMap := map[uint64]bool{}
+	for _, contactAttempt := range contactAttempts {
+		personIDMap[contactAttempt.PersonID] = true
+		userIDMap[contactAttempt.UserID] = true
+		contactResultIDMap[contactAttempt.ContactResultID] = true
+	}
+
+	personIDToVoterIDMap := map[uint64]string{}
+	{
+		var persons []*schema.Person
+		err := db.Session(&gorm.Session{}).
+			Select("id", "voter_id").
+			Where("id IN (?)", slices.Collect(maps.Keys(personIDMap))).
+			Find(&persons).
+			Error
+		if err != nil {
+			return nil, fmt.Errorf("could not find persons: %w", err)
+		}
+		for _, person := range persons {
+			personIDToVoterIDMap[person.ID] = person.VoterID
+		}
+	}
+
+	userIDToUsernameMap := map[uint64]string{}
+	{
+		var users []*schema.User
+		err := db.Session(&gorm.Session{}).
+			Where("id IN (?)", slices.Collect(maps.Keys(userIDMap))).
+			Find(&users).
+			Error
+		if err != nil {
+			return nil, fmt.Errorf("could not find users: %w", err)
+		}
+		for userID := range userIDMap {
+			userIDToUsernameMap[userID] = "user #" + fmt.Sprintf("%d", userID)
+		}
+		for _, user := range users {
+			userIDToUsernameMap[user.ID] = user.Username
+		}
+	}
+
+	contactResultIDToCodeMap := map[uint64]string{}
+	{
+		var contactResults []*schema.ContactResult
+		err := db.Session(&gorm.Session{}).
+			Where("id IN (?)", slices.Collect(maps.Keys(contactResultIDMap))).
+			Find(&contactResults).
+			Error
+		if err != nil {
+			return nil, fmt.Errorf("could not find contact results: %w", err)
+		}
+		for _, contactResult := range contactResults {
+			contactResultIDToCodeMap[contactResult.ID] = contactResult.Code
+		}
+	}
+
+	for _, contactAttempt := range contactAttempts {
+		output = append(output, &downballotapi.ContactAttempt{
+			ID:        fmt.Sprintf("%d", contactAttempt.ID),
+			VoterID:   personIDToVoterIDMap[contactAttempt.PersonID],
+			UserID:    fmt.Sprintf("%d", contactAttempt.UserID),
+			Username:  userIDToUsernameMap[contactAttempt.UserID],
+			Timestamp: resttype.DateTime(contactAttempt.Timestamp),
+			Channel:   downballotapi.ContactChannel(contactAttempt.Channel),
+			Result:    contactResultIDToCodeMap[contactAttempt.ContactResultID],
+			Note:      contactAttempt.Note,
+		})
+	}
+	return output, nil
+}
+
+// contactFilterFieldPrefix is the start of the name of every contact filter field.
+//
+// Person fields cannot use this prefix, since the filters would never see them.
+const contactFilterFieldPrefix = "contact."
+
+// contactFilterField is a pseudo-field that filters the persons by their contact attempts.
+type contactFilterField struct {
+	Expression string // This is the SQL expression for the field; it refers to the person as "person.id".
+	Integer    bool   // If true, then the field is compared as an integer.
+}
+
+// lastContactAttemptExpression returns an expression for a column of the most recent contact attempt for the person.
+//
+// The contact result is joined in as "contact_result".
+func lastContactAttemptExpression(column string) string {
+	return "(SELECT " + column + " FROM contact_attempt" +
+		" INNER JOIN contact_result ON contact_result.id = contact_attempt.contact_result_id" +
+		" WHERE contact_attempt.person_id = person.id" +
+		" ORDER BY contact_attempt.timestamp DESC, contact_attempt.id DESC LIMIT 1)"
+}
+
+// contactFilterFields are the contact filter fields, by name.
+//
+// These allow filters such as "contact.last_result = not_home" or "contact.count < 2".
+var contactFilterFields = map[string]*contactFilterField{
+	contactFilterFieldPrefix + "count": {
+		Expression: "(SELECT COUNT(*) FROM contact_attempt WHERE contact_attempt.person_id = person.id)",
+		Integer:    true,
+	},
+	contactFilterFieldPrefix + "last_result": {
+		Expression: lastContactAttemptExpression("contact_result.code"),
+	},
+	contactFilterFieldPrefix + "last_channel": {
+		Expression: lastContactAttemptExpression("contact_attempt.channel"),
+	},
+}
+
+// buildContactFilterCondition builds the condition for a clause against a contact filter field.
+//
+// The contact result codes and channels are always lowercase, so the values are lowercased to match.
+func buildContactFilterCondition(db *gorm.DB, clause *filter.ClauseCondition) (*gorm.DB, error) {
+	contactFilterField := contactFilterFields[clause.Name]
+	if contactFilterField == nil {
+		return nil, fmt.Errorf("unknown field: %s", clause.Name)
+	}
+	expression := contactFilterField.Expression
+	if clause.Function != "" {
+		return nil, restfulwrapper.NewAPIResponseError(http.StatusBadRequest, fmt.Sprintf("Field %q cannot be given a function", clause.Name))
+	}
+
+	subquery := db.Session(&gorm.Session{NewDB: true, Initialized: true})
+	for _, value := range clause.Values {
+		var typedValue any = strings.ToLower(value)
+		if contactFilterField.Integer {
+			switch clause.Operation {
+			case filter.OperationWildcard, filter.OperationNotWildcard:
+				return nil, restfulwrapper.NewAPIResponseError(http.StatusBadRequest, fmt.Sprintf("Field %q cannot be matched by a pattern", clause.Name))
+			}
+			integerValue, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, restfulwrapper.NewAPIResponseError(http.StatusBadRequest, fmt.Sprintf("Field %q can only be compared with an integer", clause.Name))
+			}
+			typedValue = integerValue
+		}
+
+		switch clause.Operation {
+		case filter.OperationEquals:
+			subquery = subquery.Or(expression+" = ?", typedValue)
+		case filter.OperationNotEquals:
+			subquery = subquery.Where(expression+" IS NULL OR "+expression+" != ?", typedValue)
+		case filter.OperationGreaterThan:
+			subquery = subquery.Or(expression+" > ?", typedValue)
+		case filter.OperationGreaterThanOrEqual:
+			subquery = subquery.Or(expression+" >= ?", typedValue)
+		case filter.OperationLessThan:
+			subquery = subquery.Or(expression+" < ?", typedValue)
+		case filter.OperationLessThanOrEqual:
+			subquery = subquery.Or(expression+" <= ?", typedValue)
+		case filter.OperationWildcard:
+			subquery = subquery.Or(expression+" LIKE ?", strings.ReplaceAll(strings.ToLower(value), "*", "%"))
+		case filter.OperationNotWildcard:
+			subquery = subquery.Where(expression+" NOT LIKE ?", strings.ReplaceAll(strings.ToLower(value), "*", "%"))
+		case filter.OperationNear, filter.OperationWithin:
+			return nil, restfulwrapper.NewAPIResponseError(http.StatusBadRequest, fmt.Sprintf("Field %q cannot be compared with %q", clause.Name, clause.Operation))
+		default:
+			return nil, fmt.Errorf("unknown operation: %s", clause.Operation)
+		}
+	}
+	return subquery, nil
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/database"
+	"github.com/downballot/downballot/internal/filter"
+	"github.com/downballot/downballot/internal/geo"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+// coordinatesField is the name of the field that has the location of each person, as "latitude,longitude".
+//
+// This is the field that puts a person in a turf, and that the "distance" pseudo-field is measured from.
+const coordinatesField = "coordinates"
+
+// distancePseudoField is the name of the pseudo-field that has the distance (in meters) of each person from a point.
+//
+// It is only available when a point is given, and it can be returned and sorted like any other field.
+const distancePseudoField = "distance"
+
+// coordinatesWildcardDistance is how close (in meters) coordinates must be to match "coordinates ~ latitude,longitude".
+const coordinatesWildcardDistance = 100
+
+// coordinatesNearExpression returns a condition that matches the coordinates that are within the distance (in meters)
+// of the point.
+//
+// The bounding box around the point is checked first, so that the database only has to work out the distance for the
+// coordinates that are close.
+func coordinatesNearExpression(db *gorm.DB, fieldColumn string, point geo.Point, distance float64) (string, []any) {
+	latitude := database.CastFloat(db, database.TextBefore(db, fieldColumn))
+	longitude := database.CastFloat(db, database.TextAfter(db, fieldColumn))
+	box := geo.BoxAround(point, distance)
+
+	expression := "(" + latitude + " BETWEEN ? AND ? AND " + longitude + " BETWEEN ? AND ? AND " + database.Distance(db, latitude, longitude, point.Latitude, point.Longitude) + " <= ?)"
+	return expression, []any{box.MinLatitude, box.MaxLatitude, box.MinLongitude, box.MaxLongitude, distance}
+}
+
+// coordinatesWithinExpression returns a condition that matches the coordinates that are inside of the box.
+func coordinatesWithinExpression(db *gorm.DB, fieldColumn string, box geo.Box) (string, []any) {
+	latitude := database.CastFloat(db, database.TextBefore(db, fieldColumn))
+	longitude := database.CastFloat(db, database.TextAfter(db, fieldColumn))
+
+	expression := "(" + latitude + " BETWEEN ? AND ? AND " + longitude + " BETWEEN ? AND ?)"
+	return expression, []any{box.MinLatitude, box.MaxLatitude, box.MinLongitude, box.MaxLongitude}
+}
+
+// buildCoordinatesCondition builds the condition for one of the geographic operations:
+//
+//   - "coordinates near (latitude, longitude, distance)" matches the coordinates within the distance of the point.
+//   - "coordinates within box(latitude, longitude, latitude, longitude)" matches the coordinates inside of the box
+//     with those two corners.
+func buildCoordinatesCondition(db *gorm.DB, fieldColumn string, personFieldDefinition *schema.PersonFieldDefinition, clause *filter.ClauseCondition) (*gorm.DB, error) {
+	if personFieldDefinition.Type != schema.PersonFieldDefinitionTypeCoordinates {
+		return nil, restfulwrapper.NewAPIResponseError(http.StatusBadRequest, fmt.Sprintf("Field %q is not a coordinates field, so it cannot be compared with %q", clause.Name, clause.Operation))
+	}
+
+	var numbers []float64
+	parseNumbers := func(values []string) error {
+		for _, value := range values {
+			number, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return restfulwrapper.NewAPIResponseError(http.StatusBadRequest, fmt.Sprintf("Invalid number for %q: %q", clause.Operation, value))
+			}
+			numbers = append(numbers, number)
+		}
+		return nil
+	}
+
+	var expression string
+	var args []any
+	switch clause.Operation {
+	case filter.OperationNear:
+		if clause.Function != "" || len(clause.Values) != 3 {
+			return nil, restfulwrapper.NewAPIResponseError(http.StatusBadRequest, fmt.Sprintf("Operation %q takes a latitude, a longitude, and a distance, such as: %s near (43.61, -116.20, 2km)", clause.Operation, clause.Name))
+		}
+		err := parseNumbers(clause.Values[:2])
+		if err != nil {
+			return nil, err
+		}
+		point, err := geo.ParsePoint(clause.Values[0] + "," + clause.Values[1])
+		if err != nil {
+			return nil, restfulwrapper.NewAPIResponseError(http.StatusBadRequest, err.Error())
+		}
+		distance, err := geo.ParseDistance(clause.Values[2])
+		if err != nil {
+			return nil, restfulwrapper.NewAPIResponseError(http.StatusBadRequest, err.Error())
+		}
+		expression, args = coordinatesNearExpression(db, fieldColumn, point, distance)
+	case filter.OperationWithin:
+		if clause.Function != "box" || len(clause.Values) != 4 {
+			return nil, restfulwrapper.NewAPIResponseError(http.StatusBadRequest, fmt.Sprintf("Operation %q takes a box with two corners, such as: %s within box(43.60, -116.21, 43.62, -116.19)", clause.Operation, clause.Name))
+		}
+		err := parseNumbers(clause.Values)
+		if err != nil {
+			return nil, err
+		}
+		box := geo.Box{
+			MinLatitude:  min(numbers[0], numbers[2]),
+			MinLongitude: min(numbers[1], numbers[3]),
+			MaxLatitude:  max(numbers[0], numbers[2]),
+			MaxLongitude: max(numbers[1], numbers[3]),
+		}
+		expression, args = coordinatesWithinExpression(db, fieldColumn, box)
+	default:
+		return nil, fmt.Errorf("unknown operation: %s", clause.Operation)
+	}
+
+	return db.Session(&gorm.Session{NewDB: true, Initialized: true}).Where(expression, args...), nil
+}
+
+// distanceSortExpression returns an expression for the distance (in whole meters) of the coordinates from the point.
+//
+// The expression is NULL if the coordinates are missing.
+func distanceSortExpression(db *gorm.DB, fieldColumn string, point geo.Point) string {
+	latitude := database.CastFloat(db, database.TextBefore(db, "NULLIF("+fieldColumn+", '')"))
+	longitude := database.CastFloat(db, database.TextAfter(db, "NULLIF("+fieldColumn+", '')"))
+	return database.CastInteger(db, "ROUND("+database.Distance(db, latitude, longitude, point.Latitude, point.Longitude)+")")
+}
+
+// addPersonDistances sets the distance pseudo-field of each person to their distance (in whole meters) from the point.
+//
+// A person without coordinates doesn't get a distance.
+func addPersonDistances(db *gorm.DB, organizationID uint64, persons []*downballotapi.Person, point geo.Point) error {
+	var personIDs []uint64
+	for _, person := range persons {
+		personID, err := strconv.ParseUint(person.ID, 10, 64)
+		if err != nil {
+			return err
+		}
+		personIDs = append(personIDs, personID)
+	}
+
+	personIDToCoordinatesMap, err := personFieldValues(db, organizationID, coordinatesField, personIDs)
+	if err != nil {
+		return err
+	}
+	for i, person := range persons {
+		location, err := geo.ParsePoint(personIDToCoordinatesMap[personIDs[i]])
+		if err != nil {
+			continue
+		}
+		if person.Fields == nil {
+			person.Fields = map[string]string{}
+		}
+		person.Fields[distancePseudoField] = strconv.FormatFloat(math.Round(geo.Distance(point, location)), 'f', 0, 64)
+	}
+	return nil
+}
+
+// parseDistanceFrom parses the point that the distances are measured from, if one was given.
+func parseDistanceFrom(input *string) (*geo.Point, error) {
+	if input == nil || strings.TrimSpace(*input) == "" {
+		return nil, nil
+	}
+	point, err := geo.ParsePoint(*input)
+	if err != nil {
+		return nil, restfulwrapper.NewAPIQueryParameterError("distance_from", err)
+	}
+	return &point, nil
+}
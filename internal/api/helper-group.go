@@ -4,13 +4,36 @@ import (
 	"cmp"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"slices"
 	"strings"
 
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
 	"github.com/downballot/downballot/internal/schema"
+	"github.com/downballot/downballot/permissionset"
+	"github.com/tekkamanendless/restfulwrapper"
 	"gorm.io/gorm"
 )
 
+// requireGroupPermission returns an error unless the user has the permission on the group.
+//
+// A group ID of "0" means that the user must have the permission for the organization as a whole.
+func requireGroupPermission(user downballotwrapper.User, organizationID uint64, groupID uint64, permission permissionset.Permission) error {
+	permissionSet := user.PermissionSetForGroup(organizationID, groupID)
+	if !permissionSet.Match(permission) {
+		return restfulwrapper.NewAPIResponseError(http.StatusForbidden, fmt.Sprintf("Forbidden: missing permission: %s", permission))
+	}
+	return nil
+}
+
+// parentGroupID returns the ID of the group's parent, or "0" if it is the root group.
+func parentGroupID(group schema.Group) uint64 {
+	if group.ParentID == nil {
+		return 0
+	}
+	return *group.ParentID
+}
+
 // getGroupsForUser returns the list of groups that the user can see.
 func getGroupsForUser(db *gorm.DB, userID any, organizationID any) ([]*schema.Group, error) {
 	var mappedGroupIDs []uint64
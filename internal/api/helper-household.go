@@ -0,0 +1,327 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/address"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+// householdAddressField is the name of the field that puts a person in a household.
+const householdAddressField = "residential_address"
+
+// convertHousehold converts a household into its API form.
+func convertHousehold(household *schema.Household, members []*downballotapi.Person) *downballotapi.Household {
+	return &downballotapi.Household{
+		ID:      fmt.Sprintf("%d", household.ID),
+		Address: household.Address,
+		Members: members,
+	}
+}
+
+// assignHouseholds puts each person into the household for their address, creating the household if necessary.
+//
+// A person whose address is blank is taken out of their household.  A person who was merged or split by hand is left
+// alone.  Any automatic household that is left without members is deleted.
+func assignHouseholds(tx *gorm.DB, organizationID uint64, personIDToAddressMap map[uint64]string) error {
+	if len(personIDToAddressMap) == 0 {
+		return nil
+	}
+	personIDs := slices.Collect(maps.Keys(personIDToAddressMap))
+
+	personIDToMemberMap := map[uint64]*schema.HouseholdMember{}
+	{
+		var members []*schema.HouseholdMember
+		err := tx.Session(&gorm.Session{NewDB: true}).
+			Where("person_id IN (?)", personIDs).
+			Find(&members).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not find household members: %w", err)
+		}
+		for _, member := range members {
+			personIDToMemberMap[member.PersonID] = member
+		}
+	}
+
+	// Figure out the address key for everyone who isn't pinned to their household.
+	personIDToKeyMap := map[uint64]string{}
+	keyToAddressMap := map[string]string{}
+	for personID, value := range personIDToAddressMap {
+		if member := personIDToMemberMap[personID]; member != nil && member.Manual {
+			continue
+		}
+		key := address.Key(value)
+		personIDToKeyMap[personID] = key
+		if key != "" {
+			if _, ok := keyToAddressMap[key]; !ok {
+				keyToAddressMap[key] = value
+			}
+		}
+	}
+
+	keyToHouseholdIDMap := map[string]uint64{}
+	if len(keyToAddressMap) > 0 {
+		var households []*schema.Household
+		err := tx.Session(&gorm.Session{NewDB: true}).
+			Where("organization_id = ?", organizationID).
+			Where("address_key IN (?)", slices.Collect(maps.Keys(keyToAddressMap))).
+			Find(&households).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not find households: %w", err)
+		}
+		for _, household := range households {
+			keyToHouseholdIDMap[*household.AddressKey] = household.ID
+		}
+
+		var newHouseholds []*schema.Household
+		for key, value := range keyToAddressMap {
+			if _, ok := keyToHouseholdIDMap[key]; ok {
+				continue
+			}
+			newHouseholds = append(newHouseholds, &schema.Household{
+				OrganizationID: organizationID,
+				AddressKey:     &key,
+				Address:        value,
+			})
+		}
+		if len(newHouseholds) > 0 {
+			err = tx.Session(&gorm.Session{NewDB: true}).
+				CreateInBatches(&newHouseholds, 2000).
+				Error
+			if err != nil {
+				return fmt.Errorf("could not create households: %w", err)
+			}
+			for _, household := range newHouseholds {
+				keyToHouseholdIDMap[*household.AddressKey] = household.ID
+			}
+		}
+	}
+
+	// These are the households that someone moved out of; they may be empty now.
+	previousHouseholdIDMap := map[uint64]bool{}
+	var newMembers []*schema.HouseholdMember
+	for personID, key := range personIDToKeyMap {
+		member := personIDToMemberMap[personID]
+		if key == "" {
+			if member == nil {
+				continue
+			}
+			err := tx.Session(&gorm.Session{NewDB: true}).
+				Where("person_id = ?", personID).
+				Delete(&schema.HouseholdMember{}).
+				Error
+			if err != nil {
+				return fmt.Errorf("could not delete household member: %w", err)
+			}
+			previousHouseholdIDMap[member.HouseholdID] = true
+			continue
+		}
+
+		householdID := keyToHouseholdIDMap[key]
+		if member == nil {
+			newMembers = append(newMembers, &schema.HouseholdMember{
+				PersonID:    personID,
+				HouseholdID: householdID,
+			})
+			continue
+		}
+		if member.HouseholdID == householdID {
+			continue
+		}
+		err := tx.Session(&gorm.Session{NewDB: true}).
+			Model(&schema.HouseholdMember{}).
+			Where("person_id = ?", personID).
+			Update("household_id", householdID).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not update household member: %w", err)
+		}
+		previousHouseholdIDMap[member.HouseholdID] = true
+	}
+	if len(newMembers) > 0 {
+		err := tx.Session(&gorm.Session{NewDB: true}).
+			CreateInBatches(&newMembers, 2000).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not create household members: %w", err)
+		}
+	}
+
+	if len(previousHouseholdIDMap) > 0 {
+		err := deleteEmptyHouseholds(tx, slices.Collect(maps.Keys(previousHouseholdIDMap)))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteEmptyHouseholds deletes the given households if they have no members left.
+//
+// Households that were split off by hand are kept, since they are only ever removed by a merge.
+func deleteEmptyHouseholds(tx *gorm.DB, householdIDs []uint64) error {
+	err := tx.Session(&gorm.Session{NewDB: true}).
+		Where("id IN (?)", householdIDs).
+		Where("address_key IS NOT NULL").
+		Where("id NOT IN (SELECT household_id FROM household_member WHERE household_id IN (?))", householdIDs).
+		Delete(&schema.Household{}).
+		Error
+	if err != nil {
+		return fmt.Errorf("could not delete empty households: %w", err)
+	}
+	return nil
+}
+
+// loadHouseholdIDs returns the household ID of each of the given persons that is in a household.
+func loadHouseholdIDs(db *gorm.DB, personIDs []uint64) (map[uint64]uint64, error) {
+	output := map[uint64]uint64{}
+	if len(personIDs) == 0 {
+		return output, nil
+	}
+
+	var members []*schema.HouseholdMember
+	err := db.Session(&gorm.Session{}).
+		Where("person_id IN (?)", personIDs).
+		Find(&members).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("could not find household members: %w", err)
+	}
+	for _, member := range members {
+		output[member.PersonID] = member.HouseholdID
+	}
+	return output, nil
+}
+
+// parseHouseholdIDs parses the given household IDs.
+func parseHouseholdIDs(input []string) ([]uint64, error) {
+	var output []uint64
+	for _, value := range input {
+		householdID, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid household ID: %q", value)
+		}
+		output = append(output, householdID)
+	}
+	return output, nil
+}
+
+// filterHouseholdsPage returns a single page of the households that have a person that matches the filter, in order
+// of their IDs.  Each household only lists the members that the user can see and that match the filter.
+//
+// If a group ID is given, then only the persons in that group are considered.  If there are more households after
+// this page, then the token for the next page is also returned; the token is simply the last household ID.
+func filterHouseholdsPage(ctx context.Context, db *gorm.DB, userID uint64, organizationID uint64, groupID *uint64, filterString *string, returnFields *[]string, pageTokenString *string, limit int) ([]*downballotapi.Household, string, error) {
+	query, fieldDefinitionByIDMap, fieldDefinitionByNameMap, err := preparePersonQuery(ctx, db, userID, organizationID, groupID, filterString)
+	if err != nil {
+		return nil, "", err
+	}
+	query = query.Joins("INNER JOIN household_member ON household_member.person_id = person.id")
+
+	var householdIDs []uint64
+	{
+		pageQuery := query.Session(&gorm.Session{})
+		if pageTokenString != nil && *pageTokenString != "" {
+			lastHouseholdID, err := strconv.ParseUint(*pageTokenString, 10, 64)
+			if err != nil {
+				return nil, "", restfulwrapper.NewAPIQueryParameterError("page_token", fmt.Errorf("invalid page token"))
+			}
+			pageQuery = pageQuery.Where("household_member.household_id > ?", lastHouseholdID)
+		}
+		err = pageQuery.
+			Distinct().
+			Order("household_member.household_id ASC").
+			Limit(limit+1).
+			Pluck("household_member.household_id", &householdIDs).
+			Error
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	var nextPageToken string
+	if len(householdIDs) > limit {
+		householdIDs = householdIDs[:limit]
+		nextPageToken = fmt.Sprintf("%d", householdIDs[len(householdIDs)-1])
+	}
+
+	output := []*downballotapi.Household{}
+	if len(householdIDs) == 0 {
+		return output, nextPageToken, nil
+	}
+
+	var households []*schema.Household
+	err = db.Session(&gorm.Session{}).
+		Where("id IN (?)", householdIDs).
+		Order("id").
+		Find(&households).
+		Error
+	if err != nil {
+		return nil, "", fmt.Errorf("could not find households: %w", err)
+	}
+
+	var personIDs []uint64
+	err = query.Session(&gorm.Session{}).
+		Where("household_member.household_id IN (?)", householdIDs).
+		Distinct().
+		Order("person.id ASC").
+		Pluck("person.id", &personIDs).
+		Error
+	if err != nil {
+		return nil, "", err
+	}
+
+	persons, err := loadPersons(db, personIDs, returnFields, fieldDefinitionByIDMap, fieldDefinitionByNameMap)
+	if err != nil {
+		return nil, "", err
+	}
+	householdIDToMembersMap := map[string][]*downballotapi.Person{}
+	for _, person := range persons {
+		householdIDToMembersMap[person.HouseholdID] = append(householdIDToMembersMap[person.HouseholdID], person)
+	}
+
+	for _, household := range households {
+		output = append(output, convertHousehold(household, householdIDToMembersMap[fmt.Sprintf("%d", household.ID)]))
+	}
+	return output, nextPageToken, nil
+}
+
+// getHousehold returns the household with the members that the user can see.
+//
+// If the user cannot see any of its members, then this returns nil.
+func getHousehold(ctx context.Context, db *gorm.DB, userID uint64, organizationID uint64, household *schema.Household) (*downballotapi.Household, error) {
+	query, fieldDefinitionByIDMap, fieldDefinitionByNameMap, err := preparePersonQuery(ctx, db, userID, organizationID, nil /*no group ID*/, nil /*no filter*/)
+	if err != nil {
+		return nil, err
+	}
+
+	var personIDs []uint64
+	err = query.
+		Joins("INNER JOIN household_member ON household_member.person_id = person.id").
+		Where("household_member.household_id = ?", household.ID).
+		Distinct().
+		Order("person.id ASC").
+		Pluck("person.id", &personIDs).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	if len(personIDs) == 0 {
+		return nil, nil
+	}
+
+	persons, err := loadPersons(db, personIDs, nil /*all fields*/, fieldDefinitionByIDMap, fieldDefinitionByNameMap)
+	if err != nil {
+		return nil, err
+	}
+	return convertHousehold(household, persons), nil
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/importprofile"
+	"github.com/downballot/downballot/internal/schema"
+	"gorm.io/gorm"
+)
+
+// errImportProfileNotFound is returned when an import profile does not exist.
+var errImportProfileNotFound = errors.New("import profile not found")
+
+// findImportProfile returns the definition of the named import profile.
+//
+// If the organization does not have a profile with that name and the name is that of the built-in
+// profile, then the built-in profile is returned.
+func findImportProfile(db *gorm.DB, organizationID uint64, name string) (importprofile.Profile, error) {
+	var importProfiles []*schema.ImportProfile
+	err := db.Session(&gorm.Session{}).
+		Where("organization_id = ?", organizationID).
+		Where("name = ?", name).
+		Limit(1).
+		Find(&importProfiles).
+		Error
+	if err != nil {
+		return importprofile.Profile{}, fmt.Errorf("could not find import profile: %w", err)
+	}
+	if len(importProfiles) == 0 {
+		if name == importprofile.DefaultName {
+			return importprofile.Default(), nil
+		}
+		return importprofile.Profile{}, errImportProfileNotFound
+	}
+
+	var profile importprofile.Profile
+	err = json.Unmarshal([]byte(importProfiles[0].Definition), &profile)
+	if err != nil {
+		return importprofile.Profile{}, fmt.Errorf("could not decode import profile: %w", err)
+	}
+	return profile, nil
+}
+
+// validateImportProfile makes sure that the profile compiles and that it only writes to fields that
+// the organization has defined.
+func validateImportProfile(db *gorm.DB, organizationID uint64, profile importprofile.Profile) error {
+	_, err := profile.Compile()
+	if err != nil {
+		return err
+	}
+
+	var fieldDefinitions []*schema.PersonFieldDefinition
+	err = db.Session(&gorm.Session{}).
+		Where("organization_id = ?", organizationID).
+		Find(&fieldDefinitions).
+		Error
+	if err != nil {
+		return fmt.Errorf("could not find field definitions: %w", err)
+	}
+	fieldDefinitionByNameMap := map[string]*schema.PersonFieldDefinition{}
+	for _, fieldDefinition := range fieldDefinitions {
+		fieldDefinitionByNameMap[fieldDefinition.Name] = fieldDefinition
+	}
+
+	for _, field := range profile.Fields() {
+		if fieldDefinitionByNameMap[field] == nil {
+			return fmt.Errorf("unknown field: %s", field)
+		}
+	}
+	return nil
+}
+
+// newImportProfile builds an import profile from its API form.
+func newImportProfile(columns map[string]string, composites []*downballotapi.ImportProfileComposite, sets []*downballotapi.ImportProfileSet, transforms []*downballotapi.ImportProfileTransform) importprofile.Profile {
+	profile := importprofile.Profile{
+		Columns: map[string]string{},
+	}
+	for column, field := range columns {
+		profile.Columns[column] = field
+	}
+	for _, composite := range composites {
+		profile.Composites = append(profile.Composites, importprofile.Composite{
+			Field:      composite.Field,
+			Expression: composite.Expression,
+		})
+	}
+	for _, set := range sets {
+		profile.Sets = append(profile.Sets, importprofile.Set{
+			Field:        set.Field,
+			ColumnPrefix: set.ColumnPrefix,
+		})
+	}
+	for _, transform := range transforms {
+		profile.Transforms = append(profile.Transforms, importprofile.Transform{
+			Field:      transform.Field,
+			Operations: transform.Operations,
+		})
+	}
+	return profile
+}
+
+// convertImportProfile converts an import profile into its API form.
+func convertImportProfile(importProfile *schema.ImportProfile) (*downballotapi.ImportProfile, error) {
+	var profile importprofile.Profile
+	err := json.Unmarshal([]byte(importProfile.Definition), &profile)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode import profile: %w", err)
+	}
+
+	output := &downballotapi.ImportProfile{
+		ID:          fmt.Sprintf("%d", importProfile.ID),
+		Name:        importProfile.Name,
+		Description: importProfile.Description,
+		Columns:     map[string]string{},
+		Composites:  []*downballotapi.ImportProfileComposite{},
+		Sets:        []*downballotapi.ImportProfileSet{},
+		Transforms:  []*downballotapi.ImportProfileTransform{},
+	}
+	for column, field := range profile.Columns {
+		output.Columns[column] = field
+	}
+	for _, composite := range profile.Composites {
+		output.Composites = append(output.Composites, &downballotapi.ImportProfileComposite{
+			Field:      composite.Field,
+			Expression: composite.Expression,
+		})
+	}
+	for _, set := range profile.Sets {
+		output.Sets = append(output.Sets, &downballotapi.ImportProfileSet{
+			Field:        set.Field,
+			ColumnPrefix: set.ColumnPrefix,
+		})
+	}
+	for _, transform := range profile.Transforms {
+		output.Transforms = append(output.Transforms, &downballotapi.ImportProfileTransform{
+			Field:      transform.Field,
+			Operations: transform.Operations,
+		})
+	}
+	return output, nil
+}
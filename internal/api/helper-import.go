@@ -0,0 +1,408 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/downballot/downballot/internal/schema/sqltype"
+	"gorm.io/gorm"
+)
+
+// importJobBatchSize is the number of rows that an import job processes in each transaction.
+const importJobBatchSize = 1000
+
+// failInterruptedImportJobs marks the import jobs that were left pending or running as failed.
+//
+// Nothing will ever finish them, so this is called when the server starts.  The batches that were
+// already processed stay imported, and the job's counts say how far it got.
+func failInterruptedImportJobs(ctx context.Context, db *gorm.DB) error {
+	result := db.Session(&gorm.Session{}).
+		Model(&schema.ImportJob{}).
+		Where("status IN (?)", []schema.ImportJobStatus{schema.ImportJobStatusPending, schema.ImportJobStatusRunning}).
+		Updates(map[string]any{
+			"status":             schema.ImportJobStatusFailed,
+			"message":            "the server stopped before the import job finished",
+			"finished_timestamp": sqltype.DateTime(time.Now()),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("could not update import jobs: %w", result.Error)
+	}
+	if result.RowsAffected > 0 {
+		slog.WarnContext(ctx, fmt.Sprintf("Marked %d interrupted import job(s) as failed.", result.RowsAffected))
+	}
+	return nil
+}
+
+// runImportJob processes an import job and records the outcome on the job.
+//
+// This is meant to be run in the background; the context must not be tied to the original request.
+// If the context is canceled, then the job stops after the current batch and is marked as failed.
+func runImportJob(ctx context.Context, db *gorm.DB, job *schema.ImportJob, persons []*schema.Person) {
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+
+		updateMap := map[string]any{}
+		updateMap["finished_timestamp"] = sqltype.DateTime(time.Now())
+		if err != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Import job %d failed: %v", job.ID, err))
+			updateMap["status"] = schema.ImportJobStatusFailed
+			updateMap["message"] = err.Error()
+		} else {
+			slog.InfoContext(ctx, fmt.Sprintf("Import job %d succeeded.", job.ID))
+			updateMap["status"] = schema.ImportJobStatusSucceeded
+		}
+		// The outcome is recorded even if the job was canceled.
+		updateErr := db.WithContext(context.WithoutCancel(ctx)).
+			Model(&schema.ImportJob{}).
+			Where("id = ?", job.ID).
+			Updates(updateMap).
+			Error
+		if updateErr != nil {
+			slog.ErrorContext(ctx, fmt.Sprintf("Could not update import job %d: %v", job.ID, updateErr))
+		}
+	}()
+
+	err = processImportJob(ctx, db, job, persons)
+}
+
+// processImportJob does the work of an import job.
+//
+// Rows that cannot be imported are recorded as errors on the job; only problems with the job as a whole
+// are returned as an error.
+func processImportJob(ctx context.Context, db *gorm.DB, job *schema.ImportJob, persons []*schema.Person) error {
+	err := db.Session(&gorm.Session{}).
+		Model(&schema.ImportJob{}).
+		Where("id = ?", job.ID).
+		Update("status", schema.ImportJobStatusRunning).
+		Error
+	if err != nil {
+		return fmt.Errorf("could not update import job: %w", err)
+	}
+
+	fieldDefinitionByNameMap := map[string]*schema.PersonFieldDefinition{}
+	fieldDefinitionByIDMap := map[uint64]*schema.PersonFieldDefinition{}
+	{
+		var fieldDefinitions []*schema.PersonFieldDefinition
+		err = db.Session(&gorm.Session{}).
+			Where("organization_id = ?", job.OrganizationID).
+			Find(&fieldDefinitions).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not find field definitions: %w", err)
+		}
+		for _, fieldDefinition := range fieldDefinitions {
+			fieldDefinitionByNameMap[fieldDefinition.Name] = fieldDefinition
+			fieldDefinitionByIDMap[fieldDefinition.ID] = fieldDefinition
+		}
+	}
+
+	voterIDToExistingPersonIDMap := map[string]uint64{}
+	{
+		var existingPersons []*schema.Person
+		err = db.Session(&gorm.Session{}).
+			Select("id", "voter_id").
+			Where("organization_id = ?", job.OrganizationID).
+			Find(&existingPersons).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not find existing persons: %w", err)
+		}
+		for _, person := range existingPersons {
+			voterIDToExistingPersonIDMap[person.VoterID] = person.ID
+		}
+	}
+
+	voterIDToRowNumberMap := map[string]uint64{} // This is used to detect duplicate voter IDs within the file.
+
+	for batchStart := 0; batchStart < len(persons); batchStart += importJobBatchSize {
+		if ctx.Err() != nil {
+			return fmt.Errorf("the import job was stopped after %d rows: %w", batchStart, ctx.Err())
+		}
+		batchEnd := min(batchStart+importJobBatchSize, len(persons))
+
+		var importErrors []*schema.ImportJobError
+		var newPersons []*schema.Person
+		var updatePersons []*schema.Person
+		for personIndex := batchStart; personIndex < batchEnd; personIndex++ {
+			person := persons[personIndex]
+			rowNumber := uint64(personIndex + 1)
+
+			rowErr := func() error {
+				if person.VoterID == "" {
+					return fmt.Errorf("missing voter ID")
+				}
+				if otherRowNumber, ok := voterIDToRowNumberMap[person.VoterID]; ok {
+					return fmt.Errorf("duplicate voter ID (see row %d)", otherRowNumber)
+				}
+				voterIDToRowNumberMap[person.VoterID] = rowNumber
+
+				existingPersonID, exists := voterIDToExistingPersonIDMap[person.VoterID]
+				for name, value := range person.Fields {
+					fieldDefinition := fieldDefinitionByNameMap[name]
+					if fieldDefinition == nil {
+						return fmt.Errorf("unknown field: %q", name)
+					}
+
+					// This is a CSV import, so if we're updating an existing person and the field is blank, then ignore it.
+					if exists && value == "" {
+						continue
+					}
+
+					err := fieldDefinition.Validate(value)
+					if err != nil {
+						return fmt.Errorf("invalid value for field %s: %w", name, err)
+					}
+				}
+
+				if exists {
+					person.ID = existingPersonID
+					updatePersons = append(updatePersons, person)
+				} else {
+					newPersons = append(newPersons, person)
+				}
+				return nil
+			}()
+			if rowErr != nil {
+				importErrors = append(importErrors, &schema.ImportJobError{
+					ImportJobID: job.ID,
+					RowNumber:   rowNumber,
+					VoterID:     person.VoterID,
+					Message:     rowErr.Error(),
+				})
+			}
+		}
+
+		var updatedCount uint64
+		var unchangedCount uint64
+		err = db.Transaction(func(tx *gorm.DB) error {
+			changedCount, err := importPersonsBatch(tx, job, newPersons, updatePersons, fieldDefinitionByNameMap, fieldDefinitionByIDMap)
+			if err != nil {
+				return err
+			}
+			updatedCount = changedCount
+			unchangedCount = uint64(len(updatePersons)) - changedCount
+
+			if len(importErrors) > 0 {
+				err = tx.Session(&gorm.Session{NewDB: true}).
+					CreateInBatches(&importErrors, 2000).
+					Error
+				if err != nil {
+					return fmt.Errorf("could not create import errors: %w", err)
+				}
+			}
+
+			err = tx.Session(&gorm.Session{NewDB: true}).
+				Model(&schema.ImportJob{}).
+				Where("id = ?", job.ID).
+				Updates(map[string]any{
+					"processed_rows": gorm.Expr("processed_rows + ?", batchEnd-batchStart),
+					"created_rows":   gorm.Expr("created_rows + ?", len(newPersons)),
+					"updated_rows":   gorm.Expr("updated_rows + ?", updatedCount),
+					"unchanged_rows": gorm.Expr("unchanged_rows + ?", unchangedCount),
+					"failed_rows":    gorm.Expr("failed_rows + ?", len(importErrors)),
+				}).
+				Error
+			if err != nil {
+				return fmt.Errorf("could not update import job: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("could not import rows %d through %d: %w", batchStart+1, batchEnd, err)
+		}
+		slog.InfoContext(ctx, fmt.Sprintf("Import job %d: processed %d of %d rows (created: %d, updated: %d, unchanged: %d, failed: %d).", job.ID, batchEnd, len(persons), len(newPersons), updatedCount, unchangedCount, len(importErrors)))
+
+	}
+
+	return nil
+}
+
+// importPersonsBatch creates the new persons and updates the existing persons for a single batch of
+// an import job.  It returns the number of existing persons that actually changed.
+//
+// If the job is a dry run, then nothing is written.
+func importPersonsBatch(tx *gorm.DB, job *schema.ImportJob, newPersons []*schema.Person, updatePersons []*schema.Person, fieldDefinitionByNameMap map[string]*schema.PersonFieldDefinition, fieldDefinitionByIDMap map[uint64]*schema.PersonFieldDefinition) (uint64, error) {
+	if len(newPersons) > 0 && !job.DryRun {
+		err := tx.Session(&gorm.Session{NewDB: true}).
+			CreateInBatches(&newPersons, 2000).
+			Error
+		if err != nil {
+			return 0, fmt.Errorf("could not create persons: %w", err)
+		}
+
+		var fields []*schema.PersonField
+		for _, person := range newPersons {
+			for name, value := range person.Fields {
+				personFieldDefinition := fieldDefinitionByNameMap[name]
+				if personFieldDefinition == nil {
+					continue
+				}
+				storedValue, blindIndex, err := personFieldDefinition.EncodeValue(value)
+				if err != nil {
+					return 0, err
+				}
+				field := &schema.PersonField{
+					PersonID:                person.ID,
+					PersonFieldDefinitionID: personFieldDefinition.ID,
+					Value:                   storedValue,
+					BlindIndex:              blindIndex,
+				}
+				fields = append(fields, field)
+			}
+		}
+		if len(fields) > 0 {
+			err = tx.Session(&gorm.Session{NewDB: true}).
+				CreateInBatches(&fields, 2000).
+				Error
+			if err != nil {
+				return 0, fmt.Errorf("could not create fields: %w", err)
+			}
+		}
+
+		personIDToFieldsMap := map[uint64]map[string]string{}
+		for _, person := range newPersons {
+			personIDToFieldsMap[person.ID] = person.Fields
+		}
+		err = updatePersonMemberships(tx, job.OrganizationID, personIDToFieldsMap)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if len(updatePersons) == 0 {
+		return 0, nil
+	}
+
+	// Load the current values of all of the persons being updated.
+	personIDToFieldsMap := map[uint64]map[string]*schema.PersonField{}
+	{
+		var personIDs []uint64
+		for _, person := range updatePersons {
+			personIDs = append(personIDs, person.ID)
+		}
+
+		var fields []*schema.PersonField
+		err := tx.Session(&gorm.Session{NewDB: true}).
+			Where("person_id IN (?)", personIDs).
+			Find(&fields).
+			Error
+		if err != nil {
+			return 0, fmt.Errorf("could not find fields: %w", err)
+		}
+		for _, field := range fields {
+			fieldDefinition := fieldDefinitionByIDMap[field.PersonFieldDefinitionID]
+			if fieldDefinition == nil {
+				continue
+			}
+			if personIDToFieldsMap[field.PersonID] == nil {
+				personIDToFieldsMap[field.PersonID] = map[string]*schema.PersonField{}
+			}
+			field.Value, err = fieldDefinition.DecodeValue(field.Value)
+			if err != nil {
+				return 0, err
+			}
+			personIDToFieldsMap[field.PersonID][fieldDefinition.Name] = field
+		}
+	}
+
+	var changedCount uint64
+	personIDToChangedFieldsMap := map[uint64]map[string]string{} // These are the fields that changed.
+	for _, person := range updatePersons {
+		existingFields := personIDToFieldsMap[person.ID]
+
+		changed := false
+		for name, value := range person.Fields {
+			// Basically, we're accepting spreadsheet input, so if a field is blank, then ignore it.
+			if value == "" {
+				continue
+			}
+
+			fieldDefinition := fieldDefinitionByNameMap[name]
+			if fieldDefinition == nil {
+				// We should have already defended against this, but play it safe.
+				return 0, fmt.Errorf("unknown field: %s", name)
+			}
+
+			audit := schema.PersonAudit{
+				UserID:                  job.UserID,
+				PersonID:                person.ID,
+				PersonFieldDefinitionID: fieldDefinition.ID,
+				Timestamp:               sqltype.DateTime(time.Now()),
+				NewValue:                &value,
+			}
+
+			existingField := existingFields[name]
+			if existingField != nil {
+				if existingField.Value == value {
+					// If the field was not changed, then don't do anything.
+					continue
+				}
+				audit.OldValue = new(string)
+				*audit.OldValue = existingField.Value
+			}
+			changed = true
+
+			if job.DryRun {
+				continue
+			}
+			if personIDToChangedFieldsMap[person.ID] == nil {
+				personIDToChangedFieldsMap[person.ID] = map[string]string{}
+			}
+			personIDToChangedFieldsMap[person.ID][name] = value
+
+			storedValue, blindIndex, err := fieldDefinition.EncodeValue(value)
+			if err != nil {
+				return 0, err
+			}
+			if existingField == nil {
+				field := schema.PersonField{
+					PersonID:                person.ID,
+					PersonFieldDefinitionID: fieldDefinition.ID,
+					Value:                   storedValue,
+					BlindIndex:              blindIndex,
+				}
+				err := tx.Session(&gorm.Session{NewDB: true}).
+					Create(&field).
+					Error
+				if err != nil {
+					return 0, fmt.Errorf("could not create field: %w", err)
+				}
+			} else {
+				err := tx.Session(&gorm.Session{NewDB: true}).
+					Model(&schema.PersonField{}).
+					Where("id = ?", existingField.ID).
+					Updates(map[string]any{"value": storedValue, "blind_index": blindIndex}).
+					Error
+				if err != nil {
+					return 0, fmt.Errorf("could not update field: %w", err)
+				}
+			}
+
+			err = audit.Encode(*fieldDefinition)
+			if err != nil {
+				return 0, err
+			}
+			err = tx.Session(&gorm.Session{NewDB: true}).
+				Create(&audit).
+				Error
+			if err != nil {
+				return 0, fmt.Errorf("could not create audit: %w", err)
+			}
+		}
+		if changed {
+			changedCount++
+		}
+	}
+
+	err := updatePersonMemberships(tx, job.OrganizationID, personIDToChangedFieldsMap)
+	if err != nil {
+		return 0, err
+	}
+	return changedCount, nil
+}
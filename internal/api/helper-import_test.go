@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/downballot/downballot/internal/databasetest"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/downballot/downballot/internal/schema/sqltype"
+	"github.com/downballot/downballot/internal/testutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFailInterruptedImportJobs(t *testing.T) {
+	testutils.Setup(t)
+
+	ctx := t.Context()
+
+	db, err := databasetest.New(ctx)
+	require.NoError(t, err)
+
+	organization := schema.Organization{Name: "Interrupted Import Jobs"}
+	require.NoError(t, db.Create(&organization).Error)
+	user := schema.User{Username: "interrupted-import-jobs@example.com"}
+	require.NoError(t, db.Create(&user).Error)
+
+	statusToJobIDMap := map[schema.ImportJobStatus]uint64{}
+	for _, status := range []schema.ImportJobStatus{schema.ImportJobStatusPending, schema.ImportJobStatusRunning, schema.ImportJobStatusSucceeded} {
+		job := schema.ImportJob{
+			OrganizationID:   organization.ID,
+			UserID:           user.ID,
+			Status:           status,
+			CreatedTimestamp: sqltype.DateTime(time.Now()),
+		}
+		require.NoError(t, db.Create(&job).Error)
+		statusToJobIDMap[status] = job.ID
+	}
+
+	err = failInterruptedImportJobs(ctx, db)
+	require.NoError(t, err)
+
+	for status, jobID := range statusToJobIDMap {
+		var job schema.ImportJob
+		require.NoError(t, db.Where("id = ?", jobID).First(&job).Error)
+		if status == schema.ImportJobStatusSucceeded {
+			assert.Equal(t, schema.ImportJobStatusSucceeded, job.Status)
+			assert.Nil(t, job.FinishedTimestamp)
+			continue
+		}
+		assert.Equal(t, schema.ImportJobStatusFailed, job.Status, "Status: %s", status)
+		assert.NotEmpty(t, job.Message)
+		assert.NotNil(t, job.FinishedTimestamp)
+	}
+}
+
+func TestBackgroundJobsShutdown(t *testing.T) {
+	testutils.Setup(t)
+
+	jobs := newBackgroundJobs()
+
+	// The job must keep running after the request that started it is over.
+	requestContext, cancelRequest := context.WithCancel(t.Context())
+	started := make(chan struct{})
+	stopped := false
+	jobs.Go(requestContext, func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		stopped = true
+	})
+	<-started
+	cancelRequest()
+
+	err := jobs.Shutdown(t.Context())
+	require.NoError(t, err)
+	assert.True(t, stopped)
+
+	// A job that won't stop holds up the shutdown until it gives up.
+	jobs = newBackgroundJobs()
+	release := make(chan struct{})
+	defer close(release)
+	jobs.Go(t.Context(), func(ctx context.Context) {
+		<-release
+	})
+	shutdownContext, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+	defer cancel()
+	err = jobs.Shutdown(shutdownContext)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,188 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/downballot/downballot/internal/apitoken"
+	"golang.org/x/oauth2"
+)
+
+// oidcStatePurpose is the purpose of the token that carries the state of an OpenID Connect login.
+const oidcStatePurpose = "oidc-state"
+
+// oidcStateLifetime is how long a user has to finish logging in with the identity provider.
+const oidcStateLifetime = 10 * time.Minute
+
+// Limits for the client nonce of an OpenID Connect login.
+const (
+	oidcClientNonceMinLength = 16  // This is enough that the nonce cannot be guessed.
+	oidcClientNonceMaxLength = 256 // This is more than any client needs.
+)
+
+// errInvalidOIDCState is returned when the state of an OpenID Connect login is invalid or expired, or when it belongs
+// to another client.
+var errInvalidOIDCState = errors.New("invalid or expired state")
+
+// errInvalidOIDCClientNonce is returned when the client nonce of an OpenID Connect login is missing or invalid.
+var errInvalidOIDCClientNonce = errors.New("invalid client nonce")
+
+// oidcClient is an OpenID Connect client.
+//
+// The identity provider is discovered the first time that it is needed (and not at startup) so that the
+// application can start even if the identity provider is unavailable.
+type oidcClient struct {
+	issuer       string // This is the issuer URL.
+	clientID     string // This is the client ID.
+	clientSecret string // This is the client secret.
+	redirectURL  string // This is where the identity provider sends the user back to.
+
+	mutex    sync.Mutex
+	provider *oidc.Provider // This is the discovered provider, if any.
+}
+
+// newOIDCClient returns a new OpenID Connect client.
+func newOIDCClient(issuer string, clientID string, clientSecret string, redirectURL string) *oidcClient {
+	return &oidcClient{
+		issuer:       issuer,
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		redirectURL:  redirectURL,
+	}
+}
+
+// oauth2Config returns the OAuth2 configuration for the identity provider.
+func (c *oidcClient) oauth2Config(ctx context.Context) (*oauth2.Config, *oidc.Provider, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.provider == nil {
+		// The provider keeps the context for fetching its keys later, so it must outlive the request.
+		provider, err := oidc.NewProvider(context.WithoutCancel(ctx), c.issuer)
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not discover identity provider: %w", err)
+		}
+		c.provider = provider
+	}
+
+	config := &oauth2.Config{
+		ClientID:     c.clientID,
+		ClientSecret: c.clientSecret,
+		RedirectURL:  c.redirectURL,
+		Endpoint:     c.provider.Endpoint(),
+		Scopes:       []string{oidc.ScopeOpenID, "email"},
+	}
+	return config, c.provider, nil
+}
+
+// AuthorizationURL returns the URL to send the user to.
+func (c *oidcClient) AuthorizationURL(ctx context.Context, state string, nonce string) (string, error) {
+	config, _, err := c.oauth2Config(ctx)
+	if err != nil {
+		return "", err
+	}
+	return config.AuthCodeURL(state, oidc.Nonce(nonce)), nil
+}
+
+// newOIDCState returns the state for a new OpenID Connect login, along with its nonce.
+//
+// The state is signed so that nothing has to be stored until the user comes back.  It is bound to the client nonce, so
+// that someone else's state (and code) cannot be used to log the client in as someone else.
+func (a *API) newOIDCState(clientNonce string) (string, string, error) {
+	if len(clientNonce) < oidcClientNonceMinLength || len(clientNonce) > oidcClientNonceMaxLength {
+		return "", "", errInvalidOIDCClientNonce
+	}
+
+	nonceBytes := make([]byte, 32)
+	_, err := rand.Read(nonceBytes)
+	if err != nil {
+		return "", "", fmt.Errorf("could not generate nonce: %w", err)
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(nonceBytes)
+
+	state, err := a.signToken(apitoken.TokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Id:        nonce,
+			ExpiresAt: time.Now().Add(oidcStateLifetime).Unix(),
+		},
+		Purpose: oidcStatePurpose,
+		Binding: hashOIDCClientNonce(clientNonce),
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return state, nonce, nil
+}
+
+// parseOIDCState returns the nonce from the state of an OpenID Connect login.
+//
+// This fails with `errInvalidOIDCState` if the state is not valid or if it was not started with the client nonce.
+func (a *API) parseOIDCState(state string, clientNonce string) (string, error) {
+	claims, err := a.parseToken(state)
+	if err != nil {
+		return "", errInvalidOIDCState
+	}
+	if claims.Purpose != oidcStatePurpose || claims.Id == "" {
+		return "", errInvalidOIDCState
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Binding), []byte(hashOIDCClientNonce(clientNonce))) != 1 {
+		return "", errInvalidOIDCState
+	}
+	return claims.Id, nil
+}
+
+// hashOIDCClientNonce returns the hash of a client nonce, which is what the state stores.
+func hashOIDCClientNonce(clientNonce string) string {
+	hash := sha256.Sum256([]byte(clientNonce))
+	return hex.EncodeToString(hash[:])
+}
+
+// oidcIdentity is what we use from the identity provider's ID token.
+type oidcIdentity struct {
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+// Exchange exchanges the authorization code for the user's identity.
+//
+// The nonce is the one from the state, which must match the one in the ID token.
+func (c *oidcClient) Exchange(ctx context.Context, code string, nonce string) (*oidcIdentity, error) {
+	config, provider, err := c.oauth2Config(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := config.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("could not exchange code: %w", err)
+	}
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, fmt.Errorf("missing ID token")
+	}
+
+	idToken, err := provider.Verifier(&oidc.Config{ClientID: c.clientID}).Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("could not verify ID token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, fmt.Errorf("mismatched nonce")
+	}
+
+	var identity oidcIdentity
+	err = idToken.Claims(&identity)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse ID token claims: %w", err)
+	}
+	return &identity, nil
+}
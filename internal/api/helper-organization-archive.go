@@ -0,0 +1,716 @@
+package api
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/api/resttype"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/emicklei/go-restful/v3"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+// organizationArchiveBatchSize is the number of rows to load from the database at a time during an archive.
+const organizationArchiveBatchSize = 1000
+
+// organizationDeletionTokenLifetime is how long the confirmation token from an archive may be used
+// to delete the organization.
+const organizationDeletionTokenLifetime = 24 * time.Hour
+
+// Organization archive formats.
+const (
+	organizationArchiveFormatJSON = "json"
+	organizationArchiveFormatZIP  = "zip"
+)
+
+// newOrganizationDeletionToken returns a new confirmation token along with the hash to store.
+func newOrganizationDeletionToken() (string, string, error) {
+	contents := make([]byte, 32)
+	_, err := rand.Read(contents)
+	if err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(contents)
+	return token, hashOrganizationDeletionToken(token), nil
+}
+
+// hashOrganizationDeletionToken returns the hash of a confirmation token.
+func hashOrganizationDeletionToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// organizationArchive streams a full archive of an organization to the response.
+//
+// The archive is a single JSON document (optionally inside of a zip file) that is written one section
+// at a time; the persons and audits are loaded in batches so that large organizations don't have to fit in memory.
+type organizationArchive struct {
+	ctx               context.Context
+	db                *gorm.DB
+	organization      schema.Organization
+	format            string
+	confirmationToken string
+	timestamp         time.Time
+}
+
+var _ restfulwrapper.Writer = (*organizationArchive)(nil)
+
+// Write implements restfulwrapper.Writer.
+func (a *organizationArchive) Write(resp *restful.Response) {
+	filename := fmt.Sprintf("organization-%d", a.organization.ID)
+	resp.Header().Set("X-Confirmation-Token", a.confirmationToken)
+	switch a.format {
+	case organizationArchiveFormatJSON:
+		resp.Header().Set("Content-Type", "application/json")
+		resp.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.json"`)
+	case organizationArchiveFormatZIP:
+		resp.Header().Set("Content-Type", "application/zip")
+		resp.Header().Set("Content-Disposition", `attachment; filename="`+filename+`.zip"`)
+	}
+	resp.WriteHeader(http.StatusOK)
+
+	var err error
+	switch a.format {
+	case organizationArchiveFormatJSON:
+		err = a.write(resp)
+	case organizationArchiveFormatZIP:
+		zipWriter := zip.NewWriter(resp)
+		var w io.Writer
+		w, err = zipWriter.Create(filename + ".json")
+		if err == nil {
+			err = a.write(w)
+		}
+		if err == nil {
+			err = zipWriter.Close()
+		}
+	}
+	if err != nil {
+		// The headers have already been sent, so there's nothing more that we can tell the client.
+		slog.ErrorContext(a.ctx, fmt.Sprintf("Could not archive organization %d: %v", a.organization.ID, err))
+	}
+}
+
+// write writes the archive as a JSON document.
+//
+// The document has the same shape as downballotapi.OrganizationArchive.
+func (a *organizationArchive) write(w io.Writer) error {
+	sectionCount := 0
+	writeSection := func(name string, f func(emit func(any) error) error, list bool) error {
+		prefix := ","
+		if sectionCount == 0 {
+			prefix = "{"
+		}
+		sectionCount++
+
+		_, err := fmt.Fprintf(w, "%s%q:", prefix, name)
+		if err != nil {
+			return err
+		}
+		if list {
+			_, err = io.WriteString(w, "[")
+			if err != nil {
+				return err
+			}
+		}
+		itemCount := 0
+		err = f(func(v any) error {
+			if list && itemCount > 0 {
+				_, err := io.WriteString(w, ",")
+				if err != nil {
+					return err
+				}
+			}
+			itemCount++
+
+			contents, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(contents)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("could not write %s: %w", name, err)
+		}
+		if list {
+			_, err = io.WriteString(w, "]")
+			if err != nil {
+				return err
+			}
+		}
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+		return nil
+	}
+	writeValue := func(name string, v any) error {
+		return writeSection(name, func(emit func(any) error) error {
+			return emit(v)
+		}, false)
+	}
+	writeList := func(name string, f func(emit func(any) error) error) error {
+		return writeSection(name, f, true)
+	}
+
+	err := writeValue("confirmation_token", a.confirmationToken)
+	if err != nil {
+		return err
+	}
+	err = writeValue("archive_timestamp", resttype.DateTime(a.timestamp))
+	if err != nil {
+		return err
+	}
+	err = writeValue("organization", downballotapi.Organization{
+		ID:   fmt.Sprintf("%d", a.organization.ID),
+		Name: a.organization.Name,
+	})
+	if err != nil {
+		return err
+	}
+
+	fieldDefinitionByIDMap := map[uint64]*schema.PersonFieldDefinition{}
+	fieldDefinitionByNameMap := map[string]*schema.PersonFieldDefinition{}
+	err = writeList("person_fields", func(emit func(any) error) error {
+		var fieldDefinitions []*schema.PersonFieldDefinition
+		err := a.db.Session(&gorm.Session{}).
+			Where("organization_id = ?", a.organization.ID).
+			Order("id").
+			Find(&fieldDefinitions).
+			Error
+		if err != nil {
+			return err
+		}
+		for _, fieldDefinition := range fieldDefinitions {
+			fieldDefinitionByIDMap[fieldDefinition.ID] = fieldDefinition
+			fieldDefinitionByNameMap[fieldDefinition.Name] = fieldDefinition
+			err = emit(&downballotapi.PersonField{
+				ID:            fmt.Sprintf("%d", fieldDefinition.ID),
+				Name:          fieldDefinition.Name,
+				Type:          downballotapi.PersonFieldDefinitionType(fieldDefinition.Type),
+				AllowEmpty:    fieldDefinition.AllowEmpty,
+				AllowedValues: fieldDefinition.AllowedValues,
+				AllowedRegex:  fieldDefinition.AllowedRegex,
+				Encrypted:     fieldDefinition.Encrypted,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	personIDToVoterIDMap := map[uint64]string{}
+	err = writeList("persons", func(emit func(any) error) error {
+		var lastID uint64
+		for {
+			var personIDs []uint64
+			err := a.db.Session(&gorm.Session{}).
+				Model(&schema.Person{}).
+				Where("organization_id = ?", a.organization.ID).
+				Where("id > ?", lastID).
+				Order("id").
+				Limit(organizationArchiveBatchSize).
+				Pluck("id", &personIDs).
+				Error
+			if err != nil {
+				return err
+			}
+			if len(personIDs) == 0 {
+				return nil
+			}
+			lastID = personIDs[len(personIDs)-1]
+
+			persons, err := loadPersons(a.db, personIDs, nil /*all fields*/, fieldDefinitionByIDMap, fieldDefinitionByNameMap)
+			if err != nil {
+				return err
+			}
+			for _, person := range persons {
+				personID, err := strconv.ParseUint(person.ID, 10, 64)
+				if err != nil {
+					return err
+				}
+				personIDToVoterIDMap[personID] = person.VoterID
+				err = emit(person)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	userIDToUsernameMap := map[uint64]string{}
+	err = writeList("audits", func(emit func(any) error) error {
+		var lastID uint64
+		for {
+			var audits []*schema.PersonAudit
+			err := a.db.Session(&gorm.Session{}).
+				Where("person_id IN (SELECT id FROM person WHERE organization_id = ?)", a.organization.ID).
+				Where("id > ?", lastID).
+				Order("id").
+				Limit(organizationArchiveBatchSize).
+				Find(&audits).
+				Error
+			if err != nil {
+				return err
+			}
+			if len(audits) == 0 {
+				return nil
+			}
+			lastID = audits[len(audits)-1].ID
+
+			err = a.loadUsernames(userIDToUsernameMap, audits)
+			if err != nil {
+				return err
+			}
+
+			for _, audit := range audits {
+				var fieldName string
+				if fieldDefinition := fieldDefinitionByIDMap[audit.PersonFieldDefinitionID]; fieldDefinition != nil {
+					fieldName = fieldDefinition.Name
+					err = audit.Decode(*fieldDefinition)
+					if err != nil {
+						return err
+					}
+				}
+				err = emit(&downballotapi.PersonAudit{
+					ID:        fmt.Sprintf("%d", audit.ID),
+					Username:  userIDToUsernameMap[audit.UserID],
+					VoterID:   personIDToVoterIDMap[audit.PersonID],
+					Timestamp: resttype.DateTime(audit.Timestamp),
+					Field:     fieldName,
+					OldValue:  audit.OldValue,
+					NewValue:  audit.NewValue,
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	var groupIDs []uint64
+	err = writeList("groups", func(emit func(any) error) error {
+		var groups []*schema.Group
+		err := a.db.Session(&gorm.Session{}).
+			Where("organization_id = ?", a.organization.ID).
+			Order("id").
+			Find(&groups).
+			Error
+		if err != nil {
+			return err
+		}
+		for _, group := range groups {
+			groupIDs = append(groupIDs, group.ID)
+			o := &downballotapi.Group{
+				ID:     fmt.Sprintf("%d", group.ID),
+				Name:   group.Name,
+				Filter: group.Filter,
+			}
+			if group.ParentID != nil {
+				o.ParentID = fmt.Sprintf("%d", *group.ParentID)
+			}
+			err = emit(o)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = writeList("filters", func(emit func(any) error) error {
+		var filters []*schema.Filter
+		err := a.db.Session(&gorm.Session{}).
+			Where("organization_id = ?", a.organization.ID).
+			Order("id").
+			Find(&filters).
+			Error
+		if err != nil {
+			return err
+		}
+		for _, filter := range filters {
+			o := &downballotapi.Filter{
+				ID:          fmt.Sprintf("%d", filter.ID),
+				Name:        filter.Name,
+				Description: filter.Description,
+				Filter:      filter.Filter,
+			}
+			if filter.UserID != nil {
+				o.UserID = new(string)
+				*o.UserID = fmt.Sprintf("%d", *filter.UserID)
+			}
+			err = emit(o)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = writeList("users", func(emit func(any) error) error {
+		var userOrganizationMaps []*schema.UserOrganizationMap
+		err := a.db.Session(&gorm.Session{}).
+			Where("organization_id = ?", a.organization.ID).
+			Order("user_id").
+			Find(&userOrganizationMaps).
+			Error
+		if err != nil {
+			return err
+		}
+		userIDToUserMap := map[uint64]*schema.User{}
+		if len(userOrganizationMaps) > 0 {
+			var userIDs []uint64
+			for _, userOrganizationMap := range userOrganizationMaps {
+				userIDs = append(userIDs, userOrganizationMap.UserID)
+			}
+			var users []*schema.User
+			err = a.db.Session(&gorm.Session{}).
+				Where("id IN (?)", userIDs).
+				Find(&users).
+				Error
+			if err != nil {
+				return err
+			}
+			for _, user := range users {
+				userIDToUserMap[user.ID] = user
+			}
+		}
+		for _, userOrganizationMap := range userOrganizationMaps {
+			o := &downballotapi.User{
+				ID:     fmt.Sprintf("%d", userOrganizationMap.UserID),
+				Owner:  userOrganizationMap.Owner,
+				RoleID: convertRoleID(userOrganizationMap.RoleID),
+			}
+			if user := userIDToUserMap[userOrganizationMap.UserID]; user != nil {
+				o.Username = user.Username
+				o.Name = user.Name
+			}
+			err = emit(o)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = writeList("group_users", func(emit func(any) error) error {
+		if len(groupIDs) == 0 {
+			return nil
+		}
+		var userGroupMaps []*schema.UserGroupMap
+		err := a.db.Session(&gorm.Session{}).
+			Where("group_id IN (?)", groupIDs).
+			Order("group_id, user_id").
+			Find(&userGroupMaps).
+			Error
+		if err != nil {
+			return err
+		}
+		for _, userGroupMap := range userGroupMaps {
+			err = emit(&downballotapi.OrganizationArchiveGroupUser{
+				GroupID: fmt.Sprintf("%d", userGroupMap.GroupID),
+				UserID:  fmt.Sprintf("%d", userGroupMap.UserID),
+				Owner:   userGroupMap.Owner,
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = writeList("import_profiles", func(emit func(any) error) error {
+		var importProfiles []*schema.ImportProfile
+		err := a.db.Session(&gorm.Session{}).
+			Where("organization_id = ?", a.organization.ID).
+			Order("id").
+			Find(&importProfiles).
+			Error
+		if err != nil {
+			return err
+		}
+		for _, importProfile := range importProfiles {
+			o, err := convertImportProfile(importProfile)
+			if err != nil {
+				return err
+			}
+			err = emit(o)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = writeList("roles", func(emit func(any) error) error {
+		var roles []*schema.Role
+		err := a.db.Session(&gorm.Session{}).
+			Where("organization_id = ?", a.organization.ID).
+			Order("id").
+			Find(&roles).
+			Error
+		if err != nil {
+			return err
+		}
+		for _, role := range roles {
+			err = emit(convertRole(role))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = writeList("contact_results", func(emit func(any) error) error {
+		var contactResults []*schema.ContactResult
+		err := a.db.Session(&gorm.Session{}).
+			Where("organization_id = ?", a.organization.ID).
+			Order("id").
+			Find(&contactResults).
+			Error
+		if err != nil {
+			return err
+		}
+		for _, contactResult := range contactResults {
+			err = emit(convertContactResult(contactResult))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = writeList("contact_attempts", func(emit func(any) error) error {
+		var lastID uint64
+		for {
+			var contactAttempts []*schema.ContactAttempt
+			err := a.db.Session(&gorm.Session{}).
+				Where("person_id IN (SELECT id FROM person WHERE organization_id = ?)", a.organization.ID).
+				Where("id > ?", lastID).
+				Order("id").
+				Limit(organizationArchiveBatchSize).
+				Find(&contactAttempts).
+				Error
+			if err != nil {
+				return err
+			}
+			if len(contactAttempts) == 0 {
+				return nil
+			}
+			lastID = contactAttempts[len(contactAttempts)-1].ID
+
+			output, err := convertContactAttempts(a.db, contactAttempts)
+			if err != nil {
+				return err
+			}
+			for _, o := range output {
+				err = emit(o)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = writeList("households", func(emit func(any) error) error {
+		var lastID uint64
+		for {
+			var households []*schema.Household
+			err := a.db.Session(&gorm.Session{}).
+				Where("organization_id = ?", a.organization.ID).
+				Where("id > ?", lastID).
+				Order("id").
+				Limit(organizationArchiveBatchSize).
+				Find(&households).
+				Error
+			if err != nil {
+				return err
+			}
+			if len(households) == 0 {
+				return nil
+			}
+			lastID = households[len(households)-1].ID
+
+			for _, household := range households {
+				err = emit(convertHousehold(household, nil /*members are given by the persons*/))
+				if err != nil {
+					return err
+				}
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = writeList("turfs", func(emit func(any) error) error {
+		var lastID uint64
+		for {
+			var turfs []*schema.Turf
+			err := a.db.Session(&gorm.Session{}).
+				Where("organization_id = ?", a.organization.ID).
+				Where("id > ?", lastID).
+				Order("id").
+				Limit(organizationArchiveBatchSize).
+				Find(&turfs).
+				Error
+			if err != nil {
+				return err
+			}
+			if len(turfs) == 0 {
+				return nil
+			}
+			lastID = turfs[len(turfs)-1].ID
+
+			for _, turf := range turfs {
+				err = emit(convertTurf(turf, nil /*members are found from the coordinates of the persons*/))
+				if err != nil {
+					return err
+				}
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	err = writeList("surveys", func(emit func(any) error) error {
+		var surveys []*schema.Survey
+		err := a.db.Session(&gorm.Session{}).
+			Where("organization_id = ?", a.organization.ID).
+			Order("id").
+			Find(&surveys).
+			Error
+		if err != nil {
+			return err
+		}
+		output, err := convertSurveys(a.db, surveys)
+		if err != nil {
+			return err
+		}
+		for _, o := range output {
+			err = emit(o)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = writeList("survey_responses", func(emit func(any) error) error {
+		var lastID uint64
+		for {
+			var surveyResponses []*schema.SurveyResponse
+			err := a.db.Session(&gorm.Session{}).
+				Where("survey_id IN (SELECT id FROM survey WHERE organization_id = ?)", a.organization.ID).
+				Where("id > ?", lastID).
+				Order("id").
+				Limit(organizationArchiveBatchSize).
+				Find(&surveyResponses).
+				Error
+			if err != nil {
+				return err
+			}
+			if len(surveyResponses) == 0 {
+				return nil
+			}
+			lastID = surveyResponses[len(surveyResponses)-1].ID
+
+			output, err := convertSurveyResponses(a.db, surveyResponses)
+			if err != nil {
+				return err
+			}
+			for _, o := range output {
+				err = emit(o)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "}\n")
+	return err
+}
+
+// loadUsernames adds the usernames of the authors of the audits to the map.
+func (a *organizationArchive) loadUsernames(userIDToUsernameMap map[uint64]string, audits []*schema.PersonAudit) error {
+	missingUserIDMap := map[uint64]bool{}
+	for _, audit := range audits {
+		if _, ok := userIDToUsernameMap[audit.UserID]; !ok {
+			missingUserIDMap[audit.UserID] = true
+		}
+	}
+	if len(missingUserIDMap) == 0 {
+		return nil
+	}
+
+	var users []*schema.User
+	err := a.db.Session(&gorm.Session{}).
+		Where("id IN (?)", slices.Collect(maps.Keys(missingUserIDMap))).
+		Find(&users).
+		Error
+	if err != nil {
+		return err
+	}
+	for userID := range missingUserIDMap {
+		userIDToUsernameMap[userID] = "user #" + fmt.Sprintf("%d", userID)
+	}
+	for _, user := range users {
+		userIDToUsernameMap[user.ID] = user.Username
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package api
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+
+	"github.com/downballot/downballot/internal/filter"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/emicklei/go-restful/v3"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+// personExportBatchSize is the number of persons to load from the database at a time during an export.
+const personExportBatchSize = 1000
+
+// Person export formats.
+const (
+	personExportFormatCSV    = "csv"
+	personExportFormatNDJSON = "ndjson"
+)
+
+// personExport streams a list of persons to the response in batches.
+type personExport struct {
+	ctx                      context.Context
+	db                       *gorm.DB
+	query                    *gorm.DB // This is the base query for the persons.
+	format                   string   // This is the output format.
+	columns                  []string // These are the columns to write, in order.
+	fieldDefinitionByIDMap   map[uint64]*schema.PersonFieldDefinition
+	fieldDefinitionByNameMap map[string]*schema.PersonFieldDefinition
+}
+
+var _ restfulwrapper.Writer = (*personExport)(nil)
+
+// newPersonExport validates the export parameters and prepares the export.
+//
+// Nothing is read from the database beyond the field definitions until the export is written.
+func newPersonExport(ctx context.Context, db *gorm.DB, userID uint64, organizationID uint64, groupID *uint64, filterString *string, fields *[]string, format string) (*personExport, error) {
+	switch format {
+	case personExportFormatCSV, personExportFormatNDJSON:
+	default:
+		return nil, restfulwrapper.NewAPIQueryParameterError("format", fmt.Errorf("unsupported format: %s", format))
+	}
+
+	if filterString != nil {
+		_, err := filter.Parse(ctx, *filterString)
+		if err != nil {
+			return nil, restfulwrapper.NewAPIQueryParameterError("filter", err)
+		}
+	}
+
+	query, fieldDefinitionByIDMap, fieldDefinitionByNameMap, err := preparePersonQuery(ctx, db, userID, organizationID, groupID, filterString)
+	if err != nil {
+		return nil, err
+	}
+
+	// The voter ID always comes first, unless the caller has explicitly placed it elsewhere.
+	var columns []string
+	if fields != nil {
+		if !slices.Contains(*fields, "voter_id") {
+			columns = append(columns, "voter_id")
+		}
+		for _, fieldName := range *fields {
+			if fieldName != "voter_id" && fieldDefinitionByNameMap[fieldName] == nil {
+				return nil, restfulwrapper.NewAPIQueryParameterError("fields", fmt.Errorf("unknown field: %s", fieldName))
+			}
+			if slices.Contains(columns, fieldName) {
+				return nil, restfulwrapper.NewAPIQueryParameterError("fields", fmt.Errorf("duplicate field: %s", fieldName))
+			}
+			columns = append(columns, fieldName)
+		}
+	} else {
+		var fieldNames []string
+		for fieldName := range fieldDefinitionByNameMap {
+			fieldNames = append(fieldNames, fieldName)
+		}
+		slices.Sort(fieldNames)
+
+		columns = append(columns, "voter_id")
+		columns = append(columns, fieldNames...)
+	}
+
+	export := &personExport{
+		ctx:                      ctx,
+		db:                       db,
+		query:                    query,
+		format:                   format,
+		columns:                  columns,
+		fieldDefinitionByIDMap:   fieldDefinitionByIDMap,
+		fieldDefinitionByNameMap: fieldDefinitionByNameMap,
+	}
+	return export, nil
+}
+
+// Write implements restfulwrapper.Writer.
+func (e *personExport) Write(resp *restful.Response) {
+	switch e.format {
+	case personExportFormatCSV:
+		resp.Header().Set("Content-Type", "text/csv")
+		resp.Header().Set("Content-Disposition", `attachment; filename="persons.csv"`)
+	case personExportFormatNDJSON:
+		resp.Header().Set("Content-Type", "application/x-ndjson")
+		resp.Header().Set("Content-Disposition", `attachment; filename="persons.ndjson"`)
+	}
+	resp.WriteHeader(http.StatusOK)
+
+	err := e.write(resp)
+	if err != nil {
+		// The headers have already been sent, so there's nothing more that we can tell the client.
+		slog.ErrorContext(e.ctx, fmt.Sprintf("Could not export persons: %v", err))
+	}
+}
+
+// write writes the persons to the writer, one batch at a time.
+func (e *personExport) write(w io.Writer) error {
+	var csvWriter *csv.Writer
+	var jsonEncoder *json.Encoder
+	switch e.format {
+	case personExportFormatCSV:
+		csvWriter = csv.NewWriter(w)
+		err := csvWriter.Write(e.columns)
+		if err != nil {
+			return err
+		}
+	case personExportFormatNDJSON:
+		jsonEncoder = json.NewEncoder(w)
+	}
+
+	var returnFields []string
+	for _, column := range e.columns {
+		if column != "voter_id" {
+			returnFields = append(returnFields, column)
+		}
+	}
+
+	var pageToken *personPageToken
+	for {
+		personIDs, nextPageToken, err := pagePersonIDs(e.db, e.query, nil /*no sort*/, e.fieldDefinitionByNameMap, pageToken, personExportBatchSize)
+		if err != nil {
+			return fmt.Errorf("could not find persons: %w", err)
+		}
+
+		persons, err := loadPersons(e.db, personIDs, &returnFields, e.fieldDefinitionByIDMap, e.fieldDefinitionByNameMap)
+		if err != nil {
+			return fmt.Errorf("could not load persons: %w", err)
+		}
+
+		for _, person := range persons {
+			switch e.format {
+			case personExportFormatCSV:
+				row := make([]string, len(e.columns))
+				for i, column := range e.columns {
+					if column == "voter_id" {
+						row[i] = person.VoterID
+					} else {
+						row[i] = person.Fields[column]
+					}
+				}
+				err = csvWriter.Write(row)
+			case personExportFormatNDJSON:
+				err = jsonEncoder.Encode(person)
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if csvWriter != nil {
+			csvWriter.Flush()
+			err = csvWriter.Error()
+			if err != nil {
+				return err
+			}
+		}
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+
+		if nextPageToken == "" {
+			break
+		}
+		pageToken, err = decodePersonPageToken(nextPageToken, nil /*no sort*/)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/filter"
+	"github.com/downballot/downballot/internal/importprofile"
+	"github.com/downballot/downballot/internal/schema"
+	"gorm.io/gorm"
+)
+
+// personFieldReferences lists everything that refers to a person field.
+type personFieldReferences struct {
+	Groups          []*schema.Group
+	Filters         []*schema.Filter
+	ImportProfiles  []*schema.ImportProfile
+	SurveyQuestions []*schema.SurveyQuestion // These refer to the field by ID, so they don't need to be renamed.
+}
+
+// Empty returns true if there are no references.
+func (r personFieldReferences) Empty() bool {
+	return len(r.Groups) == 0 && len(r.Filters) == 0 && len(r.ImportProfiles) == 0 && len(r.SurveyQuestions) == 0
+}
+
+// String returns a human-readable list of the references.
+func (r personFieldReferences) String() string {
+	var parts []string
+	for _, group := range r.Groups {
+		parts = append(parts, fmt.Sprintf("group %q", group.Name))
+	}
+	for _, f := range r.Filters {
+		parts = append(parts, fmt.Sprintf("filter %q", f.Name))
+	}
+	for _, importProfile := range r.ImportProfiles {
+		parts = append(parts, fmt.Sprintf("import profile %q", importProfile.Name))
+	}
+	for _, surveyQuestion := range r.SurveyQuestions {
+		parts = append(parts, fmt.Sprintf("survey question %q", surveyQuestion.Prompt))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// findPersonFieldReferences finds the groups, filters, import profiles, and survey questions in the organization that
+// refer to the field.
+//
+// Filters that cannot be parsed are skipped, since they cannot be used anyway.
+func findPersonFieldReferences(ctx context.Context, db *gorm.DB, organizationID uint64, fieldName string) (personFieldReferences, error) {
+	var output personFieldReferences
+
+	var groups []*schema.Group
+	err := db.Session(&gorm.Session{}).
+		Where("organization_id = ?", organizationID).
+		Find(&groups).
+		Error
+	if err != nil {
+		return output, fmt.Errorf("could not find groups: %w", err)
+	}
+	for _, group := range groups {
+		clause, err := filter.Parse(ctx, group.Filter)
+		if err != nil {
+			slog.WarnContext(ctx, fmt.Sprintf("Could not parse filter for group %d: %v", group.ID, err))
+			continue
+		}
+		if slices.Contains(filter.Fields(clause), fieldName) {
+			output.Groups = append(output.Groups, group)
+		}
+	}
+
+	var filters []*schema.Filter
+	err = db.Session(&gorm.Session{}).
+		Where("organization_id = ?", organizationID).
+		Find(&filters).
+		Error
+	if err != nil {
+		return output, fmt.Errorf("could not find filters: %w", err)
+	}
+	for _, f := range filters {
+		clause, err := filter.Parse(ctx, f.Filter)
+		if err != nil {
+			slog.WarnContext(ctx, fmt.Sprintf("Could not parse filter %d: %v", f.ID, err))
+			continue
+		}
+		if slices.Contains(filter.Fields(clause), fieldName) {
+			output.Filters = append(output.Filters, f)
+		}
+	}
+
+	var importProfiles []*schema.ImportProfile
+	err = db.Session(&gorm.Session{}).
+		Where("organization_id = ?", organizationID).
+		Find(&importProfiles).
+		Error
+	if err != nil {
+		return output, fmt.Errorf("could not find import profiles: %w", err)
+	}
+	for _, importProfile := range importProfiles {
+		var profile importprofile.Profile
+		err = json.Unmarshal([]byte(importProfile.Definition), &profile)
+		if err != nil {
+			slog.WarnContext(ctx, fmt.Sprintf("Could not decode import profile %d: %v", importProfile.ID, err))
+			continue
+		}
+		if slices.Contains(profile.Fields(), fieldName) {
+			output.ImportProfiles = append(output.ImportProfiles, importProfile)
+		}
+	}
+
+	err = db.Session(&gorm.Session{}).
+		Where("person_field_definition_id IN (SELECT id FROM person_field_definition WHERE organization_id = ? AND name = ?)", organizationID, fieldName).
+		Find(&output.SurveyQuestions).
+		Error
+	if err != nil {
+		return output, fmt.Errorf("could not find survey questions: %w", err)
+	}
+
+	return output, nil
+}
+
+// renamePersonFieldReferences rewrites the groups, filters, and import profiles that refer to a field so that
+// they use its new name.
+func renamePersonFieldReferences(ctx context.Context, tx *gorm.DB, references personFieldReferences, oldName string, newName string) error {
+	for _, group := range references.Groups {
+		clause, err := filter.Parse(ctx, group.Filter)
+		if err != nil {
+			return fmt.Errorf("could not parse filter for group %d: %w", group.ID, err)
+		}
+		filter.RenameField(clause, oldName, newName)
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Model(&schema.Group{}).
+			Where("id = ?", group.ID).
+			Update("filter", clause.String()).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not update group %d: %w", group.ID, err)
+		}
+	}
+
+	for _, f := range references.Filters {
+		clause, err := filter.Parse(ctx, f.Filter)
+		if err != nil {
+			return fmt.Errorf("could not parse filter %d: %w", f.ID, err)
+		}
+		filter.RenameField(clause, oldName, newName)
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Model(&schema.Filter{}).
+			Where("id = ?", f.ID).
+			Update("filter", clause.String()).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not update filter %d: %w", f.ID, err)
+		}
+	}
+
+	for _, importProfile := range references.ImportProfiles {
+		var profile importprofile.Profile
+		err := json.Unmarshal([]byte(importProfile.Definition), &profile)
+		if err != nil {
+			return fmt.Errorf("could not decode import profile %d: %w", importProfile.ID, err)
+		}
+		profile.RenameField(oldName, newName)
+		definition, err := json.Marshal(profile)
+		if err != nil {
+			return fmt.Errorf("could not encode import profile %d: %w", importProfile.ID, err)
+		}
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Model(&schema.ImportProfile{}).
+			Where("id = ?", importProfile.ID).
+			Update("definition", string(definition)).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not update import profile %d: %w", importProfile.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// findInvalidPersonFieldValues checks every existing value of a field against its (possibly updated) definition.
+//
+// The existing values are decoded with the stored definition, since they were encoded with it.
+func findInvalidPersonFieldValues(db *gorm.DB, storedDefinition schema.PersonFieldDefinition, personFieldDefinition schema.PersonFieldDefinition) ([]*downballotapi.PersonFieldInvalidValue, error) {
+	rows, err := db.Session(&gorm.Session{}).
+		Table("person_field").
+		Select("person.voter_id, person_field.value").
+		Joins("INNER JOIN person ON person.id = person_field.person_id").
+		Where("person_field.person_field_definition_id = ?", personFieldDefinition.ID).
+		Order("person.voter_id").
+		Rows()
+	if err != nil {
+		return nil, fmt.Errorf("could not find person fields: %w", err)
+	}
+	defer rows.Close()
+
+	output := []*downballotapi.PersonFieldInvalidValue{}
+	for rows.Next() {
+		var voterID string
+		var value string
+		err = rows.Scan(&voterID, &value)
+		if err != nil {
+			return nil, fmt.Errorf("could not read person field: %w", err)
+		}
+		value, err = storedDefinition.DecodeValue(value)
+		if err != nil {
+			return nil, err
+		}
+
+		err = personFieldDefinition.Validate(value)
+		if err != nil {
+			output = append(output, &downballotapi.PersonFieldInvalidValue{
+				VoterID: voterID,
+				Value:   value,
+				Error:   err.Error(),
+			})
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("could not read person fields: %w", err)
+	}
+	return output, nil
+}
+
+// reencodePersonFieldValues re-encodes every existing value of a field (and its history) when the field is encrypted or
+// decrypted.
+//
+// The values are decoded with the old definition and encoded with the new one.
+func reencodePersonFieldValues(tx *gorm.DB, oldDefinition schema.PersonFieldDefinition, newDefinition schema.PersonFieldDefinition) error {
+	var fields []*schema.PersonField
+	err := tx.Session(&gorm.Session{NewDB: true}).
+		Where("person_field_definition_id = ?", oldDefinition.ID).
+		Find(&fields).
+		Error
+	if err != nil {
+		return fmt.Errorf("could not find person fields: %w", err)
+	}
+	for _, field := range fields {
+		value, err := oldDefinition.DecodeValue(field.Value)
+		if err != nil {
+			return err
+		}
+		storedValue, blindIndex, err := newDefinition.EncodeValue(value)
+		if err != nil {
+			return err
+		}
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Model(&schema.PersonField{}).
+			Where("id = ?", field.ID).
+			Updates(map[string]any{"value": storedValue, "blind_index": blindIndex}).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not update person field: %w", err)
+		}
+	}
+
+	var audits []*schema.PersonAudit
+	err = tx.Session(&gorm.Session{NewDB: true}).
+		Where("person_field_definition_id = ?", oldDefinition.ID).
+		Find(&audits).
+		Error
+	if err != nil {
+		return fmt.Errorf("could not find person audits: %w", err)
+	}
+	for _, audit := range audits {
+		err = audit.Decode(oldDefinition)
+		if err != nil {
+			return err
+		}
+		err = audit.Encode(newDefinition)
+		if err != nil {
+			return err
+		}
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Model(&schema.PersonAudit{}).
+			Where("id = ?", audit.ID).
+			Updates(map[string]any{"old_value": audit.OldValue, "new_value": audit.NewValue}).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not update person audit: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,324 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/downballot/downballot/internal/database"
+	"github.com/downballot/downballot/internal/geo"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+// checkPageLimit makes sure that the "limit" query parameter is a valid page size.
+func checkPageLimit(limit int) error {
+	if limit <= 0 {
+		return restfulwrapper.NewAPIQueryParameterError("limit", fmt.Errorf("limit must be positive: %d", limit))
+	}
+	if limit > MaxPageSize {
+		return restfulwrapper.NewAPIQueryParameterError("limit", fmt.Errorf("limit must be at most %d: %d", MaxPageSize, limit))
+	}
+	return nil
+}
+
+// personSortField is a single field to sort the persons by.
+type personSortField struct {
+	Name       string // The name of the field.
+	Descending bool   // True if the field is sorted in descending order.
+	Numeric    bool   // True if the field is sorted numerically.
+
+	DistanceFrom *geo.Point // If set, then this is the distance pseudo-field, measured from this point.
+}
+
+// String returns the canonical form of the sort field.
+//
+// The distance pseudo-field includes its point so that a page token can't be reused from a different point.
+func (f personSortField) String() string {
+	output := f.Name
+	if f.DistanceFrom != nil {
+		output += "@" + f.DistanceFrom.String()
+	}
+	if f.Descending {
+		return "-" + output
+	}
+	return output
+}
+
+// parsePersonSort parses a list of sort specifications, such as "name_last" or "-birthday_year".
+//
+// A leading "-" sorts the field in descending order; otherwise, it is sorted in ascending order.
+//
+// If a point is given, then the persons can also be sorted by the distance pseudo-field, which is their distance from it.
+func parsePersonSort(input []string, fieldDefinitionByNameMap map[string]*schema.PersonFieldDefinition, distanceFrom *geo.Point) ([]personSortField, error) {
+	var output []personSortField
+	seen := map[string]bool{}
+	for _, item := range input {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		sortField := personSortField{
+			Name: item,
+		}
+		if strings.HasPrefix(item, "-") {
+			sortField.Name = item[1:]
+			sortField.Descending = true
+		} else if strings.HasPrefix(item, "+") {
+			sortField.Name = item[1:]
+		}
+
+		switch sortField.Name {
+		case "voter_id":
+			// This is a column on the person itself.
+		case distancePseudoField:
+			if distanceFrom == nil {
+				return nil, fmt.Errorf("field %s can only be sorted when a point is given", sortField.Name)
+			}
+			fieldDefinition := fieldDefinitionByNameMap[coordinatesField]
+			if fieldDefinition == nil {
+				return nil, fmt.Errorf("unknown field: %s", coordinatesField)
+			}
+			if fieldDefinition.Encrypted {
+				return nil, fmt.Errorf("field %s is encrypted, so it cannot be sorted", coordinatesField)
+			}
+			sortField.Numeric = true
+			sortField.DistanceFrom = distanceFrom
+		default:
+			fieldDefinition := fieldDefinitionByNameMap[sortField.Name]
+			if fieldDefinition == nil {
+				return nil, fmt.Errorf("unknown field: %s", sortField.Name)
+			}
+			if fieldDefinition.Encrypted {
+				return nil, fmt.Errorf("field %s is encrypted, so it cannot be sorted", sortField.Name)
+			}
+			switch fieldDefinition.Type {
+			case schema.PersonFieldDefinitionTypeInteger:
+				sortField.Numeric = true
+			case schema.PersonFieldDefinitionTypeDate:
+				// Dates are stored as "YYYY-MM-DD", so sorting them as text is chronological.
+			}
+		}
+
+		if seen[sortField.Name] {
+			return nil, fmt.Errorf("duplicate field: %s", sortField.Name)
+		}
+		seen[sortField.Name] = true
+
+		output = append(output, sortField)
+	}
+	return output, nil
+}
+
+// personPageToken is the decoded form of the opaque page token.
+//
+// The token records the sort keys of the last person on the previous page so that the next page
+// can pick up immediately after it, regardless of any persons that were added or removed in the meantime.
+type personPageToken struct {
+	Sort   string `json:"s"` // This is the canonical sort that the token was generated for.
+	Values []any  `json:"v"` // These are the sort key values of the last person.
+	ID     uint64 `json:"i"` // This is the ID of the last person.
+}
+
+// encodePersonPageToken encodes a page token.
+func encodePersonPageToken(token personPageToken) (string, error) {
+	contents, err := json.Marshal(token)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(contents), nil
+}
+
+// decodePersonPageToken decodes a page token and validates it against the sort fields.
+func decodePersonPageToken(input string, sortFields []personSortField) (*personPageToken, error) {
+	contents, err := base64.RawURLEncoding.DecodeString(input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page token")
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(string(contents)))
+	decoder.UseNumber()
+
+	var token personPageToken
+	err = decoder.Decode(&token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page token")
+	}
+	if token.Sort != personSortString(sortFields) {
+		return nil, fmt.Errorf("page token does not match the sort")
+	}
+	if len(token.Values) != len(sortFields) {
+		return nil, fmt.Errorf("invalid page token")
+	}
+
+	for i, sortField := range sortFields {
+		if sortField.Numeric {
+			number, ok := token.Values[i].(json.Number)
+			if !ok {
+				return nil, fmt.Errorf("invalid page token")
+			}
+			value, err := number.Int64()
+			if err != nil {
+				return nil, fmt.Errorf("invalid page token")
+			}
+			token.Values[i] = value
+		} else {
+			value, ok := token.Values[i].(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid page token")
+			}
+			token.Values[i] = value
+		}
+	}
+	return &token, nil
+}
+
+// personSortString returns the canonical form of the sort fields.
+func personSortString(sortFields []personSortField) string {
+	var parts []string
+	for _, sortField := range sortFields {
+		parts = append(parts, sortField.String())
+	}
+	return strings.Join(parts, ",")
+}
+
+// pagePersonIDs applies the sort and page token to the person query and returns the IDs of the persons
+// on the page, in order, along with the token for the next page (if there is one).
+//
+// Every sort key is an expression that can never be NULL (missing values sort first), so the same
+// expression can be used for both the ORDER BY and the keyset comparison against the page token.
+// The person ID is always used as the final tie-breaker.
+func pagePersonIDs(db *gorm.DB, query *gorm.DB, sortFields []personSortField, fieldDefinitionByNameMap map[string]*schema.PersonFieldDefinition, pageToken *personPageToken, limit int) ([]uint64, string, error) {
+	// Make sure that the base query can be safely reused for multiple pages.
+	query = query.Session(&gorm.Session{})
+
+	var sortExpressions []string
+	for sortIndex, sortField := range sortFields {
+		var column string
+		var numericExpression string
+		switch {
+		case sortField.Name == "voter_id":
+			column = "person.voter_id"
+		case sortField.DistanceFrom != nil:
+			tableName := fmt.Sprintf("person_field_sort%d", sortIndex+1)
+			query = query.Joins("/* "+sortField.Name+" */ LEFT OUTER JOIN person_field AS "+tableName+" ON person.id = "+tableName+".person_id AND "+tableName+".person_field_definition_id = ?", fieldDefinitionByNameMap[coordinatesField].ID)
+			numericExpression = distanceSortExpression(db, tableName+".value", *sortField.DistanceFrom)
+		default:
+			tableName := fmt.Sprintf("person_field_sort%d", sortIndex+1)
+			query = query.Joins("/* "+sortField.Name+" */ LEFT OUTER JOIN person_field AS "+tableName+" ON person.id = "+tableName+".person_id AND "+tableName+".person_field_definition_id = ?", fieldDefinitionByNameMap[sortField.Name].ID)
+			column = tableName + ".value"
+		}
+
+		var sortExpression string
+		if sortField.Numeric {
+			if numericExpression == "" {
+				numericExpression = database.CastInteger(db, "NULLIF("+column+", '')")
+			}
+			sortExpression = "COALESCE(" + numericExpression + ", -9223372036854775807)"
+		} else {
+			sortExpression = "LOWER(COALESCE(" + column + ", ''))"
+		}
+		sortExpressions = append(sortExpressions, sortExpression)
+	}
+
+	if pageToken != nil {
+		// Build out the keyset condition; for sort keys (a, b), this is:
+		//    (a > ?) OR (a = ? AND b > ?) OR (a = ? AND b = ? AND person.id > ?)
+		var orParts []string
+		var vars []any
+		for i := 0; i <= len(sortFields); i++ {
+			var andParts []string
+			for j := 0; j < i; j++ {
+				andParts = append(andParts, sortExpressions[j]+" = ?")
+				vars = append(vars, pageToken.Values[j])
+			}
+			if i < len(sortFields) {
+				operation := ">"
+				if sortFields[i].Descending {
+					operation = "<"
+				}
+				andParts = append(andParts, sortExpressions[i]+" "+operation+" ?")
+				vars = append(vars, pageToken.Values[i])
+			} else {
+				andParts = append(andParts, "person.id > ?")
+				vars = append(vars, pageToken.ID)
+			}
+			orParts = append(orParts, "("+strings.Join(andParts, " AND ")+")")
+		}
+		query = query.Where(db.Session(&gorm.Session{NewDB: true, Initialized: true}).Where(strings.Join(orParts, " OR "), vars...))
+	}
+
+	selectColumns := []string{"person.id AS id"}
+	for sortIndex, sortExpression := range sortExpressions {
+		selectColumns = append(selectColumns, fmt.Sprintf("%s AS sort_key_%d", sortExpression, sortIndex))
+
+		direction := "ASC"
+		if sortFields[sortIndex].Descending {
+			direction = "DESC"
+		}
+		query = query.Order(fmt.Sprintf("sort_key_%d %s", sortIndex, direction))
+	}
+	query = query.Order("person.id ASC")
+
+	rows, err := query.
+		Distinct().
+		Select(strings.Join(selectColumns, ", ")).
+		Limit(limit + 1).
+		Rows()
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var personIDs []uint64
+	var lastValues []any
+	for rows.Next() {
+		if len(personIDs) == limit {
+			// There is at least one more person, so we need a token for the next page.
+			token, err := encodePersonPageToken(personPageToken{
+				Sort:   personSortString(sortFields),
+				Values: lastValues,
+				ID:     personIDs[len(personIDs)-1],
+			})
+			if err != nil {
+				return nil, "", fmt.Errorf("could not encode page token: %w", err)
+			}
+			return personIDs, token, nil
+		}
+
+		var personID uint64
+		destinations := []any{&personID}
+		for _, sortField := range sortFields {
+			if sortField.Numeric {
+				destinations = append(destinations, new(sql.NullInt64))
+			} else {
+				destinations = append(destinations, new(sql.NullString))
+			}
+		}
+		err = rows.Scan(destinations...)
+		if err != nil {
+			return nil, "", err
+		}
+
+		lastValues = make([]any, 0, len(sortFields))
+		for _, destination := range destinations[1:] {
+			switch typedDestination := destination.(type) {
+			case *sql.NullInt64:
+				lastValues = append(lastValues, typedDestination.Int64)
+			case *sql.NullString:
+				lastValues = append(lastValues, typedDestination.String)
+			}
+		}
+		personIDs = append(personIDs, personID)
+	}
+	err = rows.Err()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return personIDs, "", nil
+}
@@ -5,16 +5,47 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"slices"
-	"strconv"
 	"strings"
+	"time"
 
 	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/api/downballotwrapper"
+	"github.com/downballot/downballot/internal/database"
 	"github.com/downballot/downballot/internal/filter"
+	"github.com/downballot/downballot/internal/geo"
 	"github.com/downballot/downballot/internal/schema"
+	"github.com/downballot/downballot/internal/schema/sqltype"
+	"github.com/downballot/downballot/permissionset"
+	"github.com/tekkamanendless/restfulwrapper"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// isNotTrueExpression wraps an expression as "(expression) IS NOT TRUE".
+//
+// Unlike "NOT (expression)", this is true when the expression is NULL.
+type isNotTrueExpression struct {
+	Expression clause.Expression
+}
+
+// Build implements clause.Expression.
+func (e isNotTrueExpression) Build(builder clause.Builder) {
+	builder.WriteString("(")
+	e.Expression.Build(builder)
+	builder.WriteString(") IS NOT TRUE")
+}
+
+// newIsNotTrueExpression negates the WHERE conditions of the given query.
+func newIsNotTrueExpression(query *gorm.DB) (clause.Expression, error) {
+	whereClause, ok := query.Statement.Clauses["WHERE"].Expression.(clause.Where)
+	if !ok || len(whereClause.Exprs) == 0 {
+		return nil, fmt.Errorf("empty NOT clause")
+	}
+	return isNotTrueExpression{Expression: whereClause}, nil
+}
+
 func buildPersonQuery(ctx context.Context, db *gorm.DB, organizationID uint64, groupHierarchies [][]*schema.Group, filterString *string, fieldDefinitionByNameMap map[string]*schema.PersonFieldDefinition) (*gorm.DB, error) {
 	query := db.Session(&gorm.Session{}).
 		Model(&schema.Person{}).
@@ -39,6 +70,9 @@ func buildPersonQuery(ctx context.Context, db *gorm.DB, organizationID uint64, g
 		case "voter_id":
 			return nil
 		}
+		if contactFilterFields[fieldName] != nil {
+			return nil
+		}
 
 		personFieldDefinition := fieldDefinitionByNameMap[fieldName]
 		if personFieldDefinition == nil {
@@ -64,8 +98,12 @@ func buildPersonQuery(ctx context.Context, db *gorm.DB, organizationID uint64, g
 		return nil
 	}
 
-	var recursiveBuildInfo func(clause filter.Clause) error
-	recursiveBuildInfo = func(clause filter.Clause) error {
+	// recursiveBuildInfo walks the clause tree and registers the field tables that are needed.
+	//
+	// If the clause is negated (that is, it appears somewhere inside of a NOT), then none of its fields
+	// can be required, since a person without the field will match the negated clause.
+	var recursiveBuildInfo func(clause filter.Clause, negated bool) error
+	recursiveBuildInfo = func(clause filter.Clause, negated bool) error {
 		slog.DebugContext(ctx, fmt.Sprintf("recursiveBuildInfo: clause: %+v", clause))
 		switch typedClause := clause.(type) {
 		case *filter.ClauseCondition:
@@ -89,11 +127,15 @@ func buildPersonQuery(ctx context.Context, db *gorm.DB, organizationID uint64, g
 				// Inner join is fine.
 			case filter.OperationNotWildcard:
 				// Inner join is fine.
+			case filter.OperationNear:
+				// Inner join is fine.
+			case filter.OperationWithin:
+				// Inner join is fine.
 			default:
 				return fmt.Errorf("unknown operation: %s", typedClause.Operation)
 			}
 
-			err := registerFieldTableIfNecessary(typedClause.Name, innerJoin)
+			err := registerFieldTableIfNecessary(typedClause.Name, innerJoin && !negated)
 			if err != nil {
 				return err
 			}
@@ -107,7 +149,7 @@ func buildPersonQuery(ctx context.Context, db *gorm.DB, organizationID uint64, g
 		case *filter.ClauseIsNotNull:
 			slog.DebugContext(ctx, fmt.Sprintf("recursiveBuildInfo: ClauseIsNotNull: %+v", typedClause))
 
-			err := registerFieldTableIfNecessary(typedClause.Name, true)
+			err := registerFieldTableIfNecessary(typedClause.Name, !negated)
 			if err != nil {
 				return err
 			}
@@ -117,17 +159,28 @@ func buildPersonQuery(ctx context.Context, db *gorm.DB, organizationID uint64, g
 			for _, groupClause := range typedClause.Clauses {
 				switch typedClause.Operation {
 				case filter.ClauseGroupOperationAnd:
-					err := recursiveBuildInfo(groupClause)
+					err := recursiveBuildInfo(groupClause, negated)
 					if err != nil {
 						return err
 					}
 				case filter.ClauseGroupOperationOr:
-					err := recursiveBuildInfo(groupClause)
+					err := recursiveBuildInfo(groupClause, negated)
 					if err != nil {
 						return err
 					}
 				}
 			}
+		case *filter.ClauseFunction:
+			slog.DebugContext(ctx, fmt.Sprintf("recursiveBuildInfo: function: %+v", typedClause))
+
+			// The functions don't need any field tables.
+		case *filter.ClauseNot:
+			slog.DebugContext(ctx, fmt.Sprintf("recursiveBuildInfo: not: %+v", typedClause))
+
+			err := recursiveBuildInfo(typedClause.Clause, true)
+			if err != nil {
+				return err
+			}
 		default:
 			return fmt.Errorf("unknown clause type: %T", typedClause)
 		}
@@ -141,6 +194,9 @@ func buildPersonQuery(ctx context.Context, db *gorm.DB, organizationID uint64, g
 		case "voter_id":
 			return "person.voter_id", nil
 		}
+		if contactFilterField := contactFilterFields[fieldName]; contactFilterField != nil {
+			return contactFilterField.Expression, nil
+		}
 
 		fieldInfo := fieldInfoMap[fieldName]
 		if fieldInfo == nil {
@@ -158,6 +214,16 @@ func buildPersonQuery(ctx context.Context, db *gorm.DB, organizationID uint64, g
 		case *filter.ClauseCondition:
 			slog.DebugContext(ctx, fmt.Sprintf("f: condition: %+v", typedClause))
 
+			// The contact filter fields come from the contact attempts rather than the person fields.
+			if contactFilterFields[typedClause.Name] != nil {
+				subquery, err := buildContactFilterCondition(db, typedClause)
+				if err != nil {
+					return err
+				}
+				groupQuery = groupQuery.Where(subquery)
+				return nil
+			}
+
 			personFieldDefinition := fieldDefinitionByNameMap[typedClause.Name]
 			if personFieldDefinition == nil {
 				return fmt.Errorf("unknown field: %s", typedClause.Name)
@@ -168,9 +234,43 @@ func buildPersonQuery(ctx context.Context, db *gorm.DB, organizationID uint64, g
 				return fmt.Errorf("could not get field column (%T) %q: %w", typedClause, typedClause.Name, err)
 			}
 
+			// An encrypted field can only be compared by the blind indexes of its values.
+			values := typedClause.Values
+			if personFieldDefinition.Encrypted {
+				switch typedClause.Operation {
+				case filter.OperationEquals, filter.OperationNotEquals:
+				default:
+					return restfulwrapper.NewAPIResponseError(http.StatusBadRequest, fmt.Sprintf("Field %q is encrypted, so it can only be compared with '=' or '!='", typedClause.Name))
+				}
+
+				fieldColumn = fieldInfoMap[typedClause.Name].TableName + ".blind_index"
+				values = nil
+				for _, value := range typedClause.Values {
+					blindIndex, err := personFieldDefinition.BlindIndex(value)
+					if err != nil {
+						return err
+					}
+					values = append(values, blindIndex)
+				}
+			}
+
+			// The geographic operations take all of their values at once.
+			switch typedClause.Operation {
+			case filter.OperationNear, filter.OperationWithin:
+				subquery, err := buildCoordinatesCondition(db, fieldColumn, personFieldDefinition, typedClause)
+				if err != nil {
+					return err
+				}
+				groupQuery = groupQuery.Where(subquery)
+				return nil
+			}
+			if typedClause.Function != "" {
+				return restfulwrapper.NewAPIResponseError(http.StatusBadRequest, fmt.Sprintf("Operation %q cannot be given a function", typedClause.Operation))
+			}
+
 			// We need to create a parenthetical subquery and add everything to that.
 			subquery := db.Session(&gorm.Session{NewDB: true, Initialized: true})
-			for _, value := range typedClause.Values {
+			for _, value := range values {
 				switch typedClause.Operation {
 				case filter.OperationEquals:
 					subquery = subquery.Or(fieldColumn+" = ?", value)
@@ -179,76 +279,52 @@ func buildPersonQuery(ctx context.Context, db *gorm.DB, organizationID uint64, g
 				case filter.OperationGreaterThan:
 					switch personFieldDefinition.Type {
 					case "integer":
-						subquery = subquery.Or("CAST("+fieldColumn+" AS INTEGER) > ?", value)
+						subquery = subquery.Or(database.CastInteger(db, fieldColumn)+" > ?", value)
 					default:
 						subquery = subquery.Or(fieldColumn+" > ?", value)
 					}
 				case filter.OperationGreaterThanOrEqual:
 					switch personFieldDefinition.Type {
 					case "integer":
-						subquery = subquery.Or("CAST("+fieldColumn+" AS INTEGER) >= ?", value)
+						subquery = subquery.Or(database.CastInteger(db, fieldColumn)+" >= ?", value)
 					default:
 						subquery = subquery.Or(fieldColumn+" >= ?", value)
 					}
 				case filter.OperationLessThan:
 					switch personFieldDefinition.Type {
 					case "integer":
-						subquery = subquery.Or("CAST("+fieldColumn+" AS INTEGER) < ?", value)
+						subquery = subquery.Or(database.CastInteger(db, fieldColumn)+" < ?", value)
 					default:
 						subquery = subquery.Or(fieldColumn+" < ?", value)
 					}
 				case filter.OperationLessThanOrEqual:
 					switch personFieldDefinition.Type {
 					case "integer":
-						subquery = subquery.Or("CAST("+fieldColumn+" AS INTEGER) <= ?", value)
+						subquery = subquery.Or(database.CastInteger(db, fieldColumn)+" <= ?", value)
 					default:
 						subquery = subquery.Or(fieldColumn+" <= ?", value)
 					}
 				case filter.OperationWildcard:
 					switch personFieldDefinition.Type {
 					case schema.PersonFieldDefinitionTypeCoordinates:
-						parts := strings.SplitN(value, ",", 2)
-						if len(parts) != 2 {
-							return fmt.Errorf("invalid coordinates value: %s", value)
-						}
-						latitude, err := strconv.ParseFloat(parts[0], 64)
+						point, err := geo.ParsePoint(value)
 						if err != nil {
-							return fmt.Errorf("invalid latitude: %w", err)
+							return restfulwrapper.NewAPIResponseError(http.StatusBadRequest, err.Error())
 						}
-						longitude, err := strconv.ParseFloat(parts[1], 64)
-						if err != nil {
-							return fmt.Errorf("invalid longitude: %w", err)
-						}
-						oneMeter := 0.000009
-						subquery = subquery.Or(
-							db.Session(&gorm.Session{NewDB: true, Initialized: true}).
-								Where("CAST(SUBSTR("+fieldColumn+", 1, INSTR("+fieldColumn+", ',')) AS REAL) BETWEEN ? AND ?", latitude-100*oneMeter, latitude+100*oneMeter).
-								Where("CAST(SUBSTR("+fieldColumn+", INSTR("+fieldColumn+", ',') + 1) AS REAL) BETWEEN ? AND ?", longitude-100*oneMeter, longitude+100*oneMeter),
-						)
+						expression, args := coordinatesNearExpression(db, fieldColumn, point, coordinatesWildcardDistance)
+						subquery = subquery.Or(expression, args...)
 					default:
 						subquery = subquery.Or(fieldColumn+" LIKE ?", strings.ReplaceAll(value, "*", "%"))
 					}
 				case filter.OperationNotWildcard:
 					switch personFieldDefinition.Type {
 					case schema.PersonFieldDefinitionTypeCoordinates:
-						parts := strings.SplitN(value, ",", 2)
-						if len(parts) != 2 {
-							return fmt.Errorf("invalid coordinates value: %s", value)
-						}
-						latitude, err := strconv.ParseFloat(parts[0], 64)
+						point, err := geo.ParsePoint(value)
 						if err != nil {
-							return fmt.Errorf("invalid latitude: %w", err)
+							return restfulwrapper.NewAPIResponseError(http.StatusBadRequest, err.Error())
 						}
-						longitude, err := strconv.ParseFloat(parts[1], 64)
-						if err != nil {
-							return fmt.Errorf("invalid longitude: %w", err)
-						}
-						oneMeter := 0.000009
-						subquery = subquery.Where(
-							db.Session(&gorm.Session{NewDB: true, Initialized: true}).
-								Or("CAST(SUBSTR("+fieldColumn+", 1, INSTR("+fieldColumn+", ',')) AS REAL) NOT BETWEEN ? AND ?", latitude-100*oneMeter, latitude+100*oneMeter).
-								Or("CAST(SUBSTR("+fieldColumn+", INSTR("+fieldColumn+", ',') + 1) AS REAL) NOT BETWEEN ? AND ?", longitude-100*oneMeter, longitude+100*oneMeter),
-						)
+						expression, args := coordinatesNearExpression(db, fieldColumn, point, coordinatesWildcardDistance)
+						subquery = subquery.Where("NOT "+expression, args...)
 					default:
 						subquery = subquery.Where(fieldColumn+" NOT LIKE ?", strings.ReplaceAll(value, "*", "%"))
 					}
@@ -292,6 +368,36 @@ func buildPersonQuery(ctx context.Context, db *gorm.DB, organizationID uint64, g
 					groupQuery.Or(newQuery)
 				}
 			}
+		case *filter.ClauseFunction:
+			slog.DebugContext(ctx, fmt.Sprintf("f: function: %+v", typedClause))
+
+			switch typedClause.Name {
+			case "within_turf":
+				subquery, err := buildWithinTurfCondition(db, organizationID, typedClause)
+				if err != nil {
+					return err
+				}
+				groupQuery = groupQuery.Where(subquery)
+			default:
+				return restfulwrapper.NewAPIResponseError(http.StatusBadRequest, fmt.Sprintf("Unknown filter function: %s", typedClause.Name))
+			}
+		case *filter.ClauseNot:
+			slog.DebugContext(ctx, fmt.Sprintf("f: not: %+v", typedClause))
+
+			newQuery := db.Session(&gorm.Session{NewDB: true, Initialized: true})
+			err := f(typedClause.Clause, newQuery)
+			if err != nil {
+				return err
+			}
+
+			// Every field is LEFT OUTER JOINed, so a condition against a field that the person does not have
+			// evaluates to NULL rather than false.  A plain NOT would leave that as NULL and drop the person,
+			// so we use "IS NOT TRUE" to treat "unknown" as "did not match" before negating it.
+			notExpression, err := newIsNotTrueExpression(newQuery)
+			if err != nil {
+				return err
+			}
+			groupQuery.Where(notExpression)
 		default:
 			return fmt.Errorf("unknown clause type: %T", typedClause)
 		}
@@ -335,7 +441,7 @@ func buildPersonQuery(ctx context.Context, db *gorm.DB, organizationID uint64, g
 			}
 
 			// Build the field info map; this will populate `fieldInfoMap`.
-			err = recursiveBuildInfo(groupClause)
+			err = recursiveBuildInfo(groupClause, false)
 			if err != nil {
 				return nil, err
 			}
@@ -390,9 +496,101 @@ func buildPersonQuery(ctx context.Context, db *gorm.DB, organizationID uint64, g
 }
 
 func filterPersons(ctx context.Context, db *gorm.DB, userID uint64, organizationID uint64, groupID *uint64, filterString *string, returnFields *[]string, limit int) ([]*downballotapi.Person, error) {
+	persons, _, err := filterPersonsPage(ctx, db, userID, organizationID, groupID, filterString, returnFields, nil /*no sort*/, nil /*no distance*/, nil /*first page*/, limit)
+	return persons, err
+}
+
+// filterPersonsWithPermission is like filterPersons, but it only returns the persons for which the user has the permission.
+//
+// If the user has the permission for the whole organization, then this is every person that the user can see.
+// Otherwise, this is every person in the groups on which the user has been granted the permission.
+func filterPersonsWithPermission(ctx context.Context, db *gorm.DB, user downballotwrapper.User, organizationID uint64, permission permissionset.Permission, filterString *string, returnFields *[]string, limit int) ([]*downballotapi.Person, error) {
+	permissionSet := user.PermissionSetForOrganization(organizationID)
+	if permissionSet.Match(permission) {
+		return filterPersons(ctx, db, user.ID, organizationID, nil /*no group ID*/, filterString, returnFields, limit)
+	}
+
+	output := []*downballotapi.Person{}
+	personIDMap := map[string]bool{}
+	for _, groupID := range user.GroupIDsWithPermission(organizationID, permission) {
+		persons, err := filterPersons(ctx, db, user.ID, organizationID, &groupID, filterString, returnFields, limit)
+		if err != nil {
+			return nil, err
+		}
+		for _, person := range persons {
+			if personIDMap[person.ID] {
+				continue
+			}
+			personIDMap[person.ID] = true
+			output = append(output, person)
+			if len(output) >= limit {
+				return output, nil
+			}
+		}
+	}
+	return output, nil
+}
+
+// filterPersonsPage returns a single page of persons, sorted by the given fields (see `parsePersonSort`).
+//
+// If there are more persons after this page, then the token for the next page is also returned.
+//
+// If a point is given, then each person also gets the distance pseudo-field (their distance from it), which can be sorted.
+func filterPersonsPage(ctx context.Context, db *gorm.DB, userID uint64, organizationID uint64, groupID *uint64, filterString *string, returnFields *[]string, sort []string, distanceFrom *geo.Point, pageTokenString *string, limit int) ([]*downballotapi.Person, string, error) {
+	query, fieldDefinitionByIDMap, fieldDefinitionByNameMap, err := preparePersonQuery(ctx, db, userID, organizationID, groupID, filterString)
+	if err != nil {
+		return nil, "", err
+	}
+
+	sortFields, err := parsePersonSort(sort, fieldDefinitionByNameMap, distanceFrom)
+	if err != nil {
+		return nil, "", restfulwrapper.NewAPIQueryParameterError("sort", err)
+	}
+
+	var pageToken *personPageToken
+	if pageTokenString != nil && *pageTokenString != "" {
+		pageToken, err = decodePersonPageToken(*pageTokenString, sortFields)
+		if err != nil {
+			return nil, "", restfulwrapper.NewAPIQueryParameterError("page_token", err)
+		}
+	}
+
+	personIDs, nextPageToken, err := pagePersonIDs(db, query, sortFields, fieldDefinitionByNameMap, pageToken, limit)
+	if err != nil {
+		return nil, "", err
+	}
+
+	includeDistance := distanceFrom != nil
+	if distanceFrom != nil && returnFields != nil {
+		// The distance isn't a real field, so don't ask for it.
+		includeDistance = slices.Contains(*returnFields, distancePseudoField)
+		fieldNames := slices.DeleteFunc(slices.Clone(*returnFields), func(fieldName string) bool {
+			return fieldName == distancePseudoField
+		})
+		returnFields = &fieldNames
+	}
+
+	output, err := loadPersons(db, personIDs, returnFields, fieldDefinitionByIDMap, fieldDefinitionByNameMap)
+	if err != nil {
+		return nil, "", err
+	}
+	if includeDistance {
+		err = addPersonDistances(db, organizationID, output, *distanceFrom)
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	return output, nextPageToken, nil
+}
+
+// preparePersonQuery loads the field definitions for the organization and builds the query for the
+// persons that the user can see (see `buildPersonQuery`).
+//
+// If a group ID is given, then only the persons in that group are considered.
+func preparePersonQuery(ctx context.Context, db *gorm.DB, userID uint64, organizationID uint64, groupID *uint64, filterString *string) (*gorm.DB, map[uint64]*schema.PersonFieldDefinition, map[string]*schema.PersonFieldDefinition, error) {
 	groupHierarchies, err := getGroupHierarchiesForUser(db, userID, organizationID)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
 	slog.InfoContext(ctx, fmt.Sprintf("Hierarchies: (%d)", len(groupHierarchies)))
 
@@ -405,7 +603,7 @@ func filterPersons(ctx context.Context, db *gorm.DB, userID uint64, organization
 			Find(&fieldDefinitions).
 			Error
 		if err != nil {
-			return nil, fmt.Errorf("could not find field definitions: %w", err)
+			return nil, nil, nil, fmt.Errorf("could not find field definitions: %w", err)
 		}
 		for _, fieldDefinition := range fieldDefinitions {
 			fieldDefinitionByIDMap[fieldDefinition.ID] = fieldDefinition
@@ -426,7 +624,7 @@ func filterPersons(ctx context.Context, db *gorm.DB, userID uint64, organization
 		}
 
 		if len(groupHierarchy) == 0 {
-			return nil, fmt.Errorf("could not find hierarchy for group.id=%d", *groupID)
+			return nil, nil, nil, fmt.Errorf("could not find hierarchy for group.id=%d", *groupID)
 		}
 
 		groupHierarchies = [][]*schema.Group{groupHierarchy}
@@ -435,34 +633,50 @@ func filterPersons(ctx context.Context, db *gorm.DB, userID uint64, organization
 
 	query, err := buildPersonQuery(ctx, db, organizationID, groupHierarchies, filterString, fieldDefinitionByNameMap)
 	if err != nil {
-		return nil, err
+		return nil, nil, nil, err
 	}
+	return query, fieldDefinitionByIDMap, fieldDefinitionByNameMap, nil
+}
 
+// loadPersons loads the persons with the given IDs, in the same order as the IDs.
+//
+// If `returnFields` is given, then only those fields are loaded.
+func loadPersons(db *gorm.DB, personIDs []uint64, returnFields *[]string, fieldDefinitionByIDMap map[uint64]*schema.PersonFieldDefinition, fieldDefinitionByNameMap map[string]*schema.PersonFieldDefinition) ([]*downballotapi.Person, error) {
 	var persons []*schema.Person
-	err = query.
-		Distinct().
-		Limit(limit).
-		Find(&persons).
-		Error
-	if err != nil {
-		return nil, err
+	if len(personIDs) > 0 {
+		err := db.Session(&gorm.Session{}).
+			Where("id IN (?)", personIDs).
+			Find(&persons).
+			Error
+		if err != nil {
+			return nil, err
+		}
 	}
-
-	var personIDs []uint64
-	for _, person := range persons {
-		personIDs = append(personIDs, person.ID)
+	{
+		// Put the persons back in the requested order.
+		personIndexMap := map[uint64]int{}
+		for index, personID := range personIDs {
+			personIndexMap[personID] = index
+		}
+		slices.SortFunc(persons, func(left, right *schema.Person) int {
+			return cmp.Compare(personIndexMap[left.ID], personIndexMap[right.ID])
+		})
 	}
 
 	output := make([]*downballotapi.Person, 0, len(persons))
 
 	personFieldsMap := map[uint64]map[string]string{}
-	{
+	if len(personIDs) > 0 {
 		var fields []*schema.PersonField
 		query := db.Session(&gorm.Session{}).
 			Where("person_id IN (?)", personIDs)
 		if returnFields != nil {
 			fieldDefinitionIDs := []uint64{}
 			for _, fieldName := range *returnFields {
+				if fieldName == "voter_id" {
+					// This is always returned.
+					continue
+				}
 				fieldDefinition := fieldDefinitionByNameMap[fieldName]
 				if fieldDefinition == nil {
 					return nil, fmt.Errorf("unknown field: %s", fieldName)
@@ -485,16 +699,28 @@ func filterPersons(ctx context.Context, db *gorm.DB, userID uint64, organization
 			if personFieldDefinition == nil {
 				return nil, fmt.Errorf("unknown field definition: %d", field.PersonFieldDefinitionID)
 			}
-			personFieldsMap[field.PersonID][personFieldDefinition.Name] = field.Value
+			value, err := personFieldDefinition.DecodeValue(field.Value)
+			if err != nil {
+				return nil, err
+			}
+			personFieldsMap[field.PersonID][personFieldDefinition.Name] = value
 		}
 	}
 
+	personIDToHouseholdIDMap, err := loadHouseholdIDs(db, personIDs)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, person := range persons {
 		o := &downballotapi.Person{
 			ID:      fmt.Sprintf("%d", person.ID),
 			VoterID: person.VoterID,
 			Fields:  map[string]string{},
 		}
+		if householdID, ok := personIDToHouseholdIDMap[person.ID]; ok {
+			o.HouseholdID = fmt.Sprintf("%d", householdID)
+		}
 
 		fields := personFieldsMap[person.ID]
 		for name, value := range fields {
@@ -569,3 +795,153 @@ func filterPersonsCount(ctx context.Context, db *gorm.DB, userID uint64, organiz
 
 	return groupIDToCountMap, nil
 }
+
+// findPersonFieldDefinition returns the organization's field definition with the given name, or nil if there is none.
+func findPersonFieldDefinition(tx *gorm.DB, organizationID uint64, name string) (*schema.PersonFieldDefinition, error) {
+	var fieldDefinitions []*schema.PersonFieldDefinition
+	err := tx.Session(&gorm.Session{NewDB: true}).
+		Where("organization_id = ?", organizationID).
+		Where("name = ?", name).
+		Find(&fieldDefinitions).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("could not find field definitions: %w", err)
+	}
+	if len(fieldDefinitions) == 0 {
+		return nil, nil
+	}
+	return fieldDefinitions[0], nil
+}
+
+// personFieldValues returns the (decoded) value of the named field for each of the given persons.
+//
+// Each person that does not have a value is given a blank one.  If the organization does not have the field, then
+// every value is blank.
+func personFieldValues(tx *gorm.DB, organizationID uint64, name string, personIDs []uint64) (map[uint64]string, error) {
+	output := map[uint64]string{}
+	for _, personID := range personIDs {
+		output[personID] = ""
+	}
+	if len(personIDs) == 0 {
+		return output, nil
+	}
+
+	fieldDefinition, err := findPersonFieldDefinition(tx, organizationID, name)
+	if err != nil {
+		return nil, err
+	}
+	if fieldDefinition == nil {
+		return output, nil
+	}
+
+	var fields []*schema.PersonField
+	err = tx.Session(&gorm.Session{NewDB: true}).
+		Where("person_id IN (?)", personIDs).
+		Where("person_field_definition_id = ?", fieldDefinition.ID).
+		Find(&fields).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("could not find fields: %w", err)
+	}
+	for _, field := range fields {
+		value, err := fieldDefinition.DecodeValue(field.Value)
+		if err != nil {
+			return nil, err
+		}
+		output[field.PersonID] = value
+	}
+	return output, nil
+}
+
+// setPersonField sets a field on a person and records the change in the person's audit.
+//
+// If the field already has the value, then nothing is done.
+func setPersonField(tx *gorm.DB, userID uint64, personID uint64, fieldDefinition *schema.PersonFieldDefinition, oldValue *string, value string) error {
+	if oldValue != nil && *oldValue == value {
+		return nil
+	}
+
+	storedValue, blindIndex, err := fieldDefinition.EncodeValue(value)
+	if err != nil {
+		return err
+	}
+
+	var fields []*schema.PersonField
+	err = tx.Session(&gorm.Session{NewDB: true}).
+		Where("person_id = ?", personID).
+		Where("person_field_definition_id = ?", fieldDefinition.ID).
+		Find(&fields).
+		Error
+	if err != nil {
+		return fmt.Errorf("could not find fields: %w", err)
+	}
+	if len(fields) == 0 {
+		field := schema.PersonField{
+			PersonID:                personID,
+			PersonFieldDefinitionID: fieldDefinition.ID,
+			Value:                   storedValue,
+			BlindIndex:              blindIndex,
+		}
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Create(&field).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not create field: %w", err)
+		}
+	} else {
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Model(&schema.PersonField{}).
+			Where("id = ?", fields[0].ID).
+			Updates(map[string]any{"value": storedValue, "blind_index": blindIndex}).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not update field: %w", err)
+		}
+	}
+
+	audit := schema.PersonAudit{
+		PersonID:                personID,
+		UserID:                  userID,
+		PersonFieldDefinitionID: fieldDefinition.ID,
+		Timestamp:               sqltype.DateTime(time.Now()),
+		OldValue:                oldValue,
+		NewValue:                &value,
+	}
+	err = audit.Encode(*fieldDefinition)
+	if err != nil {
+		return err
+	}
+	err = tx.Session(&gorm.Session{NewDB: true}).
+		Create(&audit).
+		Error
+	if err != nil {
+		return fmt.Errorf("could not create audit: %w", err)
+	}
+	return nil
+}
+
+// updatePersonMemberships updates the households and turfs of the persons whose fields changed.
+//
+// Only the fields that changed need to be given for each person; a blank value means that the field was removed.
+func updatePersonMemberships(tx *gorm.DB, organizationID uint64, personIDToFieldsMap map[uint64]map[string]string) error {
+	personIDToAddressMap := map[uint64]string{}
+	personIDToCoordinatesMap := map[uint64]string{}
+	for personID, fields := range personIDToFieldsMap {
+		if value, ok := fields[householdAddressField]; ok {
+			personIDToAddressMap[personID] = value
+		}
+		if value, ok := fields[coordinatesField]; ok {
+			personIDToCoordinatesMap[personID] = value
+		}
+	}
+
+	err := assignHouseholds(tx, organizationID, personIDToAddressMap)
+	if err != nil {
+		return err
+	}
+	err = assignTurfs(tx, organizationID, personIDToCoordinatesMap)
+	if err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/iam"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/downballot/downballot/permissionset"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+// validatePermissions makes sure that every permission (such as for a role or an API token) is valid and matches at least one real permission.
+//
+// This catches typos, since a permission that matches nothing would otherwise be silently ignored.
+func validatePermissions(permissions []string) error {
+	for _, permissionString := range permissions {
+		permission := permissionset.Permission(permissionString)
+		if !permission.Valid() {
+			return fmt.Errorf("invalid permission: %q", permissionString)
+		}
+		matched := false
+		for _, iamPermission := range iam.Permissions {
+			if permission.Matches(iamPermission) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("unknown permission: %q", permissionString)
+		}
+	}
+	return nil
+}
+
+// checkGrantablePermissions makes sure that the user already has every permission that they are trying to grant
+// (by creating or changing a role, or by giving someone a role) or to take away (by taking someone's role away).
+//
+// Otherwise, someone who can manage roles could give themselves anything.  A wildcard is checked against each of
+// the real permissions that it matches, since having "role:update" must not count as having "*".
+func checkGrantablePermissions(permissionSet permissionset.PermissionSet, permissions []string) error {
+	for _, permissionString := range permissions {
+		permission := permissionset.Permission(permissionString)
+		for _, iamPermission := range iam.Permissions {
+			if permission.Matches(iamPermission) && !permissionSet.Match(iamPermission) {
+				return restfulwrapper.NewAPIResponseError(http.StatusForbidden, fmt.Sprintf("Cannot grant or take away %q without having %q", permissionString, iamPermission))
+			}
+		}
+	}
+	return nil
+}
+
+// checkGrantableOwner makes sure that the user already has every permission that an owner of the organization has.
+//
+// This is needed both to make someone an owner and to take that away from them.
+func checkGrantableOwner(permissionSet permissionset.PermissionSet) error {
+	for _, iamPermission := range iam.Permissions {
+		if !permissionSet.Match(iamPermission) {
+			return restfulwrapper.NewAPIResponseError(http.StatusForbidden, fmt.Sprintf("Cannot change whether a user is an owner without having %q", iamPermission))
+		}
+	}
+	return nil
+}
+
+// checkRevocableRole makes sure that the user already has every permission of the role that they are trying to take
+// away from someone (by clearing it or replacing it).
+//
+// Otherwise, someone who can manage users could strip the people who outrank them.
+func checkRevocableRole(db *gorm.DB, permissionSet permissionset.PermissionSet, roleID uint64) error {
+	var roles []*schema.Role
+	err := db.Session(&gorm.Session{}).
+		Where("id = ?", roleID).
+		Limit(1).
+		Find(&roles).
+		Error
+	if err != nil {
+		return fmt.Errorf("could not find role: %w", err)
+	}
+	if len(roles) == 0 {
+		return nil
+	}
+	return checkGrantablePermissions(permissionSet, roles[0].Permissions)
+}
+
+// findRole parses a role ID and makes sure that the role belongs to the organization.
+func findRole(db *gorm.DB, organizationID uint64, roleIDString string) (*schema.Role, error) {
+	roleID, err := strconv.ParseUint(roleIDString, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid role ID: %q", roleIDString)
+	}
+
+	var roles []*schema.Role
+	err = db.Session(&gorm.Session{}).
+		Where("id = ?", roleID).
+		Where("organization_id = ?", organizationID).
+		Limit(1).
+		Find(&roles).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("could not find role: %w", err)
+	}
+	if len(roles) == 0 {
+		return nil, fmt.Errorf("unknown role ID: %q", roleIDString)
+	}
+	return roles[0], nil
+}
+
+// convertRole converts a role into its API form.
+func convertRole(role *schema.Role) *downballotapi.Role {
+	return &downballotapi.Role{
+		ID:          fmt.Sprintf("%d", role.ID),
+		Name:        role.Name,
+		Description: role.Description,
+		Permissions: append([]string{}, role.Permissions...),
+	}
+}
+
+// convertRoleID converts an optional role ID into its API form.
+func convertRoleID(roleID *uint64) string {
+	if roleID == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d", *roleID)
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/downballot/downballot/internal/apitoken"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/downballot/downballot/internal/schema/sqltype"
+	"gorm.io/gorm"
+)
+
+// revokeUserSessions signs the user out everywhere.
+//
+// This rotates the user's session identifier (which invalidates every token issued to the user) and
+// marks all of the user's sessions and API tokens as revoked.  It returns the number of sessions that were revoked.
+func revokeUserSessions(tx *gorm.DB, userID uint64) (int64, error) {
+	err := tx.Session(&gorm.Session{NewDB: true}).
+		Model(&schema.User{}).
+		Where("id = ?", userID).
+		Update("session_identifier", gorm.Expr("session_identifier + 1")).
+		Error
+	if err != nil {
+		return 0, fmt.Errorf("could not rotate session identifier: %w", err)
+	}
+
+	result := tx.Session(&gorm.Session{NewDB: true}).
+		Model(&schema.Session{}).
+		Where("user_id = ?", userID).
+		Where("revoked_timestamp IS NULL").
+		Update("revoked_timestamp", sqltype.DateTime(time.Now()))
+	if result.Error != nil {
+		return 0, fmt.Errorf("could not revoke sessions: %w", result.Error)
+	}
+
+	// API tokens don't carry the session identifier, so they have to be revoked on their own.
+	err = tx.Session(&gorm.Session{NewDB: true}).
+		Model(&schema.APIToken{}).
+		Where("user_id = ?", userID).
+		Where("revoked_timestamp IS NULL").
+		Update("revoked_timestamp", sqltype.DateTime(time.Now())).
+		Error
+	if err != nil {
+		return 0, fmt.Errorf("could not revoke API tokens: %w", err)
+	}
+	return result.RowsAffected, nil
+}
+
+// issueSessionToken records a new session for the user and returns a signed token for it.
+//
+// The session is what lets the token be revoked later.
+func (a *API) issueSessionToken(db *gorm.DB, userID uint64, claims apitoken.TokenClaims, userAgent string, sourceAddress string) (string, error) {
+	now := time.Now()
+	session := schema.Session{
+		UserID:           userID,
+		CreatedTimestamp: sqltype.DateTime(now),
+		UserAgent:        truncateString(userAgent, 512),
+		SourceAddress:    truncateString(sourceAddress, 64),
+	}
+	if claims.ExpiresAt > 0 {
+		expirationTimestamp := sqltype.DateTime(time.Unix(claims.ExpiresAt, 0))
+		session.ExpirationTimestamp = &expirationTimestamp
+	}
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return tx.Session(&gorm.Session{NewDB: true}).
+			Create(&session).
+			Error
+	})
+	if err != nil {
+		return "", fmt.Errorf("could not create session: %w", err)
+	}
+	claims.Id = fmt.Sprintf("%d", session.ID)
+	claims.IssuedAt = now.Unix()
+
+	return a.signToken(claims)
+}
+
+// truncateString truncates the string to at most the given number of bytes.
+func truncateString(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+	return value[:length]
+}
@@ -0,0 +1,474 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/api/resttype"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+// surveyQuestions is a survey's questions, in order, along with the choices for each question.
+type surveyQuestions struct {
+	Questions              []*schema.SurveyQuestion
+	QuestionIDToChoicesMap map[uint64][]*schema.SurveyChoice
+	FieldDefinitionByIDMap map[uint64]*schema.PersonFieldDefinition // These are the fields that the questions are linked to.
+}
+
+// loadSurveyQuestions loads the questions of the given surveys, by survey ID.
+func loadSurveyQuestions(db *gorm.DB, surveyIDs []uint64) (map[uint64]*surveyQuestions, error) {
+	output := map[uint64]*surveyQuestions{}
+	for _, surveyID := range surveyIDs {
+		output[surveyID] = &surveyQuestions{
+			QuestionIDToChoicesMap: map[uint64][]*schema.SurveyChoice{},
+			FieldDefinitionByIDMap: map[uint64]*schema.PersonFieldDefinition{},
+		}
+	}
+	if len(surveyIDs) == 0 {
+		return output, nil
+	}
+
+	var questions []*schema.SurveyQuestion
+	err := db.Session(&gorm.Session{NewDB: true}).
+		Where("survey_id IN (?)", surveyIDs).
+		Order("position ASC, id ASC").
+		Find(&questions).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("could not find survey questions: %w", err)
+	}
+	if len(questions) == 0 {
+		return output, nil
+	}
+
+	questionIDToSurveyIDMap := map[uint64]uint64{}
+	fieldDefinitionIDMap := map[uint64]bool{}
+	for _, question := range questions {
+		questionIDToSurveyIDMap[question.ID] = question.SurveyID
+		output[question.SurveyID].Questions = append(output[question.SurveyID].Questions, question)
+		if question.PersonFieldDefinitionID != nil {
+			fieldDefinitionIDMap[*question.PersonFieldDefinitionID] = true
+		}
+	}
+
+	var choices []*schema.SurveyChoice
+	err = db.Session(&gorm.Session{NewDB: true}).
+		Where("survey_question_id IN (?)", slices.Collect(maps.Keys(questionIDToSurveyIDMap))).
+		Order("position ASC, id ASC").
+		Find(&choices).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("could not find survey choices: %w", err)
+	}
+	for _, choice := range choices {
+		o := output[questionIDToSurveyIDMap[choice.SurveyQuestionID]]
+		o.QuestionIDToChoicesMap[choice.SurveyQuestionID] = append(o.QuestionIDToChoicesMap[choice.SurveyQuestionID], choice)
+	}
+
+	if len(fieldDefinitionIDMap) > 0 {
+		var fieldDefinitions []*schema.PersonFieldDefinition
+		err = db.Session(&gorm.Session{NewDB: true}).
+			Where("id IN (?)", slices.Collect(maps.Keys(fieldDefinitionIDMap))).
+			Find(&fieldDefinitions).
+			Error
+		if err != nil {
+			return nil, fmt.Errorf("could not find field definitions: %w", err)
+		}
+		for _, fieldDefinition := range fieldDefinitions {
+			for _, o := range output {
+				o.FieldDefinitionByIDMap[fieldDefinition.ID] = fieldDefinition
+			}
+		}
+	}
+	return output, nil
+}
+
+// Field returns the field that the question is linked to, or nil if there is none.
+func (q *surveyQuestions) Field(question *schema.SurveyQuestion) *schema.PersonFieldDefinition {
+	if question.PersonFieldDefinitionID == nil {
+		return nil
+	}
+	return q.FieldDefinitionByIDMap[*question.PersonFieldDefinitionID]
+}
+
+// convertSurveys converts the surveys (with their questions) into their API form.
+func convertSurveys(db *gorm.DB, surveys []*schema.Survey) ([]*downballotapi.Survey, error) {
+	var surveyIDs []uint64
+	for _, survey := range surveys {
+		surveyIDs = append(surveyIDs, survey.ID)
+	}
+	surveyIDToQuestionsMap, err := loadSurveyQuestions(db, surveyIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	output := []*downballotapi.Survey{}
+	for _, survey := range surveys {
+		o := &downballotapi.Survey{
+			ID:          fmt.Sprintf("%d", survey.ID),
+			Name:        survey.Name,
+			Description: survey.Description,
+			Active:      survey.Active,
+			Questions:   []*downballotapi.SurveyQuestion{},
+		}
+		questions := surveyIDToQuestionsMap[survey.ID]
+		for _, question := range questions.Questions {
+			q := &downballotapi.SurveyQuestion{
+				ID:      fmt.Sprintf("%d", question.ID),
+				Prompt:  question.Prompt,
+				Type:    downballotapi.SurveyQuestionType(question.Type),
+				Choices: []*downballotapi.SurveyChoice{},
+			}
+			if fieldDefinition := questions.Field(question); fieldDefinition != nil {
+				q.Field = fieldDefinition.Name
+			}
+			for _, choice := range questions.QuestionIDToChoicesMap[question.ID] {
+				q.Choices = append(q.Choices, &downballotapi.SurveyChoice{
+					Code:  choice.Code,
+					Label: choice.Label,
+					Value: choice.Value,
+				})
+			}
+			o.Questions = append(o.Questions, q)
+		}
+		output = append(output, o)
+	}
+	return output, nil
+}
+
+// setSurveyQuestions validates the questions and replaces the survey's questions with them.
+//
+// Each question must have a prompt and a type.  A "choice" question must have at least one choice, and each choice
+// must have a unique code.  If a question is linked to a field, then every value that the question can write
+// (each choice's value) must be valid for that field; the answer to a "text" question is checked when it is given.
+//
+// If any of the questions is invalid, then this returns a body error.
+func setSurveyQuestions(tx *gorm.DB, organizationID uint64, surveyID uint64, questions []*downballotapi.SurveyQuestion) error {
+	var schemaQuestions []*schema.SurveyQuestion
+	var schemaChoices [][]*schema.SurveyChoice
+	for questionIndex, question := range questions {
+		if question == nil {
+			return restfulwrapper.NewAPIBodyError(fmt.Errorf("question %d: missing question", questionIndex+1))
+		}
+		if strings.TrimSpace(question.Prompt) == "" {
+			return restfulwrapper.NewAPIBodyError(fmt.Errorf("question %d: missing prompt", questionIndex+1))
+		}
+
+		schemaQuestion := &schema.SurveyQuestion{
+			SurveyID: surveyID,
+			Position: questionIndex + 1,
+			Prompt:   question.Prompt,
+			Type:     schema.SurveyQuestionType(question.Type),
+		}
+
+		var fieldDefinition *schema.PersonFieldDefinition
+		if question.Field != "" {
+			var err error
+			fieldDefinition, err = findPersonFieldDefinition(tx, organizationID, question.Field)
+			if err != nil {
+				return err
+			}
+			if fieldDefinition == nil {
+				return restfulwrapper.NewAPIBodyError(fmt.Errorf("question %d: unknown field: %s", questionIndex+1, question.Field))
+			}
+			schemaQuestion.PersonFieldDefinitionID = &fieldDefinition.ID
+		}
+
+		var choices []*schema.SurveyChoice
+		switch schemaQuestion.Type {
+		case schema.SurveyQuestionTypeChoice:
+			if len(question.Choices) == 0 {
+				return restfulwrapper.NewAPIBodyError(fmt.Errorf("question %d: missing choices", questionIndex+1))
+			}
+			codeMap := map[string]bool{}
+			for choiceIndex, choice := range question.Choices {
+				if choice == nil {
+					return restfulwrapper.NewAPIBodyError(fmt.Errorf("question %d: choice %d: missing choice", questionIndex+1, choiceIndex+1))
+				}
+				code, err := normalizeCode(choice.Code)
+				if err != nil {
+					return restfulwrapper.NewAPIBodyError(fmt.Errorf("question %d: choice %d: %w", questionIndex+1, choiceIndex+1, err))
+				}
+				if codeMap[code] {
+					return restfulwrapper.NewAPIBodyError(fmt.Errorf("question %d: duplicate code: %s", questionIndex+1, code))
+				}
+				codeMap[code] = true
+
+				label := choice.Label
+				if label == "" {
+					label = code
+				}
+				if fieldDefinition != nil {
+					err = fieldDefinition.Validate(choice.Value)
+					if err != nil {
+						return restfulwrapper.NewAPIBodyError(fmt.Errorf("question %d: choice %d: invalid value for field %s: %w", questionIndex+1, choiceIndex+1, fieldDefinition.Name, err))
+					}
+				}
+				choices = append(choices, &schema.SurveyChoice{
+					Position: choiceIndex + 1,
+					Code:     code,
+					Label:    label,
+					Value:    choice.Value,
+				})
+			}
+		case schema.SurveyQuestionTypeText:
+			if len(question.Choices) > 0 {
+				return restfulwrapper.NewAPIBodyError(fmt.Errorf("question %d: a %q question cannot have choices", questionIndex+1, question.Type))
+			}
+		default:
+			return restfulwrapper.NewAPIBodyError(fmt.Errorf("question %d: unknown type: %q", questionIndex+1, question.Type))
+		}
+
+		schemaQuestions = append(schemaQuestions, schemaQuestion)
+		schemaChoices = append(schemaChoices, choices)
+	}
+
+	questionSubquery := tx.Session(&gorm.Session{NewDB: true}).Model(&schema.SurveyQuestion{}).Select("id").Where("survey_id = ?", surveyID)
+	err := tx.Session(&gorm.Session{NewDB: true}).
+		Where("survey_question_id IN (?)", questionSubquery).
+		Delete(&schema.SurveyChoice{}).
+		Error
+	if err != nil {
+		return fmt.Errorf("could not delete survey choices: %w", err)
+	}
+	err = tx.Session(&gorm.Session{NewDB: true}).
+		Where("survey_id = ?", surveyID).
+		Delete(&schema.SurveyQuestion{}).
+		Error
+	if err != nil {
+		return fmt.Errorf("could not delete survey questions: %w", err)
+	}
+
+	for questionIndex, schemaQuestion := range schemaQuestions {
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			Create(schemaQuestion).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not create survey question: %w", err)
+		}
+		for _, choice := range schemaChoices[questionIndex] {
+			choice.SurveyQuestionID = schemaQuestion.ID
+			err = tx.Session(&gorm.Session{NewDB: true}).
+				Create(choice).
+				Error
+			if err != nil {
+				return fmt.Errorf("could not create survey choice: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// activateSurvey makes the survey the organization's active survey, and deactivates every other survey.
+func activateSurvey(tx *gorm.DB, organizationID uint64, surveyID uint64) error {
+	err := tx.Session(&gorm.Session{NewDB: true}).
+		Model(&schema.Survey{}).
+		Where("organization_id = ?", organizationID).
+		Where("id <> ?", surveyID).
+		Update("active", false).
+		Error
+	if err != nil {
+		return fmt.Errorf("could not deactivate surveys: %w", err)
+	}
+	err = tx.Session(&gorm.Session{NewDB: true}).
+		Model(&schema.Survey{}).
+		Where("id = ?", surveyID).
+		Update("active", true).
+		Error
+	if err != nil {
+		return fmt.Errorf("could not activate survey: %w", err)
+	}
+	return nil
+}
+
+// countSurveyResponses returns the number of responses to the survey.
+func countSurveyResponses(db *gorm.DB, surveyID uint64) (int64, error) {
+	var count int64
+	err := db.Session(&gorm.Session{NewDB: true}).
+		Model(&schema.SurveyResponse{}).
+		Where("survey_id = ?", surveyID).
+		Count(&count).
+		Error
+	if err != nil {
+		return 0, fmt.Errorf("could not count survey responses: %w", err)
+	}
+	return count, nil
+}
+
+// convertSurveyResponses converts the survey responses (with their answers) into their API form.
+//
+// This looks up the voter IDs and the usernames that the responses refer to.
+func convertSurveyResponses(db *gorm.DB, surveyResponses []*schema.SurveyResponse) ([]*downballotapi.SurveyResponse, error) {
+	output := []*downballotapi.SurveyResponse{}
+	if len(surveyResponses) == 0 {
+		return output, nil
+	}
+
+	surveyResponseIDs := make([]uint64, 0, len(surveyResponses))
+	personIDMap := map[uint64]bool{}
+	userIDMap := map[uint64]bool{}
+	for _, surveyResponse := range surveyResponses {
+		surveyResponseIDs = append(surveyResponseIDs, surveyResponse.ID)
+		personIDMap[surveyResponse.PersonID] = true
+		userIDMap[surveyResponse.UserID] = true
+	}
+
+	personIDToVoterIDMap := map[uint64]string{}
+	{
+		var persons []*schema.Person
+		err := db.Session(&gorm.Session{}).
+			Select("id", "voter_id").
+			Where("id IN (?)", slices.Collect(maps.Keys(personIDMap))).
+			Find(&persons).
+			Error
+		if err != nil {
+			return nil, fmt.Errorf("could not find persons: %w", err)
+		}
+		for _, person := range persons {
+			personIDToVoterIDMap[person.ID] = person.VoterID
+		}
+	}
+
+	userIDToUsernameMap := map[uint64]string{}
+	{
+		var users []*schema.User
+		err := db.Session(&gorm.Session{}).
+			Where("id IN (?)", slices.Collect(maps.Keys(userIDMap))).
+			Find(&users).
+			Error
+		if err != nil {
+			return nil, fmt.Errorf("could not find users: %w", err)
+		}
+		for userID := range userIDMap {
+			userIDToUsernameMap[userID] = "user #" + fmt.Sprintf("%d", userID)
+		}
+		for _, user := range users {
+			userIDToUsernameMap[user.ID] = user.Username
+		}
+	}
+
+	surveyResponseIDToAnswersMap := map[uint64][]*downballotapi.SurveyAnswer{}
+	{
+		var answers []*schema.SurveyAnswer
+		err := db.Session(&gorm.Session{}).
+			Where("survey_response_id IN (?)", surveyResponseIDs).
+			Order("id ASC").
+			Find(&answers).
+			Error
+		if err != nil {
+			return nil, fmt.Errorf("could not find survey answers: %w", err)
+		}
+		for _, answer := range answers {
+			surveyResponseIDToAnswersMap[answer.SurveyResponseID] = append(surveyResponseIDToAnswersMap[answer.SurveyResponseID], &downballotapi.SurveyAnswer{
+				QuestionID: fmt.Sprintf("%d", answer.SurveyQuestionID),
+				Value:      answer.Value,
+			})
+		}
+	}
+
+	for _, surveyResponse := range surveyResponses {
+		o := &downballotapi.SurveyResponse{
+			ID:        fmt.Sprintf("%d", surveyResponse.ID),
+			SurveyID:  fmt.Sprintf("%d", surveyResponse.SurveyID),
+			VoterID:   personIDToVoterIDMap[surveyResponse.PersonID],
+			UserID:    fmt.Sprintf("%d", surveyResponse.UserID),
+			Username:  userIDToUsernameMap[surveyResponse.UserID],
+			Timestamp: resttype.DateTime(surveyResponse.Timestamp),
+			Answers:   surveyResponseIDToAnswersMap[surveyResponse.ID],
+		}
+		if surveyResponse.ContactAttemptID != nil {
+			o.ContactAttemptID = fmt.Sprintf("%d", *surveyResponse.ContactAttemptID)
+		}
+		if o.Answers == nil {
+			o.Answers = []*downballotapi.SurveyAnswer{}
+		}
+		output = append(output, o)
+	}
+	return output, nil
+}
+
+// latestSurveyResponseCondition matches a survey response only if it is the most recent one by the person to the survey.
+const latestSurveyResponseCondition = "NOT EXISTS (SELECT 1 FROM survey_response AS newer_response" +
+	" WHERE newer_response.survey_id = survey_response.survey_id" +
+	" AND newer_response.person_id = survey_response.person_id" +
+	" AND (newer_response.timestamp > survey_response.timestamp OR (newer_response.timestamp = survey_response.timestamp AND newer_response.id > survey_response.id)))"
+
+// surveyGroupResult counts the answers to each of the survey's questions by the persons in the group.
+//
+// Only the most recent response by each person is counted.
+func surveyGroupResult(ctx context.Context, db *gorm.DB, userID uint64, organizationID uint64, group *schema.Group, surveyID uint64, questions *surveyQuestions) (*downballotapi.SurveyGroupResult, error) {
+	query, _, _, err := preparePersonQuery(ctx, db, userID, organizationID, &group.ID, nil /*no filter*/)
+	if err != nil {
+		return nil, err
+	}
+	personSubquery := query.Select("person.id")
+
+	output := &downballotapi.SurveyGroupResult{
+		ID:        fmt.Sprintf("%d", group.ID),
+		Name:      group.Name,
+		Questions: []*downballotapi.SurveyQuestionResult{},
+	}
+
+	err = db.Session(&gorm.Session{NewDB: true}).
+		Model(&schema.SurveyResponse{}).
+		Where("survey_response.survey_id = ?", surveyID).
+		Where("survey_response.person_id IN (?)", personSubquery).
+		Where(latestSurveyResponseCondition).
+		Count(&output.Responses).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("could not count survey responses: %w", err)
+	}
+
+	var rows []struct {
+		QuestionID uint64
+		Value      string
+		Count      int64
+	}
+	err = db.Session(&gorm.Session{NewDB: true}).
+		Table("survey_answer").
+		Select("survey_answer.survey_question_id AS question_id, survey_answer.value AS value, COUNT(*) AS count").
+		Joins("INNER JOIN survey_response ON survey_response.id = survey_answer.survey_response_id").
+		Where("survey_response.survey_id = ?", surveyID).
+		Where("survey_response.person_id IN (?)", personSubquery).
+		Where(latestSurveyResponseCondition).
+		Group("survey_answer.survey_question_id, survey_answer.value").
+		Scan(&rows).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("could not count survey answers: %w", err)
+	}
+	questionIDToValueToCountMap := map[uint64]map[string]int64{}
+	for _, row := range rows {
+		if questionIDToValueToCountMap[row.QuestionID] == nil {
+			questionIDToValueToCountMap[row.QuestionID] = map[string]int64{}
+		}
+		questionIDToValueToCountMap[row.QuestionID][row.Value] += row.Count
+	}
+
+	for _, question := range questions.Questions {
+		o := &downballotapi.SurveyQuestionResult{
+			QuestionID: fmt.Sprintf("%d", question.ID),
+			Prompt:     question.Prompt,
+			Choices:    []*downballotapi.SurveyChoiceResult{},
+		}
+		for _, count := range questionIDToValueToCountMap[question.ID] {
+			o.Answers += count
+		}
+		for _, choice := range questions.QuestionIDToChoicesMap[question.ID] {
+			o.Choices = append(o.Choices, &downballotapi.SurveyChoiceResult{
+				Code:  choice.Code,
+				Label: choice.Label,
+				Count: questionIDToValueToCountMap[question.ID][choice.Code],
+			})
+		}
+		output.Questions = append(output.Questions, o)
+	}
+	return output, nil
+}
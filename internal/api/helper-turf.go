@@ -0,0 +1,362 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/database"
+	"github.com/downballot/downballot/internal/filter"
+	"github.com/downballot/downballot/internal/geo"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/tekkamanendless/restfulwrapper"
+	"gorm.io/gorm"
+)
+
+// convertTurf converts a turf into its API form.
+func convertTurf(turf *schema.Turf, memberCount *uint64) *downballotapi.Turf {
+	return &downballotapi.Turf{
+		ID:          fmt.Sprintf("%d", turf.ID),
+		Name:        turf.Name,
+		Description: turf.Description,
+		Geometry:    json.RawMessage(turf.Geometry),
+		Members:     memberCount,
+	}
+}
+
+// setTurfGeometry parses the GeoJSON and sets the turf's geometry and bounding box.
+func setTurfGeometry(turf *schema.Turf, input []byte) error {
+	geometry, err := geo.ParseGeoJSON(input)
+	if err != nil {
+		return err
+	}
+	contents, err := geometry.GeoJSON()
+	if err != nil {
+		return err
+	}
+	bounds := geometry.Bounds()
+
+	turf.Geometry = string(contents)
+	turf.MinLatitude = bounds.MinLatitude
+	turf.MinLongitude = bounds.MinLongitude
+	turf.MaxLatitude = bounds.MaxLatitude
+	turf.MaxLongitude = bounds.MaxLongitude
+	return nil
+}
+
+// createTurfs creates the turfs and finds their members.
+//
+// A turf cannot have the same name as another turf in the organization.
+func createTurfs(tx *gorm.DB, organizationID uint64, turfs []*schema.Turf) error {
+	nameMap := map[string]bool{}
+	var names []string
+	for _, turf := range turfs {
+		if nameMap[strings.ToLower(turf.Name)] {
+			return restfulwrapper.NewAPIBodyError(fmt.Errorf("duplicate name: %s", turf.Name))
+		}
+		nameMap[strings.ToLower(turf.Name)] = true
+		names = append(names, turf.Name)
+	}
+
+	var existingNames []string
+	err := tx.Session(&gorm.Session{NewDB: true}).
+		Model(&schema.Turf{}).
+		Where("organization_id = ?", organizationID).
+		Where("name IN (?)", names).
+		Pluck("name", &existingNames).
+		Error
+	if err != nil {
+		return fmt.Errorf("could not find turfs: %w", err)
+	}
+	if len(existingNames) > 0 {
+		return restfulwrapper.NewAPIBodyError(fmt.Errorf("duplicate name: %s", existingNames[0]))
+	}
+
+	err = tx.Session(&gorm.Session{NewDB: true}).
+		CreateInBatches(&turfs, 2000).
+		Error
+	if err != nil {
+		return fmt.Errorf("could not create turfs: %w", err)
+	}
+	for _, turf := range turfs {
+		err = refreshTurfMembers(tx, turf)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// countTurfMembers returns the number of persons in each of the given turfs.
+func countTurfMembers(db *gorm.DB, turfIDs []uint64) (map[uint64]uint64, error) {
+	output := map[uint64]uint64{}
+	if len(turfIDs) == 0 {
+		return output, nil
+	}
+
+	var rows []struct {
+		TurfID uint64
+		Count  uint64
+	}
+	err := db.Session(&gorm.Session{NewDB: true}).
+		Model(&schema.TurfMember{}).
+		Select("turf_id, COUNT(*) AS count").
+		Where("turf_id IN (?)", turfIDs).
+		Group("turf_id").
+		Scan(&rows).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("could not count turf members: %w", err)
+	}
+	for _, row := range rows {
+		output[row.TurfID] = row.Count
+	}
+	return output, nil
+}
+
+// refreshTurfMembers replaces the members of the turf with every person in the organization whose coordinates are
+// inside of it.
+func refreshTurfMembers(tx *gorm.DB, turf *schema.Turf) error {
+	err := tx.Session(&gorm.Session{NewDB: true}).
+		Where("turf_id = ?", turf.ID).
+		Delete(&schema.TurfMember{}).
+		Error
+	if err != nil {
+		return fmt.Errorf("could not delete turf members: %w", err)
+	}
+
+	geometry, err := geo.ParseGeoJSON([]byte(turf.Geometry))
+	if err != nil {
+		return fmt.Errorf("could not parse turf geometry: %w", err)
+	}
+
+	fieldDefinition, err := findPersonFieldDefinition(tx, turf.OrganizationID, coordinatesField)
+	if err != nil {
+		return err
+	}
+	if fieldDefinition == nil {
+		return nil
+	}
+
+	query := tx.Session(&gorm.Session{NewDB: true}).
+		Where("person_field_definition_id = ?", fieldDefinition.ID)
+	if !fieldDefinition.Encrypted {
+		// Only look at the persons inside of the bounding box; the database can't do anything finer than that.
+		query = query.
+			Where(database.CastFloat(tx, database.TextBefore(tx, "value"))+" BETWEEN ? AND ?", turf.MinLatitude, turf.MaxLatitude).
+			Where(database.CastFloat(tx, database.TextAfter(tx, "value"))+" BETWEEN ? AND ?", turf.MinLongitude, turf.MaxLongitude)
+	}
+
+	var members []*schema.TurfMember
+	var fields []*schema.PersonField
+	err = query.FindInBatches(&fields, 2000, func(batch *gorm.DB, batchNumber int) error {
+		for _, field := range fields {
+			value, err := fieldDefinition.DecodeValue(field.Value)
+			if err != nil {
+				return err
+			}
+			point, err := geo.ParsePoint(value)
+			if err != nil {
+				// A person with bad coordinates can't be placed anywhere.
+				continue
+			}
+			if geometry.Contains(point) {
+				members = append(members, &schema.TurfMember{
+					TurfID:   turf.ID,
+					PersonID: field.PersonID,
+				})
+			}
+		}
+		return nil
+	}).Error
+	if err != nil {
+		return fmt.Errorf("could not find fields: %w", err)
+	}
+
+	if len(members) > 0 {
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			CreateInBatches(&members, 2000).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not create turf members: %w", err)
+		}
+	}
+	return nil
+}
+
+// assignTurfs puts each person into every turf that contains their coordinates (and takes them out of every other).
+//
+// A person whose coordinates are blank or invalid is taken out of all of their turfs.
+func assignTurfs(tx *gorm.DB, organizationID uint64, personIDToCoordinatesMap map[uint64]string) error {
+	if len(personIDToCoordinatesMap) == 0 {
+		return nil
+	}
+	personIDs := slices.Collect(maps.Keys(personIDToCoordinatesMap))
+
+	err := tx.Session(&gorm.Session{NewDB: true}).
+		Where("person_id IN (?)", personIDs).
+		Delete(&schema.TurfMember{}).
+		Error
+	if err != nil {
+		return fmt.Errorf("could not delete turf members: %w", err)
+	}
+
+	personIDToPointMap := map[uint64]geo.Point{}
+	box := geo.Box{
+		MinLatitude:  math.Inf(1),
+		MinLongitude: math.Inf(1),
+		MaxLatitude:  math.Inf(-1),
+		MaxLongitude: math.Inf(-1),
+	}
+	for personID, value := range personIDToCoordinatesMap {
+		point, err := geo.ParsePoint(value)
+		if err != nil {
+			continue
+		}
+		personIDToPointMap[personID] = point
+		box.MinLatitude = math.Min(box.MinLatitude, point.Latitude)
+		box.MinLongitude = math.Min(box.MinLongitude, point.Longitude)
+		box.MaxLatitude = math.Max(box.MaxLatitude, point.Latitude)
+		box.MaxLongitude = math.Max(box.MaxLongitude, point.Longitude)
+	}
+	if len(personIDToPointMap) == 0 {
+		return nil
+	}
+
+	// Only the turfs whose bounding boxes overlap the points could contain any of them.
+	var turfs []*schema.Turf
+	err = tx.Session(&gorm.Session{NewDB: true}).
+		Where("organization_id = ?", organizationID).
+		Where("min_latitude <= ? AND max_latitude >= ?", box.MaxLatitude, box.MinLatitude).
+		Where("min_longitude <= ? AND max_longitude >= ?", box.MaxLongitude, box.MinLongitude).
+		Find(&turfs).
+		Error
+	if err != nil {
+		return fmt.Errorf("could not find turfs: %w", err)
+	}
+
+	var members []*schema.TurfMember
+	for _, turf := range turfs {
+		geometry, err := geo.ParseGeoJSON([]byte(turf.Geometry))
+		if err != nil {
+			return fmt.Errorf("could not parse geometry for turf %d: %w", turf.ID, err)
+		}
+		for personID, point := range personIDToPointMap {
+			if geometry.Contains(point) {
+				members = append(members, &schema.TurfMember{
+					TurfID:   turf.ID,
+					PersonID: personID,
+				})
+			}
+		}
+	}
+	if len(members) > 0 {
+		err = tx.Session(&gorm.Session{NewDB: true}).
+			CreateInBatches(&members, 2000).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not create turf members: %w", err)
+		}
+	}
+	return nil
+}
+
+// buildWithinTurfCondition builds the condition for "within_turf(name)", which matches the persons in the named turf.
+func buildWithinTurfCondition(db *gorm.DB, organizationID uint64, clause *filter.ClauseFunction) (*gorm.DB, error) {
+	if len(clause.Arguments) != 1 {
+		return nil, restfulwrapper.NewAPIResponseError(http.StatusBadRequest, fmt.Sprintf("Function %q takes exactly one argument: the name of the turf", clause.Name))
+	}
+
+	var turfs []*schema.Turf
+	err := db.Session(&gorm.Session{NewDB: true}).
+		Where("organization_id = ?", organizationID).
+		Where("name = ?", clause.Arguments[0]).
+		Find(&turfs).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("could not find turfs: %w", err)
+	}
+	if len(turfs) == 0 {
+		return nil, restfulwrapper.NewAPIResponseError(http.StatusBadRequest, fmt.Sprintf("Unknown turf: %s", clause.Arguments[0]))
+	}
+
+	subquery := db.Session(&gorm.Session{NewDB: true, Initialized: true}).
+		Where("person.id IN (SELECT person_id FROM turf_member WHERE turf_id = ?)", turfs[0].ID)
+	return subquery, nil
+}
+
+// turfDoor is a single door that a canvasser knocks on.
+type turfDoor struct {
+	HouseholdID uint64     // This is 0 if the person is not in a household.
+	Address     string     // This is the household's address, or the person's address if they are not in a household.
+	Point       *geo.Point // This is nil if none of the persons have valid coordinates.
+	PersonIDs   []uint64
+}
+
+// findTurfDoors groups the given persons by their doors, in the order in which each door's first person was given.
+//
+// The persons in a household share a door, which is at the coordinates of the first of them that has any.
+func findTurfDoors(db *gorm.DB, organizationID uint64, personIDs []uint64) ([]*turfDoor, error) {
+	var output []*turfDoor
+	householdIDToDoorMap := map[uint64]*turfDoor{}
+	for start := 0; start < len(personIDs); start += 2000 {
+		batch := personIDs[start:min(start+2000, len(personIDs))]
+
+		personIDToCoordinatesMap, err := personFieldValues(db, organizationID, coordinatesField, batch)
+		if err != nil {
+			return nil, err
+		}
+		personIDToAddressMap, err := personFieldValues(db, organizationID, householdAddressField, batch)
+		if err != nil {
+			return nil, err
+		}
+		personIDToHouseholdIDMap, err := loadHouseholdIDs(db, batch)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, personID := range batch {
+			householdID := personIDToHouseholdIDMap[personID]
+			door := householdIDToDoorMap[householdID]
+			if door == nil || householdID == 0 {
+				door = &turfDoor{
+					HouseholdID: householdID,
+					Address:     personIDToAddressMap[personID],
+				}
+				output = append(output, door)
+				if householdID != 0 {
+					householdIDToDoorMap[householdID] = door
+				}
+			}
+			door.PersonIDs = append(door.PersonIDs, personID)
+			if door.Point == nil {
+				point, err := geo.ParsePoint(personIDToCoordinatesMap[personID])
+				if err == nil {
+					door.Point = &point
+				}
+			}
+		}
+	}
+
+	// A household has its own address, which is the same for all of its members.
+	householdIDs := slices.Collect(maps.Keys(householdIDToDoorMap))
+	for start := 0; start < len(householdIDs); start += 2000 {
+		var households []*schema.Household
+		err := db.Session(&gorm.Session{NewDB: true}).
+			Where("id IN (?)", householdIDs[start:min(start+2000, len(householdIDs))]).
+			Find(&households).
+			Error
+		if err != nil {
+			return nil, fmt.Errorf("could not find households: %w", err)
+		}
+		for _, household := range households {
+			householdIDToDoorMap[household.ID].Address = household.Address
+		}
+	}
+	return output, nil
+}
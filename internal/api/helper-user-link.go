@@ -0,0 +1,168 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/mail"
+	"net/url"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/downballot/downballot/internal/apitoken"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/downballot/downballot/internal/schema/sqltype"
+	"github.com/tekkamanendless/go-mailer"
+	"gorm.io/gorm"
+)
+
+// User link purposes.
+const (
+	userLinkPurposeVerify        = "verify"         // The link verifies the user's e-mail address.
+	userLinkPurposeResetPassword = "reset-password" // The link resets the user's credentials.
+)
+
+// Lifetimes for the user links.
+const (
+	userLinkVerifyLifetime        = 7 * 24 * time.Hour
+	userLinkResetPasswordLifetime = 1 * time.Hour
+)
+
+// errInvalidUserLink is returned when a user link is invalid, expired, or already used.
+var errInvalidUserLink = errors.New("invalid or expired link")
+
+// signToken signs the claims with the active JWT signing key.
+func (a *API) signToken(claims jwt.Claims) (string, error) {
+	return a.keyring.Sign(claims)
+}
+
+// parseToken parses a token that was signed by `signToken`.
+func (a *API) parseToken(tokenString string) (apitoken.TokenClaims, error) {
+	var claims apitoken.TokenClaims
+	err := a.keyring.Parse(tokenString, &claims)
+	if err != nil {
+		return claims, err
+	}
+	return claims, nil
+}
+
+// newUserLink creates a single-use link for the user and returns its token.
+func (a *API) newUserLink(tx *gorm.DB, user *schema.User, purpose string, lifetime time.Duration) (string, error) {
+	nonceBytes := make([]byte, 32)
+	_, err := rand.Read(nonceBytes)
+	if err != nil {
+		return "", fmt.Errorf("could not generate nonce: %w", err)
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(nonceBytes)
+
+	now := time.Now()
+	userLink := schema.UserLink{
+		UserID:              user.ID,
+		Purpose:             purpose,
+		NonceHash:           hashUserLinkNonce(nonce),
+		CreatedTimestamp:    sqltype.DateTime(now),
+		ExpirationTimestamp: sqltype.DateTime(now.Add(lifetime)),
+	}
+	err = tx.Session(&gorm.Session{NewDB: true}).
+		Create(&userLink).
+		Error
+	if err != nil {
+		return "", fmt.Errorf("could not create user link: %w", err)
+	}
+
+	claims := apitoken.TokenClaims{
+		StandardClaims: jwt.StandardClaims{
+			Subject:   user.Username,
+			Id:        nonce,
+			ExpiresAt: now.Add(lifetime).Unix(),
+		},
+		Purpose: purpose,
+	}
+	return a.signToken(claims)
+}
+
+// hashUserLinkNonce returns the hash of a user link's nonce.
+func hashUserLinkNonce(nonce string) string {
+	hash := sha256.Sum256([]byte(nonce))
+	return hex.EncodeToString(hash[:])
+}
+
+// useUserLink uses up a single-use link and returns the user that it was for.
+//
+// This fails with `errInvalidUserLink` if the token is not valid for the purpose.
+func (a *API) useUserLink(tx *gorm.DB, tokenString string, purpose string) (*schema.User, error) {
+	claims, err := a.parseToken(tokenString)
+	if err != nil {
+		return nil, errInvalidUserLink
+	}
+	if claims.Purpose != purpose || claims.Id == "" {
+		return nil, errInvalidUserLink
+	}
+
+	var userLinks []*schema.UserLink
+	err = tx.Session(&gorm.Session{NewDB: true}).
+		Where("nonce_hash = ?", hashUserLinkNonce(claims.Id)).
+		Where("purpose = ?", purpose).
+		Where("used_timestamp IS NULL").
+		Where("expiration_timestamp > ?", sqltype.DateTime(time.Now())).
+		Preload("User").
+		Limit(1).
+		Find(&userLinks).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("could not find user link: %w", err)
+	}
+	if len(userLinks) == 0 || userLinks[0].User == nil || userLinks[0].User.Username != claims.Subject {
+		return nil, errInvalidUserLink
+	}
+	userLink := userLinks[0]
+
+	// Only one request can use the link, even if two of them race.
+	result := tx.Session(&gorm.Session{NewDB: true}).
+		Model(&schema.UserLink{}).
+		Where("id = ?", userLink.ID).
+		Where("used_timestamp IS NULL").
+		Update("used_timestamp", sqltype.DateTime(time.Now()))
+	if result.Error != nil {
+		return nil, fmt.Errorf("could not update user link: %w", result.Error)
+	}
+	if result.RowsAffected != 1 {
+		return nil, errInvalidUserLink
+	}
+
+	return userLink.User, nil
+}
+
+// userLinkURL returns the link to send to the user.
+//
+// If no base URL is configured, then this is just the token.
+func (a *API) userLinkURL(path string, token string) string {
+	if a.baseURL == "" {
+		return token
+	}
+	return a.baseURL + path + "?" + url.Values{"token": []string{token}}.Encode()
+}
+
+// sendEmail sends an e-mail from the system.
+func (a *API) sendEmail(ctx context.Context, to string, subject string, body string) error {
+	if a.mailer == nil {
+		slog.WarnContext(ctx, "No mailer configured; could not send e-mail.")
+		return nil
+	}
+	return a.mailer.SendMail(ctx, mailer.Message{
+		From: mail.Address{
+			Name:    "Downballot",
+			Address: "noreply@app.downballot.io",
+		},
+		To: mail.Address{
+			Address: to,
+		},
+		Subject:       subject,
+		BodyPlainText: body,
+	})
+}
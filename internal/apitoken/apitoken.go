@@ -13,6 +13,8 @@ type TokenClaims struct {
 
 	// Custom claims go here.
 	SessionIdentifier uint64 `json:"session_identifier"`
+	Purpose           string `json:"purpose,omitempty"` // If set, then the token is only good for this purpose (such as verifying an e-mail address), not for authentication.
+	Binding           string `json:"binding,omitempty"` // If set, then the token is only good for the client that knows the value that this is the hash of.
 }
 
 // Valid returns an error of the claims are invalid (or nil otherwise).
@@ -0,0 +1,316 @@
+package apitoken
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// LegacyKeyID is the ID of the key for tokens that do not have a key ID.
+//
+// Tokens that were issued before key IDs were introduced don't have one, so they are checked against this key.
+const LegacyKeyID = "default"
+
+// ErrNoSigningKey is returned when a token needs to be signed but there is no signing key.
+var ErrNoSigningKey = errors.New("no signing key")
+
+// Keyring is the set of keys for signing and verifying tokens.
+//
+// Each key has an ID, which is put in the "kid" header of every token that it signs.  Only one key signs new tokens;
+// the others are kept so that the tokens that they signed keep working until they expire, which is what allows the
+// signing key to be rotated without logging everyone out.
+type Keyring struct {
+	allowUnsigned bool            // If set, then unsigned tokens are used when there are no keys at all.  This is only for development.
+	keys          map[string]*key // These are the keys by ID.
+	signingKey    *key            // This is the key that signs new tokens, if any.
+}
+
+// key is a single key in the keyring.
+type key struct {
+	id              string
+	method          jwt.SigningMethod
+	signingKey      any // This is nil if the key can only verify tokens.
+	verificationKey any
+}
+
+// NewKeyring returns a new, empty keyring.
+//
+// If `allowUnsigned` is set and no keys are added, then tokens will be neither signed nor verified.  This must never be
+// used outside of development.
+func NewKeyring(allowUnsigned bool) *Keyring {
+	return &Keyring{
+		allowUnsigned: allowUnsigned,
+		keys:          map[string]*key{},
+	}
+}
+
+// AddSecret adds a shared secret (HMAC) key.
+func (k *Keyring) AddSecret(id string, secret []byte) error {
+	if len(secret) == 0 {
+		return fmt.Errorf("key %q: empty secret", id)
+	}
+	return k.add(&key{
+		id:              id,
+		method:          jwt.SigningMethodHS512,
+		signingKey:      secret,
+		verificationKey: secret,
+	})
+}
+
+// AddPrivateKeyPEM adds an RSA, ECDSA, or Ed25519 private key, which can both sign and verify tokens.
+func (k *Keyring) AddPrivateKeyPEM(id string, contents string) error {
+	block, _ := pem.Decode([]byte(contents))
+	if block == nil {
+		return fmt.Errorf("key %q: could not decode PEM", id)
+	}
+
+	var privateKey any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		privateKey, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		privateKey, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		privateKey, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return fmt.Errorf("key %q: could not parse private key: %w", id, err)
+	}
+
+	signer, ok := privateKey.(crypto.Signer)
+	if !ok {
+		return fmt.Errorf("key %q: unsupported private key type %T", id, privateKey)
+	}
+	method, err := signingMethodForPublicKey(signer.Public())
+	if err != nil {
+		return fmt.Errorf("key %q: %w", id, err)
+	}
+	return k.add(&key{
+		id:              id,
+		method:          method,
+		signingKey:      privateKey,
+		verificationKey: signer.Public(),
+	})
+}
+
+// AddPublicKeyPEM adds an RSA, ECDSA, or Ed25519 public key, which can only verify tokens.
+func (k *Keyring) AddPublicKeyPEM(id string, contents string) error {
+	block, _ := pem.Decode([]byte(contents))
+	if block == nil {
+		return fmt.Errorf("key %q: could not decode PEM", id)
+	}
+
+	var publicKey any
+	var err error
+	switch block.Type {
+	case "RSA PUBLIC KEY":
+		publicKey, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		publicKey, err = x509.ParsePKIXPublicKey(block.Bytes)
+	}
+	if err != nil {
+		return fmt.Errorf("key %q: could not parse public key: %w", id, err)
+	}
+
+	method, err := signingMethodForPublicKey(publicKey)
+	if err != nil {
+		return fmt.Errorf("key %q: %w", id, err)
+	}
+	return k.add(&key{
+		id:              id,
+		method:          method,
+		verificationKey: publicKey,
+	})
+}
+
+// add adds a key to the keyring.
+func (k *Keyring) add(newKey *key) error {
+	if newKey.id == "" {
+		return fmt.Errorf("missing key ID")
+	}
+	if _, ok := k.keys[newKey.id]; ok {
+		return fmt.Errorf("key %q: duplicate key ID", newKey.id)
+	}
+	k.keys[newKey.id] = newKey
+	return nil
+}
+
+// SetSigningKey sets the key that signs new tokens.
+func (k *Keyring) SetSigningKey(id string) error {
+	signingKey, ok := k.keys[id]
+	if !ok {
+		return fmt.Errorf("key %q: unknown key ID", id)
+	}
+	if signingKey.signingKey == nil {
+		return fmt.Errorf("key %q: key cannot sign tokens", id)
+	}
+	k.signingKey = signingKey
+	return nil
+}
+
+// SigningKeyID returns the ID of the key that signs new tokens, if any.
+func (k *Keyring) SigningKeyID() string {
+	if k.signingKey == nil {
+		return ""
+	}
+	return k.signingKey.id
+}
+
+// KeyIDs returns the IDs of all of the keys.
+func (k *Keyring) KeyIDs() []string {
+	var output []string
+	for id := range k.keys {
+		output = append(output, id)
+	}
+	slices.Sort(output)
+	return output
+}
+
+// CanSign returns true if the keyring can sign a token, even if that means not signing it at all (in development).
+func (k *Keyring) CanSign() bool {
+	return k.signingKey != nil || k.unsigned()
+}
+
+// unsigned returns true if tokens are neither signed nor verified.
+func (k *Keyring) unsigned() bool {
+	return k.allowUnsigned && len(k.keys) == 0
+}
+
+// Sign signs the claims with the signing key.
+func (k *Keyring) Sign(claims jwt.Claims) (string, error) {
+	if k.signingKey == nil {
+		if k.unsigned() {
+			return jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+		}
+		return "", ErrNoSigningKey
+	}
+
+	token := jwt.NewWithClaims(k.signingKey.method, claims)
+	token.Header["kid"] = k.signingKey.id
+	return token.SignedString(k.signingKey.signingKey)
+}
+
+// Parse parses and verifies a token, filling in the claims.
+//
+// The token is verified with the key named by its "kid" header (or the legacy key if it doesn't have one).
+func (k *Keyring) Parse(tokenString string, claims jwt.Claims) error {
+	_, err := jwt.ParseWithClaims(tokenString, claims,
+		func(t *jwt.Token) (any, error) {
+			if t.Method == jwt.SigningMethodNone {
+				if k.unsigned() {
+					return jwt.UnsafeAllowNoneSignatureType, nil
+				}
+				return nil, fmt.Errorf("unsigned tokens are not allowed")
+			}
+
+			keyID := LegacyKeyID
+			if value, ok := t.Header["kid"]; ok {
+				keyID, ok = value.(string)
+				if !ok {
+					return nil, fmt.Errorf("invalid key ID")
+				}
+			}
+			verificationKey, ok := k.keys[keyID]
+			if !ok {
+				return nil, fmt.Errorf("unknown key ID: %q", keyID)
+			}
+			// Never let the token choose the algorithm; otherwise, a public key could be used as an HMAC secret.
+			if t.Method.Alg() != verificationKey.method.Alg() {
+				return nil, fmt.Errorf("key %q: unexpected signing method %q", keyID, t.Method.Alg())
+			}
+			return verificationKey.verificationKey, nil
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("could not parse token: %v", err)
+	}
+	return nil
+}
+
+// JSONWebKeySet is a JSON Web Key Set (RFC 7517).
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// JSONWebKey is a public key in a JSON Web Key Set.
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	N         string `json:"n,omitempty"`   // RSA modulus.
+	E         string `json:"e,omitempty"`   // RSA exponent.
+	Curve     string `json:"crv,omitempty"` // ECDSA or EdDSA curve.
+	X         string `json:"x,omitempty"`   // ECDSA x coordinate or EdDSA public key.
+	Y         string `json:"y,omitempty"`   // ECDSA y coordinate.
+}
+
+// JWKS returns the public keys as a JSON Web Key Set.
+//
+// Shared secrets are never published.
+func (k *Keyring) JWKS() JSONWebKeySet {
+	output := JSONWebKeySet{
+		Keys: []JSONWebKey{},
+	}
+	for _, id := range k.KeyIDs() {
+		currentKey := k.keys[id]
+		jsonWebKey := JSONWebKey{
+			Use:       "sig",
+			Algorithm: currentKey.method.Alg(),
+			KeyID:     currentKey.id,
+		}
+		switch publicKey := currentKey.verificationKey.(type) {
+		case *rsa.PublicKey:
+			jsonWebKey.KeyType = "RSA"
+			jsonWebKey.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jsonWebKey.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case *ecdsa.PublicKey:
+			size := (publicKey.Curve.Params().BitSize + 7) / 8
+			jsonWebKey.KeyType = "EC"
+			jsonWebKey.Curve = publicKey.Curve.Params().Name
+			jsonWebKey.X = base64.RawURLEncoding.EncodeToString(publicKey.X.FillBytes(make([]byte, size)))
+			jsonWebKey.Y = base64.RawURLEncoding.EncodeToString(publicKey.Y.FillBytes(make([]byte, size)))
+		case ed25519.PublicKey:
+			jsonWebKey.KeyType = "OKP"
+			jsonWebKey.Curve = "Ed25519"
+			jsonWebKey.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		default:
+			continue
+		}
+		output.Keys = append(output.Keys, jsonWebKey)
+	}
+	return output
+}
+
+// signingMethodForPublicKey returns the signing method to use with a public key.
+func signingMethodForPublicKey(publicKey any) (jwt.SigningMethod, error) {
+	switch publicKey := publicKey.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case *ecdsa.PublicKey:
+		switch publicKey.Curve {
+		case elliptic.P256():
+			return jwt.SigningMethodES256, nil
+		case elliptic.P384():
+			return jwt.SigningMethodES384, nil
+		case elliptic.P521():
+			return jwt.SigningMethodES512, nil
+		}
+		return nil, fmt.Errorf("unsupported curve: %s", publicKey.Curve.Params().Name)
+	case ed25519.PublicKey:
+		return SigningMethodEdDSA, nil
+	}
+	return nil, fmt.Errorf("unsupported public key type %T", publicKey)
+}
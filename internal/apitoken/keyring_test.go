@@ -0,0 +1,164 @@
+package apitoken
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"testing"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// encodePrivateKey encodes a private key as PEM.
+func encodePrivateKey(t *testing.T, privateKey crypto.Signer) (string, string) {
+	privateBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	require.NoError(t, err)
+	publicBytes, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	require.NoError(t, err)
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateBytes})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicBytes}))
+}
+
+func TestKeyring(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err)
+	_, ed25519Key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	rows := []struct {
+		description string
+		privateKey  crypto.Signer
+		algorithm   string
+		keyType     string
+	}{
+		{
+			description: "RSA",
+			privateKey:  rsaKey,
+			algorithm:   "RS256",
+			keyType:     "RSA",
+		},
+		{
+			description: "ECDSA",
+			privateKey:  ecdsaKey,
+			algorithm:   "ES384",
+			keyType:     "EC",
+		},
+		{
+			description: "Ed25519",
+			privateKey:  ed25519Key,
+			algorithm:   "EdDSA",
+			keyType:     "OKP",
+		},
+	}
+	for rowIndex, row := range rows {
+		t.Run(fmt.Sprintf("%d/%s", rowIndex, row.description), func(t *testing.T) {
+			privatePEM, publicPEM := encodePrivateKey(t, row.privateKey)
+
+			signer := NewKeyring(false)
+			require.NoError(t, signer.AddPrivateKeyPEM("key", privatePEM))
+			require.NoError(t, signer.SetSigningKey("key"))
+			token, err := signer.Sign(TokenClaims{StandardClaims: jwt.StandardClaims{Subject: "user"}})
+			require.NoError(t, err)
+
+			// Another service only needs the public key to verify the token.
+			verifier := NewKeyring(false)
+			require.NoError(t, verifier.AddPublicKeyPEM("key", publicPEM))
+			assert.False(t, verifier.CanSign())
+			var claims TokenClaims
+			require.NoError(t, verifier.Parse(token, &claims))
+			assert.Equal(t, "user", claims.Subject)
+
+			jwks := signer.JWKS()
+			require.Len(t, jwks.Keys, 1)
+			assert.Equal(t, "key", jwks.Keys[0].KeyID)
+			assert.Equal(t, row.algorithm, jwks.Keys[0].Algorithm)
+			assert.Equal(t, row.keyType, jwks.Keys[0].KeyType)
+		})
+	}
+}
+
+func TestKeyringRotation(t *testing.T) {
+	keyring := NewKeyring(false)
+	require.NoError(t, keyring.AddSecret("old", []byte("old-secret")))
+	require.NoError(t, keyring.SetSigningKey("old"))
+	oldToken, err := keyring.Sign(TokenClaims{StandardClaims: jwt.StandardClaims{Subject: "old"}})
+	require.NoError(t, err)
+
+	require.NoError(t, keyring.AddSecret("new", []byte("new-secret")))
+	require.NoError(t, keyring.SetSigningKey("new"))
+	newToken, err := keyring.Sign(TokenClaims{StandardClaims: jwt.StandardClaims{Subject: "new"}})
+	require.NoError(t, err)
+
+	var claims TokenClaims
+	require.NoError(t, keyring.Parse(oldToken, &claims))
+	assert.Equal(t, "old", claims.Subject)
+	require.NoError(t, keyring.Parse(newToken, &claims))
+	assert.Equal(t, "new", claims.Subject)
+
+	// Once the old key is gone, its tokens stop working.
+	retired := NewKeyring(false)
+	require.NoError(t, retired.AddSecret("new", []byte("new-secret")))
+	assert.Error(t, retired.Parse(oldToken, &claims))
+	assert.NoError(t, retired.Parse(newToken, &claims))
+
+	// Secrets are never published.
+	assert.Empty(t, keyring.JWKS().Keys)
+}
+
+func TestKeyringLegacyToken(t *testing.T) {
+	// Tokens from before key IDs were introduced have no "kid" header.
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS512, TokenClaims{StandardClaims: jwt.StandardClaims{Subject: "legacy"}}).SignedString([]byte("secret"))
+	require.NoError(t, err)
+
+	keyring := NewKeyring(false)
+	require.NoError(t, keyring.AddSecret(LegacyKeyID, []byte("secret")))
+	var claims TokenClaims
+	require.NoError(t, keyring.Parse(token, &claims))
+	assert.Equal(t, "legacy", claims.Subject)
+}
+
+func TestKeyringUnsigned(t *testing.T) {
+	claims := TokenClaims{StandardClaims: jwt.StandardClaims{Subject: "user"}}
+
+	production := NewKeyring(false)
+	assert.False(t, production.CanSign())
+	_, err := production.Sign(claims)
+	assert.ErrorIs(t, err, ErrNoSigningKey)
+
+	development := NewKeyring(true)
+	assert.True(t, development.CanSign())
+	token, err := development.Sign(claims)
+	require.NoError(t, err)
+	assert.NoError(t, development.Parse(token, &TokenClaims{}))
+	assert.Error(t, production.Parse(token, &TokenClaims{}))
+
+	// Once there is a key, unsigned tokens are refused even in development.
+	require.NoError(t, development.AddSecret("key", []byte("secret")))
+	assert.Error(t, development.Parse(token, &TokenClaims{}))
+}
+
+func TestKeyringAlgorithmConfusion(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, publicPEM := encodePrivateKey(t, rsaKey)
+
+	keyring := NewKeyring(false)
+	require.NoError(t, keyring.AddPublicKeyPEM("key", publicPEM))
+
+	// Signing with the public key as an HMAC secret must not work.
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, TokenClaims{StandardClaims: jwt.StandardClaims{Subject: "user"}})
+	token.Header["kid"] = "key"
+	tokenString, err := token.SignedString([]byte(publicPEM))
+	require.NoError(t, err)
+	assert.Error(t, keyring.Parse(tokenString, &TokenClaims{}))
+}
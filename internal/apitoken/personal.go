@@ -0,0 +1,37 @@
+package apitoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// PersonalTokenPrefix is the prefix of every personal API token.
+//
+// This is how a personal API token is told apart from a JWT, and it makes the tokens easy to spot if they leak.
+const PersonalTokenPrefix = "dbt_"
+
+// NewPersonalToken generates a new personal API token.
+func NewPersonalToken() (string, error) {
+	tokenBytes := make([]byte, 32)
+	_, err := rand.Read(tokenBytes)
+	if err != nil {
+		return "", fmt.Errorf("could not generate token: %w", err)
+	}
+	return PersonalTokenPrefix + base64.RawURLEncoding.EncodeToString(tokenBytes), nil
+}
+
+// IsPersonalToken returns true if the token looks like a personal API token.
+func IsPersonalToken(token string) bool {
+	return strings.HasPrefix(token, PersonalTokenPrefix)
+}
+
+// HashPersonalToken returns the hash of a personal API token.
+// Only the hash is ever stored.
+func HashPersonalToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
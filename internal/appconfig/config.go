@@ -1,5 +1,7 @@
 package appconfig
 
+import "github.com/downballot/downballot/internal/schema/sqltype"
+
 // Config is the configuration for the API.
 type Config struct {
 	DatabaseDriver string `json:"database_driver"`
@@ -9,7 +11,59 @@ type Config struct {
 	JWTPublicKey  string `json:"jwt_public_key"`
 	JWTPrivateKey string `json:"jwt_private_key"`
 
+	JWTKeys         []JWTKey `json:"jwt_keys"`
+	JWTSigningKeyID string   `json:"jwt_signing_key_id"`
+	Development     bool     `json:"development"`
+
 	MasterToken    string `json:"master_token"`
 	EncryptionKey  string `json:"encryption_key"`
 	SendGridAPIKey string `json:"sendgrid_api_key"`
+	BaseURL        string `json:"base_url"`
+
+	EncryptionKeys  []EncryptionKey `json:"encryption_keys"`
+	EncryptionKeyID string          `json:"encryption_key_id"`
+	BlindIndexKey   string          `json:"blind_index_key"` // This is the key for finding encrypted person fields; it can never be changed.
+
+	OIDCIssuer       string `json:"oidc_issuer"`
+	OIDCClientID     string `json:"oidc_client_id"`
+	OIDCClientSecret string `json:"oidc_client_secret"`
+}
+
+// JWTKey is a key for signing and verifying tokens.
+type JWTKey struct {
+	ID         string `json:"id"`
+	Secret     string `json:"secret"`
+	PrivateKey string `json:"private_key"`
+	PublicKey  string `json:"public_key"`
+}
+
+// EncryptionKey is a key for encrypting and decrypting database values.
+type EncryptionKey struct {
+	ID  string `json:"id"`
+	Key string `json:"key"`
+}
+
+// SetEncryptionKeys installs the encryption keys (and the blind-index key).
+//
+// The legacy encryption key has the key ID "default".
+func (c Config) SetEncryptionKeys() error {
+	err := sqltype.SetBlindIndexKey(c.BlindIndexKey)
+	if err != nil {
+		return err
+	}
+
+	var keys []sqltype.EncryptionKey
+	if c.EncryptionKey != "" {
+		keys = append(keys, sqltype.EncryptionKey{
+			ID:  sqltype.LegacyEncryptionKeyID,
+			Key: c.EncryptionKey,
+		})
+	}
+	for _, key := range c.EncryptionKeys {
+		keys = append(keys, sqltype.EncryptionKey{
+			ID:  key.ID,
+			Key: key.Key,
+		})
+	}
+	return sqltype.SetEncryptionKeys(keys, c.EncryptionKeyID)
 }
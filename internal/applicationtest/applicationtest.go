@@ -2,6 +2,11 @@ package applicationtest
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -10,6 +15,7 @@ import (
 	"github.com/downballot/downballot/internal/api"
 	"github.com/downballot/downballot/internal/application"
 	"github.com/downballot/downballot/internal/databasetest"
+	"github.com/downballot/downballot/internal/oidctest"
 	"github.com/stretchr/testify/require"
 	"github.com/tekkamanendless/go-mailer"
 	"github.com/tekkamanendless/restapiclient"
@@ -21,7 +27,8 @@ type Server struct {
 	application *application.App
 	db          *gorm.DB
 	httpServer  *httptest.Server
-	config      api.Config // This is the configuration for the API.
+	oidcIssuer  *oidctest.Issuer // This is the stand-in OpenID Connect identity provider.
+	config      api.Config       // This is the configuration for the API.
 }
 
 // ContextHandler is a handler that adds modifies the context.Context of the request.
@@ -51,9 +58,39 @@ func New(t *testing.T, ctx context.Context) *Server {
 
 	app := application.New(ctx, db)
 
+	oidcIssuer, err := oidctest.New("my-oidc-client-id", "my-oidc-client-secret")
+	require.NoError(t, err)
+
+	// Sign tokens with an ECDSA key, but keep an old secret around as if the key had just been rotated.
+	var jwtPrivateKey string
+	{
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		contents, err := x509.MarshalPKCS8PrivateKey(privateKey)
+		require.NoError(t, err)
+		jwtPrivateKey = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: contents}))
+	}
+
 	apiConfig := api.Config{
+		JWTKeys: []api.JWTKey{
+			{
+				ID:     "retired",
+				Secret: "my-retired-jwt-secret",
+			},
+			{
+				ID:         "active",
+				PrivateKey: jwtPrivateKey,
+			},
+		},
+		JWTSigningKeyID: "active",
+
 		MasterToken:    "my-master-token",
 		SendGridAPIKey: "my-sendgrid-api-key",
+		BaseURL:        "https://app.example.com",
+
+		OIDCIssuer:       oidcIssuer.URL(),
+		OIDCClientID:     oidcIssuer.ClientID,
+		OIDCClientSecret: oidcIssuer.ClientSecret,
 	}
 
 	myHandler := http.NewServeMux()
@@ -64,6 +101,7 @@ func New(t *testing.T, ctx context.Context) *Server {
 
 		apiContainer := apiInstance.Container(ctx)
 		myHandler.Handle("/api/", apiContainer)
+		myHandler.Handle("/.well-known/", apiContainer)
 	}
 	contextHandler := NewContextHandler(myHandler, func(ctx context.Context) context.Context {
 		return mailer.WithDummyMode(ctx)
@@ -74,6 +112,7 @@ func New(t *testing.T, ctx context.Context) *Server {
 		application: app,
 		db:          db,
 		httpServer:  httpServer,
+		oidcIssuer:  oidcIssuer,
 		config:      apiConfig,
 	}
 	return s
@@ -82,6 +121,7 @@ func New(t *testing.T, ctx context.Context) *Server {
 // Close down the server.
 func (s *Server) Close() {
 	s.httpServer.Close()
+	s.oidcIssuer.Close()
 }
 
 // DB returns a database handle.
@@ -94,6 +134,11 @@ func (s *Server) URL() string {
 	return s.httpServer.URL
 }
 
+// OIDCIssuer returns the stand-in OpenID Connect identity provider.
+func (s *Server) OIDCIssuer() *oidctest.Issuer {
+	return s.oidcIssuer
+}
+
 // Config returns the configuration for the API.
 func (s *Server) Config() api.Config {
 	return s.config
@@ -9,10 +9,14 @@ import (
 	"github.com/tekkamanendless/gormslog"
 	_ "github.com/tekkamanendless/sqlite"
 	"github.com/tekkamanendless/sqlite/driver/gorm/sqlite"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
 // New creates a new database connection.
+//
+// The driver is one of "sqlite3", "postgres", or "mysql".
 func New(ctx context.Context, driverName string, connectionString string) (*gorm.DB, error) {
 	var db *gorm.DB
 	var err error
@@ -28,6 +32,31 @@ func New(ctx context.Context, driverName string, connectionString string) (*gorm
 		if err != nil {
 			return nil, err
 		}
+	case "postgres":
+		// PostgreSQL's case-insensitive text type is an extension.
+		db, err = gorm.Open(caseInsensitiveDialector{
+			Dialector: postgres.Open(connectionString),
+			translate: func(dataType string) string {
+				return "citext"
+			},
+		}, config)
+		if err != nil {
+			return nil, err
+		}
+		err = db.Exec("CREATE EXTENSION IF NOT EXISTS citext").Error
+		if err != nil {
+			return nil, fmt.Errorf("could not create the citext extension: %w", err)
+		}
+	case "mysql":
+		db, err = gorm.Open(caseInsensitiveDialector{
+			Dialector: mysql.Open(connectionString),
+			translate: func(dataType string) string {
+				return dataType + " CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci"
+			},
+		}, config)
+		if err != nil {
+			return nil, err
+		}
 	default:
 		return nil, fmt.Errorf("invalid database driver: %s", driverName)
 	}
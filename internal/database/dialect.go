@@ -0,0 +1,149 @@
+package database
+
+import (
+	"regexp"
+	"strconv"
+
+	"github.com/downballot/downballot/internal/geo"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
+)
+
+// caseInsensitiveTypeRegexp matches the case-insensitive column types in the schema.
+//
+// The schema was written for SQLite, where "collate nocase" makes a column case-insensitive.
+var caseInsensitiveTypeRegexp = regexp.MustCompile(`(?i)^\s*(varchar\(\d+\)|text)\s+collate\s+nocase\s*$`)
+
+// caseInsensitiveDialector translates the case-insensitive column types in the schema into the
+// dialect's own.
+//
+// Everything else is left to the wrapped dialector.
+type caseInsensitiveDialector struct {
+	gorm.Dialector
+	translate func(dataType string) string // This translates a case-insensitive type (such as "varchar(256)") for the dialect.
+}
+
+// DataTypeOf returns the column type for a field.
+func (d caseInsensitiveDialector) DataTypeOf(field *schema.Field) string {
+	dataType := d.Dialector.DataTypeOf(field)
+	if matches := caseInsensitiveTypeRegexp.FindStringSubmatch(dataType); matches != nil {
+		return d.translate(matches[1])
+	}
+	return dataType
+}
+
+// Migrator returns the wrapped dialector's migrator, but with this dialector's column types.
+func (d caseInsensitiveDialector) Migrator(db *gorm.DB) gorm.Migrator {
+	switch dialector := d.Dialector.(type) {
+	case *mysql.Dialector:
+		return mysql.Migrator{
+			Migrator: migrator.Migrator{
+				Config: migrator.Config{
+					DB:        db,
+					Dialector: d,
+				},
+			},
+			Dialector: *dialector,
+		}
+	case *postgres.Dialector:
+		return postgres.Migrator{
+			Migrator: migrator.Migrator{
+				Config: migrator.Config{
+					DB:                          db,
+					Dialector:                   d,
+					CreateIndexAfterCreateTable: true,
+				},
+			},
+		}
+	}
+	return d.Dialector.Migrator(db)
+}
+
+// Translate translates the wrapped dialector's errors into the Gorm built-in ones.
+func (d caseInsensitiveDialector) Translate(err error) error {
+	if translator, ok := d.Dialector.(gorm.ErrorTranslator); ok {
+		return translator.Translate(err)
+	}
+	return err
+}
+
+// SavePoint creates a save point with the wrapped dialector.
+func (d caseInsensitiveDialector) SavePoint(tx *gorm.DB, name string) error {
+	if savePointer, ok := d.Dialector.(gorm.SavePointerDialectorInterface); ok {
+		return savePointer.SavePoint(tx, name)
+	}
+	return gorm.ErrUnsupportedDriver
+}
+
+// RollbackTo rolls back to a save point with the wrapped dialector.
+func (d caseInsensitiveDialector) RollbackTo(tx *gorm.DB, name string) error {
+	if savePointer, ok := d.Dialector.(gorm.SavePointerDialectorInterface); ok {
+		return savePointer.RollbackTo(tx, name)
+	}
+	return gorm.ErrUnsupportedDriver
+}
+
+// CastInteger returns an expression that converts a text expression into an integer.
+func CastInteger(db *gorm.DB, expression string) string {
+	switch db.Dialector.Name() {
+	case "mysql":
+		return "CAST(" + expression + " AS SIGNED)"
+	}
+	return "CAST(" + expression + " AS INTEGER)"
+}
+
+// CastFloat returns an expression that converts a text expression into a floating-point number.
+func CastFloat(db *gorm.DB, expression string) string {
+	switch db.Dialector.Name() {
+	case "mysql":
+		return "CAST(" + expression + " AS DOUBLE)"
+	case "postgres":
+		return "CAST(" + expression + " AS DOUBLE PRECISION)"
+	}
+	return "CAST(" + expression + " AS REAL)"
+}
+
+// TextBefore returns an expression for the part of a text expression before the first comma.
+func TextBefore(db *gorm.DB, expression string) string {
+	switch db.Dialector.Name() {
+	case "mysql":
+		return "SUBSTRING_INDEX(" + expression + ", ',', 1)"
+	case "postgres":
+		return "SPLIT_PART(" + expression + ", ',', 1)"
+	}
+	return "SUBSTR(" + expression + ", 1, INSTR(" + expression + ", ',') - 1)"
+}
+
+// TextAfter returns an expression for the part of a text expression after the first comma.
+func TextAfter(db *gorm.DB, expression string) string {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return "SUBSTR(" + expression + ", STRPOS(" + expression + ", ',') + 1)"
+	}
+	return "SUBSTR(" + expression + ", INSTR(" + expression + ", ',') + 1)"
+}
+
+// Distance returns an expression for the great-circle (haversine) distance, in meters, between a point and the given
+// coordinates.
+//
+// The latitude and longitude expressions are in degrees.
+func Distance(db *gorm.DB, latitudeExpression string, longitudeExpression string, latitude float64, longitude float64) string {
+	latitudeString := strconv.FormatFloat(latitude, 'f', -1, 64)
+	longitudeString := strconv.FormatFloat(longitude, 'f', -1, 64)
+
+	// This is sin²(Δφ/2) + cos(φ1)·cos(φ2)·sin²(Δλ/2).
+	sinLatitude := "SIN(RADIANS(" + latitudeExpression + " - " + latitudeString + ") / 2)"
+	sinLongitude := "SIN(RADIANS(" + longitudeExpression + " - " + longitudeString + ") / 2)"
+	h := sinLatitude + " * " + sinLatitude + " + COS(RADIANS(" + latitudeExpression + ")) * COS(RADIANS(" + latitudeString + ")) * " + sinLongitude + " * " + sinLongitude
+
+	// Rounding can push the value just past 1, which is out of range for ASIN.
+	least := "LEAST"
+	switch db.Dialector.Name() {
+	case "sqlite":
+		least = "MIN"
+	}
+	return "(" + strconv.FormatFloat(2*geo.EarthRadius, 'f', -1, 64) + " * ASIN(SQRT(" + least + "(1, " + h + "))))"
+}
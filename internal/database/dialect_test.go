@@ -0,0 +1,103 @@
+package database
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+func TestCaseInsensitiveDialector(t *testing.T) {
+	rows := []struct {
+		name      string
+		dialector gorm.Dialector
+		username  string // This is the expected type of the (case-insensitive) username.
+		secret    string // This is the expected type of the (encrypted) TOTP secret.
+	}{
+		{
+			name: "postgres",
+			dialector: caseInsensitiveDialector{
+				Dialector: postgres.New(postgres.Config{DSN: "host=localhost"}),
+				translate: func(dataType string) string {
+					return "citext"
+				},
+			},
+			username: "citext",
+			secret:   "BYTEA NOT NULL",
+		},
+		{
+			name: "mysql",
+			dialector: caseInsensitiveDialector{
+				Dialector: mysql.New(mysql.Config{DSN: "user@tcp(localhost)/downballot", SkipInitializeWithVersion: true}),
+				translate: func(dataType string) string {
+					return dataType + " CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci"
+				},
+			},
+			username: "varchar(256) CHARACTER SET utf8mb4 COLLATE utf8mb4_unicode_ci",
+			secret:   "BLOB NOT NULL",
+		},
+	}
+	for _, row := range rows {
+		t.Run(row.name, func(t *testing.T) {
+			db, err := gorm.Open(row.dialector, &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+			require.NoError(t, err)
+			assert.Equal(t, row.name, db.Dialector.Name())
+
+			fullDataTypeOf := func(model any, fieldName string) string {
+				statement := &gorm.Statement{DB: db}
+				require.NoError(t, statement.Parse(model))
+				field := statement.Schema.LookUpField(fieldName)
+				require.NotNil(t, field)
+				return db.Migrator().FullDataTypeOf(field).SQL
+			}
+			assert.Equal(t, row.username, fullDataTypeOf(&schema.User{}, "username"))
+			assert.Equal(t, row.secret, fullDataTypeOf(&schema.UserTOTP{}, "secret"))
+		})
+	}
+}
+
+func TestCoordinateExpressions(t *testing.T) {
+	db, err := New(t.Context(), "sqlite3", "file::memory:")
+	require.NoError(t, err)
+
+	var output struct {
+		Latitude  float64
+		Longitude float64
+	}
+	column := "'40.7128,-74.006'"
+	err = db.Raw("SELECT " + CastFloat(db, TextBefore(db, column)) + " AS latitude, " + CastFloat(db, TextAfter(db, column)) + " AS longitude").Scan(&output).Error
+	require.NoError(t, err)
+	assert.Equal(t, 40.7128, output.Latitude)
+	assert.Equal(t, -74.006, output.Longitude)
+}
+
+func TestDistance(t *testing.T) {
+	db, err := New(t.Context(), "sqlite3", "file::memory:")
+	require.NoError(t, err)
+
+	rows := []struct {
+		description string
+		column      string
+		latitude    float64
+		longitude   float64
+		output      float64
+	}{
+		{"Same point", "'40.7128,-74.006'", 40.7128, -74.006, 0},
+		{"One degree north", "'41.7128,-74.006'", 40.7128, -74.006, 111195},
+		{"One degree east at 60 degrees", "'60,1'", 60, 0, 55597},
+	}
+	for rowIndex, row := range rows {
+		t.Run(fmt.Sprintf("%d/%s", rowIndex, row.description), func(t *testing.T) {
+			var output float64
+			expression := Distance(db, CastFloat(db, TextBefore(db, row.column)), CastFloat(db, TextAfter(db, row.column)), row.latitude, row.longitude)
+			err := db.Raw("SELECT " + expression).Scan(&output).Error
+			require.NoError(t, err)
+			assert.InDelta(t, row.output, output, 1)
+		})
+	}
+}
@@ -3,7 +3,9 @@ package databasetest
 import (
 	"context"
 	"fmt"
+	"os"
 	"strings"
+	"sync"
 
 	"github.com/downballot/downballot/internal/database"
 	"github.com/downballot/downballot/internal/migrator"
@@ -11,8 +13,32 @@ import (
 	"gorm.io/gorm"
 )
 
+// These environment variables choose the database for the tests.
+//
+// By default, the tests use an in-memory SQLite database.  To run them against another database, set the driver
+// (such as "postgres" or "mysql") and the connection string.  That database is emptied the first time that it is
+// used, so never point this at a real one; since every test package empties it, run the tests with "-p 1".
+const (
+	EnvironmentDriver           = "TEST_DATABASE_DRIVER"
+	EnvironmentConnectionString = "TEST_DATABASE_STRING"
+)
+
+// dropOnce makes sure that an external database is only emptied once per test binary, which matches the in-memory
+// database being shared by every connection.
+var dropOnce sync.Once
+
 func New(ctx context.Context) (*gorm.DB, error) {
-	db, err := database.New(ctx, "sqlite3", "file::memory:?cache=shared&parseTime=true")
+	driverName := "sqlite3"
+	connectionString := "file::memory:?cache=shared&parseTime=true"
+	if value := os.Getenv(EnvironmentDriver); value != "" {
+		driverName = value
+		connectionString = os.Getenv(EnvironmentConnectionString)
+		if connectionString == "" {
+			return nil, fmt.Errorf("missing %s", EnvironmentConnectionString)
+		}
+	}
+
+	db, err := database.New(ctx, driverName, connectionString)
 	if err != nil {
 		return nil, fmt.Errorf("could not connect to database: %w", err)
 	}
@@ -22,8 +48,23 @@ func New(ctx context.Context) (*gorm.DB, error) {
 		encryptionKey := strings.Repeat("00", 32)
 		sqltype.SetEncryptionKey(encryptionKey)
 	}
+	if err := sqltype.CheckEncryption(); err != nil {
+		err = sqltype.SetBlindIndexKey(strings.Repeat("01", 32))
+		if err != nil {
+			return nil, fmt.Errorf("could not set blind-index key: %w", err)
+		}
+	}
+
+	if driverName != "sqlite3" {
+		dropOnce.Do(func() {
+			err = migrator.Drop(db)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("could not empty database: %w", err)
+		}
+	}
 
-	err = migrator.Migrate(db)
+	err = migrator.Migrate(ctx, db)
 	if err != nil {
 		return nil, fmt.Errorf("could not migrate database: %w", err)
 	}
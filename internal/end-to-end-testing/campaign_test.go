@@ -1,17 +1,32 @@
 package endtoendtesting
 
 import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"net/url"
 	"os"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/dgrijalva/jwt-go"
 	"github.com/downballot/downballot/downballotapi"
+	"github.com/downballot/downballot/internal/api/resttype"
+	"github.com/downballot/downballot/internal/apitoken"
 	"github.com/downballot/downballot/internal/applicationtest"
 	"github.com/downballot/downballot/internal/filter"
 	"github.com/downballot/downballot/internal/schema"
 	"github.com/downballot/downballot/internal/testutils"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/totp"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/tekkamanendless/go-mailer"
@@ -59,6 +74,8 @@ func TestCampaign(t *testing.T) {
 	group2Filter := "political_party = 'Navy'"
 	group2Id := ""
 
+	linkTokenRegexp := regexp.MustCompile(`\?token=([A-Za-z0-9._-]+)`)
+
 	t.Log("Register the admin user.")
 	{
 		input := downballotapi.RegisterUserRequest{
@@ -70,6 +87,19 @@ func TestCampaign(t *testing.T) {
 		require.NoError(t, err)
 		adminUserId = output.ID
 		t.Logf("User ID: %s", adminUserId)
+
+		message := mailer.Dummy().LastMessageInInbox(application.Config().SendGridAPIKey, adminUsername)
+		require.NotNil(t, message)
+		require.Equal(t, "Verify your Downballot account", message.Subject)
+		matches := linkTokenRegexp.FindStringSubmatch(message.BodyPlainText)
+		require.Len(t, matches, 2)
+
+		var verifyOutput downballotapi.VerifyUserResponse
+		err = application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/user/verify", downballotapi.VerifyUserRequest{
+			Token: matches[1],
+		}, &verifyOutput)
+		require.NoError(t, err)
+		assert.Equal(t, adminUserId, verifyOutput.ID)
 	}
 
 	t.Log("Register user 1.")
@@ -83,6 +113,19 @@ func TestCampaign(t *testing.T) {
 		require.NoError(t, err)
 		user1Id = output.ID
 		t.Logf("User 1 ID: %s", user1Id)
+
+		message := mailer.Dummy().LastMessageInInbox(application.Config().SendGridAPIKey, user1Username)
+		require.NotNil(t, message)
+		require.Equal(t, "Verify your Downballot account", message.Subject)
+		matches := linkTokenRegexp.FindStringSubmatch(message.BodyPlainText)
+		require.Len(t, matches, 2)
+
+		var verifyOutput downballotapi.VerifyUserResponse
+		err = application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/user/verify", downballotapi.VerifyUserRequest{
+			Token: matches[1],
+		}, &verifyOutput)
+		require.NoError(t, err)
+		assert.Equal(t, user1Id, verifyOutput.ID)
 	}
 
 	t.Log("Register user 2.")
@@ -96,6 +139,19 @@ func TestCampaign(t *testing.T) {
 		require.NoError(t, err)
 		user2Id = output.ID
 		t.Logf("User 2 ID: %s", user2Id)
+
+		message := mailer.Dummy().LastMessageInInbox(application.Config().SendGridAPIKey, user2Username)
+		require.NotNil(t, message)
+		require.Equal(t, "Verify your Downballot account", message.Subject)
+		matches := linkTokenRegexp.FindStringSubmatch(message.BodyPlainText)
+		require.Len(t, matches, 2)
+
+		var verifyOutput downballotapi.VerifyUserResponse
+		err = application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/user/verify", downballotapi.VerifyUserRequest{
+			Token: matches[1],
+		}, &verifyOutput)
+		require.NoError(t, err)
+		assert.Equal(t, user2Id, verifyOutput.ID)
 	}
 
 	t.Log("Log in as the admin user.")
@@ -326,6 +382,42 @@ func TestCampaign(t *testing.T) {
 		}
 	}
 
+	// waitForImportJob waits for an import job to finish and then returns it.
+	waitForImportJob := func(jobID string) downballotapi.GetImportJobResponse {
+		var output downballotapi.GetImportJobResponse
+		require.Eventually(t, func() bool {
+			err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person/import/"+jobID, nil, &output)
+			require.NoError(t, err)
+			require.NotNil(t, output.Job)
+			return output.Job.Status == string(schema.ImportJobStatusSucceeded) || output.Job.Status == string(schema.ImportJobStatusFailed)
+		}, 30*time.Second, 50*time.Millisecond)
+		return output
+	}
+
+	t.Log("Import the voter file as the admin user as a dry run.")
+	{
+		input, err := os.ReadFile("testdata/voterfile.csv")
+		require.NoError(t, err)
+		var output downballotapi.ImportPersonResponse
+		err = adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/person/import?dry_run=true", restapiclient.RawBytes(input), &output, restapiclient.OptionHeader("Content-Type", "text/csv"))
+		require.NoError(t, err)
+		require.NotEmpty(t, output.JobID)
+		assert.Equal(t, uint64(11), output.Records)
+
+		job := waitForImportJob(output.JobID)
+		t.Logf("Job: %+v", job.Job)
+		assert.Equal(t, string(schema.ImportJobStatusSucceeded), job.Job.Status)
+		assert.True(t, job.Job.DryRun)
+		assert.Equal(t, uint64(11), job.Job.ProcessedRows)
+		assert.Equal(t, uint64(11), job.Job.CreatedRows)
+		assert.Empty(t, job.Errors)
+
+		var personsOutput downballotapi.ListPersonsResponse
+		err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person", nil, &personsOutput)
+		require.NoError(t, err)
+		assert.Empty(t, personsOutput.Persons)
+	}
+
 	t.Log("Import the voter file as the admin user.")
 	{
 		input, err := os.ReadFile("testdata/voterfile.csv")
@@ -334,6 +426,43 @@ func TestCampaign(t *testing.T) {
 		err = adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/person/import", restapiclient.RawBytes(input), &output, restapiclient.OptionHeader("Content-Type", "text/csv"))
 		require.NoError(t, err)
 		t.Logf("Persons: %v", output.Records)
+
+		job := waitForImportJob(output.JobID)
+		t.Logf("Job: %+v", job.Job)
+		assert.Equal(t, string(schema.ImportJobStatusSucceeded), job.Job.Status)
+		assert.False(t, job.Job.DryRun)
+		assert.Equal(t, uint64(11), job.Job.ProcessedRows)
+		assert.Equal(t, uint64(11), job.Job.CreatedRows)
+		assert.Equal(t, uint64(0), job.Job.FailedRows)
+		assert.NotNil(t, job.Job.FinishedTimestamp)
+	}
+
+	t.Log("Import a file with bad rows as the admin user.")
+	{
+		input := "Voter_ID,Year_of_Birth\n,1950\n1001,nineteen forty-nine\n1002,1952\n1001,1949\n"
+		var output downballotapi.ImportPersonResponse
+		err := adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/person/import", restapiclient.RawBytes(input), &output, restapiclient.OptionHeader("Content-Type", "text/csv"))
+		require.NoError(t, err)
+
+		job := waitForImportJob(output.JobID)
+		t.Logf("Job: %+v", job.Job)
+		assert.Equal(t, string(schema.ImportJobStatusSucceeded), job.Job.Status)
+		assert.Equal(t, uint64(4), job.Job.ProcessedRows)
+		assert.Equal(t, uint64(0), job.Job.CreatedRows)
+		assert.Equal(t, uint64(0), job.Job.UpdatedRows)
+		assert.Equal(t, uint64(1), job.Job.UnchangedRows)
+		assert.Equal(t, uint64(3), job.Job.FailedRows)
+
+		var errorReport restapiclient.RawBytes
+		err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person/import/"+output.JobID, nil, &errorReport, restapiclient.OptionHeader("Accept", "text/csv"))
+		require.NoError(t, err)
+		records, err := csv.NewReader(bytes.NewReader(errorReport)).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 4)
+		assert.Equal(t, []string{"row", "voter_id", "message"}, records[0])
+		assert.Equal(t, []string{"1", ""}, records[1][0:2])
+		assert.Equal(t, []string{"2", "1001"}, records[2][0:2])
+		assert.Equal(t, []string{"4", "1001"}, records[3][0:2])
 	}
 
 	t.Log("List all persons named Garp with 'monk' in the last name as the admin user.")
@@ -345,6 +474,115 @@ func TestCampaign(t *testing.T) {
 		assert.Len(t, output.Persons, 1)
 	}
 
+	t.Log("List all persons who are not Navy Monkeys as the admin user.")
+	{
+		var output downballotapi.ListPersonsResponse
+		err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person?filter="+url.QueryEscape("NOT (name_last = monkey AND political_party = navy)"), nil, &output)
+		require.NoError(t, err)
+		t.Logf("Persons: %v", output.Persons)
+		assert.Len(t, output.Persons, 10)
+	}
+
+	t.Log("List all persons without a middle name of 'D' (including those with no middle name) as the admin user.")
+	{
+		var output downballotapi.ListPersonsResponse
+		err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person?filter="+url.QueryEscape("NOT name_middle = d"), nil, &output)
+		require.NoError(t, err)
+		t.Logf("Persons: %v", output.Persons)
+		assert.Len(t, output.Persons, 8)
+	}
+
+	t.Log("Page through all persons sorted by birth year (descending) as the admin user.")
+	{
+		var voterIDs []string
+		pageToken := ""
+		for pageIndex := 0; ; pageIndex++ {
+			require.Less(t, pageIndex, 10, "too many pages")
+
+			path := "/api/v1/organization/" + organizationId + "/person?limit=4&sort=-birthday_year,voter_id"
+			if pageToken != "" {
+				path += "&page_token=" + url.QueryEscape(pageToken)
+			}
+			var output downballotapi.ListPersonsResponse
+			err := adminClient.Do(ctx, http.MethodGet, path, nil, &output)
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(output.Persons), 4)
+			for _, person := range output.Persons {
+				voterIDs = append(voterIDs, person.VoterID)
+			}
+			if output.NextPageToken == "" {
+				break
+			}
+			pageToken = output.NextPageToken
+		}
+		assert.Equal(t, []string{"1008", "1009", "1011", "1010", "1007", "1005", "1006", "1004", "1002", "1001", "1003"}, voterIDs)
+	}
+
+	t.Log("The page size defaults to 1000 and is capped.")
+	{
+		var output downballotapi.ListPersonsResponse
+		err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person", nil, &output)
+		require.NoError(t, err)
+		assert.Len(t, output.Persons, 11)
+		assert.Empty(t, output.NextPageToken)
+
+		for _, limit := range []string{"0", "-1", "10001"} {
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person?limit="+limit, nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest, "Limit: %s", limit)
+		}
+	}
+
+	t.Log("Export the Monkeys as CSV as the admin user.")
+	{
+		var output restapiclient.RawBytes
+		err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person/export?filter="+url.QueryEscape("name_last = monkey")+"&fields=name_last,name_first", nil, &output, restapiclient.OptionHeader("Accept", "text/csv"))
+		require.NoError(t, err)
+		t.Logf("Export: %s", string(output))
+		records, err := csv.NewReader(bytes.NewReader(output)).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 3)
+		assert.Equal(t, []string{"voter_id", "name_last", "name_first"}, records[0])
+		assert.ElementsMatch(t, [][]string{{"1001", "MONKEY", "LUFFY"}, {"1009", "MONKEY", "GARP"}}, records[1:])
+	}
+
+	t.Log("The voter ID can be placed explicitly, but no column can be repeated.")
+	{
+		var output restapiclient.RawBytes
+		err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person/export?filter="+url.QueryEscape("name_last = monkey")+"&fields=name_last,voter_id", nil, &output, restapiclient.OptionHeader("Accept", "text/csv"))
+		require.NoError(t, err)
+		records, err := csv.NewReader(bytes.NewReader(output)).ReadAll()
+		require.NoError(t, err)
+		require.NotEmpty(t, records)
+		assert.Equal(t, []string{"name_last", "voter_id"}, records[0])
+
+		for _, fields := range []string{"voter_id,name_last,voter_id", "name_last,name_last"} {
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person/export?fields="+fields, nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest, "Fields: %s", fields)
+		}
+	}
+
+	t.Log("Export all persons as NDJSON as the admin user.")
+	{
+		var output restapiclient.RawBytes
+		err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person/export?format=ndjson&fields=voter_id", nil, &output, restapiclient.OptionHeader("Accept", "application/x-ndjson"))
+		require.NoError(t, err)
+		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+		require.Len(t, lines, 11)
+		for _, line := range lines {
+			var person downballotapi.Person
+			err := json.Unmarshal([]byte(line), &person)
+			require.NoError(t, err)
+			assert.NotEmpty(t, person.VoterID)
+		}
+	}
+
+	t.Log("List persons with an invalid sort as the admin user.")
+	{
+		var output downballotapi.ListPersonsResponse
+		err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person?sort=bogus_field", nil, &output)
+		require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+	}
+
 	t.Logf("Create group 1 %q matching: %q", group1Name, group2Filter)
 	{
 		input := downballotapi.CreateGroupRequest{
@@ -737,4 +975,2401 @@ func TestCampaign(t *testing.T) {
 			}
 		}
 	})
+
+	t.Log("Import a voter file with a different layout using an import profile as the admin user.")
+	{
+		var importProfileID string
+		{
+			var output downballotapi.CreateImportProfileResponse
+			err := adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/import-profile", downballotapi.CreateImportProfileRequest{
+				Name: "other-state",
+				Columns: map[string]string{
+					"id":      "voter_id",
+					"first":   "name_first",
+					"surname": "name_last",
+					"born":    "birthday_year",
+				},
+				Composites: []*downballotapi.ImportProfileComposite{
+					{Field: "name", Expression: "first + ' ' + surname"},
+				},
+			}, &output)
+			require.NoError(t, err)
+			require.NotEmpty(t, output.ID)
+			importProfileID = output.ID
+		}
+
+		t.Log("A profile that writes to an unknown field is rejected.")
+		{
+			err := adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/import-profile", downballotapi.CreateImportProfileRequest{
+				Name:    "bogus",
+				Columns: map[string]string{"id": "no_such_field"},
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+		}
+
+		{
+			var output downballotapi.PatchImportProfileResponse
+			err := adminClient.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/import-profile/"+importProfileID, downballotapi.PatchImportProfileRequest{
+				Transforms: []*downballotapi.ImportProfileTransform{
+					{Field: "name", Operations: []string{"upper"}},
+					{Field: "name_last", Operations: []string{"upper"}},
+				},
+			}, &output)
+			require.NoError(t, err)
+			assert.Equal(t, "other-state", output.ImportProfile.Name)
+			assert.Len(t, output.ImportProfile.Columns, 4)
+			assert.Len(t, output.ImportProfile.Composites, 1)
+			assert.Len(t, output.ImportProfile.Transforms, 2)
+		}
+
+		{
+			var output downballotapi.ListImportProfilesResponse
+			err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/import-profile", nil, &output)
+			require.NoError(t, err)
+			require.Len(t, output.ImportProfiles, 1)
+			assert.Equal(t, importProfileID, output.ImportProfiles[0].ID)
+		}
+
+		t.Log("An unknown profile is rejected.")
+		{
+			input := "ID,First,Surname,Born\n2001,Zoro,Roronoa,1951\n"
+			err := adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/person/import?profile=no-such-profile", restapiclient.RawBytes(input), nil, restapiclient.OptionHeader("Content-Type", "text/csv"))
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+		}
+
+		{
+			input := "ID,First,Surname,Born\n2001,Zoro,Roronoa,1951\n"
+			var output downballotapi.ImportPersonResponse
+			err := adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/person/import?profile=other-state", restapiclient.RawBytes(input), &output, restapiclient.OptionHeader("Content-Type", "text/csv"))
+			require.NoError(t, err)
+
+			job := waitForImportJob(output.JobID)
+			assert.Equal(t, string(schema.ImportJobStatusSucceeded), job.Job.Status)
+			assert.Equal(t, uint64(1), job.Job.CreatedRows)
+			assert.Empty(t, job.Errors)
+		}
+
+		{
+			var output downballotapi.GetPersonResponse
+			err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person/2001", nil, &output)
+			require.NoError(t, err)
+			assert.Equal(t, "ZORO RORONOA", output.Person.Fields["name"])
+			assert.Equal(t, "Zoro", output.Person.Fields["name_first"])
+			assert.Equal(t, "RORONOA", output.Person.Fields["name_last"])
+			assert.Equal(t, "1951", output.Person.Fields["birthday_year"])
+		}
+
+		{
+			err := adminClient.Do(ctx, http.MethodDelete, "/api/v1/organization/"+organizationId+"/import-profile/"+importProfileID, nil, nil)
+			require.NoError(t, err)
+
+			var output downballotapi.ListImportProfilesResponse
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/import-profile", nil, &output)
+			require.NoError(t, err)
+			assert.Empty(t, output.ImportProfiles)
+		}
+	}
+
+	t.Log("Rename, retype, and delete person fields as the admin user.")
+	{
+		personFieldIDMap := map[string]string{}
+		{
+			var output downballotapi.ListPersonFieldsResponse
+			err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person-field", nil, &output)
+			require.NoError(t, err)
+			for _, personField := range output.PersonFields {
+				personFieldIDMap[personField.Name] = personField.ID
+			}
+			require.NotEmpty(t, personFieldIDMap["political_party"])
+			require.NotEmpty(t, personFieldIDMap["name_first"])
+			require.NotEmpty(t, personFieldIDMap["candidate.notes"])
+		}
+
+		t.Log("A field that is used by a group cannot be deleted.")
+		{
+			err := adminClient.Do(ctx, http.MethodDelete, "/api/v1/organization/"+organizationId+"/person-field/"+personFieldIDMap["political_party"], nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusConflict)
+		}
+
+		t.Log("Renaming a field renames it in the groups that use it.")
+		{
+			name := "party"
+			var output downballotapi.PatchPersonFieldResponse
+			err := adminClient.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/person-field/"+personFieldIDMap["political_party"], downballotapi.PatchPersonFieldRequest{
+				Name: &name,
+			}, &output)
+			require.NoError(t, err)
+			assert.Equal(t, "party", output.PersonField.Name)
+		}
+		{
+			var output downballotapi.GetGroupResponse
+			err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/group/"+group2Id, nil, &output)
+			require.NoError(t, err)
+			require.NotNil(t, output.Group)
+			assert.Equal(t, "party = Navy", output.Group.Filter)
+		}
+		{
+			var output downballotapi.ListPersonsResponse
+			err := user2Client.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person", nil, &output)
+			require.NoError(t, err)
+			assert.Len(t, output.Persons, 3)
+		}
+
+		t.Log("Changing the type of a field reports the values that would no longer be valid.")
+		{
+			fieldType := downballotapi.PersonFieldDefinitionTypeInteger
+			var output downballotapi.PatchPersonFieldResponse
+			err := adminClient.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/person-field/"+personFieldIDMap["name_first"]+"?dry_run=true", downballotapi.PatchPersonFieldRequest{
+				Type: &fieldType,
+			}, &output)
+			require.NoError(t, err)
+			assert.Equal(t, downballotapi.PersonFieldDefinitionTypeInteger, output.PersonField.Type)
+			assert.Len(t, output.InvalidValues, 12)
+			for _, invalidValue := range output.InvalidValues {
+				if invalidValue.VoterID == "2001" {
+					assert.Equal(t, "Zoro", invalidValue.Value)
+				}
+			}
+
+			err = adminClient.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/person-field/"+personFieldIDMap["name_first"], downballotapi.PatchPersonFieldRequest{
+				Type: &fieldType,
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusConflict)
+		}
+		{
+			var output downballotapi.GetPersonFieldResponse
+			err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person-field/"+personFieldIDMap["name_first"], nil, &output)
+			require.NoError(t, err)
+			require.NotNil(t, output.PersonField)
+			assert.Equal(t, downballotapi.PersonFieldDefinitionTypeString, output.PersonField.Type)
+		}
+
+		t.Log("A field that is used by a saved filter can only be deleted with force.")
+		{
+			var output downballotapi.CreateFilterResponse
+			err := adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/filter", downballotapi.CreateFilterRequest{
+				Name:   "Has notes",
+				Filter: "'candidate.notes' IS NOT NULL",
+			}, &output)
+			require.NoError(t, err)
+
+			err = adminClient.Do(ctx, http.MethodDelete, "/api/v1/organization/"+organizationId+"/person-field/"+personFieldIDMap["candidate.notes"], nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusConflict)
+
+			err = adminClient.Do(ctx, http.MethodDelete, "/api/v1/organization/"+organizationId+"/person-field/"+personFieldIDMap["candidate.notes"]+"?force=true", nil, nil)
+			require.NoError(t, err)
+
+			err = adminClient.Do(ctx, http.MethodDelete, "/api/v1/organization/"+organizationId+"/filter/"+output.ID, nil, nil)
+			require.NoError(t, err)
+		}
+		{
+			var output downballotapi.ListPersonFieldsResponse
+			err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person-field", nil, &output)
+			require.NoError(t, err)
+			var names []string
+			for _, personField := range output.PersonFields {
+				names = append(names, personField.Name)
+			}
+			assert.Contains(t, names, "party")
+			assert.NotContains(t, names, "political_party")
+			assert.NotContains(t, names, "candidate.notes")
+		}
+	}
+
+	t.Log("Encrypt a sensitive person field as the admin user.")
+	{
+		phoneFieldID := ""
+		{
+			var output downballotapi.CreatePersonFieldResponse
+			err := adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/person-field", downballotapi.CreatePersonFieldRequest{
+				Name:      "phone",
+				Type:      downballotapi.PersonFieldDefinitionTypeString,
+				Encrypted: true,
+			}, &output)
+			require.NoError(t, err)
+			assert.True(t, output.PersonField.Encrypted)
+			phoneFieldID = output.PersonField.ID
+		}
+		for voterID, phone := range map[string]string{"1001": "555-0101", "1009": "555-0109"} {
+			var output downballotapi.GetPersonResponse
+			err := adminClient.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/person/"+voterID, downballotapi.PatchPersonRequest{
+				Fields: map[string]*string{
+					"phone": &phone,
+				},
+			}, &output)
+			require.NoError(t, err)
+			assert.Equal(t, phone, output.Person.Fields["phone"])
+		}
+
+		// rawPhoneValues returns the phone values as they are stored in the database.
+		rawPhoneValues := func() []string {
+			var output []string
+			err := application.DB().Table("person_field").Where("person_field_definition_id = ?", phoneFieldID).Pluck("value", &output).Error
+			require.NoError(t, err)
+			require.Len(t, output, 2)
+			return output
+		}
+		for _, value := range rawPhoneValues() {
+			assert.NotContains(t, value, "555")
+		}
+
+		t.Log("An encrypted field can be found by equality.")
+		{
+			var output downballotapi.ListPersonsResponse
+			err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person?filter="+url.QueryEscape("phone = '555-0101'"), nil, &output)
+			require.NoError(t, err)
+			require.Len(t, output.Persons, 1)
+			assert.Equal(t, "1001", output.Persons[0].VoterID)
+			assert.Equal(t, "555-0101", output.Persons[0].Fields["phone"])
+		}
+		{
+			var output downballotapi.ListPersonsResponse
+			err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person?filter="+url.QueryEscape("phone != '555-0101'"), nil, &output)
+			require.NoError(t, err)
+			var voterIDs []string
+			for _, person := range output.Persons {
+				voterIDs = append(voterIDs, person.VoterID)
+			}
+			assert.NotContains(t, voterIDs, "1001")
+			assert.Contains(t, voterIDs, "1009")
+		}
+
+		t.Log("An encrypted field cannot be matched by a pattern or a range, or sorted.")
+		{
+			err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person?filter="+url.QueryEscape("phone ~ '555*'"), nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person?filter="+url.QueryEscape("phone > '555'"), nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person?sort=phone", nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+		}
+
+		t.Log("The history of an encrypted field is readable.")
+		{
+			var output downballotapi.ListPersonAuditsResponse
+			err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person/1001/audit?fields=phone", nil, &output)
+			require.NoError(t, err)
+			require.Len(t, output.Audits, 1)
+			assert.Nil(t, output.Audits[0].OldValue)
+			require.NotNil(t, output.Audits[0].NewValue)
+			assert.Equal(t, "555-0101", *output.Audits[0].NewValue)
+		}
+
+		t.Log("A value that would be too long once it is encrypted is rejected.")
+		{
+			for length, expectedErr := range map[int]error{149: nil, 150: httperror.ErrStatusBadRequest, 256: httperror.ErrStatusBadRequest} {
+				phone := strings.Repeat("5", length)
+				err := adminClient.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/person/1009", downballotapi.PatchPersonRequest{
+					Fields: map[string]*string{
+						"phone": &phone,
+					},
+				}, nil)
+				if expectedErr == nil {
+					require.NoError(t, err, "Length: %d", length)
+				} else {
+					require.ErrorIs(t, err, expectedErr, "Length: %d", length)
+				}
+			}
+
+			phone := "555-0109"
+			err := adminClient.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/person/1009", downballotapi.PatchPersonRequest{
+				Fields: map[string]*string{
+					"phone": &phone,
+				},
+			}, nil)
+			require.NoError(t, err)
+		}
+
+		t.Log("Decrypting the field re-encodes the existing values.")
+		{
+			encrypted := false
+			err := adminClient.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/person-field/"+phoneFieldID, downballotapi.PatchPersonFieldRequest{
+				Encrypted: &encrypted,
+			}, nil)
+			require.NoError(t, err)
+			assert.ElementsMatch(t, []string{"555-0101", "555-0109"}, rawPhoneValues())
+
+			var output downballotapi.ListPersonsResponse
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person?filter="+url.QueryEscape("phone ~ '555*'"), nil, &output)
+			require.NoError(t, err)
+			assert.Len(t, output.Persons, 2)
+
+			err = adminClient.Do(ctx, http.MethodDelete, "/api/v1/organization/"+organizationId+"/person-field/"+phoneFieldID, nil, nil)
+			require.NoError(t, err)
+		}
+	}
+
+	t.Log("Give user 1 a role so that they can update persons.")
+	{
+		luffyVoterID := ""
+		{
+			var output downballotapi.ListPersonsResponse
+			err := user1Client.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person", nil, &output)
+			require.NoError(t, err)
+			for _, person := range output.Persons {
+				if person.Fields["name"] == "LUFFY D MONKEY" {
+					luffyVoterID = person.VoterID
+				}
+			}
+			require.NotEmpty(t, luffyVoterID)
+		}
+
+		donated := "true"
+		updateLuffy := downballotapi.PatchPersonRequest{
+			Fields: map[string]*string{
+				"candidate.donated": &donated,
+			},
+		}
+
+		{
+			var output downballotapi.ListPermissionsResponse
+			err := user1Client.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/permission", nil, &output)
+			require.NoError(t, err)
+			assert.Contains(t, output.Permissions, "person:read")
+			assert.NotContains(t, output.Permissions, "person:update")
+
+			err = user1Client.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/person/"+luffyVoterID, updateLuffy, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusForbidden)
+		}
+
+		t.Log("A role with an unknown permission is rejected.")
+		{
+			err := adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/role", downballotapi.CreateRoleRequest{
+				Name:        "bogus",
+				Permissions: []string{"persn:update"},
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+		}
+
+		var canvasserRoleID string
+		{
+			var output downballotapi.CreateRoleResponse
+			err := adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/role", downballotapi.CreateRoleRequest{
+				Name:        "canvasser",
+				Permissions: []string{"person:read", "person:update"},
+			}, &output)
+			require.NoError(t, err)
+			require.NotEmpty(t, output.ID)
+			canvasserRoleID = output.ID
+		}
+		var dataManagerRoleID string
+		{
+			var output downballotapi.CreateRoleResponse
+			err := adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/role", downballotapi.CreateRoleRequest{
+				Name:        "data manager",
+				Permissions: []string{"person:*", "person-field-definition:*"},
+			}, &output)
+			require.NoError(t, err)
+			dataManagerRoleID = output.ID
+		}
+		{
+			var output downballotapi.ListRolesResponse
+			err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/role", nil, &output)
+			require.NoError(t, err)
+			require.Len(t, output.Roles, 2)
+			assert.Equal(t, "canvasser", output.Roles[0].Name)
+			assert.Equal(t, "data manager", output.Roles[1].Name)
+		}
+
+		{
+			var output downballotapi.PatchOrganizationUserResponse
+			err := adminClient.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/user/"+user1Id, downballotapi.PatchOrganizationUserRequest{
+				RoleID: &canvasserRoleID,
+			}, &output)
+			require.NoError(t, err)
+			assert.Equal(t, canvasserRoleID, output.User.RoleID)
+			assert.False(t, output.User.Owner)
+		}
+
+		{
+			var output downballotapi.ListPermissionsResponse
+			err := user1Client.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/permission", nil, &output)
+			require.NoError(t, err)
+			assert.Contains(t, output.Permissions, "organization:read")
+			assert.Contains(t, output.Permissions, "person:update")
+			assert.NotContains(t, output.Permissions, "person:delete")
+
+			var person downballotapi.GetPersonResponse
+			err = user1Client.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/person/"+luffyVoterID, updateLuffy, &person)
+			require.NoError(t, err)
+			assert.Equal(t, "true", person.Person.Fields["candidate.donated"])
+		}
+
+		t.Log("A user who can manage roles and users cannot grant more than they have.")
+		{
+			var roleManagerRoleID string
+			{
+				var output downballotapi.CreateRoleResponse
+				err := adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/role", downballotapi.CreateRoleRequest{
+					Name:        "role manager",
+					Permissions: []string{"role:*", "organization.user:update", "person:update"},
+				}, &output)
+				require.NoError(t, err)
+				roleManagerRoleID = output.ID
+
+				err = adminClient.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/user/"+user1Id, downballotapi.PatchOrganizationUserRequest{
+					RoleID: &roleManagerRoleID,
+				}, nil)
+				require.NoError(t, err)
+			}
+
+			for _, permissions := range [][]string{{"*"}, {"person:*"}, {"role:*", "organization.user:*"}} {
+				err := user1Client.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/role", downballotapi.CreateRoleRequest{
+					Name:        "escalation",
+					Permissions: permissions,
+				}, nil)
+				require.ErrorIs(t, err, httperror.ErrStatusForbidden, "Permissions: %v", permissions)
+
+				err = user1Client.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/role/"+roleManagerRoleID, downballotapi.PatchRoleRequest{
+					Permissions: permissions,
+				}, nil)
+				require.ErrorIs(t, err, httperror.ErrStatusForbidden, "Permissions: %v", permissions)
+			}
+
+			err := user1Client.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/user/"+user1Id, downballotapi.PatchOrganizationUserRequest{
+				RoleID: &dataManagerRoleID,
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusForbidden)
+
+			owner := true
+			err = user1Client.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/user/"+user1Id, downballotapi.PatchOrganizationUserRequest{
+				Owner: &owner,
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusForbidden)
+
+			t.Log("Nor can they take away more than they have.")
+			{
+				owner := false
+				err = user1Client.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/user/"+adminUserId, downballotapi.PatchOrganizationUserRequest{
+					Owner: &owner,
+				}, nil)
+				require.ErrorIs(t, err, httperror.ErrStatusForbidden)
+
+				err = adminClient.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/user/"+user2Id, downballotapi.PatchOrganizationUserRequest{
+					RoleID: &dataManagerRoleID,
+				}, nil)
+				require.NoError(t, err)
+
+				noRoleID := ""
+				for _, roleID := range []string{noRoleID, roleManagerRoleID} {
+					err = user1Client.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/user/"+user2Id, downballotapi.PatchOrganizationUserRequest{
+						RoleID: &roleID,
+					}, nil)
+					require.ErrorIs(t, err, httperror.ErrStatusForbidden, "Role ID: %q", roleID)
+				}
+
+				err = adminClient.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/user/"+user2Id, downballotapi.PatchOrganizationUserRequest{
+					RoleID: &noRoleID,
+				}, nil)
+				require.NoError(t, err)
+			}
+
+			// Permissions that the user already has can be granted.
+			err = user1Client.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/role/"+canvasserRoleID, downballotapi.PatchRoleRequest{
+				Permissions: []string{"person:read", "person:update", "role:read"},
+			}, nil)
+			require.NoError(t, err)
+			err = user1Client.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/user/"+user1Id, downballotapi.PatchOrganizationUserRequest{
+				RoleID: &canvasserRoleID,
+			}, nil)
+			require.NoError(t, err)
+
+			var permissions downballotapi.ListPermissionsResponse
+			err = user1Client.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/permission", nil, &permissions)
+			require.NoError(t, err)
+			assert.Contains(t, permissions.Permissions, "role:read")
+			assert.NotContains(t, permissions.Permissions, "role:update")
+
+			err = adminClient.Do(ctx, http.MethodDelete, "/api/v1/organization/"+organizationId+"/role/"+roleManagerRoleID, nil, nil)
+			require.NoError(t, err)
+		}
+
+		t.Log("Deleting the role takes its permissions away.")
+		{
+			err := adminClient.Do(ctx, http.MethodDelete, "/api/v1/organization/"+organizationId+"/role/"+canvasserRoleID, nil, nil)
+			require.NoError(t, err)
+
+			var output downballotapi.GetUserResponse
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/user/"+user1Id, nil, &output)
+			require.NoError(t, err)
+			require.NotNil(t, output.User)
+			assert.Empty(t, output.User.RoleID)
+
+			var permissions downballotapi.ListPermissionsResponse
+			err = user1Client.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/permission", nil, &permissions)
+			require.NoError(t, err)
+			assert.NotContains(t, permissions.Permissions, "person:update")
+		}
+	}
+
+	t.Log("Make user 1 the owner of group 1 so that they can manage it.")
+	{
+		luffyVoterID := ""
+		sengokuVoterID := ""
+		{
+			var output downballotapi.ListPersonsResponse
+			err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person", nil, &output)
+			require.NoError(t, err)
+			for _, person := range output.Persons {
+				switch person.Fields["name"] {
+				case "LUFFY D MONKEY":
+					luffyVoterID = person.VoterID
+				case "SENGOKU BUDDHA":
+					sengokuVoterID = person.VoterID
+				}
+			}
+			require.NotEmpty(t, luffyVoterID)
+			require.NotEmpty(t, sengokuVoterID)
+		}
+
+		donated := "false"
+		updateDonated := downballotapi.PatchPersonRequest{
+			Fields: map[string]*string{
+				"candidate.donated": &donated,
+			},
+		}
+
+		{
+			err := user1Client.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/group", downballotapi.CreateGroupRequest{
+				ParentID: group1Id,
+				Name:     "Straw hats",
+				Filter:   "name_first = 'Luffy'",
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusForbidden)
+		}
+
+		{
+			owner := true
+			var output downballotapi.GetGroupUserResponse
+			err := adminClient.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/group/"+group1Id+"/user/"+user1Id, downballotapi.PatchGroupUserRequest{
+				Owner: &owner,
+			}, &output)
+			require.NoError(t, err)
+			assert.True(t, output.GroupUser.Owner)
+		}
+
+		{
+			var output downballotapi.ListPermissionsResponse
+			err := user1Client.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/permission", nil, &output)
+			require.NoError(t, err)
+			assert.NotContains(t, output.Permissions, "group:create")
+			assert.NotContains(t, output.Permissions, "person:update")
+
+			err = user1Client.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/permission?group_id="+group1Id, nil, &output)
+			require.NoError(t, err)
+			assert.Contains(t, output.Permissions, "group:create")
+			assert.Contains(t, output.Permissions, "group.user:create")
+			assert.Contains(t, output.Permissions, "person:update")
+			assert.NotContains(t, output.Permissions, "organization.user:create")
+		}
+
+		t.Log("User 1 can create a sub-group of their group, but not a group anywhere else.")
+		subGroupID := ""
+		{
+			var output downballotapi.CreateGroupResponse
+			err := user1Client.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/group", downballotapi.CreateGroupRequest{
+				ParentID: group1Id,
+				Name:     "Straw hats",
+				Filter:   "name_first = 'Luffy'",
+			}, &output)
+			require.NoError(t, err)
+			require.NotEmpty(t, output.ID)
+			subGroupID = output.ID
+
+			err = user1Client.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/group", downballotapi.CreateGroupRequest{
+				ParentID: rootGroupId,
+				Name:     "Everyone",
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+		}
+
+		t.Log("User 1 can rename the sub-group, but not their own group.")
+		{
+			name := "Straw Hat Pirates"
+			var output downballotapi.GetGroupResponse
+			err := user1Client.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/group/"+subGroupID, downballotapi.PatchGroupRequest{
+				Name: &name,
+			}, &output)
+			require.NoError(t, err)
+			assert.Equal(t, name, output.Group.Name)
+
+			filter := ""
+			err = user1Client.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/group/"+group1Id, downballotapi.PatchGroupRequest{
+				Filter: &filter,
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusForbidden)
+		}
+
+		t.Log("User 1 can add a user to the sub-group, but not to the organization.")
+		{
+			err := user1Client.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/user/"+user2Id+"/group", downballotapi.AddUserToGroupRequest{
+				GroupID: subGroupID,
+			}, nil)
+			require.NoError(t, err)
+
+			var output downballotapi.ListGroupUsersResponse
+			err = user1Client.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/group/"+subGroupID+"/user", nil, &output)
+			require.NoError(t, err)
+			require.Len(t, output.GroupUsers, 1)
+			assert.Equal(t, user2Id, output.GroupUsers[0].User.ID)
+			assert.False(t, output.GroupUsers[0].Owner)
+
+			err = user1Client.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/user", downballotapi.AddUserToOrganizationRequest{
+				Username: adminUsername,
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusForbidden)
+		}
+
+		t.Log("User 1 can update the persons in their group, but user 2 (who only belongs to it) cannot.")
+		{
+			var output downballotapi.GetPersonResponse
+			err := user1Client.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/person/"+luffyVoterID, updateDonated, &output)
+			require.NoError(t, err)
+			assert.Equal(t, donated, output.Person.Fields["candidate.donated"])
+
+			err = user1Client.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/person/"+sengokuVoterID, updateDonated, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusNotFound)
+
+			err = user2Client.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/person/"+luffyVoterID, updateDonated, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusForbidden)
+		}
+
+		t.Log("User 1 can delete the sub-group.")
+		{
+			err := user1Client.Do(ctx, http.MethodDelete, "/api/v1/organization/"+organizationId+"/group/"+subGroupID, nil, nil)
+			require.NoError(t, err)
+		}
+	}
+
+	t.Log("Log contact attempts as user 1, who owns group 1.")
+	{
+		luffyVoterID := ""
+		sengokuVoterID := ""
+		personCount := 0
+		{
+			var output downballotapi.ListPersonsResponse
+			err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person", nil, &output)
+			require.NoError(t, err)
+			personCount = len(output.Persons)
+			for _, person := range output.Persons {
+				switch person.Fields["name"] {
+				case "LUFFY D MONKEY":
+					luffyVoterID = person.VoterID
+				case "SENGOKU BUDDHA":
+					sengokuVoterID = person.VoterID
+				}
+			}
+			require.NotEmpty(t, luffyVoterID)
+			require.NotEmpty(t, sengokuVoterID)
+		}
+
+		notHomeID := ""
+		{
+			var output downballotapi.CreateContactResultResponse
+			err := adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/contact-result", downballotapi.CreateContactResultRequest{
+				Code: "not_home",
+				Name: "Not home",
+			}, &output)
+			require.NoError(t, err)
+			notHomeID = output.ID
+
+			err = adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/contact-result", downballotapi.CreateContactResultRequest{
+				Code: "Supporter",
+				Name: "Supporter",
+			}, &output)
+			require.NoError(t, err)
+			assert.Equal(t, "supporter", output.Code)
+		}
+
+		t.Log("A contact result needs a simple, unique code.")
+		{
+			err := adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/contact-result", downballotapi.CreateContactResultRequest{
+				Code: "not home",
+				Name: "Not home",
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+
+			err = adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/contact-result", downballotapi.CreateContactResultRequest{
+				Code: "NOT_HOME",
+				Name: "Not home",
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+
+			var output downballotapi.ListContactResultsResponse
+			err = user1Client.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/contact-result", nil, &output)
+			require.ErrorIs(t, err, httperror.ErrStatusForbidden)
+
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/contact-result", nil, &output)
+			require.NoError(t, err)
+			require.Len(t, output.ContactResults, 2)
+			assert.Equal(t, "not_home", output.ContactResults[0].Code)
+			assert.Equal(t, "supporter", output.ContactResults[1].Code)
+		}
+
+		{
+			timestamp := resttype.DateTime(time.Now().Add(-time.Hour))
+			var output downballotapi.CreateContactAttemptResponse
+			err := user1Client.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/person/"+luffyVoterID+"/contact-attempt", downballotapi.CreateContactAttemptRequest{
+				Timestamp: &timestamp,
+				Channel:   downballotapi.ContactChannelDoor,
+				Result:    "not_home",
+			}, &output)
+			require.NoError(t, err)
+			assert.Equal(t, luffyVoterID, output.VoterID)
+			assert.Equal(t, user1Username, output.Username)
+			assert.Equal(t, "not_home", output.Result)
+
+			err = user1Client.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/person/"+luffyVoterID+"/contact-attempt", downballotapi.CreateContactAttemptRequest{
+				Channel: downballotapi.ContactChannelPhone,
+				Result:  "supporter",
+				Note:    "Wants a yard sign.",
+			}, &output)
+			require.NoError(t, err)
+		}
+
+		t.Log("A contact attempt needs a known channel and result.")
+		{
+			err := user1Client.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/person/"+luffyVoterID+"/contact-attempt", downballotapi.CreateContactAttemptRequest{
+				Channel: "carrier pigeon",
+				Result:  "not_home",
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+
+			err = user1Client.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/person/"+luffyVoterID+"/contact-attempt", downballotapi.CreateContactAttemptRequest{
+				Channel: downballotapi.ContactChannelDoor,
+				Result:  "moved",
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+		}
+
+		t.Log("User 1 can only log contact attempts for the persons in their group, and user 2 cannot log any.")
+		{
+			input := downballotapi.CreateContactAttemptRequest{
+				Channel: downballotapi.ContactChannelDoor,
+				Result:  "not_home",
+			}
+			err := user1Client.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/person/"+sengokuVoterID+"/contact-attempt", input, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusNotFound)
+
+			err = user2Client.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/person/"+luffyVoterID+"/contact-attempt", input, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusForbidden)
+		}
+
+		t.Log("List the contact attempts for the person and for the user.")
+		{
+			var output downballotapi.ListContactAttemptsResponse
+			err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person/"+luffyVoterID+"/contact-attempt", nil, &output)
+			require.NoError(t, err)
+			require.Len(t, output.ContactAttempts, 2)
+			assert.Equal(t, "supporter", output.ContactAttempts[0].Result)
+			assert.Equal(t, downballotapi.ContactChannelPhone, output.ContactAttempts[0].Channel)
+			assert.Equal(t, "Wants a yard sign.", output.ContactAttempts[0].Note)
+			assert.Equal(t, "not_home", output.ContactAttempts[1].Result)
+
+			err = user1Client.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/user/"+user1Id+"/contact-attempt", nil, &output)
+			require.NoError(t, err)
+			assert.Len(t, output.ContactAttempts, 2)
+
+			err = user1Client.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/user/"+adminUserId+"/contact-attempt", nil, &output)
+			require.ErrorIs(t, err, httperror.ErrStatusForbidden)
+
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/user/"+user1Id+"/contact-attempt", nil, &output)
+			require.NoError(t, err)
+			assert.Len(t, output.ContactAttempts, 2)
+		}
+
+		t.Log("Filter the persons by their contact attempts.")
+		{
+			var output downballotapi.ListPersonsResponse
+			err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person?filter="+url.QueryEscape("contact.last_result = supporter"), nil, &output)
+			require.NoError(t, err)
+			require.Len(t, output.Persons, 1)
+			assert.Equal(t, luffyVoterID, output.Persons[0].VoterID)
+
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person?filter="+url.QueryEscape("contact.last_result = not_home"), nil, &output)
+			require.NoError(t, err)
+			assert.Empty(t, output.Persons)
+
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person?filter="+url.QueryEscape("contact.count < 2"), nil, &output)
+			require.NoError(t, err)
+			assert.Len(t, output.Persons, personCount-1)
+
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person?filter="+url.QueryEscape("contact.count >= 2 AND contact.last_channel = phone"), nil, &output)
+			require.NoError(t, err)
+			require.Len(t, output.Persons, 1)
+			assert.Equal(t, luffyVoterID, output.Persons[0].VoterID)
+
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person?filter="+url.QueryEscape("contact.last_result IS NULL"), nil, &output)
+			require.NoError(t, err)
+			assert.Len(t, output.Persons, personCount-1)
+
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person?filter="+url.QueryEscape("contact.count = many"), nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+		}
+
+		t.Log("A contact result that has been recorded cannot be deleted.")
+		{
+			err := adminClient.Do(ctx, http.MethodDelete, "/api/v1/organization/"+organizationId+"/contact-result/"+notHomeID, nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusConflict)
+		}
+
+		t.Log("A person field cannot use the name of a contact filter field.")
+		{
+			err := adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/person-field", downballotapi.CreatePersonFieldRequest{
+				Name: "contact.count",
+				Type: downballotapi.PersonFieldDefinitionTypeInteger,
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+		}
+	}
+
+	t.Log("Group the persons into households.")
+	{
+		luffy := &downballotapi.Person{}
+		sengoku := &downballotapi.Person{}
+		addressCount := 0
+		{
+			var output downballotapi.ListPersonsResponse
+			err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person", nil, &output)
+			require.NoError(t, err)
+			for _, person := range output.Persons {
+				if person.Fields["residential_address"] != "" {
+					addressCount++
+					assert.NotEmpty(t, person.HouseholdID, person.VoterID)
+				} else {
+					assert.Empty(t, person.HouseholdID, person.VoterID)
+				}
+				switch person.Fields["name"] {
+				case "LUFFY D MONKEY":
+					luffy = person
+				case "SENGOKU BUDDHA":
+					sengoku = person
+				}
+			}
+			require.NotEmpty(t, luffy.Fields["residential_address"])
+			require.NotEmpty(t, sengoku.Fields["residential_address"])
+			assert.NotEqual(t, luffy.HouseholdID, sengoku.HouseholdID)
+		}
+
+		{
+			var output downballotapi.ListHouseholdsResponse
+			err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/household", nil, &output)
+			require.NoError(t, err)
+			assert.Len(t, output.Households, addressCount)
+			assert.Empty(t, output.NextPageToken)
+		}
+
+		t.Log("Moving Sengoku to Luffy's address (written differently) puts them in the same household.")
+		{
+			address := strings.ToLower(luffy.Fields["residential_address"])
+			var output downballotapi.GetPersonResponse
+			err := adminClient.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/person/"+sengoku.VoterID, downballotapi.PatchPersonRequest{
+				Fields: map[string]*string{"residential_address": &address},
+			}, &output)
+			require.NoError(t, err)
+			assert.Equal(t, luffy.HouseholdID, output.Person.HouseholdID)
+
+			var householdOutput downballotapi.GetHouseholdResponse
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/household/"+luffy.HouseholdID, nil, &householdOutput)
+			require.NoError(t, err)
+			assert.Equal(t, luffy.Fields["residential_address"], householdOutput.Household.Address)
+			assert.Len(t, householdOutput.Household.Members, 2)
+
+			// Sengoku's old household is gone.
+			var listOutput downballotapi.ListHouseholdsResponse
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/household", nil, &listOutput)
+			require.NoError(t, err)
+			assert.Len(t, listOutput.Households, addressCount-1)
+
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/household/"+sengoku.HouseholdID, nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusNotFound)
+		}
+
+		t.Log("List one row per household, with only the members who match the filter.")
+		{
+			var output downballotapi.ListHouseholdsResponse
+			err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/household?filter="+url.QueryEscape("voter_id = ("+luffy.VoterID+", "+sengoku.VoterID+")"), nil, &output)
+			require.NoError(t, err)
+			require.Len(t, output.Households, 1)
+			assert.Equal(t, luffy.HouseholdID, output.Households[0].ID)
+			assert.Len(t, output.Households[0].Members, 2)
+
+			var fieldsOutput downballotapi.ListHouseholdsResponse
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/household?filter="+url.QueryEscape("voter_id = "+sengoku.VoterID)+"&fields=name", nil, &fieldsOutput)
+			require.NoError(t, err)
+			require.Len(t, fieldsOutput.Households, 1)
+			require.Len(t, fieldsOutput.Households[0].Members, 1)
+			assert.Equal(t, map[string]string{"name": "SENGOKU BUDDHA"}, fieldsOutput.Households[0].Members[0].Fields)
+
+			// User 1 only sees the members in their group.
+			err = user1Client.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/group/"+group1Id+"/household", nil, &output)
+			require.NoError(t, err)
+			found := false
+			for _, household := range output.Households {
+				if household.ID == luffy.HouseholdID {
+					found = true
+					require.Len(t, household.Members, 1)
+					assert.Equal(t, luffy.VoterID, household.Members[0].VoterID)
+				}
+			}
+			assert.True(t, found)
+
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/household?limit=2", nil, &output)
+			require.NoError(t, err)
+			require.Len(t, output.Households, 2)
+			require.NotEmpty(t, output.NextPageToken)
+			lastHouseholdID := output.Households[1].ID
+
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/household?limit=2&page_token="+url.QueryEscape(output.NextPageToken), nil, &output)
+			require.NoError(t, err)
+			require.NotEmpty(t, output.Households)
+			assert.NotEqual(t, lastHouseholdID, output.Households[0].ID)
+
+			var csvOutput restapiclient.RawBytes
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/household?filter="+url.QueryEscape("voter_id = "+luffy.VoterID), nil, &csvOutput, restapiclient.OptionHeader("Accept", "text/csv"))
+			require.NoError(t, err)
+			records, err := csv.NewReader(bytes.NewReader(csvOutput)).ReadAll()
+			require.NoError(t, err)
+			require.Len(t, records, 2)
+			assert.Equal(t, []string{"household_id", "address", "members", "voter_ids"}, records[0])
+			assert.Equal(t, []string{luffy.HouseholdID, luffy.Fields["residential_address"], "1", luffy.VoterID}, records[1])
+		}
+
+		t.Log("Split Sengoku off of the household, and then merge them back in.")
+		{
+			input := downballotapi.SplitHouseholdRequest{
+				VoterIDs: []string{sengoku.VoterID},
+			}
+			err := user1Client.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/household/"+luffy.HouseholdID+"/split", input, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusForbidden)
+
+			var output downballotapi.SplitHouseholdResponse
+			err = adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/household/"+luffy.HouseholdID+"/split", input, &output)
+			require.NoError(t, err)
+			require.NotNil(t, output.Household)
+			require.Len(t, output.Household.Members, 1)
+			assert.Equal(t, sengoku.VoterID, output.Household.Members[0].VoterID)
+			assert.NotEqual(t, luffy.HouseholdID, output.Household.ID)
+			splitHouseholdID := output.Household.ID
+
+			err = adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/household/"+luffy.HouseholdID+"/split", input, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+
+			// Rebuilding the households leaves Sengoku where they were put by hand.
+			var rebuildOutput downballotapi.RebuildHouseholdsResponse
+			err = adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/household/rebuild", downballotapi.RebuildHouseholdsRequest{}, &rebuildOutput)
+			require.NoError(t, err)
+			assert.NotZero(t, rebuildOutput.Persons)
+
+			var personOutput downballotapi.GetPersonResponse
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person/"+sengoku.VoterID, nil, &personOutput)
+			require.NoError(t, err)
+			assert.Equal(t, splitHouseholdID, personOutput.Person.HouseholdID)
+
+			var mergeOutput downballotapi.MergeHouseholdsResponse
+			err = adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/household/"+luffy.HouseholdID+"/merge", downballotapi.MergeHouseholdsRequest{
+				HouseholdIDs: []string{splitHouseholdID},
+			}, &mergeOutput)
+			require.NoError(t, err)
+			require.NotNil(t, mergeOutput.Household)
+			assert.Len(t, mergeOutput.Household.Members, 2)
+
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/household/"+splitHouseholdID, nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusNotFound)
+
+			err = adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/household/"+luffy.HouseholdID+"/merge", downballotapi.MergeHouseholdsRequest{
+				HouseholdIDs: []string{luffy.HouseholdID},
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+		}
+	}
+
+	t.Log("Put the persons into turfs.")
+	{
+		var persons []*downballotapi.Person
+		{
+			var output downballotapi.ListPersonsResponse
+			err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person?sort=voter_id", nil, &output)
+			require.NoError(t, err)
+			require.GreaterOrEqual(t, len(output.Persons), 5)
+			persons = output.Persons[:4]
+		}
+		noCoordinatesVoterID := ""
+		{
+			var output downballotapi.ListPersonsResponse
+			err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person?sort=voter_id&filter="+url.QueryEscape("coordinates IS NULL"), nil, &output)
+			require.NoError(t, err)
+			require.NotEmpty(t, output.Persons)
+			noCoordinatesVoterID = output.Persons[len(output.Persons)-1].VoterID
+		}
+
+		// The first two persons are on the west side of town, and the other two are on the east side.
+		coordinates := []string{"39.68,-75.75", "39.69,-75.75", "39.68,-75.70", "39.69,-75.70"}
+		setCoordinates := func(voterID string, value string) {
+			err := adminClient.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/person/"+voterID, downballotapi.PatchPersonRequest{
+				Fields: map[string]*string{"coordinates": &value},
+			}, nil)
+			require.NoError(t, err)
+		}
+		for i, person := range persons {
+			setCoordinates(person.VoterID, coordinates[i])
+		}
+		voterIDs := func(persons []*downballotapi.Person) []string {
+			var output []string
+			for _, person := range persons {
+				output = append(output, person.VoterID)
+			}
+			slices.Sort(output)
+			return output
+		}
+		filterVoterIDs := func(filter string) []string {
+			var output downballotapi.ListPersonsResponse
+			err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person?filter="+url.QueryEscape(filter), nil, &output)
+			require.NoError(t, err)
+			return voterIDs(output.Persons)
+		}
+
+		westGeometry := json.RawMessage(`{"type":"Polygon","coordinates":[[[-75.76,39.67],[-75.74,39.67],[-75.74,39.70],[-75.76,39.70],[-75.76,39.67]]]}`)
+		var westTurfID string
+		{
+			err := user1Client.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/turf", downballotapi.CreateTurfRequest{
+				Name:     "West",
+				Geometry: westGeometry,
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusForbidden)
+
+			var output downballotapi.CreateTurfResponse
+			err = adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/turf", downballotapi.CreateTurfRequest{
+				Name:        "West",
+				Description: "The west side of town.",
+				Geometry:    westGeometry,
+			}, &output)
+			require.NoError(t, err)
+			require.NotNil(t, output.Members)
+			assert.Equal(t, uint64(2), *output.Members)
+			westTurfID = output.ID
+
+			err = adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/turf", downballotapi.CreateTurfRequest{
+				Name:     "west",
+				Geometry: westGeometry,
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+
+			err = adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/turf", downballotapi.CreateTurfRequest{
+				Name:     "Nowhere",
+				Geometry: json.RawMessage(`{"type":"Point","coordinates":[-75.75,39.68]}`),
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+		}
+
+		t.Log("Filter the persons by turf.")
+		{
+			assert.Equal(t, voterIDs(persons[:2]), filterVoterIDs("within_turf(west)"))
+			assert.Equal(t, voterIDs(persons[2:3]), filterVoterIDs("NOT within_turf(West) AND voter_id = ("+persons[0].VoterID+", "+persons[2].VoterID+")"))
+
+			err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person?filter="+url.QueryEscape("within_turf(Nowhere)"), nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person?filter="+url.QueryEscape("within_town(West)"), nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+
+			// Moving a person moves them into (and out of) the turf.
+			setCoordinates(persons[2].VoterID, "39.685,-75.745")
+			assert.Equal(t, voterIDs(persons[:3]), filterVoterIDs("within_turf(West)"))
+			setCoordinates(persons[2].VoterID, coordinates[2])
+			assert.Equal(t, voterIDs(persons[:2]), filterVoterIDs("within_turf(West)"))
+		}
+
+		t.Log("Growing the turf picks up the persons inside of it.")
+		{
+			var output downballotapi.PatchTurfResponse
+			err := adminClient.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/turf/"+westTurfID, downballotapi.PatchTurfRequest{
+				Geometry: json.RawMessage(`{"type":"Polygon","coordinates":[[[-75.76,39.67],[-75.69,39.67],[-75.69,39.70],[-75.76,39.70],[-75.76,39.67]]]}`),
+			}, &output)
+			require.NoError(t, err)
+			require.NotNil(t, output.Turf.Members)
+			assert.Equal(t, uint64(4), *output.Turf.Members)
+			assert.Equal(t, "The west side of town.", output.Turf.Description)
+			assert.Equal(t, voterIDs(persons), filterVoterIDs("within_turf(West)"))
+
+			var listOutput downballotapi.ListTurfsResponse
+			err = user1Client.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/turf", nil, &listOutput)
+			require.NoError(t, err)
+			require.Len(t, listOutput.Turfs, 1)
+			assert.Equal(t, "West", listOutput.Turfs[0].Name)
+		}
+
+		t.Log("Get the walk list for the turf.")
+		{
+			var output downballotapi.GetWalkListResponse
+			err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/turf/"+westTurfID+"/walk-list?start="+url.QueryEscape("39.679,-75.751"), nil, &output)
+			require.NoError(t, err)
+			require.Len(t, output.Stops, 4)
+			var route []string
+			for i, stop := range output.Stops {
+				assert.Equal(t, i+1, stop.Stop)
+				require.Len(t, stop.Persons, 1)
+				route = append(route, stop.Persons[0].VoterID)
+				assert.Equal(t, stop.Persons[0].Fields["residential_address"], stop.Address)
+			}
+			// From the start, the route goes north, then east, then south.
+			assert.Equal(t, []string{persons[0].VoterID, persons[1].VoterID, persons[3].VoterID, persons[2].VoterID}, route)
+			assert.InDelta(t, 1112, output.Stops[1].Distance, 2)
+			assert.Greater(t, output.Distance, 1112.0+4000.0)
+
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/turf/"+westTurfID+"/walk-list?start=nowhere", nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+
+			var csvOutput restapiclient.RawBytes
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/turf/"+westTurfID+"/walk-list?fields=name&filter="+url.QueryEscape("voter_id = "+persons[1].VoterID), nil, &csvOutput, restapiclient.OptionHeader("Accept", "text/csv"))
+			require.NoError(t, err)
+			records, err := csv.NewReader(bytes.NewReader(csvOutput)).ReadAll()
+			require.NoError(t, err)
+			require.Len(t, records, 2)
+			assert.Equal(t, []string{"stop", "address", "voter_id", "name"}, records[0])
+			assert.Equal(t, []string{"1", persons[1].Fields["residential_address"], persons[1].VoterID, persons[1].Fields["name"]}, records[1])
+		}
+
+		t.Log("Cut the persons into turfs.")
+		{
+			var output downballotapi.CutTurfsResponse
+			err := adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/turf/cut", downballotapi.CutTurfsRequest{
+				Filter:     "within_turf(West)",
+				Count:      2,
+				NamePrefix: "Cut",
+			}, &output)
+			require.NoError(t, err)
+			require.Len(t, output.Turfs, 2)
+			assert.Equal(t, "Cut 1", output.Turfs[0].Name)
+			assert.Equal(t, "Cut 2", output.Turfs[1].Name)
+			for _, turf := range output.Turfs {
+				require.NotNil(t, turf.Members)
+				assert.Equal(t, uint64(2), *turf.Members)
+			}
+			assert.Equal(t, voterIDs(persons[:2]), filterVoterIDs(`within_turf("Cut 1")`))
+			assert.Equal(t, voterIDs(persons[2:]), filterVoterIDs(`within_turf("Cut 2")`))
+
+			// The names have been taken.
+			err = adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/turf/cut", downballotapi.CutTurfsRequest{
+				Filter:     "within_turf(West)",
+				Count:      2,
+				NamePrefix: "Cut",
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+
+			err = adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/turf/cut", downballotapi.CutTurfsRequest{
+				Filter:     "voter_id = " + noCoordinatesVoterID,
+				Count:      2,
+				NamePrefix: "Empty",
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+
+			err = adminClient.Do(ctx, http.MethodDelete, "/api/v1/organization/"+organizationId+"/turf/"+output.Turfs[1].ID, nil, nil)
+			require.NoError(t, err)
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/turf/"+output.Turfs[1].ID, nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusNotFound)
+		}
+
+		t.Log("Find the persons near a point.")
+		{
+			// The persons are about 1.1 km apart from north to south and about 4.3 km apart from west to east.
+			assert.Equal(t, voterIDs(persons[:2]), filterVoterIDs("coordinates near (39.68, -75.75, 2km)"))
+			assert.Equal(t, voterIDs(persons[:1]), filterVoterIDs("coordinates near (39.68, -75.75, 0.5mi)"))
+			assert.Equal(t, voterIDs(persons[:3]), filterVoterIDs("coordinates near (39.68, -75.75, 2.7mi)"))
+			assert.Equal(t, voterIDs(persons[:1]), filterVoterIDs("coordinates near (39.68, -75.75, 500)"))
+			assert.Equal(t, voterIDs(persons[:2]), filterVoterIDs("coordinates within box(39.675, -75.755, 39.695, -75.745)"))
+			assert.Equal(t, voterIDs(persons[:2]), filterVoterIDs("coordinates within box(39.695, -75.745, 39.675, -75.755)"))
+			assert.Equal(t, voterIDs(persons[1:2]), filterVoterIDs("coordinates within box(39.675, -75.755, 39.695, -75.695) AND NOT coordinates near (39.68, -75.70, 2km) AND voter_id != "+persons[0].VoterID))
+			assert.Equal(t, voterIDs(persons[:1]), filterVoterIDs(`coordinates ~ "39.68,-75.75"`))
+			assert.Equal(t, voterIDs(persons[1:2]), filterVoterIDs(`coordinates !~ "39.68,-75.75" AND coordinates near (39.68, -75.75, 2km)`))
+
+			for _, badFilter := range []string{
+				"coordinates near (39.68, -75.75)",
+				"coordinates near (39.68, -75.75, 2parsecs)",
+				"coordinates near (north, -75.75, 2km)",
+				"coordinates within box(39.675, -75.755, 39.695)",
+				"coordinates within circle(39.675, -75.755, 39.695, -75.745)",
+				"coordinates near box(39.675, -75.755, 39.695, -75.745)",
+				"name near (39.68, -75.75, 2km)",
+				"contact.count near (39.68, -75.75, 2km)",
+			} {
+				err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person?filter="+url.QueryEscape(badFilter), nil, nil)
+				require.ErrorIs(t, err, httperror.ErrStatusBadRequest, "Filter: %s", badFilter)
+			}
+		}
+
+		t.Log("Sort the persons by their distance from a point.")
+		{
+			baseURL := "/api/v1/organization/" + organizationId + "/person?distance_from=" + url.QueryEscape("39.68,-75.75") + "&filter=" + url.QueryEscape("coordinates near (39.68, -75.75, 5km)")
+
+			var output downballotapi.ListPersonsResponse
+			err := adminClient.Do(ctx, http.MethodGet, baseURL+"&sort=distance&fields=name,distance", nil, &output)
+			require.NoError(t, err)
+			require.Len(t, output.Persons, 4)
+			assert.Equal(t, []string{persons[0].VoterID, persons[1].VoterID, persons[2].VoterID, persons[3].VoterID}, []string{output.Persons[0].VoterID, output.Persons[1].VoterID, output.Persons[2].VoterID, output.Persons[3].VoterID})
+			assert.Equal(t, map[string]string{"name": persons[0].Fields["name"], "distance": "0"}, output.Persons[0].Fields)
+			distance, err := strconv.Atoi(output.Persons[1].Fields["distance"])
+			require.NoError(t, err)
+			assert.InDelta(t, 1112, distance, 2)
+			distance, err = strconv.Atoi(output.Persons[2].Fields["distance"])
+			require.NoError(t, err)
+			assert.InDelta(t, 4278, distance, 5)
+
+			// The distance is sorted numerically, and it can be paged through.
+			err = adminClient.Do(ctx, http.MethodGet, baseURL+"&sort=-distance&limit=2", nil, &output)
+			require.NoError(t, err)
+			require.Len(t, output.Persons, 2)
+			assert.Equal(t, []string{persons[3].VoterID, persons[2].VoterID}, []string{output.Persons[0].VoterID, output.Persons[1].VoterID})
+			assert.Contains(t, output.Persons[0].Fields, "distance")
+			assert.Contains(t, output.Persons[0].Fields, "coordinates")
+			require.NotEmpty(t, output.NextPageToken)
+			var nextOutput downballotapi.ListPersonsResponse
+			err = adminClient.Do(ctx, http.MethodGet, baseURL+"&sort=-distance&limit=2&page_token="+url.QueryEscape(output.NextPageToken), nil, &nextOutput)
+			require.NoError(t, err)
+			require.Len(t, nextOutput.Persons, 2)
+			assert.Equal(t, []string{persons[1].VoterID, persons[0].VoterID}, []string{nextOutput.Persons[0].VoterID, nextOutput.Persons[1].VoterID})
+			assert.Empty(t, nextOutput.NextPageToken)
+
+			// The page token is tied to the point.
+			err = adminClient.Do(ctx, http.MethodGet, strings.Replace(baseURL, url.QueryEscape("39.68,-75.75"), url.QueryEscape("39.69,-75.75"), 1)+"&sort=-distance&limit=2&page_token="+url.QueryEscape(output.NextPageToken), nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+
+			// The distance is only available from a point.
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person?sort=distance", nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person?sort=distance&distance_from=nowhere", nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+		}
+	}
+
+	t.Log("Run a survey as user 1, who owns group 1.")
+	{
+		luffyVoterID := ""
+		garpVoterID := ""
+		{
+			var output downballotapi.ListPersonsResponse
+			err := user1Client.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person", nil, &output)
+			require.NoError(t, err)
+			for _, person := range output.Persons {
+				switch person.Fields["name"] {
+				case "LUFFY D MONKEY":
+					luffyVoterID = person.VoterID
+				case "GARP D MONKEY":
+					garpVoterID = person.VoterID
+				}
+			}
+			require.NotEmpty(t, luffyVoterID)
+			require.NotEmpty(t, garpVoterID)
+		}
+
+		supportQuestion := &downballotapi.SurveyQuestion{
+			Prompt: "Will you support the candidate?",
+			Type:   downballotapi.SurveyQuestionTypeChoice,
+			Field:  "candidate.support",
+			Choices: []*downballotapi.SurveyChoice{
+				{Code: "Yes", Label: "Yes", Value: "+2"},
+				{Code: "maybe", Label: "Maybe", Value: "0"},
+				{Code: "no", Label: "No", Value: "-2"},
+			},
+		}
+		commentQuestion := &downballotapi.SurveyQuestion{
+			Prompt: "Is there anything that you would like the candidate to know?",
+			Type:   downballotapi.SurveyQuestionTypeText,
+		}
+
+		surveyID := ""
+		supportQuestionID := ""
+		commentQuestionID := ""
+		{
+			input := downballotapi.CreateSurveyRequest{
+				Name:      "Support",
+				Questions: []*downballotapi.SurveyQuestion{supportQuestion, commentQuestion},
+			}
+			err := user1Client.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/survey", input, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusForbidden)
+
+			var output downballotapi.CreateSurveyResponse
+			err = adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/survey", input, &output)
+			require.NoError(t, err)
+			surveyID = output.ID
+			assert.False(t, output.Active)
+			require.Len(t, output.Questions, 2)
+			supportQuestionID = output.Questions[0].ID
+			commentQuestionID = output.Questions[1].ID
+			assert.Equal(t, "candidate.support", output.Questions[0].Field)
+			require.Len(t, output.Questions[0].Choices, 3)
+			assert.Equal(t, "yes", output.Questions[0].Choices[0].Code)
+			assert.Equal(t, downballotapi.SurveyQuestionTypeText, output.Questions[1].Type)
+
+			err = adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/survey", input, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+		}
+
+		t.Log("A survey question needs valid choices and a known field.")
+		{
+			for _, question := range []*downballotapi.SurveyQuestion{
+				{Prompt: "", Type: downballotapi.SurveyQuestionTypeText},
+				{Prompt: "Why?", Type: "essay"},
+				{Prompt: "Why?", Type: downballotapi.SurveyQuestionTypeText, Field: "no_such_field"},
+				{Prompt: "Why?", Type: downballotapi.SurveyQuestionTypeText, Choices: []*downballotapi.SurveyChoice{{Code: "yes", Label: "Yes"}}},
+				{Prompt: "Will you?", Type: downballotapi.SurveyQuestionTypeChoice},
+				{Prompt: "Will you?", Type: downballotapi.SurveyQuestionTypeChoice, Choices: []*downballotapi.SurveyChoice{{Code: "not sure", Label: "Not sure"}}},
+				{Prompt: "Will you?", Type: downballotapi.SurveyQuestionTypeChoice, Choices: []*downballotapi.SurveyChoice{{Code: "yes", Label: "Yes"}, {Code: "YES", Label: "Yes"}}},
+				{Prompt: "Will you?", Type: downballotapi.SurveyQuestionTypeChoice, Field: "candidate.support", Choices: []*downballotapi.SurveyChoice{{Code: "yes", Label: "Yes", Value: "+3"}}},
+			} {
+				err := adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/survey", downballotapi.CreateSurveyRequest{
+					Name:      "Invalid",
+					Questions: []*downballotapi.SurveyQuestion{question},
+				}, nil)
+				require.ErrorIs(t, err, httperror.ErrStatusBadRequest, "Question: %+v", question)
+			}
+		}
+
+		t.Log("Activate the survey.")
+		{
+			var output downballotapi.GetSurveyResponse
+			err := user1Client.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/survey/active", nil, &output)
+			require.ErrorIs(t, err, httperror.ErrStatusNotFound)
+
+			err = user1Client.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/person/"+luffyVoterID+"/survey-response", downballotapi.CreateSurveyResponseRequest{
+				Answers: []*downballotapi.SurveyAnswer{{QuestionID: supportQuestionID, Value: "yes"}},
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+
+			active := true
+			err = adminClient.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/survey/"+surveyID, downballotapi.PatchSurveyRequest{
+				Active: &active,
+			}, nil)
+			require.NoError(t, err)
+
+			err = user1Client.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/survey/active", nil, &output)
+			require.NoError(t, err)
+			require.NotNil(t, output.Survey)
+			assert.Equal(t, surveyID, output.Survey.ID)
+			assert.True(t, output.Survey.Active)
+		}
+
+		t.Log("Record the responses to the survey.")
+		{
+			timestamp := resttype.DateTime(time.Now().Add(-time.Hour))
+			var output downballotapi.CreateSurveyResponseResponse
+			err := user1Client.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/person/"+luffyVoterID+"/survey-response", downballotapi.CreateSurveyResponseRequest{
+				Timestamp: &timestamp,
+				Answers: []*downballotapi.SurveyAnswer{
+					{QuestionID: supportQuestionID, Value: "Yes"},
+					{QuestionID: commentQuestionID, Value: "Wants a yard sign."},
+				},
+			}, &output)
+			require.NoError(t, err)
+			assert.Equal(t, surveyID, output.SurveyID)
+			assert.Equal(t, luffyVoterID, output.VoterID)
+			assert.Equal(t, user1Username, output.Username)
+			require.Len(t, output.Answers, 2)
+			assert.Equal(t, "yes", output.Answers[0].Value)
+
+			// Answering the linked question sets the field, and the change is audited.
+			var personOutput downballotapi.GetPersonResponse
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person/"+luffyVoterID, nil, &personOutput)
+			require.NoError(t, err)
+			assert.Equal(t, "+2", personOutput.Person.Fields["candidate.support"])
+
+			var auditOutput downballotapi.ListPersonAuditsResponse
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person/"+luffyVoterID+"/audit?fields=candidate.support", nil, &auditOutput)
+			require.NoError(t, err)
+			require.Len(t, auditOutput.Audits, 1)
+			assert.Equal(t, user1Username, auditOutput.Audits[0].Username)
+			require.NotNil(t, auditOutput.Audits[0].NewValue)
+			assert.Equal(t, "+2", *auditOutput.Audits[0].NewValue)
+
+			// Luffy changed their mind.
+			err = user1Client.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/person/"+luffyVoterID+"/survey-response", downballotapi.CreateSurveyResponseRequest{
+				Answers: []*downballotapi.SurveyAnswer{
+					{QuestionID: supportQuestionID, Value: "no"},
+				},
+			}, &output)
+			require.NoError(t, err)
+
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person/"+luffyVoterID, nil, &personOutput)
+			require.NoError(t, err)
+			assert.Equal(t, "-2", personOutput.Person.Fields["candidate.support"])
+
+			err = user1Client.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/person/"+garpVoterID+"/survey-response", downballotapi.CreateSurveyResponseRequest{
+				SurveyID: surveyID,
+				Answers: []*downballotapi.SurveyAnswer{
+					{QuestionID: supportQuestionID, Value: "maybe"},
+					{QuestionID: commentQuestionID, Value: "Call back after the harvest."},
+				},
+			}, &output)
+			require.NoError(t, err)
+		}
+
+		t.Log("A survey response needs valid answers.")
+		{
+			for _, answers := range [][]*downballotapi.SurveyAnswer{
+				{},
+				{{QuestionID: "0", Value: "yes"}},
+				{{QuestionID: supportQuestionID, Value: "sometimes"}},
+				{{QuestionID: supportQuestionID, Value: "yes"}, {QuestionID: supportQuestionID, Value: "no"}},
+				{{QuestionID: commentQuestionID, Value: " "}},
+			} {
+				err := user1Client.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/person/"+luffyVoterID+"/survey-response", downballotapi.CreateSurveyResponseRequest{
+					Answers: answers,
+				}, nil)
+				require.ErrorIs(t, err, httperror.ErrStatusBadRequest, "Answers: %+v", answers)
+			}
+
+			err := user1Client.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/person/"+luffyVoterID+"/survey-response", downballotapi.CreateSurveyResponseRequest{
+				ContactAttemptID: "0",
+				Answers:          []*downballotapi.SurveyAnswer{{QuestionID: supportQuestionID, Value: "yes"}},
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+		}
+
+		t.Log("User 2 cannot record survey responses.")
+		{
+			err := user2Client.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/person/"+luffyVoterID+"/survey-response", downballotapi.CreateSurveyResponseRequest{
+				Answers: []*downballotapi.SurveyAnswer{{QuestionID: supportQuestionID, Value: "yes"}},
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusForbidden)
+		}
+
+		t.Log("List the survey responses for the person.")
+		{
+			var output downballotapi.ListSurveyResponsesResponse
+			err := user1Client.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person/"+luffyVoterID+"/survey-response", nil, &output)
+			require.NoError(t, err)
+			require.Len(t, output.SurveyResponses, 2)
+			require.Len(t, output.SurveyResponses[0].Answers, 1)
+			assert.Equal(t, "no", output.SurveyResponses[0].Answers[0].Value)
+			require.Len(t, output.SurveyResponses[1].Answers, 2)
+			assert.Equal(t, "Wants a yard sign.", output.SurveyResponses[1].Answers[1].Value)
+		}
+
+		t.Log("Count the results of the survey for group 1.")
+		{
+			var output downballotapi.GetSurveyResultsResponse
+			err := user1Client.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/survey/"+surveyID+"/result?group_ids="+group1Id, nil, &output)
+			require.NoError(t, err)
+			require.Len(t, output.Groups, 1)
+			assert.Equal(t, group1Id, output.Groups[0].ID)
+			assert.Equal(t, int64(2), output.Groups[0].Responses)
+			require.Len(t, output.Groups[0].Questions, 2)
+
+			// Only Luffy's most recent response is counted.
+			supportResult := output.Groups[0].Questions[0]
+			assert.Equal(t, supportQuestionID, supportResult.QuestionID)
+			assert.Equal(t, int64(2), supportResult.Answers)
+			require.Len(t, supportResult.Choices, 3)
+			assert.Equal(t, []int64{0, 1, 1}, []int64{supportResult.Choices[0].Count, supportResult.Choices[1].Count, supportResult.Choices[2].Count})
+
+			commentResult := output.Groups[0].Questions[1]
+			assert.Equal(t, int64(1), commentResult.Answers)
+			assert.Empty(t, commentResult.Choices)
+
+			var csvOutput restapiclient.RawBytes
+			err = user1Client.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/survey/"+surveyID+"/result?group_ids="+group1Id, nil, &csvOutput, restapiclient.OptionHeader("Accept", "text/csv"))
+			require.NoError(t, err)
+			records, err := csv.NewReader(bytes.NewReader(csvOutput)).ReadAll()
+			require.NoError(t, err)
+			require.Len(t, records, 6)
+			assert.Equal(t, []string{"group_id", "group", "question_id", "question", "answer", "count"}, records[0])
+			assert.Equal(t, []string{group1Id, group1Name, supportQuestionID, supportQuestion.Prompt, "", "2"}, records[1])
+			assert.Equal(t, []string{group1Id, group1Name, supportQuestionID, supportQuestion.Prompt, "no", "1"}, records[4])
+
+			err = user1Client.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/survey/"+surveyID+"/result?group_ids="+group2Id, nil, &output)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+		}
+
+		t.Log("Only the groups in which the user may read the responses are counted.")
+		{
+			var output downballotapi.GetSurveyResultsResponse
+			err := user1Client.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/survey/"+surveyID+"/result", nil, &output)
+			require.NoError(t, err)
+			require.Len(t, output.Groups, 1)
+			assert.Equal(t, group1Id, output.Groups[0].ID)
+
+			// User 2 is only a member of their groups, so they may not read the responses in any of them.
+			err = user2Client.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/survey/"+surveyID+"/result?group_ids="+group1Id, nil, &output)
+			require.ErrorIs(t, err, httperror.ErrStatusForbidden)
+		}
+
+		t.Log("A survey that has responses cannot be changed or deleted.")
+		{
+			err := adminClient.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/survey/"+surveyID, downballotapi.PatchSurveyRequest{
+				Questions: []*downballotapi.SurveyQuestion{commentQuestion},
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusConflict)
+
+			err = adminClient.Do(ctx, http.MethodDelete, "/api/v1/organization/"+organizationId+"/survey/"+surveyID, nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusConflict)
+
+			active := false
+			var output downballotapi.PatchSurveyResponse
+			err = adminClient.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId+"/survey/"+surveyID, downballotapi.PatchSurveyRequest{
+				Active: &active,
+			}, &output)
+			require.NoError(t, err)
+			assert.False(t, output.Survey.Active)
+			assert.Len(t, output.Survey.Questions, 2)
+
+			err = user1Client.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/survey/active", nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusNotFound)
+		}
+
+		t.Log("A field that is used by a survey question cannot be deleted.")
+		{
+			var output downballotapi.ListPersonFieldsResponse
+			err := adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person-field", nil, &output)
+			require.NoError(t, err)
+			personFieldID := ""
+			for _, personField := range output.PersonFields {
+				if personField.Name == "candidate.support" {
+					personFieldID = personField.ID
+				}
+			}
+			require.NotEmpty(t, personFieldID)
+
+			err = adminClient.Do(ctx, http.MethodDelete, "/api/v1/organization/"+organizationId+"/person-field/"+personFieldID, nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusConflict)
+		}
+	}
+
+	t.Log("Register a user who has to verify their e-mail address before they can do anything.")
+	{
+		user3Username := "user3@example.com"
+		user3Id := ""
+
+		{
+			var output downballotapi.RegisterUserResponse
+			err := application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/user", downballotapi.RegisterUserRequest{
+				Name:     "Somebody Else",
+				Username: user3Username,
+			}, &output)
+			require.NoError(t, err)
+			user3Id = output.ID
+		}
+
+		t.Log("An unverified username can be registered again.")
+		{
+			var output downballotapi.RegisterUserResponse
+			err := application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/user", downballotapi.RegisterUserRequest{
+				Name:     "User Three",
+				Username: user3Username,
+			}, &output)
+			require.NoError(t, err)
+			assert.Equal(t, user3Id, output.ID)
+			assert.Equal(t, "User Three", output.Name)
+		}
+
+		t.Log("A verified username cannot be registered again.")
+		{
+			err := application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/user", downballotapi.RegisterUserRequest{
+				Name:     "Imposter",
+				Username: user1Username,
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusConflict)
+		}
+
+		t.Log("An unverified user cannot log in or be added to an organization.")
+		{
+			err := application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/authentication/email", downballotapi.EmailRequest{
+				Email: user3Username,
+			}, nil)
+			require.NoError(t, err)
+			message := mailer.Dummy().LastMessageInInbox(application.Config().SendGridAPIKey, user3Username)
+			require.NotNil(t, message)
+			assert.Equal(t, "Verify your Downballot account", message.Subject)
+
+			err = application.UnauthenticatedClient().Login(ctx, &downballotapi.LoginRequest{
+				Username: user3Username,
+				Password: "123456",
+			})
+			require.ErrorIs(t, err, httperror.ErrStatusUnauthorized)
+
+			err = adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/user", downballotapi.AddUserToOrganizationRequest{
+				Username: user3Username,
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+		}
+
+		t.Log("The verification link can only be used once.")
+		{
+			message := mailer.Dummy().LastMessageInInbox(application.Config().SendGridAPIKey, user3Username)
+			require.NotNil(t, message)
+			matches := linkTokenRegexp.FindStringSubmatch(message.BodyPlainText)
+			require.Len(t, matches, 2)
+
+			err := application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/user/verify", downballotapi.VerifyUserRequest{
+				Token: matches[1],
+			}, nil)
+			require.NoError(t, err)
+
+			err = application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/user/verify", downballotapi.VerifyUserRequest{
+				Token: matches[1],
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+
+			t.Log("The verification link cannot be used as an API token.")
+			client := downballotapi.New(application.URL(), restapiclient.OptionHeader("Authorization", "Bearer "+matches[1]))
+			err = client.Do(ctx, http.MethodGet, "/api/v1/organization", nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusUnauthorized)
+
+			err = adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/user", downballotapi.AddUserToOrganizationRequest{
+				Username: user3Username,
+			}, nil)
+			require.NoError(t, err)
+		}
+	}
+
+	t.Log("Reset user 2's password, which signs them out everywhere.")
+	{
+		err := application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/authentication/reset-password", downballotapi.ResetPasswordRequest{
+			Username: user2Username,
+		}, nil)
+		require.NoError(t, err)
+
+		message := mailer.Dummy().LastMessageInInbox(application.Config().SendGridAPIKey, user2Username)
+		require.NotNil(t, message)
+		require.Equal(t, "Reset your Downballot password", message.Subject)
+		matches := linkTokenRegexp.FindStringSubmatch(message.BodyPlainText)
+		require.Len(t, matches, 2)
+
+		t.Log("A reset link cannot be used to verify a user.")
+		err = application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/user/verify", downballotapi.VerifyUserRequest{
+			Token: matches[1],
+		}, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+
+		var output downballotapi.ResetPasswordConfirmResponse
+		err = application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/authentication/reset-password/confirm", downballotapi.ResetPasswordConfirmRequest{
+			Token: matches[1],
+		}, &output)
+		require.NoError(t, err)
+		assert.Equal(t, user2Username, output.Email)
+
+		err = application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/authentication/reset-password/confirm", downballotapi.ResetPasswordConfirmRequest{
+			Token: matches[1],
+		}, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+
+		err = user2Client.Do(ctx, http.MethodGet, "/api/v1/organization", nil, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusUnauthorized)
+	}
+
+	t.Log("Manage user 1's sessions.")
+	{
+		var loginOutput downballotapi.LoginResponse
+		err := user1Client.Do(ctx, http.MethodPost, "/api/v1/authentication/login", downballotapi.LoginRequest{}, &loginOutput)
+		require.NoError(t, err)
+		require.NotEmpty(t, loginOutput.Token)
+		secondClient := downballotapi.New(application.URL(), restapiclient.OptionHeader("Authorization", "Bearer "+loginOutput.Token))
+
+		var secondSessionID string
+		{
+			var output downballotapi.ListSessionsResponse
+			err = secondClient.Do(ctx, http.MethodGet, "/api/v1/authentication/session", nil, &output)
+			require.NoError(t, err)
+			require.GreaterOrEqual(t, len(output.Sessions), 2)
+			for _, session := range output.Sessions {
+				if session.Current {
+					require.Empty(t, secondSessionID, "Only one session should be current")
+					secondSessionID = session.ID
+				}
+			}
+			require.NotEmpty(t, secondSessionID)
+		}
+
+		t.Log("Revoking a session only signs out that session.")
+		{
+			err = user1Client.Do(ctx, http.MethodDelete, "/api/v1/authentication/session/"+secondSessionID, nil, nil)
+			require.NoError(t, err)
+
+			err = secondClient.Do(ctx, http.MethodGet, "/api/v1/organization", nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusUnauthorized)
+
+			err = user1Client.Do(ctx, http.MethodGet, "/api/v1/organization", nil, nil)
+			require.NoError(t, err)
+
+			err = user1Client.Do(ctx, http.MethodDelete, "/api/v1/authentication/session/"+secondSessionID, nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusNotFound)
+		}
+
+		var user1ID string
+		{
+			var output downballotapi.AuthenticationStatusResponse
+			err = user1Client.Do(ctx, http.MethodGet, "/api/v1/authentication/status", nil, &output)
+			require.NoError(t, err)
+			require.NotNil(t, output.User)
+			user1ID = output.User.ID
+		}
+
+		t.Log("Only a system administrator can log out another user.")
+		{
+			var tokenOutput downballotapi.CreateAPITokenResponse
+			err = user1Client.Do(ctx, http.MethodPost, "/api/v1/authentication/api-token", downballotapi.CreateAPITokenRequest{
+				Name:           "Spreadsheet",
+				OrganizationID: organizationId,
+				Permissions:    []string{"person:read"},
+			}, &tokenOutput)
+			require.NoError(t, err)
+			tokenClient := downballotapi.New(application.URL(), restapiclient.OptionHeader("Authorization", "Bearer "+tokenOutput.Token))
+			err = tokenClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person", nil, nil)
+			require.NoError(t, err)
+
+			err = adminClient.Do(ctx, http.MethodPost, "/api/v1/user/"+user1ID+"/logout", downballotapi.LogoutRequest{}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusForbidden)
+
+			var output downballotapi.LogoutResponse
+			err = masterClient.Do(ctx, http.MethodPost, "/api/v1/user/"+user1ID+"/logout", downballotapi.LogoutRequest{}, &output)
+			require.NoError(t, err)
+			assert.GreaterOrEqual(t, output.RevokedSessions, int64(1))
+
+			err = user1Client.Do(ctx, http.MethodGet, "/api/v1/organization", nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusUnauthorized)
+
+			// The user's API tokens are revoked as well.
+			err = tokenClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person", nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusUnauthorized)
+		}
+	}
+
+	t.Log("Log out user 3 everywhere.")
+	{
+		user3Username := "user3@example.com"
+		user3Client := application.UnauthenticatedClient()
+		err := user3Client.Do(ctx, http.MethodPost, "/api/v1/authentication/email", downballotapi.EmailRequest{
+			Email: user3Username,
+		}, nil)
+		require.NoError(t, err)
+
+		message := mailer.Dummy().LastMessageInInbox(application.Config().SendGridAPIKey, user3Username)
+		require.NotNil(t, message)
+		matches := regexp.MustCompile(`(?m)^\s*(\d{6})`).FindStringSubmatch(message.BodyPlainText)
+		require.Len(t, matches, 2)
+
+		err = user3Client.Login(ctx, &downballotapi.LoginRequest{
+			Username: user3Username,
+			Password: matches[1],
+		})
+		require.NoError(t, err)
+
+		var output downballotapi.LogoutResponse
+		err = user3Client.Do(ctx, http.MethodPost, "/api/v1/authentication/logout", downballotapi.LogoutRequest{}, &output)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), output.RevokedSessions)
+
+		err = user3Client.Do(ctx, http.MethodGet, "/api/v1/organization", nil, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusUnauthorized)
+	}
+
+	t.Log("Lock out a username after too many failed logins.")
+	{
+		username := "locked-out@example.com"
+		for i := 0; i < 5; i++ {
+			err := application.UnauthenticatedClient().Login(ctx, &downballotapi.LoginRequest{
+				Username: username,
+				Password: "000000",
+			})
+			require.ErrorIs(t, err, httperror.ErrStatusUnauthorized, "Attempt %d", i+1)
+		}
+
+		err := application.UnauthenticatedClient().Login(ctx, &downballotapi.LoginRequest{
+			Username: username,
+			Password: "000000",
+		})
+		require.ErrorIs(t, err, httperror.ErrStatusTooManyRequests)
+
+		contents, err := json.Marshal(downballotapi.LoginRequest{
+			Username: username,
+			Password: "000000",
+		})
+		require.NoError(t, err)
+		response, err := http.Post(application.URL()+"/api/v1/authentication/login", "application/json", bytes.NewReader(contents))
+		require.NoError(t, err)
+		defer response.Body.Close()
+		assert.Equal(t, http.StatusTooManyRequests, response.StatusCode)
+		assert.NotEmpty(t, response.Header.Get("Retry-After"))
+	}
+
+	t.Log("Parallel guesses cannot get past the lockout.")
+	{
+		username := "guessed-in-parallel@example.com"
+		errs := make([]error, 10)
+		var wg sync.WaitGroup
+		for i := range errs {
+			wg.Go(func() {
+				errs[i] = application.UnauthenticatedClient().Login(ctx, &downballotapi.LoginRequest{
+					Username: username,
+					Password: "000000",
+				})
+			})
+		}
+		wg.Wait()
+
+		var unauthorizedCount int
+		for i, err := range errs {
+			if errors.Is(err, httperror.ErrStatusUnauthorized) {
+				unauthorizedCount++
+				continue
+			}
+			require.ErrorIs(t, err, httperror.ErrStatusTooManyRequests, "Attempt %d", i+1)
+		}
+		assert.Equal(t, 5, unauthorizedCount)
+	}
+
+	t.Log("Limit the number of e-mails sent to an address.")
+	{
+		email := "flooded@example.com"
+		for i := 0; i < 5; i++ {
+			err := application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/authentication/email", downballotapi.EmailRequest{
+				Email: email,
+			}, nil)
+			require.NoError(t, err, "E-mail %d", i+1)
+		}
+
+		err := application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/authentication/email", downballotapi.EmailRequest{
+			Email: email,
+		}, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusTooManyRequests)
+
+		err = application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/authentication/reset-password", downballotapi.ResetPasswordRequest{
+			Username: email,
+		}, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusTooManyRequests)
+	}
+
+	t.Log("Create a personal API token for an integration.")
+	{
+		t.Log("The permissions must be valid.")
+		{
+			err := adminClient.Do(ctx, http.MethodPost, "/api/v1/authentication/api-token", downballotapi.CreateAPITokenRequest{
+				Name:           "Typo",
+				OrganizationID: organizationId,
+				Permissions:    []string{"person:reed"},
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+		}
+
+		var output downballotapi.CreateAPITokenResponse
+		err := adminClient.Do(ctx, http.MethodPost, "/api/v1/authentication/api-token", downballotapi.CreateAPITokenRequest{
+			Name:           "Dialer",
+			OrganizationID: organizationId,
+			Permissions:    []string{"person:read", "person:update"},
+		}, &output)
+		require.NoError(t, err)
+		require.NotNil(t, output.APIToken)
+		require.NotEmpty(t, output.Token)
+		assert.Equal(t, "Dialer", output.APIToken.Name)
+		assert.Equal(t, organizationId, output.APIToken.OrganizationID)
+		apiTokenID := output.APIToken.ID
+		tokenClient := downballotapi.New(application.URL(), restapiclient.OptionHeader("Authorization", "Bearer "+output.Token))
+
+		t.Log("The token only has the permissions that it was given.")
+		{
+			var personOutput downballotapi.ListPersonsResponse
+			err = tokenClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person", nil, &personOutput)
+			require.NoError(t, err)
+			assert.NotEmpty(t, personOutput.Persons)
+
+			err = tokenClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/group", nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusForbidden)
+		}
+
+		t.Log("The token cannot be used to manage the user's account.")
+		{
+			err = tokenClient.Do(ctx, http.MethodPost, "/api/v1/authentication/login", downballotapi.LoginRequest{}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusForbidden)
+
+			err = tokenClient.Do(ctx, http.MethodPost, "/api/v1/authentication/api-token", downballotapi.CreateAPITokenRequest{
+				Name:           "Escalation",
+				OrganizationID: organizationId,
+				Permissions:    []string{"*"},
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusForbidden)
+		}
+
+		t.Log("List and revoke the token.")
+		{
+			var listOutput downballotapi.ListAPITokensResponse
+			err = adminClient.Do(ctx, http.MethodGet, "/api/v1/authentication/api-token", nil, &listOutput)
+			require.NoError(t, err)
+			require.Len(t, listOutput.APITokens, 1)
+			assert.Equal(t, apiTokenID, listOutput.APITokens[0].ID)
+			assert.Equal(t, []string{"person:read", "person:update"}, listOutput.APITokens[0].Permissions)
+
+			err = adminClient.Do(ctx, http.MethodDelete, "/api/v1/authentication/api-token/"+apiTokenID, nil, nil)
+			require.NoError(t, err)
+
+			err = tokenClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId+"/person", nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusUnauthorized)
+		}
+	}
+
+	t.Log("Log in with the OpenID Connect identity provider.")
+	{
+		// clientNonce is what the client keeps to itself until it finishes logging in.
+		clientNonce := "the-client-nonce-for-this-browser"
+
+		// oidcAuthorize goes through the identity provider as the given identity and returns the code and state that it sends back.
+		oidcAuthorize := func(email string, emailVerified bool) (string, string) {
+			var output downballotapi.OIDCStartResponse
+			err := application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/authentication/oidc/start", downballotapi.OIDCStartRequest{
+				ClientNonce: clientNonce,
+			}, &output)
+			require.NoError(t, err)
+			require.NotEmpty(t, output.AuthorizationURL)
+
+			application.OIDCIssuer().SetIdentity(email, emailVerified)
+			httpClient := &http.Client{
+				CheckRedirect: func(req *http.Request, via []*http.Request) error {
+					return http.ErrUseLastResponse
+				},
+			}
+			response, err := httpClient.Get(output.AuthorizationURL)
+			require.NoError(t, err)
+			defer response.Body.Close()
+			require.Equal(t, http.StatusFound, response.StatusCode)
+
+			location, err := url.Parse(response.Header.Get("Location"))
+			require.NoError(t, err)
+			assert.Equal(t, "/ui/oidc-callback", location.Path)
+			require.NotEmpty(t, location.Query().Get("state"))
+			return location.Query().Get("code"), location.Query().Get("state")
+		}
+
+		code, state := oidcAuthorize(user1Username, true)
+		var output downballotapi.LoginResponse
+		err := application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/authentication/oidc/login", downballotapi.OIDCLoginRequest{
+			Code:        code,
+			State:       state,
+			ClientNonce: clientNonce,
+		}, &output)
+		require.NoError(t, err)
+		assert.Equal(t, user1Username, output.UserID)
+		require.NotEmpty(t, output.Token)
+
+		oidcClient := downballotapi.New(application.URL(), restapiclient.OptionHeader("Authorization", "Bearer "+output.Token))
+		var statusOutput downballotapi.AuthenticationStatusResponse
+		err = oidcClient.Do(ctx, http.MethodGet, "/api/v1/authentication/status", nil, &statusOutput)
+		require.NoError(t, err)
+		require.NotNil(t, statusOutput.User)
+		assert.Equal(t, user1Username, statusOutput.User.Email)
+
+		t.Log("An organization owner must enroll an authenticator app.")
+		code, state = oidcAuthorize(adminUsername, true)
+		err = application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/authentication/oidc/login", downballotapi.OIDCLoginRequest{
+			Code:        code,
+			State:       state,
+			ClientNonce: clientNonce,
+		}, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusUnauthorized)
+		assert.Contains(t, err.Error(), "Authenticator enrollment required")
+
+		t.Log("A code can only be used once.")
+		code, state = oidcAuthorize(user1Username, true)
+		err = application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/authentication/oidc/login", downballotapi.OIDCLoginRequest{
+			Code:        code,
+			State:       state,
+			ClientNonce: clientNonce,
+		}, nil)
+		require.NoError(t, err)
+		err = application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/authentication/oidc/login", downballotapi.OIDCLoginRequest{
+			Code:        code,
+			State:       state,
+			ClientNonce: clientNonce,
+		}, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusUnauthorized)
+
+		t.Log("The client nonce is required to start logging in.")
+		err = application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/authentication/oidc/start", downballotapi.OIDCStartRequest{}, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+
+		t.Log("Only the client that started logging in can finish, so a code and state cannot be passed to someone else.")
+		code, state = oidcAuthorize(user1Username, true)
+		err = application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/authentication/oidc/login", downballotapi.OIDCLoginRequest{
+			Code:        code,
+			State:       state,
+			ClientNonce: "the-client-nonce-for-another-browser",
+		}, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+		err = application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/authentication/oidc/login", downballotapi.OIDCLoginRequest{
+			Code:  code,
+			State: state,
+		}, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+
+		t.Log("The state must be one that we issued.")
+		code, _ = oidcAuthorize(adminUsername, true)
+		err = application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/authentication/oidc/login", downballotapi.OIDCLoginRequest{
+			Code:  code,
+			State: "bogus",
+		}, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+
+		t.Log("The identity provider must have verified the e-mail address.")
+		code, state = oidcAuthorize(adminUsername, false)
+		err = application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/authentication/oidc/login", downballotapi.OIDCLoginRequest{
+			Code:        code,
+			State:       state,
+			ClientNonce: clientNonce,
+		}, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusUnauthorized)
+
+		t.Log("The user must have been verified the usual way.")
+		{
+			err := application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/user", downballotapi.RegisterUserRequest{
+				Name:     "Not Verified",
+				Username: "oidc-unverified@example.com",
+			}, nil)
+			require.NoError(t, err)
+
+			code, state = oidcAuthorize("oidc-unverified@example.com", true)
+			err = application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/authentication/oidc/login", downballotapi.OIDCLoginRequest{
+				Code:        code,
+				State:       state,
+				ClientNonce: clientNonce,
+			}, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusUnauthorized)
+
+			var users []schema.User
+			err = application.DB().Where("username = ?", "oidc-unverified@example.com").Find(&users).Error
+			require.NoError(t, err)
+			require.Len(t, users, 1)
+			assert.False(t, users[0].Verified)
+		}
+
+		t.Log("The e-mail address must belong to a user.")
+		code, state = oidcAuthorize("stranger@example.com", true)
+		err = application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/authentication/oidc/login", downballotapi.OIDCLoginRequest{
+			Code:        code,
+			State:       state,
+			ClientNonce: clientNonce,
+		}, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusUnauthorized)
+	}
+
+	t.Log("Enroll an authenticator app as a second factor for the admin user.")
+	{
+		var enrollOutput downballotapi.EnrollAuthenticatorResponse
+		err := adminClient.Do(ctx, http.MethodPost, "/api/v1/authentication/authenticator", downballotapi.EnrollAuthenticatorRequest{}, &enrollOutput)
+		require.NoError(t, err)
+		require.NotEmpty(t, enrollOutput.Secret)
+		assert.True(t, strings.HasPrefix(enrollOutput.ProvisioningURI, "otpauth://totp/Downballot:"), "Provisioning URI: %s", enrollOutput.ProvisioningURI)
+
+		// appCode returns the code that the authenticator app shows at the given time.
+		// A code can only be used once, so each use has to come from a later time period.
+		secret := enrollOutput.Secret
+		appCode := func(offset time.Duration) string {
+			code, err := totp.GenerateCodeCustom(secret, time.Now().Add(offset), totp.ValidateOpts{
+				Period:    30,
+				Digits:    otp.DigitsSix,
+				Algorithm: otp.AlgorithmSHA1,
+			})
+			require.NoError(t, err)
+			return code
+		}
+
+		err = adminClient.Do(ctx, http.MethodPost, "/api/v1/authentication/email", downballotapi.EmailRequest{
+			Email: adminUsername,
+		}, nil)
+		require.NoError(t, err)
+		message := mailer.Dummy().LastMessageInInbox(application.Config().SendGridAPIKey, adminUsername)
+		require.NotNil(t, message)
+		matches := regexp.MustCompile(`(?m)^\s*(\d{6})`).FindStringSubmatch(message.BodyPlainText)
+		require.Len(t, matches, 2)
+		adminPassword := matches[1]
+
+		t.Log("An organization owner cannot log in until the app has been confirmed.")
+		err = application.UnauthenticatedClient().Login(ctx, &downballotapi.LoginRequest{
+			Username: adminUsername,
+			Password: adminPassword,
+		})
+		require.ErrorIs(t, err, httperror.ErrStatusUnauthorized)
+		assert.Contains(t, err.Error(), "Authenticator enrollment required")
+
+		err = adminClient.Do(ctx, http.MethodPost, "/api/v1/authentication/authenticator/confirm", downballotapi.ConfirmAuthenticatorRequest{
+			Code: "000000",
+		}, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+
+		var confirmOutput downballotapi.RecoveryCodesResponse
+		err = adminClient.Do(ctx, http.MethodPost, "/api/v1/authentication/authenticator/confirm", downballotapi.ConfirmAuthenticatorRequest{
+			Code: appCode(0),
+		}, &confirmOutput)
+		require.NoError(t, err)
+		require.Len(t, confirmOutput.RecoveryCodes, 10)
+
+		err = adminClient.Do(ctx, http.MethodPost, "/api/v1/authentication/authenticator", downballotapi.EnrollAuthenticatorRequest{}, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusConflict)
+
+		var statusOutput downballotapi.AuthenticatorStatusResponse
+		err = adminClient.Do(ctx, http.MethodGet, "/api/v1/authentication/authenticator", nil, &statusOutput)
+		require.NoError(t, err)
+		assert.True(t, statusOutput.Enrolled)
+		assert.True(t, statusOutput.Confirmed)
+		assert.Equal(t, 10, statusOutput.RecoveryCodesRemaining)
+
+		t.Log("The e-mailed password alone is no longer enough.")
+		err = application.UnauthenticatedClient().Login(ctx, &downballotapi.LoginRequest{
+			Username: adminUsername,
+			Password: adminPassword,
+		})
+		require.ErrorIs(t, err, httperror.ErrStatusUnauthorized)
+
+		err = application.UnauthenticatedClient().Login(ctx, &downballotapi.LoginRequest{
+			Username:          adminUsername,
+			Password:          adminPassword,
+			AuthenticatorCode: "000000",
+		})
+		require.ErrorIs(t, err, httperror.ErrStatusUnauthorized)
+
+		err = application.UnauthenticatedClient().Login(ctx, &downballotapi.LoginRequest{
+			Username:          adminUsername,
+			Password:          adminPassword,
+			AuthenticatorCode: appCode(30 * time.Second),
+		})
+		require.NoError(t, err)
+
+		t.Log("A recovery code works once, and the dashes and case don't matter.")
+		recoveryCode := strings.ToUpper(strings.ReplaceAll(confirmOutput.RecoveryCodes[0], "-", ""))
+		err = application.UnauthenticatedClient().Login(ctx, &downballotapi.LoginRequest{
+			Username:          adminUsername,
+			Password:          adminPassword,
+			AuthenticatorCode: recoveryCode,
+		})
+		require.NoError(t, err)
+
+		err = application.UnauthenticatedClient().Login(ctx, &downballotapi.LoginRequest{
+			Username:          adminUsername,
+			Password:          adminPassword,
+			AuthenticatorCode: recoveryCode,
+		})
+		require.ErrorIs(t, err, httperror.ErrStatusUnauthorized)
+
+		t.Log("Replacing the recovery codes requires a code from the app.")
+		err = adminClient.Do(ctx, http.MethodPost, "/api/v1/authentication/authenticator/recovery-codes", downballotapi.RecoveryCodesRequest{
+			Code: confirmOutput.RecoveryCodes[1],
+		}, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+
+		t.Log("Remove the app, which needs a code as well.")
+		err = adminClient.Do(ctx, http.MethodDelete, "/api/v1/authentication/authenticator", nil, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+
+		err = adminClient.Do(ctx, http.MethodDelete, "/api/v1/authentication/authenticator?code="+url.QueryEscape(confirmOutput.RecoveryCodes[1]), nil, nil)
+		require.NoError(t, err)
+
+		err = adminClient.Do(ctx, http.MethodGet, "/api/v1/authentication/authenticator", nil, &statusOutput)
+		require.NoError(t, err)
+		assert.False(t, statusOutput.Enrolled)
+		assert.Equal(t, 0, statusOutput.RecoveryCodesRemaining)
+
+		err = application.UnauthenticatedClient().Login(ctx, &downballotapi.LoginRequest{
+			Username: adminUsername,
+			Password: adminPassword,
+		})
+		require.ErrorIs(t, err, httperror.ErrStatusUnauthorized)
+
+		t.Log("An organization owner can enroll an app while logging in.")
+		key, err := totp.Generate(totp.GenerateOpts{
+			Issuer:      "Downballot",
+			AccountName: adminUsername,
+		})
+		require.NoError(t, err)
+		secret = key.Secret()
+
+		err = application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/authentication/login", downballotapi.LoginRequest{
+			Username:            adminUsername,
+			Password:            adminPassword,
+			AuthenticatorSecret: "too-short",
+			AuthenticatorCode:   appCode(0),
+		}, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+
+		err = application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/authentication/login", downballotapi.LoginRequest{
+			Username:            adminUsername,
+			Password:            adminPassword,
+			AuthenticatorSecret: key.Secret(),
+			AuthenticatorCode:   "000000",
+		}, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusUnauthorized)
+
+		var loginOutput downballotapi.LoginResponse
+		err = application.UnauthenticatedClient().Do(ctx, http.MethodPost, "/api/v1/authentication/login", downballotapi.LoginRequest{
+			Username:            adminUsername,
+			Password:            adminPassword,
+			AuthenticatorSecret: key.Secret(),
+			AuthenticatorCode:   appCode(0),
+		}, &loginOutput)
+		require.NoError(t, err)
+		require.NotEmpty(t, loginOutput.Token)
+		require.Len(t, loginOutput.RecoveryCodes, 10)
+
+		err = application.UnauthenticatedClient().Login(ctx, &downballotapi.LoginRequest{
+			Username: adminUsername,
+			Password: adminPassword,
+		})
+		require.ErrorIs(t, err, httperror.ErrStatusUnauthorized)
+		assert.Contains(t, err.Error(), "Authenticator code required")
+
+		err = application.UnauthenticatedClient().Login(ctx, &downballotapi.LoginRequest{
+			Username:          adminUsername,
+			Password:          adminPassword,
+			AuthenticatorCode: appCode(30 * time.Second),
+		})
+		require.NoError(t, err)
+
+		t.Log("Guessing codes with a token locks the user out, even once the right code is given.")
+		for range 5 {
+			err = adminClient.Do(ctx, http.MethodDelete, "/api/v1/authentication/authenticator?code=000000", nil, nil)
+			require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+		}
+		err = adminClient.Do(ctx, http.MethodPost, "/api/v1/authentication/authenticator/recovery-codes", downballotapi.RecoveryCodesRequest{
+			Code: appCode(60 * time.Second),
+		}, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusTooManyRequests)
+		err = adminClient.Do(ctx, http.MethodDelete, "/api/v1/authentication/authenticator?code="+url.QueryEscape(loginOutput.RecoveryCodes[0]), nil, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusTooManyRequests)
+	}
+
+	t.Log("Publish the public JWT keys.")
+	{
+		response, err := http.Get(application.URL() + "/.well-known/jwks.json")
+		require.NoError(t, err)
+		defer response.Body.Close()
+		require.Equal(t, http.StatusOK, response.StatusCode)
+
+		var jwks apitoken.JSONWebKeySet
+		err = json.NewDecoder(response.Body).Decode(&jwks)
+		require.NoError(t, err)
+		require.Len(t, jwks.Keys, 1, "The retired secret must not be published")
+		assert.Equal(t, "active", jwks.Keys[0].KeyID)
+		assert.Equal(t, "EC", jwks.Keys[0].KeyType)
+		assert.Equal(t, "ES256", jwks.Keys[0].Algorithm)
+	}
+
+	t.Log("Tokens signed with a retired key keep working, but unsigned tokens and unknown keys do not.")
+	{
+		var adminUser schema.User
+		err := application.DB().Where("username = ?", adminUsername).First(&adminUser).Error
+		require.NoError(t, err)
+		claims := apitoken.TokenClaims{
+			StandardClaims: jwt.StandardClaims{
+				Subject: adminUsername,
+			},
+			SessionIdentifier: adminUser.SessionIdentifier,
+		}
+
+		// signedClient returns a client whose token was signed with the given key.
+		signedClient := func(keyID string, secret string) *downballotapi.Client {
+			keyring := apitoken.NewKeyring(false)
+			require.NoError(t, keyring.AddSecret(keyID, []byte(secret)))
+			require.NoError(t, keyring.SetSigningKey(keyID))
+			token, err := keyring.Sign(claims)
+			require.NoError(t, err)
+			return downballotapi.New(application.URL(), restapiclient.OptionHeader("Authorization", "Bearer "+token))
+		}
+
+		err = signedClient("retired", "my-retired-jwt-secret").Do(ctx, http.MethodGet, "/api/v1/organization", nil, nil)
+		require.NoError(t, err)
+
+		err = signedClient("retired", "the-wrong-secret").Do(ctx, http.MethodGet, "/api/v1/organization", nil, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusUnauthorized)
+
+		err = signedClient("unknown", "my-retired-jwt-secret").Do(ctx, http.MethodGet, "/api/v1/organization", nil, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusUnauthorized)
+
+		unsignedToken, err := apitoken.NewKeyring(true).Sign(claims)
+		require.NoError(t, err)
+		unsignedClient := downballotapi.New(application.URL(), restapiclient.OptionHeader("Authorization", "Bearer "+unsignedToken))
+		err = unsignedClient.Do(ctx, http.MethodGet, "/api/v1/organization", nil, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusUnauthorized)
+	}
+
+	t.Log("Rename the organization as the admin user.")
+	{
+		name := "Renamed Campaign"
+		var output downballotapi.PatchOrganizationResponse
+		err := adminClient.Do(ctx, http.MethodPatch, "/api/v1/organization/"+organizationId, downballotapi.PatchOrganizationRequest{
+			Name: &name,
+		}, &output)
+		require.NoError(t, err)
+		assert.Equal(t, organizationId, output.Organization.ID)
+		assert.Equal(t, name, output.Organization.Name)
+
+		var getOutput downballotapi.GetOrganizationResponse
+		err = adminClient.Do(ctx, http.MethodGet, "/api/v1/organization/"+organizationId, nil, &getOutput)
+		require.NoError(t, err)
+		assert.Equal(t, name, getOutput.Organization.Name)
+	}
+
+	t.Log("Deleting the organization without archiving it first fails.")
+	{
+		err := adminClient.Do(ctx, http.MethodDelete, "/api/v1/organization/"+organizationId, nil, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+
+		err = adminClient.Do(ctx, http.MethodDelete, "/api/v1/organization/"+organizationId+"?confirmation_token=bogus", nil, nil)
+		require.ErrorIs(t, err, httperror.ErrStatusBadRequest)
+	}
+
+	t.Log("Archive the organization as a zip file as the admin user.")
+	{
+		var output restapiclient.RawBytes
+		err := adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/archive", &downballotapi.ArchiveOrganizationRequest{Format: "zip"}, &output, restapiclient.OptionHeader("Accept", "application/zip"))
+		require.NoError(t, err)
+
+		zipReader, err := zip.NewReader(bytes.NewReader(output), int64(len(output)))
+		require.NoError(t, err)
+		require.Len(t, zipReader.File, 1)
+		file, err := zipReader.File[0].Open()
+		require.NoError(t, err)
+		defer file.Close()
+
+		var archive downballotapi.OrganizationArchive
+		err = json.NewDecoder(file).Decode(&archive)
+		require.NoError(t, err)
+		assert.NotEmpty(t, archive.ConfirmationToken)
+		assert.Equal(t, "Renamed Campaign", archive.Organization.Name)
+		assert.Len(t, archive.Persons, 12)
+	}
+
+	t.Log("Archive the organization as JSON and then delete it as the admin user.")
+	{
+		var output restapiclient.RawBytes
+		err := adminClient.Do(ctx, http.MethodPost, "/api/v1/organization/"+organizationId+"/archive", &downballotapi.ArchiveOrganizationRequest{}, &output)
+		require.NoError(t, err)
+
+		var archive downballotapi.OrganizationArchive
+		err = json.Unmarshal(output, &archive)
+		require.NoError(t, err)
+		require.NotEmpty(t, archive.ConfirmationToken)
+		assert.Equal(t, organizationId, archive.Organization.ID)
+		assert.NotEmpty(t, archive.PersonFields)
+		assert.Len(t, archive.Persons, 12)
+		assert.NotEmpty(t, archive.Audits)
+		assert.NotEmpty(t, archive.Groups)
+		assert.NotEmpty(t, archive.Users)
+		assert.NotEmpty(t, archive.GroupUsers)
+		assert.Len(t, archive.ContactResults, 2)
+		assert.Len(t, archive.ContactAttempts, 2)
+		assert.NotEmpty(t, archive.Households)
+		assert.NotEmpty(t, archive.Turfs)
+		for _, person := range archive.Persons {
+			if person.VoterID == "2001" {
+				assert.Equal(t, "Zoro", person.Fields["name_first"])
+			}
+		}
+
+		err = adminClient.Do(ctx, http.MethodDelete, "/api/v1/organization/"+organizationId+"?confirmation_token="+url.QueryEscape(archive.ConfirmationToken), nil, nil)
+		require.NoError(t, err)
+
+		var listOutput downballotapi.ListOrganizationsResponse
+		err = masterClient.Do(ctx, http.MethodGet, "/api/v1/organization", nil, &listOutput)
+		require.NoError(t, err)
+		for _, organization := range listOutput.Organizations {
+			assert.NotEqual(t, organizationId, organization.ID)
+		}
+	}
 }
@@ -5,14 +5,15 @@ type ClauseCondition struct {
 	Name      string   // This is the field name.
 	Operation string   // This is the operation.
 	Values    []string // This is the list of values that could match; essentially, this an "IN" operation.
+	Function  string   // If set, then the values are the arguments to this function, such as "box" in "within box(1, 2, 3, 4)".
 }
 
 var _ Clause = (*ClauseCondition)(nil)
 
 // String returns the canonical form of the clause.
 func (c ClauseCondition) String() string {
-	output := QuoteIfNecessary(c.Name) + " " + c.Operation + " "
-	if len(c.Values) == 1 {
+	output := QuoteIfNecessary(c.Name) + " " + c.Operation + " " + c.Function
+	if len(c.Values) == 1 && c.Function == "" {
 		output += QuoteIfNecessary(c.Values[0])
 	} else {
 		output += "("
@@ -0,0 +1,22 @@
+package filter
+
+// ClauseFunction is a predicate that is called like a function, such as "within_turf(downtown)".
+type ClauseFunction struct {
+	Name      string   // This is the function name.
+	Arguments []string // These are the arguments.
+}
+
+var _ Clause = (*ClauseFunction)(nil)
+
+// String returns the canonical form of the clause.
+func (c ClauseFunction) String() string {
+	output := c.Name + "("
+	for argumentIndex, argument := range c.Arguments {
+		if argumentIndex > 0 {
+			output += ", "
+		}
+		output += QuoteIfNecessary(argument)
+	}
+	output += ")"
+	return output
+}
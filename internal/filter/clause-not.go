@@ -0,0 +1,27 @@
+package filter
+
+// ClauseNot negates another clause.
+type ClauseNot struct {
+	Clause Clause // This is the clause being negated.
+}
+
+var _ Clause = (*ClauseNot)(nil)
+
+// String returns the canonical form of the clause.
+//
+// The negated clause is always wrapped in parentheses so that the scope of the NOT is unambiguous.
+func (c ClauseNot) String() string {
+	inner := c.Clause
+	for {
+		group, ok := inner.(*ClauseGroup)
+		if !ok || len(group.Clauses) != 1 {
+			break
+		}
+		inner = group.Clauses[0]
+	}
+	if group, ok := inner.(*ClauseGroup); ok && len(group.Clauses) > 1 {
+		// Groups with multiple clauses already wrap themselves in parentheses.
+		return "NOT " + group.String()
+	}
+	return "NOT (" + inner.String() + ")"
+}
@@ -22,7 +22,12 @@ var _ Clause = (*ClauseGroup)(nil)
 func (c ClauseGroup) String() string {
 	var parts []string
 	for _, clause := range c.Clauses {
-		parts = append(parts, clause.String())
+		part := clause.String()
+		if part == "" {
+			// Empty groups do not contribute anything.
+			continue
+		}
+		parts = append(parts, part)
 	}
 	if len(parts) == 0 {
 		return ""
@@ -0,0 +1,47 @@
+package filter
+
+import (
+	"slices"
+)
+
+// Fields returns the names of the fields that the clause refers to, sorted and without duplicates.
+func Fields(clause Clause) []string {
+	var output []string
+	walkFieldNames(clause, func(name *string) {
+		output = append(output, *name)
+	})
+	slices.Sort(output)
+	return slices.Compact(output)
+}
+
+// RenameField renames every reference to a field within the clause.
+//
+// This returns true if any references were renamed.
+func RenameField(clause Clause, oldName string, newName string) bool {
+	renamed := false
+	walkFieldNames(clause, func(name *string) {
+		if *name == oldName {
+			*name = newName
+			renamed = true
+		}
+	})
+	return renamed
+}
+
+// walkFieldNames calls the function with a pointer to every field name within the clause.
+func walkFieldNames(clause Clause, f func(name *string)) {
+	switch typedClause := clause.(type) {
+	case *ClauseCondition:
+		f(&typedClause.Name)
+	case *ClauseIsNull:
+		f(&typedClause.Name)
+	case *ClauseIsNotNull:
+		f(&typedClause.Name)
+	case *ClauseGroup:
+		for _, groupClause := range typedClause.Clauses {
+			walkFieldNames(groupClause, f)
+		}
+	case *ClauseNot:
+		walkFieldNames(typedClause.Clause, f)
+	}
+}
@@ -0,0 +1,89 @@
+package filter
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFields(t *testing.T) {
+	ctx := context.Background()
+
+	rows := []struct {
+		description string
+		query       string
+		fields      []string
+	}{
+		{
+			description: "Empty",
+			query:       "",
+			fields:      nil,
+		},
+		{
+			description: "Simple condition",
+			query:       "key1 = value1",
+			fields:      []string{"key1"},
+		},
+		{
+			description: "Everything",
+			query:       "key2 = value1 OR (key1 IS NULL AND NOT (key3 IS NOT NULL OR key2 ~ key4))",
+			fields:      []string{"key1", "key2", "key3"},
+		},
+	}
+	for rowIndex, row := range rows {
+		t.Run(fmt.Sprintf("%d/%s", rowIndex, row.description), func(t *testing.T) {
+			clause, err := Parse(ctx, row.query)
+			require.Nil(t, err, "err is not nil")
+
+			assert.Equal(t, row.fields, Fields(clause))
+		})
+	}
+}
+
+func TestRenameField(t *testing.T) {
+	ctx := context.Background()
+
+	rows := []struct {
+		description string
+		query       string
+		renamed     bool
+		canonical   string
+	}{
+		{
+			description: "Empty",
+			query:       "",
+			renamed:     false,
+			canonical:   "",
+		},
+		{
+			description: "Not referenced",
+			query:       "key2 = key1",
+			renamed:     false,
+			canonical:   "key2 = key1",
+		},
+		{
+			description: "Simple condition",
+			query:       "key1 = value1",
+			renamed:     true,
+			canonical:   "'new key' = value1",
+		},
+		{
+			description: "Everything",
+			query:       "key1 = value1 OR (key1 IS NULL AND NOT (key1 IS NOT NULL OR key2 ~ key1))",
+			renamed:     true,
+			canonical:   "('new key' = value1 OR ('new key' IS NULL AND NOT ('new key' IS NOT NULL OR key2 ~ key1)))",
+		},
+	}
+	for rowIndex, row := range rows {
+		t.Run(fmt.Sprintf("%d/%s", rowIndex, row.description), func(t *testing.T) {
+			clause, err := Parse(ctx, row.query)
+			require.Nil(t, err, "err is not nil")
+
+			assert.Equal(t, row.renamed, RenameField(clause, "key1", "new key"))
+			assert.Equal(t, row.canonical, clause.String(), "canonical is incorrect")
+		})
+	}
+}
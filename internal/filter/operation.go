@@ -9,9 +9,11 @@ const (
 	OperationIsNot              string = "is not"
 	OperationLessThan           string = "<"
 	OperationLessThanOrEqual    string = "<="
+	OperationNear               string = "near"
 	OperationNotEquals          string = "!="
 	OperationWildcard           string = "~"
 	OperationNotWildcard        string = "!~"
+	OperationWithin             string = "within"
 )
 
 // ValidOperationMap is a map of valid operations.
@@ -23,7 +25,9 @@ var ValidOperationMap = map[string]bool{
 	OperationIsNot:              false, // This must not be specified directly.
 	OperationLessThan:           true,
 	OperationLessThanOrEqual:    true,
+	OperationNear:               true,
 	OperationNotEquals:          true,
 	OperationWildcard:           true,
 	OperationNotWildcard:        true,
+	OperationWithin:             true,
 }
@@ -59,6 +59,76 @@ func readParentheticalGroup(tokens *[]*Token) ([]*Token, error) {
 	return group, nil
 }
 
+// readValueList reads the values of a comma-separated parenthetical group.
+//
+// A value may be a signed number, such as "-116.20", which the tokenizer splits into a symbol and a number.
+func readValueList(group []*Token) ([]string, error) {
+	group = joinSigns(group)
+
+	var output []string
+	for groupIndex, groupToken := range group {
+		if groupIndex%2 == 0 {
+			if groupToken.Quote == "" && groupToken.Value == "," {
+				return nil, fmt.Errorf("unexpected comma in parenthetical group")
+			}
+			output = append(output, groupToken.Value)
+		} else {
+			if groupToken.Quote != "" || groupToken.Value != "," {
+				return nil, fmt.Errorf("expected comma in position %d in parenthetical group", groupIndex+1)
+			}
+		}
+	}
+	return output, nil
+}
+
+// joinSigns joins each unquoted "-" or "+" with the number that follows it.
+func joinSigns(tokens []*Token) []*Token {
+	var output []*Token
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if token.Quote == "" && token.Symbol && (token.Value == "-" || token.Value == "+") && i+1 < len(tokens) {
+			next := tokens[i+1]
+			if next.Quote == "" && !next.Symbol && next.Value != "" && (next.Value[0] == '.' || (next.Value[0] >= '0' && next.Value[0] <= '9')) {
+				output = append(output, &Token{Value: token.Value + next.Value})
+				i++
+				continue
+			}
+		}
+		output = append(output, token)
+	}
+	return output
+}
+
+// isNotKeyword returns true if the token is a NOT keyword (either "NOT" or "!").
+//
+// A field may also be named "not", so the token is only treated as a keyword when
+// it is not followed by an operation.
+func isNotKeyword(token *Token, remaining []*Token) bool {
+	if token.Quote != "" {
+		return false
+	}
+	if token.Value != "!" && strings.ToLower(token.Value) != "not" {
+		return false
+	}
+	if len(remaining) > 0 && remaining[0].Quote == "" && ValidOperationMap[strings.ToLower(remaining[0].Value)] {
+		return false
+	}
+	return true
+}
+
+// negate wraps the clause in the given number of NOT clauses.
+func negate(clause Clause, count int) Clause {
+	for i := 0; i < count; i++ {
+		clause = &ClauseNot{
+			Clause: clause,
+		}
+	}
+	return clause
+}
+
+// ParseTokens parses a list of tokens and returns a Clause.
+//
+// In order of precedence (from tightest to loosest), the operators are NOT, AND, and OR.
 func ParseTokens(tokens []*Token) (Clause, error) {
 	output := &ClauseGroup{
 		Operation: ClauseGroupOperationOr,
@@ -94,6 +164,9 @@ func ParseTokens(tokens []*Token) (Clause, error) {
 				return nil, fmt.Errorf("extra leading AND")
 			}
 
+			if len(tokens) == 0 {
+				return nil, fmt.Errorf("missing clause after AND")
+			}
 			token = tokens[0]
 			tokens = tokens[1:]
 		} else if len(andGroup.Clauses) > 0 {
@@ -102,6 +175,17 @@ func ParseTokens(tokens []*Token) (Clause, error) {
 			}
 		}
 
+		// NOT binds more tightly than AND, so it applies only to the very next condition or parenthetical group.
+		negations := 0
+		for isNotKeyword(token, tokens) {
+			if len(tokens) == 0 {
+				return nil, fmt.Errorf("missing clause after NOT")
+			}
+			negations++
+			token = tokens[0]
+			tokens = tokens[1:]
+		}
+
 		if token.Quote == "" && token.Value == "(" {
 			group, err := readParentheticalGroup(&tokens)
 			if err != nil {
@@ -113,12 +197,34 @@ func ParseTokens(tokens []*Token) (Clause, error) {
 			if err != nil {
 				return nil, err
 			}
-			andGroup.Clauses = append(andGroup.Clauses, clause)
+			if negations > 0 && len(group) == 0 {
+				return nil, fmt.Errorf("missing clause after NOT")
+			}
+			andGroup.Clauses = append(andGroup.Clauses, negate(clause, negations))
 			continue
 		}
 
 		fieldName := token.Value
 
+		// A name that is followed by a parenthetical group is a function, such as "within_turf(downtown)".
+		if token.Quote == "" && len(tokens) > 0 && tokens[0].Quote == "" && tokens[0].Value == "(" {
+			tokens = tokens[1:]
+			group, err := readParentheticalGroup(&tokens)
+			if err != nil {
+				return nil, err
+			}
+			arguments, err := readValueList(group)
+			if err != nil {
+				return nil, err
+			}
+			clause := &ClauseFunction{
+				Name:      strings.ToLower(fieldName),
+				Arguments: arguments,
+			}
+			andGroup.Clauses = append(andGroup.Clauses, negate(clause, negations))
+			continue
+		}
+
 		if len(tokens) == 0 {
 			return nil, fmt.Errorf("missing operation")
 		}
@@ -168,25 +274,22 @@ func ParseTokens(tokens []*Token) (Clause, error) {
 				Operation: operation,
 			}
 
+			// A value that is followed by a parenthetical group is a function, such as "box(1, 2, 3, 4)".
+			if token.Quote == "" && token.Value != "(" && len(tokens) > 0 && tokens[0].Quote == "" && tokens[0].Value == "(" {
+				newClause.Function = strings.ToLower(token.Value)
+				token = tokens[0]
+				tokens = tokens[1:]
+			}
+
 			if token.Quote == "" && token.Value == "(" {
 				group, err := readParentheticalGroup(&tokens)
 				if err != nil {
 					return nil, err
 				}
 
-				// Ensure that the group is comma-separated.
-				for groupIndex, groupToken := range group {
-					fmt.Printf("groupIndex: %d, groupToken: %s\n", groupIndex, groupToken.String())
-					if groupIndex%2 == 0 {
-						if groupToken.Quote == "" && groupToken.Value == "," {
-							return nil, fmt.Errorf("unexpected comma in parenthetical group")
-						}
-						newClause.Values = append(newClause.Values, groupToken.Value)
-					} else {
-						if groupToken.Quote != "" || groupToken.Value != "," {
-							return nil, fmt.Errorf("expected comma in position %d in parenthetical group", groupIndex+1)
-						}
-					}
+				newClause.Values, err = readValueList(group)
+				if err != nil {
+					return nil, err
 				}
 			} else {
 				newClause.Values = []string{token.Value}
@@ -195,7 +298,7 @@ func ParseTokens(tokens []*Token) (Clause, error) {
 			clause = newClause
 		}
 
-		andGroup.Clauses = append(andGroup.Clauses, clause)
+		andGroup.Clauses = append(andGroup.Clauses, negate(clause, negations))
 	}
 	if andGroup != nil && len(andGroup.Clauses) > 0 {
 		output.Clauses = append(output.Clauses, andGroup)
@@ -178,6 +178,154 @@ func TestParseQuery(t *testing.T) {
 			success:     true,
 			canonical:   "key1 ~ ('*1', '*2')",
 		},
+		{
+			description: "not condition",
+			query:       "NOT key1 = value1",
+			success:     true,
+			canonical:   "NOT (key1 = value1)",
+		},
+		{
+			description: "not condition with symbol",
+			query:       "!key1 = value1",
+			success:     true,
+			canonical:   "NOT (key1 = value1)",
+		},
+		{
+			description: "not group",
+			query:       "not (county = Ada AND party = Navy)",
+			success:     true,
+			canonical:   "NOT (county = Ada AND party = Navy)",
+		},
+		{
+			description: "not group with symbol",
+			query:       "!(county = Ada OR party = Navy)",
+			success:     true,
+			canonical:   "NOT (county = Ada OR party = Navy)",
+		},
+		{
+			description: "not binds tighter than and",
+			query:       "NOT key1 = value1 AND key2 = value2",
+			success:     true,
+			canonical:   "(NOT (key1 = value1) AND key2 = value2)",
+		},
+		{
+			description: "not binds tighter than or",
+			query:       "key1 = value1 OR NOT key2 = value2 AND key3 = value3",
+			success:     true,
+			canonical:   "(key1 = value1 OR (NOT (key2 = value2) AND key3 = value3))",
+		},
+		{
+			description: "not after and",
+			query:       "key1 = value1 AND NOT (key2 = value2 OR key3 = value3)",
+			success:     true,
+			canonical:   "(key1 = value1 AND NOT (key2 = value2 OR key3 = value3))",
+		},
+		{
+			description: "double not",
+			query:       "NOT NOT key1 = value1",
+			success:     true,
+			canonical:   "NOT (NOT (key1 = value1))",
+		},
+		{
+			description: "not with nested single group",
+			query:       "NOT ((key1 = value1))",
+			success:     true,
+			canonical:   "NOT (key1 = value1)",
+		},
+		{
+			description: "field named not",
+			query:       "not = value1",
+			success:     true,
+			canonical:   "not = value1",
+		},
+		{
+			description: "quoted not is a field",
+			query:       "'not' is null",
+			success:     true,
+			canonical:   "not IS NULL",
+		},
+		{
+			description: "trailing not",
+			query:       "key1 = value1 AND NOT",
+			success:     false,
+		},
+		{
+			description: "not with empty group",
+			query:       "NOT ()",
+			success:     false,
+		},
+		{
+			description: "not without and",
+			query:       "key1 = value1 NOT key2 = value2",
+			success:     false,
+		},
+		{
+			description: "trailing and",
+			query:       "key1 = value1 AND",
+			success:     false,
+		},
+		{
+			description: "function",
+			query:       "Within_Turf(downtown)",
+			success:     true,
+			canonical:   "within_turf(downtown)",
+		},
+		{
+			description: "function with quoted arguments",
+			query:       "f('a b', c)",
+			success:     true,
+			canonical:   "f('a b', c)",
+		},
+		{
+			description: "negated function with a condition",
+			query:       "NOT within_turf(downtown) AND key1 = value1",
+			success:     true,
+			canonical:   "(NOT (within_turf(downtown)) AND key1 = value1)",
+		},
+		{
+			description: "function with a bad argument list",
+			query:       "within_turf(a b)",
+			success:     false,
+		},
+		{
+			description: "unterminated function",
+			query:       "within_turf(downtown",
+			success:     false,
+		},
+		{
+			description: "near",
+			query:       "coordinates NEAR (43.61,-116.20, 2km)",
+			success:     true,
+			canonical:   "coordinates near (43.61, '-116.20', 2km)",
+		},
+		{
+			description: "signed numbers",
+			query:       "key1 = (-1, +2.5, -.5)",
+			success:     true,
+			canonical:   "key1 = ('-1', '+2.5', '-.5')",
+		},
+		{
+			description: "sign without a number",
+			query:       "key1 = (1, -x)",
+			success:     false,
+		},
+		{
+			description: "within a box",
+			query:       "coordinates within Box(43.60,-116.21, 43.62,-116.19)",
+			success:     true,
+			canonical:   "coordinates within box(43.60, '-116.21', 43.62, '-116.19')",
+		},
+		{
+			description: "within a box with one value",
+			query:       "coordinates within box(1)",
+			success:     true,
+			canonical:   "coordinates within box(1)",
+		},
+		{
+			description: "within an unterminated box",
+			query:       "coordinates within box(1, 2",
+			success:     false,
+		},
 	}
 	for rowIndex, row := range rows {
 		t.Run(fmt.Sprintf("%d/%s", rowIndex, row.description), func(t *testing.T) {
@@ -189,6 +189,24 @@ func TestTokenize(t *testing.T) {
 			success:     true,
 			tokens:      []string{"1", "!=", "2"},
 		},
+		{
+			description: "not keyword",
+			input:       "NOT (a = b)",
+			success:     true,
+			tokens:      []string{"NOT", "(", "a", "=", "b", ")"},
+		},
+		{
+			description: "not symbol",
+			input:       "!(a = b)",
+			success:     true,
+			tokens:      []string{"!", "(", "a", "=", "b", ")"},
+		},
+		{
+			description: "not symbol before field",
+			input:       "!a = b",
+			success:     true,
+			tokens:      []string{"!", "a", "=", "b"},
+		},
 		{
 			description: "greater than",
 			input:       "1 > 2",
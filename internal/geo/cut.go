@@ -0,0 +1,153 @@
+package geo
+
+import (
+	"math"
+	"slices"
+)
+
+// cutMargin is how far (in degrees; about 50 meters) the outermost cells reach beyond the outermost points.
+const cutMargin = 0.0005
+
+// Cell is one of the areas that a set of points was cut into.
+type Cell struct {
+	Box     Box   // This is the area of the cell.
+	Indexes []int // These are the indexes of the points inside of the cell.
+}
+
+// axis is a direction in which a set of points can be split.
+type axis int
+
+const (
+	axisLatitude axis = iota
+	axisLongitude
+)
+
+// value returns the point's position along the axis.
+func (a axis) value(p Point) float64 {
+	if a == axisLongitude {
+		return p.Longitude
+	}
+	return p.Latitude
+}
+
+// Cut divides the points into (at most) the given number of cells, each with roughly the same number of points.
+//
+// The points are split again and again (in proportion to the number of cells on each side) across whichever direction
+// they are spread out the most.  The cells never overlap, and no point is on the edge of a cell.  Identical points
+// always stay together, so there may be fewer cells than asked for.
+func Cut(points []Point, count int) []Cell {
+	if len(points) == 0 || count <= 0 {
+		return nil
+	}
+
+	indexes := make([]int, len(points))
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	box := Box{
+		MinLatitude:  math.Inf(1),
+		MinLongitude: math.Inf(1),
+		MaxLatitude:  math.Inf(-1),
+		MaxLongitude: math.Inf(-1),
+	}
+	for _, point := range points {
+		box.MinLatitude = math.Min(box.MinLatitude, point.Latitude)
+		box.MinLongitude = math.Min(box.MinLongitude, point.Longitude)
+		box.MaxLatitude = math.Max(box.MaxLatitude, point.Latitude)
+		box.MaxLongitude = math.Max(box.MaxLongitude, point.Longitude)
+	}
+	box.MinLatitude = math.Max(-90, box.MinLatitude-cutMargin)
+	box.MinLongitude = math.Max(-180, box.MinLongitude-cutMargin)
+	box.MaxLatitude = math.Min(90, box.MaxLatitude+cutMargin)
+	box.MaxLongitude = math.Min(180, box.MaxLongitude+cutMargin)
+
+	return cut(points, indexes, box, count)
+}
+
+// cut divides the given points (which are all inside of the box) into the given number of cells.
+func cut(points []Point, indexes []int, box Box, count int) []Cell {
+	if count <= 1 || len(indexes) <= 1 {
+		return []Cell{{Box: box, Indexes: indexes}}
+	}
+
+	// Try the direction in which the points are spread out the most first.
+	// A degree of longitude gets shorter away from the equator.
+	axes := []axis{axisLatitude, axisLongitude}
+	scale := math.Cos((box.MinLatitude + box.MaxLatitude) / 2 * math.Pi / 180)
+	if spread(points, indexes, axisLongitude)*scale > spread(points, indexes, axisLatitude) {
+		axes = []axis{axisLongitude, axisLatitude}
+	}
+
+	leftCount := count / 2
+	for _, a := range axes {
+		sorted := slices.Clone(indexes)
+		slices.SortStableFunc(sorted, func(left, right int) int {
+			return compareFloat(a.value(points[left]), a.value(points[right]))
+		})
+
+		split := splitIndex(points, sorted, a, int(math.Round(float64(len(sorted))*float64(leftCount)/float64(count))))
+		if split < 0 {
+			continue
+		}
+
+		value := (a.value(points[sorted[split-1]]) + a.value(points[sorted[split]])) / 2
+		leftBox := box
+		rightBox := box
+		switch a {
+		case axisLatitude:
+			leftBox.MaxLatitude = value
+			rightBox.MinLatitude = value
+		case axisLongitude:
+			leftBox.MaxLongitude = value
+			rightBox.MinLongitude = value
+		}
+
+		output := cut(points, sorted[:split], leftBox, leftCount)
+		output = append(output, cut(points, sorted[split:], rightBox, count-leftCount)...)
+		return output
+	}
+
+	// Every point is in the same place, so they can't be split up.
+	return []Cell{{Box: box, Indexes: indexes}}
+}
+
+// spread returns the distance (in degrees) between the smallest and largest values along the axis.
+func spread(points []Point, indexes []int, a axis) float64 {
+	minimum := math.Inf(1)
+	maximum := math.Inf(-1)
+	for _, index := range indexes {
+		minimum = math.Min(minimum, a.value(points[index]))
+		maximum = math.Max(maximum, a.value(points[index]))
+	}
+	return maximum - minimum
+}
+
+// splitIndex returns the index closest to the target at which the sorted points can be split, or -1 if there is none.
+//
+// The points can only be split between two different values, so that the line between them doesn't touch any point.
+func splitIndex(points []Point, sorted []int, a axis, target int) int {
+	target = max(1, min(len(sorted)-1, target))
+	for offset := 0; offset < len(sorted); offset++ {
+		for _, index := range []int{target - offset, target + offset} {
+			if index < 1 || index >= len(sorted) {
+				continue
+			}
+			if a.value(points[sorted[index-1]]) < a.value(points[sorted[index]]) {
+				return index
+			}
+		}
+	}
+	return -1
+}
+
+// compareFloat compares two numbers, for sorting.
+func compareFloat(left float64, right float64) int {
+	switch {
+	case left < right:
+		return -1
+	case left > right:
+		return 1
+	}
+	return 0
+}
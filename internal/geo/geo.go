@@ -0,0 +1,140 @@
+// Package geo has the geometry that the walk lists need: distances, areas (turfs), and routes.
+//
+// Everything here works on latitude and longitude directly, which is accurate enough at the scale of a neighborhood.
+package geo
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// EarthRadius is the mean radius of the Earth, in meters.
+const EarthRadius = 6371008.8
+
+// Point is a location on the Earth.
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// ParsePoint parses coordinates of the form "latitude,longitude", such as "43.61,-116.20".
+func ParsePoint(input string) (Point, error) {
+	latitudeString, longitudeString, ok := strings.Cut(input, ",")
+	if !ok {
+		return Point{}, fmt.Errorf("invalid coordinates value: %s", input)
+	}
+	latitude, err := strconv.ParseFloat(strings.TrimSpace(latitudeString), 64)
+	if err != nil {
+		return Point{}, fmt.Errorf("invalid latitude: %w", err)
+	}
+	longitude, err := strconv.ParseFloat(strings.TrimSpace(longitudeString), 64)
+	if err != nil {
+		return Point{}, fmt.Errorf("invalid longitude: %w", err)
+	}
+	if latitude < -90 || latitude > 90 {
+		return Point{}, fmt.Errorf("invalid latitude: %s", input)
+	}
+	if longitude < -180 || longitude > 180 {
+		return Point{}, fmt.Errorf("invalid longitude: %s", input)
+	}
+	return Point{Latitude: latitude, Longitude: longitude}, nil
+}
+
+// String returns the point as "latitude,longitude".
+func (p Point) String() string {
+	return strconv.FormatFloat(p.Latitude, 'f', -1, 64) + "," + strconv.FormatFloat(p.Longitude, 'f', -1, 64)
+}
+
+// Distance returns the great-circle (haversine) distance between two points, in meters.
+func Distance(a Point, b Point) float64 {
+	latitude1 := a.Latitude * math.Pi / 180
+	latitude2 := b.Latitude * math.Pi / 180
+	deltaLatitude := (b.Latitude - a.Latitude) * math.Pi / 180
+	deltaLongitude := (b.Longitude - a.Longitude) * math.Pi / 180
+
+	h := math.Sin(deltaLatitude/2)*math.Sin(deltaLatitude/2) + math.Cos(latitude1)*math.Cos(latitude2)*math.Sin(deltaLongitude/2)*math.Sin(deltaLongitude/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// distanceUnits are the units that a distance may be given in, along with the number of meters in each.
+var distanceUnits = map[string]float64{
+	"m":  1,
+	"km": 1000,
+	"ft": 0.3048,
+	"mi": 1609.344,
+}
+
+// ParseDistance parses a distance, such as "2km" or "1.5mi", and returns it in meters.
+//
+// A distance without a unit is in meters.
+func ParseDistance(input string) (float64, error) {
+	value := strings.ToLower(strings.TrimSpace(input))
+	multiplier := 1.0
+	for unit, unitMultiplier := range distanceUnits {
+		if number, ok := strings.CutSuffix(value, unit); ok {
+			// "2km" also ends in "m", but "2k" isn't a number, so only the right unit can match.
+			if _, err := strconv.ParseFloat(strings.TrimSpace(number), 64); err == nil {
+				value = strings.TrimSpace(number)
+				multiplier = unitMultiplier
+				break
+			}
+		}
+	}
+	distance, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid distance: %q (expected a number with an optional unit: m, km, ft, or mi)", input)
+	}
+	if distance < 0 || math.IsInf(distance, 0) || math.IsNaN(distance) {
+		return 0, fmt.Errorf("invalid distance: %q", input)
+	}
+	return distance * multiplier, nil
+}
+
+// Box is an area bounded by lines of latitude and longitude.
+type Box struct {
+	MinLatitude  float64
+	MinLongitude float64
+	MaxLatitude  float64
+	MaxLongitude float64
+}
+
+// Contains returns true if the point is inside of (or on the edge of) the box.
+func (b Box) Contains(p Point) bool {
+	return p.Latitude >= b.MinLatitude && p.Latitude <= b.MaxLatitude && p.Longitude >= b.MinLongitude && p.Longitude <= b.MaxLongitude
+}
+
+// Polygon returns the box as a polygon.
+func (b Box) Polygon() Polygon {
+	return Polygon{
+		{
+			{Latitude: b.MinLatitude, Longitude: b.MinLongitude},
+			{Latitude: b.MinLatitude, Longitude: b.MaxLongitude},
+			{Latitude: b.MaxLatitude, Longitude: b.MaxLongitude},
+			{Latitude: b.MaxLatitude, Longitude: b.MinLongitude},
+			{Latitude: b.MinLatitude, Longitude: b.MinLongitude},
+		},
+	}
+}
+
+// BoxAround returns the smallest box that contains every point within the given distance (in meters) of the center.
+func BoxAround(center Point, distance float64) Box {
+	deltaLatitude := distance / EarthRadius * 180 / math.Pi
+	box := Box{
+		MinLatitude:  math.Max(-90, center.Latitude-deltaLatitude),
+		MaxLatitude:  math.Min(90, center.Latitude+deltaLatitude),
+		MinLongitude: -180,
+		MaxLongitude: 180,
+	}
+	// Near the poles, every longitude is close by.
+	cosine := math.Cos(center.Latitude * math.Pi / 180)
+	if cosine > 0 {
+		deltaLongitude := deltaLatitude / cosine
+		if deltaLongitude < 180 {
+			box.MinLongitude = math.Max(-180, center.Longitude-deltaLongitude)
+			box.MaxLongitude = math.Min(180, center.Longitude+deltaLongitude)
+		}
+	}
+	return box
+}
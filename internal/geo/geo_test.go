@@ -0,0 +1,216 @@
+package geo
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePoint(t *testing.T) {
+	rows := []struct {
+		description string
+		input       string
+		output      Point
+		err         bool
+	}{
+		{"Simple", "43.61,-116.2", Point{Latitude: 43.61, Longitude: -116.2}, false},
+		{"Spaces", " 43.61 , -116.2 ", Point{Latitude: 43.61, Longitude: -116.2}, false},
+		{"Missing comma", "43.61", Point{}, true},
+		{"Bad latitude", "north,-116.2", Point{}, true},
+		{"Latitude out of range", "91,0", Point{}, true},
+		{"Longitude out of range", "0,181", Point{}, true},
+	}
+	for rowIndex, row := range rows {
+		t.Run(fmt.Sprintf("%d/%s", rowIndex, row.description), func(t *testing.T) {
+			output, err := ParsePoint(row.input)
+			if row.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, row.output, output)
+		})
+	}
+}
+
+func TestDistance(t *testing.T) {
+	rows := []struct {
+		description string
+		a           Point
+		b           Point
+		output      float64
+	}{
+		{"Same point", Point{43.61, -116.2}, Point{43.61, -116.2}, 0},
+		{"One degree of latitude", Point{0, 0}, Point{1, 0}, 111195},
+		{"One degree of longitude at 60 degrees", Point{60, 0}, Point{60, 1}, 55597},
+		{"Across the antimeridian", Point{0, 179.5}, Point{0, -179.5}, 111195},
+	}
+	for rowIndex, row := range rows {
+		t.Run(fmt.Sprintf("%d/%s", rowIndex, row.description), func(t *testing.T) {
+			assert.InDelta(t, row.output, Distance(row.a, row.b), 1)
+		})
+	}
+}
+
+func TestParseDistance(t *testing.T) {
+	rows := []struct {
+		description string
+		input       string
+		output      float64
+		err         bool
+	}{
+		{"Meters without a unit", "250", 250, false},
+		{"Meters", "250m", 250, false},
+		{"Kilometers", "2km", 2000, false},
+		{"Miles", "1 mi", 1609.344, false},
+		{"Feet", "500FT", 152.4, false},
+		{"Fraction", ".5km", 500, false},
+		{"Unknown unit", "3 furlongs", 0, true},
+		{"Missing number", "km", 0, true},
+		{"Negative", "-1km", 0, true},
+	}
+	for rowIndex, row := range rows {
+		t.Run(fmt.Sprintf("%d/%s", rowIndex, row.description), func(t *testing.T) {
+			output, err := ParseDistance(row.input)
+			if row.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.InDelta(t, row.output, output, 0.0001)
+		})
+	}
+}
+
+func TestBoxAround(t *testing.T) {
+	center := Point{Latitude: 43.61, Longitude: -116.2}
+	box := BoxAround(center, 2000)
+	for _, bearing := range []Point{{0.01798, 0}, {-0.01798, 0}, {0, 0.02484}, {0, -0.02484}} {
+		point := Point{Latitude: center.Latitude + bearing.Latitude, Longitude: center.Longitude + bearing.Longitude}
+		assert.InDelta(t, 2000, Distance(center, point), 5)
+		assert.True(t, box.Contains(point), "%v should be inside of %v", point, box)
+	}
+	assert.False(t, box.Contains(Point{Latitude: center.Latitude + 0.02, Longitude: center.Longitude}))
+	assert.False(t, box.Contains(Point{Latitude: center.Latitude, Longitude: center.Longitude - 0.03}))
+}
+
+func TestParseGeoJSON(t *testing.T) {
+	// This is a 4x4 square with a 2x2 hole in the middle.
+	square := `{"type":"Polygon","coordinates":[[[0,0],[4,0],[4,4],[0,4],[0,0]],[[1,1],[3,1],[3,3],[1,3],[1,1]]]}`
+	// This is two 1x1 squares.
+	multi := `{"type":"MultiPolygon","coordinates":[[[[0,0],[1,0],[1,1],[0,1],[0,0]]],[[[10,10],[11,10],[11,11],[10,11],[10,10]]]]}`
+
+	rows := []struct {
+		description string
+		input       string
+		err         bool
+		inside      []Point
+		outside     []Point
+	}{
+		{"Polygon with a hole", square, false, []Point{{0.5, 0.5}, {3.5, 2}}, []Point{{2, 2}, {5, 5}, {-1, 2}}},
+		{"Feature", `{"type":"Feature","properties":{},"geometry":` + square + `}`, false, []Point{{0.5, 0.5}}, []Point{{2, 2}}},
+		{"MultiPolygon", multi, false, []Point{{0.5, 0.5}, {10.5, 10.5}}, []Point{{5, 5}}},
+		{"Not JSON", `polygon`, true, nil, nil},
+		{"Point", `{"type":"Point","coordinates":[0,0]}`, true, nil, nil},
+		{"Open ring", `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1]]]}`, true, nil, nil},
+		{"Unclosed ring", `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,1],[0,1],[0,2]]]}`, true, nil, nil},
+		{"Bad position", `{"type":"Polygon","coordinates":[[[0,0],[1,0],[1,100],[0,1],[0,0]]]}`, true, nil, nil},
+	}
+	for rowIndex, row := range rows {
+		t.Run(fmt.Sprintf("%d/%s", rowIndex, row.description), func(t *testing.T) {
+			geometry, err := ParseGeoJSON([]byte(row.input))
+			if row.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			for _, point := range row.inside {
+				assert.True(t, geometry.Contains(point), "%v should be inside", point)
+			}
+			for _, point := range row.outside {
+				assert.False(t, geometry.Contains(point), "%v should be outside", point)
+			}
+
+			// The GeoJSON should survive a round trip.
+			contents, err := geometry.GeoJSON()
+			require.NoError(t, err)
+			again, err := ParseGeoJSON(contents)
+			require.NoError(t, err)
+			assert.Equal(t, geometry, again)
+		})
+	}
+
+	geometry, err := ParseGeoJSON([]byte(multi))
+	require.NoError(t, err)
+	assert.Equal(t, Box{MinLatitude: 0, MinLongitude: 0, MaxLatitude: 11, MaxLongitude: 11}, geometry.Bounds())
+}
+
+func TestCut(t *testing.T) {
+	// This is a 10x10 grid of points, plus a few duplicates.
+	var points []Point
+	for i := 0; i < 10; i++ {
+		for j := 0; j < 10; j++ {
+			points = append(points, Point{Latitude: 43.6 + float64(i)*0.001, Longitude: -116.2 + float64(j)*0.001})
+		}
+	}
+	points = append(points, points[0], points[0], points[55])
+
+	rows := []struct {
+		description string
+		points      []Point
+		count       int
+		cells       int
+	}{
+		{"No points", nil, 4, 0},
+		{"One cell", points, 1, 1},
+		{"Two cells", points, 2, 2},
+		{"Three cells", points, 3, 3},
+		{"Seven cells", points, 7, 7},
+		{"More cells than places", []Point{points[0], points[0], points[1]}, 5, 2},
+	}
+	for rowIndex, row := range rows {
+		t.Run(fmt.Sprintf("%d/%s", rowIndex, row.description), func(t *testing.T) {
+			cells := Cut(row.points, row.count)
+			require.Len(t, cells, row.cells)
+
+			seen := map[int]bool{}
+			for _, cell := range cells {
+				assert.NotEmpty(t, cell.Indexes)
+				if len(cells) == row.count {
+					// The cells should be roughly the same size.
+					assert.InDelta(t, float64(len(row.points))/float64(row.count), float64(len(cell.Indexes)), float64(len(row.points))/float64(row.count)/2+1)
+				}
+				for _, index := range cell.Indexes {
+					assert.False(t, seen[index], "point %d is in more than one cell", index)
+					seen[index] = true
+
+					// Every point should be inside of its own cell and no other.
+					assert.True(t, cell.Box.Contains(row.points[index]))
+					containing := 0
+					for _, other := range cells {
+						if other.Box.Contains(row.points[index]) {
+							containing++
+						}
+					}
+					assert.Equal(t, 1, containing, "point %d is inside of more than one cell", index)
+				}
+			}
+			assert.Len(t, seen, len(row.points))
+		})
+	}
+}
+
+func TestNearestNeighborRoute(t *testing.T) {
+	points := []Point{
+		{Latitude: 0, Longitude: 3},
+		{Latitude: 0, Longitude: 0},
+		{Latitude: 0, Longitude: 1},
+		{Latitude: 0, Longitude: 10},
+		{Latitude: 0, Longitude: 2},
+	}
+	assert.Equal(t, []int{1, 2, 4, 0, 3}, NearestNeighborRoute(points, 1))
+	assert.Equal(t, []int{0, 4, 2, 1, 3}, NearestNeighborRoute(points, 0))
+	assert.Nil(t, NearestNeighborRoute(nil, 0))
+}
@@ -0,0 +1,189 @@
+package geo
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// Polygon is a list of rings; the first ring is the outside, and any others are holes.
+//
+// Each ring is closed (its last point is the same as its first).
+type Polygon [][]Point
+
+// Geometry is an area made up of one or more polygons.
+type Geometry []Polygon
+
+// geoJSONGeometry is a GeoJSON geometry; see RFC 7946.
+type geoJSONGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometry    json.RawMessage `json:"geometry,omitempty"` // This is only set for a "Feature".
+}
+
+// ParseGeoJSON parses a GeoJSON "Polygon" or "MultiPolygon" (or a "Feature" of one).
+func ParseGeoJSON(input []byte) (Geometry, error) {
+	var value geoJSONGeometry
+	err := json.Unmarshal(input, &value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GeoJSON: %w", err)
+	}
+
+	var output Geometry
+	switch value.Type {
+	case "Feature":
+		if len(value.Geometry) == 0 {
+			return nil, fmt.Errorf("missing geometry")
+		}
+		return ParseGeoJSON(value.Geometry)
+	case "Polygon":
+		var coordinates [][][]float64
+		err = json.Unmarshal(value.Coordinates, &coordinates)
+		if err != nil {
+			return nil, fmt.Errorf("invalid coordinates: %w", err)
+		}
+		polygon, err := newPolygon(coordinates)
+		if err != nil {
+			return nil, err
+		}
+		output = append(output, polygon)
+	case "MultiPolygon":
+		var coordinates [][][][]float64
+		err = json.Unmarshal(value.Coordinates, &coordinates)
+		if err != nil {
+			return nil, fmt.Errorf("invalid coordinates: %w", err)
+		}
+		for _, polygonCoordinates := range coordinates {
+			polygon, err := newPolygon(polygonCoordinates)
+			if err != nil {
+				return nil, err
+			}
+			output = append(output, polygon)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported GeoJSON type: %q (only Polygon and MultiPolygon are supported)", value.Type)
+	}
+	if len(output) == 0 {
+		return nil, fmt.Errorf("missing polygons")
+	}
+	return output, nil
+}
+
+// newPolygon builds a polygon from GeoJSON coordinates, which are given as [longitude, latitude].
+func newPolygon(coordinates [][][]float64) (Polygon, error) {
+	if len(coordinates) == 0 {
+		return nil, fmt.Errorf("missing rings")
+	}
+	var output Polygon
+	for _, ringCoordinates := range coordinates {
+		if len(ringCoordinates) < 4 {
+			return nil, fmt.Errorf("a ring needs at least 4 positions")
+		}
+		var ring []Point
+		for _, position := range ringCoordinates {
+			if len(position) < 2 {
+				return nil, fmt.Errorf("a position needs a longitude and a latitude")
+			}
+			point := Point{Latitude: position[1], Longitude: position[0]}
+			if point.Latitude < -90 || point.Latitude > 90 || point.Longitude < -180 || point.Longitude > 180 {
+				return nil, fmt.Errorf("invalid position: [%v, %v]", position[0], position[1])
+			}
+			ring = append(ring, point)
+		}
+		if ring[0] != ring[len(ring)-1] {
+			return nil, fmt.Errorf("a ring must end where it starts")
+		}
+		output = append(output, ring)
+	}
+	return output, nil
+}
+
+// GeoJSON returns the GeoJSON form of the geometry.
+//
+// A single polygon is a "Polygon"; otherwise, it is a "MultiPolygon".
+func (g Geometry) GeoJSON() ([]byte, error) {
+	var polygons [][][][]float64
+	for _, polygon := range g {
+		var rings [][][]float64
+		for _, ring := range polygon {
+			var positions [][]float64
+			for _, point := range ring {
+				positions = append(positions, []float64{point.Longitude, point.Latitude})
+			}
+			rings = append(rings, positions)
+		}
+		polygons = append(polygons, rings)
+	}
+
+	value := map[string]any{}
+	if len(polygons) == 1 {
+		value["type"] = "Polygon"
+		value["coordinates"] = polygons[0]
+	} else {
+		value["type"] = "MultiPolygon"
+		value["coordinates"] = polygons
+	}
+	return json.Marshal(value)
+}
+
+// Bounds returns the smallest box that contains the geometry.
+func (g Geometry) Bounds() Box {
+	box := Box{
+		MinLatitude:  math.Inf(1),
+		MinLongitude: math.Inf(1),
+		MaxLatitude:  math.Inf(-1),
+		MaxLongitude: math.Inf(-1),
+	}
+	for _, polygon := range g {
+		for _, ring := range polygon {
+			for _, point := range ring {
+				box.MinLatitude = math.Min(box.MinLatitude, point.Latitude)
+				box.MinLongitude = math.Min(box.MinLongitude, point.Longitude)
+				box.MaxLatitude = math.Max(box.MaxLatitude, point.Latitude)
+				box.MaxLongitude = math.Max(box.MaxLongitude, point.Longitude)
+			}
+		}
+	}
+	return box
+}
+
+// Contains returns true if the point is inside of the geometry.
+//
+// A point that is exactly on an edge may go either way.
+func (g Geometry) Contains(p Point) bool {
+	for _, polygon := range g {
+		if polygon.Contains(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Contains returns true if the point is inside of the outside ring and not inside of any of the holes.
+func (p Polygon) Contains(point Point) bool {
+	if len(p) == 0 || !ringContains(p[0], point) {
+		return false
+	}
+	for _, hole := range p[1:] {
+		if ringContains(hole, point) {
+			return false
+		}
+	}
+	return true
+}
+
+// ringContains returns true if the point is inside of the ring, using the even-odd rule.
+func ringContains(ring []Point, point Point) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a := ring[i]
+		b := ring[j]
+		if (a.Latitude > point.Latitude) != (b.Latitude > point.Latitude) {
+			longitude := (b.Longitude-a.Longitude)*(point.Latitude-a.Latitude)/(b.Latitude-a.Latitude) + a.Longitude
+			if point.Longitude < longitude {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
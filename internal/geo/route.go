@@ -0,0 +1,40 @@
+package geo
+
+// NearestNeighborRoute returns the order in which to visit the points, starting from the point at the given index and
+// always going next to the closest point that hasn't been visited yet.
+//
+// This isn't the shortest route, but it's a reasonable one for walking a neighborhood.
+func NearestNeighborRoute(points []Point, start int) []int {
+	if len(points) == 0 {
+		return nil
+	}
+	if start < 0 || start >= len(points) {
+		start = 0
+	}
+
+	visited := make([]bool, len(points))
+	output := make([]int, 0, len(points))
+	current := start
+	for {
+		visited[current] = true
+		output = append(output, current)
+		if len(output) == len(points) {
+			break
+		}
+
+		next := -1
+		var nextDistance float64
+		for i, point := range points {
+			if visited[i] {
+				continue
+			}
+			distance := Distance(points[current], point)
+			if next < 0 || distance < nextDistance {
+				next = i
+				nextDistance = distance
+			}
+		}
+		current = next
+	}
+	return output
+}
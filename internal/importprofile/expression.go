@@ -0,0 +1,278 @@
+package importprofile
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/downballot/downballot/internal/stringer"
+)
+
+// Expression is a parsed composite-field expression.
+//
+// An expression is a series of terms joined with "+", where each term is one of:
+//   - A column name, such as `name_first`, which evaluates to the value of that column (or "" if it is missing).
+//   - A quoted string, such as `' '` or `", "`.
+//   - A function call, such as `join(", ", city, state)`.
+//
+// The supported functions are:
+//   - join(separator, value...): joins the non-empty values with the separator.
+//   - coalesce(value...): the first non-empty value.
+//   - upper(value), lower(value), trim(value).
+type Expression interface {
+	Evaluate(data map[string]string) string
+	String() string
+}
+
+// expressionColumn is the value of a column.
+type expressionColumn struct {
+	Name string
+}
+
+func (e expressionColumn) Evaluate(data map[string]string) string {
+	return data[e.Name]
+}
+
+func (e expressionColumn) String() string {
+	return e.Name
+}
+
+// expressionLiteral is a literal string.
+type expressionLiteral struct {
+	Value string
+}
+
+func (e expressionLiteral) Evaluate(data map[string]string) string {
+	return e.Value
+}
+
+func (e expressionLiteral) String() string {
+	return "'" + strings.ReplaceAll(e.Value, "'", "\\'") + "'"
+}
+
+// expressionConcat is the concatenation of its parts.
+type expressionConcat struct {
+	Parts []Expression
+}
+
+func (e expressionConcat) Evaluate(data map[string]string) string {
+	var output string
+	for _, part := range e.Parts {
+		output += part.Evaluate(data)
+	}
+	return output
+}
+
+func (e expressionConcat) String() string {
+	var parts []string
+	for _, part := range e.Parts {
+		parts = append(parts, part.String())
+	}
+	return strings.Join(parts, " + ")
+}
+
+// expressionFunction is a function call.
+type expressionFunction struct {
+	Name      string
+	Arguments []Expression
+}
+
+func (e expressionFunction) Evaluate(data map[string]string) string {
+	var values []string
+	for _, argument := range e.Arguments {
+		values = append(values, argument.Evaluate(data))
+	}
+
+	switch e.Name {
+	case "join":
+		return stringer.Join(values[1:], values[0])
+	case "coalesce":
+		for _, value := range values {
+			if strings.TrimSpace(value) != "" {
+				return value
+			}
+		}
+		return ""
+	case "upper":
+		return strings.ToUpper(values[0])
+	case "lower":
+		return strings.ToLower(values[0])
+	case "trim":
+		return strings.TrimSpace(values[0])
+	}
+	return ""
+}
+
+func (e expressionFunction) String() string {
+	var arguments []string
+	for _, argument := range e.Arguments {
+		arguments = append(arguments, argument.String())
+	}
+	return e.Name + "(" + strings.Join(arguments, ", ") + ")"
+}
+
+// expressionFunctionArguments maps each function to its minimum and maximum number of arguments.
+//
+// A maximum of -1 means that there is no maximum.
+var expressionFunctionArguments = map[string][2]int{
+	"join":     {2, -1},
+	"coalesce": {1, -1},
+	"upper":    {1, 1},
+	"lower":    {1, 1},
+	"trim":     {1, 1},
+}
+
+// expressionToken is a single token in an expression.
+type expressionToken struct {
+	Value  string
+	Quoted bool
+}
+
+// tokenizeExpression splits an expression into tokens.
+func tokenizeExpression(input string) ([]expressionToken, error) {
+	var tokens []expressionToken
+	for i := 0; i < len(input); i++ {
+		switch c := input[i]; {
+		case c == ' ' || c == '\t' || c == '\n':
+			// Whitespace only separates tokens.
+		case c == '(' || c == ')' || c == ',' || c == '+':
+			tokens = append(tokens, expressionToken{Value: string(c)})
+		case c == '\'' || c == '"':
+			var value string
+			closed := false
+			for i++; i < len(input); i++ {
+				if input[i] == '\\' && i+1 < len(input) {
+					i++
+					value += string(input[i])
+					continue
+				}
+				if input[i] == c {
+					closed = true
+					break
+				}
+				value += string(input[i])
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, expressionToken{Value: value, Quoted: true})
+		case isIdentifierCharacter(c):
+			start := i
+			for i+1 < len(input) && isIdentifierCharacter(input[i+1]) {
+				i++
+			}
+			tokens = append(tokens, expressionToken{Value: input[start : i+1]})
+		default:
+			return nil, fmt.Errorf("unexpected character: %q", c)
+		}
+	}
+	return tokens, nil
+}
+
+// isIdentifierCharacter returns true if the character can be part of a column or function name.
+//
+// This matches the characters that survive the header normalization of an import.
+func isIdentifierCharacter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '_' || c == '.'
+}
+
+// ParseExpression parses a composite-field expression.
+func ParseExpression(input string) (Expression, error) {
+	tokens, err := tokenizeExpression(input)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty expression")
+	}
+
+	expression, remaining, err := parseExpressionTokens(tokens)
+	if err != nil {
+		return nil, err
+	}
+	if len(remaining) > 0 {
+		return nil, fmt.Errorf("unexpected token: %q", remaining[0].Value)
+	}
+	return expression, nil
+}
+
+// parseExpressionTokens parses a "+"-separated series of terms and returns the remaining tokens.
+func parseExpressionTokens(tokens []expressionToken) (Expression, []expressionToken, error) {
+	var parts []Expression
+	for {
+		term, remaining, err := parseExpressionTerm(tokens)
+		if err != nil {
+			return nil, nil, err
+		}
+		parts = append(parts, term)
+		tokens = remaining
+
+		if len(tokens) == 0 || tokens[0].Quoted || tokens[0].Value != "+" {
+			break
+		}
+		tokens = tokens[1:]
+	}
+
+	if len(parts) == 1 {
+		return parts[0], tokens, nil
+	}
+	return expressionConcat{Parts: parts}, tokens, nil
+}
+
+// parseExpressionTerm parses a single term and returns the remaining tokens.
+func parseExpressionTerm(tokens []expressionToken) (Expression, []expressionToken, error) {
+	if len(tokens) == 0 {
+		return nil, nil, fmt.Errorf("unexpected end of expression")
+	}
+
+	token := tokens[0]
+	tokens = tokens[1:]
+	if token.Quoted {
+		return expressionLiteral{Value: token.Value}, tokens, nil
+	}
+	if !isIdentifierCharacter(token.Value[0]) {
+		return nil, nil, fmt.Errorf("unexpected token: %q", token.Value)
+	}
+
+	if len(tokens) == 0 || tokens[0].Quoted || tokens[0].Value != "(" {
+		return expressionColumn{Name: strings.ToLower(token.Value)}, tokens, nil
+	}
+	tokens = tokens[1:]
+
+	function := expressionFunction{
+		Name: strings.ToLower(token.Value),
+	}
+	argumentLimits, ok := expressionFunctionArguments[function.Name]
+	if !ok {
+		return nil, nil, fmt.Errorf("unknown function: %s", token.Value)
+	}
+
+	if len(tokens) > 0 && !tokens[0].Quoted && tokens[0].Value == ")" {
+		tokens = tokens[1:]
+	} else {
+		for {
+			argument, remaining, err := parseExpressionTokens(tokens)
+			if err != nil {
+				return nil, nil, err
+			}
+			function.Arguments = append(function.Arguments, argument)
+			tokens = remaining
+
+			if len(tokens) == 0 || tokens[0].Quoted {
+				return nil, nil, fmt.Errorf("missing ')' after arguments to %s", function.Name)
+			}
+			if tokens[0].Value == ")" {
+				tokens = tokens[1:]
+				break
+			}
+			if tokens[0].Value != "," {
+				return nil, nil, fmt.Errorf("unexpected token: %q", tokens[0].Value)
+			}
+			tokens = tokens[1:]
+		}
+	}
+
+	if len(function.Arguments) < argumentLimits[0] || (argumentLimits[1] >= 0 && len(function.Arguments) > argumentLimits[1]) {
+		return nil, nil, fmt.Errorf("wrong number of arguments to %s: %d", function.Name, len(function.Arguments))
+	}
+	return function, tokens, nil
+}
@@ -0,0 +1,144 @@
+package importprofile
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseExpression(t *testing.T) {
+	data := map[string]string{
+		"name_first":  "Monkey",
+		"name_middle": "",
+		"name_last":   "Luffy",
+		"city":        " Newark ",
+		"state":       "DE",
+	}
+
+	rows := []struct {
+		description string
+		input       string
+		success     bool
+		canonical   string
+		output      string
+	}{
+		{
+			description: "empty",
+			input:       "",
+			success:     false,
+		},
+		{
+			description: "column",
+			input:       "name_first",
+			success:     true,
+			canonical:   "name_first",
+			output:      "Monkey",
+		},
+		{
+			description: "missing column",
+			input:       "name_suffix",
+			success:     true,
+			canonical:   "name_suffix",
+			output:      "",
+		},
+		{
+			description: "literal",
+			input:       `"hello, world"`,
+			success:     true,
+			canonical:   "'hello, world'",
+			output:      "hello, world",
+		},
+		{
+			description: "concatenation",
+			input:       "name_first + ' ' + name_last",
+			success:     true,
+			canonical:   "name_first + ' ' + name_last",
+			output:      "Monkey Luffy",
+		},
+		{
+			description: "join skips empty values",
+			input:       "join(' ', name_first, name_middle, name_last)",
+			success:     true,
+			canonical:   "join(' ', name_first, name_middle, name_last)",
+			output:      "Monkey Luffy",
+		},
+		{
+			description: "nested functions",
+			input:       "UPPER(join(', ', city, state))",
+			success:     true,
+			canonical:   "upper(join(', ', city, state))",
+			output:      "NEWARK, DE",
+		},
+		{
+			description: "coalesce",
+			input:       "coalesce(name_middle, name_last)",
+			success:     true,
+			canonical:   "coalesce(name_middle, name_last)",
+			output:      "Luffy",
+		},
+		{
+			description: "escaped quote",
+			input:       `'it\'s'`,
+			success:     true,
+			canonical:   `'it\'s'`,
+			output:      "it's",
+		},
+		{
+			description: "unknown function",
+			input:       "reverse(name_first)",
+			success:     false,
+		},
+		{
+			description: "too many arguments",
+			input:       "upper(name_first, name_last)",
+			success:     false,
+		},
+		{
+			description: "too few arguments",
+			input:       "join(' ')",
+			success:     false,
+		},
+		{
+			description: "missing closing paren",
+			input:       "trim(name_first",
+			success:     false,
+		},
+		{
+			description: "trailing plus",
+			input:       "name_first +",
+			success:     false,
+		},
+		{
+			description: "missing plus",
+			input:       "name_first name_last",
+			success:     false,
+		},
+		{
+			description: "unterminated string",
+			input:       "name_first + ' ",
+			success:     false,
+		},
+		{
+			description: "illegal character",
+			input:       "name_first - name_last",
+			success:     false,
+		},
+	}
+	for rowIndex, row := range rows {
+		t.Run(fmt.Sprintf("%d/%s", rowIndex, row.description), func(t *testing.T) {
+			expression, err := ParseExpression(row.input)
+			if !row.success {
+				require.NotNil(t, err, "err is nil")
+				require.Nil(t, expression, "expression is not nil")
+			} else {
+				require.Nil(t, err, "err is not nil")
+				require.NotNil(t, expression, "expression is nil")
+
+				assert.Equal(t, row.canonical, expression.String(), "canonical is incorrect")
+				assert.Equal(t, row.output, expression.Evaluate(data), "output is incorrect")
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+// Package importprofile describes how the columns of a voter file turn into person fields.
+//
+// Every state publishes its voter file in its own layout, so an organization can keep a profile for each
+// layout that it works with instead of reshaping the files by hand before importing them.
+package importprofile
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultName is the name of the built-in profile.
+const DefaultName = "default"
+
+// Profile describes how to turn a row of a voter file into person fields.
+//
+// The columns of the file are referred to by their normalized header names (lowercase, with everything
+// except letters, numbers, and "." replaced with "_").
+//
+// The steps are applied in this order:
+//  1. Columns are copied into fields.
+//  2. Composites are built from the columns.
+//  3. Sets are built from the columns.
+//  4. Transforms are applied to the fields.
+type Profile struct {
+	Columns    map[string]string `json:"columns"`    // This maps each column to the field that it is copied into.
+	Composites []Composite       `json:"composites"` // These build a single field out of multiple columns.
+	Sets       []Set             `json:"sets"`       // These build a set field out of a family of columns.
+	Transforms []Transform       `json:"transforms"` // These adjust the final value of a field.
+}
+
+// Composite builds a field from an expression over the columns; see Expression for the syntax.
+//
+// If the expression evaluates to an empty string, then the field is left alone.
+type Composite struct {
+	Field      string `json:"field"`
+	Expression string `json:"expression"`
+}
+
+// Set builds a set field from every column that starts with a prefix, such as "voting_history_".
+//
+// The non-empty values are lowercased, sorted, and de-duplicated.  If the file has no columns
+// with the prefix, then the field is left alone.
+type Set struct {
+	Field        string `json:"field"`
+	ColumnPrefix string `json:"column_prefix"`
+}
+
+// Transform applies a series of operations to a field.
+//
+// The supported operations are:
+//   - "upper", "lower", "trim"
+//   - "date:<layout>", which reformats a date from the given Go time layout (such as "1/2/2006") into "2006-01-02".
+//
+// Empty values are not transformed.  If a value cannot be transformed, then it is left as-is so that
+// it will be reported when the field is validated.
+type Transform struct {
+	Field      string   `json:"field"`
+	Operations []string `json:"operations"`
+}
+
+// Default returns the built-in profile.
+//
+// This is the layout that the importer has always understood, so that existing voter files keep working.
+func Default() Profile {
+	return Profile{
+		Columns: map[string]string{
+			"year_of_birth":             "birthday_year",
+			"county":                    "county",
+			"district_representative":   "district_representative",
+			"district_school":           "district_school",
+			"district_senate":           "district_senate",
+			"name_first":                "name_first",
+			"name_middle":               "name_middle",
+			"name_last":                 "name_last",
+			"name_suffix":               "name_suffix",
+			"political_party":           "political_party",
+			"res_addr_development_name": "residential_address_development",
+			"voter_id":                  "voter_id",
+		},
+		Composites: []Composite{
+			{
+				Field:      "name",
+				Expression: `join(", ", join(" ", name_first, name_middle, name_last), name_suffix)`,
+			},
+			{
+				Field: "residential_address",
+				Expression: `join(", ",` +
+					` join(" ", res_addr_house_no, res_addr_house_no_suffix, res_addr_street_direction_prefix, res_addr_street_name, res_addr_street_type, res_addr_street_direction_suffix, res_addr_unit_type, res_addr_unit_number),` +
+					` join(" ", join(", ", res_addr_city, res_addr_state), join("-", res_addr_zip_code, res_addr_zip_4))` +
+					`)`,
+			},
+			{
+				Field: "mailing_address",
+				Expression: `join(", ",` +
+					` mail_addr_line1, mail_addr_line2, mail_addr_line3, mail_addr_line4,` +
+					` join(" ", join(", ", mail_addr_city, mail_addr_state), join("-", mail_addr_zip_code, mail_addr_zip_4))` +
+					`)`,
+			},
+			{
+				Field:      "phone_number",
+				Expression: `join("-", phone_area_code, phone_exchange, phone_last_four)`,
+			},
+		},
+		Sets: []Set{
+			{
+				Field:        "voting_history",
+				ColumnPrefix: "voting_history_",
+			},
+		},
+	}
+}
+
+// Fields returns the names of all of the fields that the profile writes to.
+func (p Profile) Fields() []string {
+	var output []string
+	for _, field := range p.Columns {
+		output = append(output, field)
+	}
+	for _, composite := range p.Composites {
+		output = append(output, composite.Field)
+	}
+	for _, set := range p.Sets {
+		output = append(output, set.Field)
+	}
+	for _, transform := range p.Transforms {
+		output = append(output, transform.Field)
+	}
+	slices.Sort(output)
+	return slices.Compact(output)
+}
+
+// RenameField renames every reference to a field within the profile.
+//
+// This returns true if any references were renamed.
+func (p *Profile) RenameField(oldName string, newName string) bool {
+	renamed := false
+	for column, field := range p.Columns {
+		if field == oldName {
+			p.Columns[column] = newName
+			renamed = true
+		}
+	}
+	for i := range p.Composites {
+		if p.Composites[i].Field == oldName {
+			p.Composites[i].Field = newName
+			renamed = true
+		}
+	}
+	for i := range p.Sets {
+		if p.Sets[i].Field == oldName {
+			p.Sets[i].Field = newName
+			renamed = true
+		}
+	}
+	for i := range p.Transforms {
+		if p.Transforms[i].Field == oldName {
+			p.Transforms[i].Field = newName
+			renamed = true
+		}
+	}
+	return renamed
+}
+
+// Compile validates the profile and prepares it for use.
+func (p Profile) Compile() (*Compiled, error) {
+	compiled := &Compiled{
+		profile: p,
+	}
+
+	for column, field := range p.Columns {
+		if column == "" {
+			return nil, fmt.Errorf("missing column name")
+		}
+		if field == "" {
+			return nil, fmt.Errorf("missing field for column %s", column)
+		}
+	}
+
+	for _, composite := range p.Composites {
+		if composite.Field == "" {
+			return nil, fmt.Errorf("missing field for composite")
+		}
+		expression, err := ParseExpression(composite.Expression)
+		if err != nil {
+			return nil, fmt.Errorf("invalid expression for composite %s: %w", composite.Field, err)
+		}
+		compiled.composites = append(compiled.composites, expression)
+	}
+
+	for _, set := range p.Sets {
+		if set.Field == "" {
+			return nil, fmt.Errorf("missing field for set")
+		}
+		if set.ColumnPrefix == "" {
+			return nil, fmt.Errorf("missing column prefix for set %s", set.Field)
+		}
+	}
+
+	for _, transform := range p.Transforms {
+		if transform.Field == "" {
+			return nil, fmt.Errorf("missing field for transform")
+		}
+		var operations []func(string) (string, error)
+		for _, operation := range transform.Operations {
+			f, err := parseOperation(operation)
+			if err != nil {
+				return nil, fmt.Errorf("invalid operation for transform %s: %w", transform.Field, err)
+			}
+			operations = append(operations, f)
+		}
+		compiled.transforms = append(compiled.transforms, operations)
+	}
+
+	return compiled, nil
+}
+
+// parseOperation parses a single transform operation.
+func parseOperation(input string) (func(string) (string, error), error) {
+	name, argument, _ := strings.Cut(input, ":")
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "upper":
+		return func(value string) (string, error) {
+			return strings.ToUpper(value), nil
+		}, nil
+	case "lower":
+		return func(value string) (string, error) {
+			return strings.ToLower(value), nil
+		}, nil
+	case "trim":
+		return func(value string) (string, error) {
+			return strings.TrimSpace(value), nil
+		}, nil
+	case "date":
+		if argument == "" {
+			return nil, fmt.Errorf("missing layout for date")
+		}
+		return func(value string) (string, error) {
+			t, err := time.Parse(argument, value)
+			if err != nil {
+				return "", err
+			}
+			return t.Format("2006-01-02"), nil
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown operation: %s", input)
+}
+
+// Compiled is a profile that is ready to be applied to rows.
+type Compiled struct {
+	profile    Profile
+	composites []Expression                     // These line up with the profile's composites.
+	transforms [][]func(string) (string, error) // These line up with the profile's transforms.
+}
+
+// Apply turns a row of data (normalized column name to value) into fields.
+//
+// The column map is used instead of the profile's own columns so that the caller can add to them.
+func (c *Compiled) Apply(data map[string]string, columnMap map[string]string) map[string]string {
+	fields := map[string]string{}
+	for name, value := range data {
+		if field := columnMap[name]; field != "" {
+			fields[field] = value
+		}
+	}
+
+	for i, composite := range c.profile.Composites {
+		value := strings.TrimSpace(c.composites[i].Evaluate(data))
+		if value != "" {
+			fields[composite.Field] = value
+		}
+	}
+
+	for _, set := range c.profile.Sets {
+		found := false
+		var values []string
+		for name, value := range data {
+			if !strings.HasPrefix(name, set.ColumnPrefix) {
+				continue
+			}
+			found = true
+			value = strings.ToLower(strings.TrimSpace(value))
+			if value != "" {
+				values = append(values, value)
+			}
+		}
+		if !found {
+			continue
+		}
+		slices.Sort(values)
+		values = slices.Compact(values)
+
+		finalValue := ""
+		if len(values) > 0 {
+			finalValue = "," + strings.Join(values, ",") + "," // We want to bracket everything with commas for easier searches later.
+		}
+		fields[set.Field] = finalValue
+	}
+
+	for i, transform := range c.profile.Transforms {
+		value, ok := fields[transform.Field]
+		if !ok || value == "" {
+			continue
+		}
+		for _, operation := range c.transforms[i] {
+			newValue, err := operation(value)
+			if err != nil {
+				break
+			}
+			value = newValue
+		}
+		fields[transform.Field] = value
+	}
+
+	return fields
+}
@@ -0,0 +1,193 @@
+package importprofile
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefault(t *testing.T) {
+	compiled, err := Default().Compile()
+	require.NoError(t, err)
+
+	data := map[string]string{
+		"voter_id":                "1001",
+		"name_first":              "LUFFY",
+		"name_middle":             "D",
+		"name_last":               "MONKEY",
+		"name_suffix":             "",
+		"res_addr_house_no":       "1",
+		"res_addr_street_name":    "MAIN",
+		"res_addr_street_type":    "ST",
+		"res_addr_city":           "NEWARK",
+		"res_addr_state":          "DE",
+		"res_addr_zip_code":       "19711",
+		"res_addr_zip_4":          "1234",
+		"phone_area_code":         "302",
+		"phone_exchange":          "555",
+		"phone_last_four":         "0001",
+		"voting_history_1":        "GE2024",
+		"voting_history_2":        "",
+		"voting_history_3":        "GE2020",
+		"some_unknown_column":     "ignored",
+		"year_of_birth":           "1949",
+		"res_addr_unit_number":    "",
+		"mail_addr_line1":         "",
+		"mail_addr_city":          "",
+		"mail_addr_zip_code":      "",
+		"political_party":         "PIRATE",
+		"district_senate":         "SS17",
+		"district_school":         "SDCA",
+		"district_representative": "RD31",
+	}
+	fields := compiled.Apply(data, Default().Columns)
+	assert.Equal(t, map[string]string{
+		"voter_id":                "1001",
+		"name":                    "LUFFY D MONKEY",
+		"name_first":              "LUFFY",
+		"name_middle":             "D",
+		"name_last":               "MONKEY",
+		"name_suffix":             "",
+		"residential_address":     "1 MAIN ST, NEWARK, DE 19711-1234",
+		"phone_number":            "302-555-0001",
+		"voting_history":          ",ge2020,ge2024,",
+		"birthday_year":           "1949",
+		"political_party":         "PIRATE",
+		"district_senate":         "SS17",
+		"district_school":         "SDCA",
+		"district_representative": "RD31",
+	}, fields)
+}
+
+func TestCompile(t *testing.T) {
+	rows := []struct {
+		description string
+		profile     Profile
+		success     bool
+	}{
+		{
+			description: "empty",
+			profile:     Profile{},
+			success:     true,
+		},
+		{
+			description: "missing column field",
+			profile: Profile{
+				Columns: map[string]string{"first": ""},
+			},
+			success: false,
+		},
+		{
+			description: "bad composite expression",
+			profile: Profile{
+				Composites: []Composite{{Field: "name", Expression: "first +"}},
+			},
+			success: false,
+		},
+		{
+			description: "missing set prefix",
+			profile: Profile{
+				Sets: []Set{{Field: "voting_history"}},
+			},
+			success: false,
+		},
+		{
+			description: "unknown operation",
+			profile: Profile{
+				Transforms: []Transform{{Field: "name", Operations: []string{"reverse"}}},
+			},
+			success: false,
+		},
+		{
+			description: "date without a layout",
+			profile: Profile{
+				Transforms: []Transform{{Field: "registration_date", Operations: []string{"date"}}},
+			},
+			success: false,
+		},
+	}
+	for rowIndex, row := range rows {
+		t.Run(fmt.Sprintf("%d/%s", rowIndex, row.description), func(t *testing.T) {
+			compiled, err := row.profile.Compile()
+			if !row.success {
+				require.NotNil(t, err, "err is nil")
+				require.Nil(t, compiled, "compiled is not nil")
+			} else {
+				require.Nil(t, err, "err is not nil")
+				require.NotNil(t, compiled, "compiled is nil")
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	profile := Profile{
+		Columns: map[string]string{
+			"id":    "voter_id",
+			"first": "name_first",
+			"last":  "name_last",
+			"reg":   "registration_date",
+		},
+		Composites: []Composite{
+			{Field: "name", Expression: "first + ' ' + last"},
+		},
+		Sets: []Set{
+			{Field: "voting_history", ColumnPrefix: "election_"},
+		},
+		Transforms: []Transform{
+			{Field: "name_last", Operations: []string{"trim", "upper"}},
+			{Field: "name", Operations: []string{"lower"}},
+			{Field: "registration_date", Operations: []string{"date:1/2/2006 15:04:05"}},
+		},
+	}
+	compiled, err := profile.Compile()
+	require.NoError(t, err)
+
+	t.Run("Everything", func(t *testing.T) {
+		fields := compiled.Apply(map[string]string{
+			"id":         "2001",
+			"first":      "Nami",
+			"last":       "Bellmere ",
+			"reg":        "3/14/2001 0:00:00",
+			"election_1": "GE2024",
+			"election_2": "ge2024",
+		}, profile.Columns)
+		assert.Equal(t, map[string]string{
+			"voter_id":          "2001",
+			"name_first":        "Nami",
+			"name_last":         "BELLMERE",
+			"name":              "nami bellmere",
+			"registration_date": "2001-03-14",
+			"voting_history":    ",ge2024,",
+		}, fields)
+	})
+
+	t.Run("Missing and bad values", func(t *testing.T) {
+		fields := compiled.Apply(map[string]string{
+			"id":  "2002",
+			"reg": "yesterday",
+		}, profile.Columns)
+		assert.Equal(t, map[string]string{
+			"voter_id":          "2002",
+			"registration_date": "yesterday",
+		}, fields)
+	})
+}
+
+func TestRenameField(t *testing.T) {
+	profile := Default()
+	assert.False(t, profile.RenameField("no_such_field", "whatever"))
+	assert.Equal(t, Default(), profile)
+
+	assert.True(t, profile.RenameField("name", "full_name"))
+	assert.True(t, profile.RenameField("voter_id", "state_voter_id"))
+	assert.True(t, profile.RenameField("voting_history", "elections"))
+	assert.Contains(t, profile.Fields(), "full_name")
+	assert.Contains(t, profile.Fields(), "state_voter_id")
+	assert.Contains(t, profile.Fields(), "elections")
+	assert.NotContains(t, profile.Fields(), "name")
+	assert.NotContains(t, profile.Fields(), "voter_id")
+	assert.NotContains(t, profile.Fields(), "voting_history")
+}
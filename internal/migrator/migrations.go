@@ -0,0 +1,149 @@
+package migrator
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Migrations are all of the migrations, in the order in which they are applied.
+//
+// To change the schema, append a new migration; never edit or reorder one that has been released.  A migration must
+// use its own copies of the models that it changes (see models-0001.go) rather than the `schema` structures, since
+// those keep changing after the migration has been released.  A migration should be safe to apply to a database that
+// already has its changes (for example, by checking `HasTable` or `HasColumn` first), since databases that were
+// created before this registry existed were migrated automatically.
+var Migrations = []Migration{
+	{
+		ID:          "0001",
+		Description: "Create the initial schema",
+		Up:          migrateInitialSchema,
+		Down:        dropTables(initialModels),
+	},
+	{
+		ID:          "0002",
+		Description: "Add contact attempts",
+		Up:          autoMigrate(contactModels),
+		Down:        dropTables(contactModels),
+	},
+	{
+		ID:          "0003",
+		Description: "Add households",
+		Up:          autoMigrate(householdModels),
+		Down:        dropTables(householdModels),
+	},
+	{
+		ID:          "0004",
+		Description: "Add turfs",
+		Up:          autoMigrate(turfModels),
+		Down:        dropTables(turfModels),
+	},
+	{
+		ID:          "0005",
+		Description: "Add surveys",
+		Up:          autoMigrate(surveyModels),
+		Down:        dropTables(surveyModels),
+	},
+}
+
+// initialModels are the tables in the initial database schema, in the order in which they are created.
+var initialModels = []any{
+	organization0001{},
+	organizationArchive0001{},
+	group0001{},
+	role0001{},
+	user0001{},
+	userGroupMap0001{},
+	userOrganizationMap0001{},
+	userTOTP0001{},
+	userAuthenticator0001{},
+	userRecoveryCode0001{},
+	userLink0001{},
+	session0001{},
+	authenticationThrottle0001{},
+	apiToken0001{},
+	filter0001{},
+	person0001{},
+	personField0001{},
+	personFieldDefinition0001{},
+	personAudit0001{},
+	importJob0001{},
+	importJobError0001{},
+	importProfile0001{},
+}
+
+// contactModels are the tables for the contact attempts.
+var contactModels = []any{
+	contactResult0002{},
+	contactAttempt0002{},
+}
+
+// householdModels are the tables for the households.
+var householdModels = []any{
+	household0003{},
+	householdMember0003{},
+}
+
+// turfModels are the tables for the turfs.
+var turfModels = []any{
+	turf0004{},
+	turfMember0004{},
+}
+
+// surveyModels are the tables for the surveys.
+var surveyModels = []any{
+	survey0005{},
+	surveyQuestion0005{},
+	surveyChoice0005{},
+	surveyResponse0005{},
+	surveyAnswer0005{},
+}
+
+// migrateInitialSchema creates the initial database schema.
+//
+// Databases from before this registry existed were migrated automatically, so this also brings those up to date.
+func migrateInitialSchema(tx *gorm.DB) error {
+	// Users that existed before e-mail verification was introduced are considered to be verified.
+	markUsersVerified := tx.Migrator().HasTable(&user0001{}) && !tx.Migrator().HasColumn(&user0001{}, "verified")
+
+	err := tx.AutoMigrate(initialModels...)
+	if err != nil {
+		return fmt.Errorf("could not auto-migrate database: %w", err)
+	}
+
+	if markUsersVerified {
+		err = tx.Session(&gorm.Session{AllowGlobalUpdate: true}).
+			Model(&user0001{}).
+			Update("verified", true).
+			Error
+		if err != nil {
+			return fmt.Errorf("could not mark existing users as verified: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// autoMigrate returns a step that creates (or updates) the tables for the given models.
+func autoMigrate(models []any) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		err := tx.AutoMigrate(models...)
+		if err != nil {
+			return fmt.Errorf("could not auto-migrate database: %w", err)
+		}
+		return nil
+	}
+}
+
+// dropTables returns a step that drops the tables for the given models, in the opposite order.
+func dropTables(models []any) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for i := len(models) - 1; i >= 0; i-- {
+			err := tx.Migrator().DropTable(models[i])
+			if err != nil {
+				return fmt.Errorf("could not drop table for %T: %w", models[i], err)
+			}
+		}
+		return nil
+	}
+}
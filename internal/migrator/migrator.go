@@ -1,30 +1,223 @@
+// Package migrator applies (and rolls back) the versioned migrations of the database schema.
+//
+// Every applied migration is recorded in the "schema_migrations" table, so each one only runs once.  The migrations are
+// applied in the order in which they are listed in `Migrations`, and they are rolled back in the opposite order.
 package migrator
 
 import (
+	"context"
 	"fmt"
+	"log/slog"
+	"time"
 
 	"github.com/downballot/downballot/internal/schema"
+	"github.com/downballot/downballot/internal/schema/sqltype"
 	"gorm.io/gorm"
 )
 
-// Migrate the database schema.
-func Migrate(db *gorm.DB) error {
-	err := db.AutoMigrate(
-		schema.Organization{},
-		schema.Group{},
-		schema.User{},
-		schema.UserGroupMap{},
-		schema.UserOrganizationMap{},
-		schema.UserTOTP{},
-		schema.Filter{},
-		schema.Person{},
-		schema.PersonField{},
-		schema.PersonFieldDefinition{},
-		schema.PersonAudit{},
-	)
-	if err != nil {
-		return fmt.Errorf("could not auto-migrate database: %w", err)
+// Migration is a single, reversible change to the database schema.
+//
+// Each step runs in its own transaction (except on MySQL, which commits schema changes immediately).
+type Migration struct {
+	ID          string                  // This is the unique ID of the migration; it must never change once the migration has been released.
+	Description string                  // This describes what the migration does.
+	Up          func(tx *gorm.DB) error // This applies the migration.
+	Down        func(tx *gorm.DB) error // This rolls back the migration; if this is nil, then the migration cannot be rolled back.
+}
+
+// Status is the status of a migration.
+type Status struct {
+	Migration        Migration
+	Applied          bool
+	AppliedTimestamp time.Time // This is when the migration was applied, if it was.
+}
+
+// ensureTable creates the "schema_migrations" table if it does not exist yet.
+func ensureTable(db *gorm.DB) error {
+	err := db.AutoMigrate(&schema.SchemaMigration{})
+	if err != nil {
+		return fmt.Errorf("could not create schema migrations table: %w", err)
+	}
+	return nil
+}
+
+// appliedMigrations returns the applied migrations, by ID.
+//
+// This fails if any of them is unknown, since that means that the database was migrated by a newer version.
+func appliedMigrations(db *gorm.DB) (map[string]*schema.SchemaMigration, error) {
+	err := ensureTable(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var schemaMigrations []*schema.SchemaMigration
+	err = db.Session(&gorm.Session{NewDB: true}).
+		Find(&schemaMigrations).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("could not find schema migrations: %w", err)
+	}
+
+	knownMigrationIDs := map[string]bool{}
+	for _, migration := range Migrations {
+		knownMigrationIDs[migration.ID] = true
 	}
 
+	output := map[string]*schema.SchemaMigration{}
+	for _, schemaMigration := range schemaMigrations {
+		if !knownMigrationIDs[schemaMigration.ID] {
+			return nil, fmt.Errorf("unknown migration %q has been applied; the database is newer than this version", schemaMigration.ID)
+		}
+		output[schemaMigration.ID] = schemaMigration
+	}
+	return output, nil
+}
+
+// GetStatus returns the status of every migration, in order.
+func GetStatus(db *gorm.DB) ([]Status, error) {
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var output []Status
+	for _, migration := range Migrations {
+		status := Status{
+			Migration: migration,
+		}
+		if schemaMigration := applied[migration.ID]; schemaMigration != nil {
+			status.Applied = true
+			status.AppliedTimestamp = time.Time(schemaMigration.AppliedTimestamp)
+		}
+		output = append(output, status)
+	}
+	return output, nil
+}
+
+// Pending returns the migrations that have not been applied yet, in order.
+func Pending(db *gorm.DB) ([]Migration, error) {
+	statuses, err := GetStatus(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var output []Migration
+	for _, status := range statuses {
+		if !status.Applied {
+			output = append(output, status.Migration)
+		}
+	}
+	return output, nil
+}
+
+// Up applies the pending migrations and returns the ones that were applied.
+//
+// If a target migration ID is given, then this stops once that migration has been applied.
+func Up(ctx context.Context, db *gorm.DB, target string) ([]Migration, error) {
+	if target != "" && findMigration(target) == nil {
+		return nil, fmt.Errorf("unknown migration: %q", target)
+	}
+
+	pending, err := Pending(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var output []Migration
+	for _, migration := range pending {
+		slog.InfoContext(ctx, fmt.Sprintf("Applying migration %s: %s", migration.ID, migration.Description))
+		err = db.Transaction(func(tx *gorm.DB) error {
+			err := migration.Up(tx)
+			if err != nil {
+				return err
+			}
+			return tx.Session(&gorm.Session{NewDB: true}).
+				Create(&schema.SchemaMigration{
+					ID:               migration.ID,
+					AppliedTimestamp: sqltype.DateTime(time.Now()),
+				}).
+				Error
+		})
+		if err != nil {
+			return output, fmt.Errorf("could not apply migration %s: %w", migration.ID, err)
+		}
+		output = append(output, migration)
+
+		if migration.ID == target {
+			break
+		}
+	}
+	return output, nil
+}
+
+// Down rolls back the given number of the most recently applied migrations and returns the ones that were rolled back.
+func Down(ctx context.Context, db *gorm.DB, steps int) ([]Migration, error) {
+	statuses, err := GetStatus(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var output []Migration
+	for i := len(statuses) - 1; i >= 0 && len(output) < steps; i-- {
+		status := statuses[i]
+		if !status.Applied {
+			continue
+		}
+		migration := status.Migration
+		if migration.Down == nil {
+			return output, fmt.Errorf("migration %s cannot be rolled back", migration.ID)
+		}
+
+		slog.InfoContext(ctx, fmt.Sprintf("Rolling back migration %s: %s", migration.ID, migration.Description))
+		err = db.Transaction(func(tx *gorm.DB) error {
+			err := migration.Down(tx)
+			if err != nil {
+				return err
+			}
+			return tx.Session(&gorm.Session{NewDB: true}).
+				Where("id = ?", migration.ID).
+				Delete(&schema.SchemaMigration{}).
+				Error
+		})
+		if err != nil {
+			return output, fmt.Errorf("could not roll back migration %s: %w", migration.ID, err)
+		}
+		output = append(output, migration)
+	}
+	return output, nil
+}
+
+// Migrate applies every pending migration.
+func Migrate(ctx context.Context, db *gorm.DB) error {
+	_, err := Up(ctx, db, "")
+	return err
+}
+
+// findMigration returns the migration with the given ID, if any.
+func findMigration(id string) *Migration {
+	for i := range Migrations {
+		if Migrations[i].ID == id {
+			return &Migrations[i]
+		}
+	}
+	return nil
+}
+
+// Drop drops every table in the database, including the record of the applied migrations.
+//
+// This is only meant for tests, which need to start with an empty database.
+func Drop(db *gorm.DB) error {
+	tables, err := db.Migrator().GetTables()
+	if err != nil {
+		return fmt.Errorf("could not list tables: %w", err)
+	}
+	var values []any
+	for _, table := range tables {
+		values = append(values, table)
+	}
+	err = db.Migrator().DropTable(values...)
+	if err != nil {
+		return fmt.Errorf("could not drop tables: %w", err)
+	}
 	return nil
 }
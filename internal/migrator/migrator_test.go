@@ -0,0 +1,118 @@
+package migrator
+
+import (
+	"testing"
+
+	"github.com/downballot/downballot/internal/database"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// currentModels are all of the current models of the database schema.
+var currentModels = []any{
+	schema.Organization{},
+	schema.OrganizationArchive{},
+	schema.Group{},
+	schema.Role{},
+	schema.User{},
+	schema.UserGroupMap{},
+	schema.UserOrganizationMap{},
+	schema.UserTOTP{},
+	schema.UserAuthenticator{},
+	schema.UserRecoveryCode{},
+	schema.UserLink{},
+	schema.Session{},
+	schema.AuthenticationThrottle{},
+	schema.APIToken{},
+	schema.Filter{},
+	schema.Person{},
+	schema.PersonField{},
+	schema.PersonFieldDefinition{},
+	schema.PersonAudit{},
+	schema.ImportJob{},
+	schema.ImportJobError{},
+	schema.ImportProfile{},
+	schema.ContactResult{},
+	schema.ContactAttempt{},
+	schema.Household{},
+	schema.HouseholdMember{},
+	schema.Turf{},
+	schema.TurfMember{},
+	schema.Survey{},
+	schema.SurveyQuestion{},
+	schema.SurveyChoice{},
+	schema.SurveyResponse{},
+	schema.SurveyAnswer{},
+}
+
+func TestMigrations(t *testing.T) {
+	ctx := t.Context()
+
+	db, err := database.New(ctx, "sqlite3", "file::memory:")
+	require.NoError(t, err)
+	{
+		sqlDB, err := db.DB()
+		require.NoError(t, err)
+		sqlDB.SetMaxOpenConns(1) // Every connection would otherwise get its own in-memory database.
+	}
+
+	pending, err := Pending(db)
+	require.NoError(t, err)
+	assert.Len(t, pending, len(Migrations))
+
+	applied, err := Up(ctx, db, "")
+	require.NoError(t, err)
+	assert.Len(t, applied, len(Migrations))
+	assert.True(t, db.Migrator().HasTable(&schema.Person{}))
+
+	// The migrations must create every column of the current models; a change to a model needs a new migration.
+	for _, model := range currentModels {
+		statement := &gorm.Statement{DB: db}
+		require.NoError(t, statement.Parse(model))
+		for _, field := range statement.Schema.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			assert.True(t, db.Migrator().HasColumn(model, field.DBName), "%s.%s", statement.Schema.Table, field.DBName)
+		}
+	}
+
+	statuses, err := GetStatus(db)
+	require.NoError(t, err)
+	require.Len(t, statuses, len(Migrations))
+	for _, status := range statuses {
+		assert.True(t, status.Applied, status.Migration.ID)
+		assert.False(t, status.AppliedTimestamp.IsZero(), status.Migration.ID)
+	}
+
+	// Applying the migrations again does nothing.
+	applied, err = Up(ctx, db, "")
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	rolledBack, err := Down(ctx, db, len(Migrations))
+	require.NoError(t, err)
+	assert.Len(t, rolledBack, len(Migrations))
+	assert.False(t, db.Migrator().HasTable(&schema.Person{}))
+
+	pending, err = Pending(db)
+	require.NoError(t, err)
+	assert.Len(t, pending, len(Migrations))
+
+	// Apply only the first migration.
+	applied, err = Up(ctx, db, Migrations[0].ID)
+	require.NoError(t, err)
+	require.Len(t, applied, 1)
+	assert.Equal(t, Migrations[0].ID, applied[0].ID)
+
+	// A migration that this version doesn't know about means that the database is too new.
+	err = db.Create(&schema.SchemaMigration{ID: "9999"}).Error
+	require.NoError(t, err)
+	_, err = Pending(db)
+	assert.Error(t, err)
+
+	_, err = Up(ctx, db, "unknown")
+	assert.Error(t, err)
+}
@@ -0,0 +1,320 @@
+package migrator
+
+import "github.com/downballot/downballot/internal/schema/sqltype"
+
+// These are the tables as migration 0001 created them.
+//
+// They are copies of the `schema` structures as they were when the migration was released, so that the migration
+// always creates the same tables, no matter how the `schema` structures change later.  Never change them; change the
+// schema with a new migration instead.
+
+type organization0001 struct {
+	ID   uint64 `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	Name string `gorm:"column:name;size:256;type:varchar(256) collate nocase"`
+}
+
+func (organization0001) TableName() string {
+	return "organization"
+}
+
+type organizationArchive0001 struct {
+	ID                  uint64            `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	OrganizationID      uint64            `gorm:"column:organization_id;not null"`
+	Organization        *organization0001 `gorm:"belongsTo;constraint:fk_organization_archive_organization,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:organization_id;references:id"`
+	UserID              uint64            `gorm:"column:user_id;not null"`
+	User                *user0001         `gorm:"belongsTo;constraint:fk_organization_archive_user,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:user_id;references:id"`
+	TokenHash           string            `gorm:"column:token_hash;not null;size:64;type:varchar(64)"`
+	CreatedTimestamp    sqltype.DateTime  `gorm:"column:created_timestamp;not null"`
+	ExpirationTimestamp sqltype.DateTime  `gorm:"column:expiration_timestamp;not null"`
+}
+
+func (organizationArchive0001) TableName() string {
+	return "organization_archive"
+}
+
+type group0001 struct {
+	ID             uint64            `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	OrganizationID uint64            `gorm:"column:organization_id;not null"`
+	Organization   *organization0001 `gorm:"belongsTo;constraint:fk_group_organization,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:organization_id;references:id"`
+	ParentID       *uint64           `gorm:"column:parent_id"`
+	Parent         *group0001        `gorm:"belongsTo;constraint:fk_group_parent,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:parent_id;references:id"`
+	Name           string            `gorm:"column:name;size:256;type:varchar(256) collate nocase"`
+	Filter         string            `gorm:"column:filter;type:text collate nocase"`
+}
+
+func (group0001) TableName() string {
+	return "group"
+}
+
+type role0001 struct {
+	ID             uint64              `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	OrganizationID uint64              `gorm:"column:organization_id;not null;uniqueIndex:idx_unique_role,priority:1"`
+	Organization   *organization0001   `gorm:"belongsTo;constraint:fk_role_organization,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:organization_id;references:id"`
+	Name           string              `gorm:"column:name;not null;size:256;type:varchar(256) collate nocase;uniqueIndex:idx_unique_role,priority:2"`
+	Description    string              `gorm:"column:description;type:text collate nocase"`
+	Permissions    sqltype.StringArray `gorm:"column:permissions;type:text"`
+}
+
+func (role0001) TableName() string {
+	return "role"
+}
+
+type user0001 struct {
+	ID                uint64 `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	Username          string `gorm:"column:username;size:256;unique;type:varchar(256) collate nocase"`
+	Name              string `gorm:"column:name;size:256;type:varchar(256) collate nocase"`
+	SessionIdentifier uint64 `gorm:"column:session_identifier;not null;default:0"`
+	Verified          bool   `gorm:"column:verified;not null;default:0"`
+}
+
+func (user0001) TableName() string {
+	return "user"
+}
+
+type userGroupMap0001 struct {
+	ID      uint64     `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	UserID  uint64     `gorm:"column:user_id;not null;uniqueIndex:idx_unique_user_group,priority:1"`
+	User    *user0001  `gorm:"belongsTo;constraint:fk_user_group_map_user,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:user_id;references:id"`
+	GroupID uint64     `gorm:"column:group_id;not null;uniqueIndex:idx_unique_user_group,priority:2"`
+	Group   *group0001 `gorm:"belongsTo;constraint:fk_user_group_map_group,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:group_id;references:id"`
+	Owner   bool       `gorm:"column:owner;not null;default:0"`
+}
+
+func (userGroupMap0001) TableName() string {
+	return "user_group_map"
+}
+
+type userOrganizationMap0001 struct {
+	ID             uint64            `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	UserID         uint64            `gorm:"column:user_id;not null;uniqueIndex:idx_unique_user_organization,priority:1"`
+	User           *user0001         `gorm:"belongsTo;constraint:fk_user_organization_map_user,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:user_id;references:id"`
+	OrganizationID uint64            `gorm:"column:organization_id;not null;uniqueIndex:idx_unique_user_organization,priority:2"`
+	Organization   *organization0001 `gorm:"belongsTo;constraint:fk_user_organization_map_organization,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:organization_id;references:id"`
+	Owner          bool              `gorm:"column:owner;not null;default:0"`
+	RoleID         *uint64           `gorm:"column:role_id"`
+	Role           *role0001         `gorm:"belongsTo;constraint:fk_user_organization_map_role,OnDelete:SET NULL,OnUpdate:CASCADE;foreignKey:role_id;references:id"`
+}
+
+func (userOrganizationMap0001) TableName() string {
+	return "user_organization_map"
+}
+
+type userTOTP0001 struct {
+	ID     uint64                  `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	UserID uint64                  `gorm:"column:user_id;not null;uniqueIndex:idx_unique_user_totp,priority:1"`
+	User   *user0001               `gorm:"belongsTo;constraint:fk_user_totp_user,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:user_id;references:id"`
+	Secret sqltype.EncryptedString `gorm:"column:secret;not null;size:256;type:varchar(256) collate nocase"`
+}
+
+func (userTOTP0001) TableName() string {
+	return "user_totp"
+}
+
+type userAuthenticator0001 struct {
+	ID                 uint64                  `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	UserID             uint64                  `gorm:"column:user_id;not null;uniqueIndex:idx_unique_user_authenticator"`
+	User               *user0001               `gorm:"belongsTo;constraint:fk_user_authenticator_user,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:user_id;references:id"`
+	Secret             sqltype.EncryptedString `gorm:"column:secret;not null;size:256;type:varchar(256) collate nocase"`
+	CreatedTimestamp   sqltype.DateTime        `gorm:"column:created_timestamp;not null"`
+	ConfirmedTimestamp *sqltype.DateTime       `gorm:"column:confirmed_timestamp"`
+	LastUsedCounter    uint64                  `gorm:"column:last_used_counter;not null;default:0"`
+}
+
+func (userAuthenticator0001) TableName() string {
+	return "user_authenticator"
+}
+
+type userRecoveryCode0001 struct {
+	ID            uint64            `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	UserID        uint64            `gorm:"column:user_id;not null;index:idx_user_recovery_code_user"`
+	User          *user0001         `gorm:"belongsTo;constraint:fk_user_recovery_code_user,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:user_id;references:id"`
+	CodeHash      string            `gorm:"column:code_hash;not null;size:64;type:varchar(64)"`
+	UsedTimestamp *sqltype.DateTime `gorm:"column:used_timestamp"`
+}
+
+func (userRecoveryCode0001) TableName() string {
+	return "user_recovery_code"
+}
+
+type userLink0001 struct {
+	ID                  uint64            `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	UserID              uint64            `gorm:"column:user_id;not null"`
+	User                *user0001         `gorm:"belongsTo;constraint:fk_user_link_user,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:user_id;references:id"`
+	Purpose             string            `gorm:"column:purpose;not null;size:32;type:varchar(32)"`
+	NonceHash           string            `gorm:"column:nonce_hash;not null;size:64;type:varchar(64);uniqueIndex:idx_unique_user_link_nonce_hash"`
+	CreatedTimestamp    sqltype.DateTime  `gorm:"column:created_timestamp;not null"`
+	ExpirationTimestamp sqltype.DateTime  `gorm:"column:expiration_timestamp;not null"`
+	UsedTimestamp       *sqltype.DateTime `gorm:"column:used_timestamp"`
+}
+
+func (userLink0001) TableName() string {
+	return "user_link"
+}
+
+type session0001 struct {
+	ID                  uint64            `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	UserID              uint64            `gorm:"column:user_id;not null;index:idx_session_user"`
+	User                *user0001         `gorm:"belongsTo;constraint:fk_session_user,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:user_id;references:id"`
+	CreatedTimestamp    sqltype.DateTime  `gorm:"column:created_timestamp;not null"`
+	ExpirationTimestamp *sqltype.DateTime `gorm:"column:expiration_timestamp"`
+	RevokedTimestamp    *sqltype.DateTime `gorm:"column:revoked_timestamp"`
+	UserAgent           string            `gorm:"column:user_agent;not null;size:512;type:varchar(512)"`
+	SourceAddress       string            `gorm:"column:source_address;not null;size:64;type:varchar(64)"`
+}
+
+func (session0001) TableName() string {
+	return "session"
+}
+
+type authenticationThrottle0001 struct {
+	ID                   uint64            `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	Kind                 string            `gorm:"column:kind;not null;size:32;type:varchar(32);uniqueIndex:idx_unique_authentication_throttle,priority:1"`
+	Key                  string            `gorm:"column:throttle_key;not null;size:256;type:varchar(256) collate nocase;uniqueIndex:idx_unique_authentication_throttle,priority:2"`
+	Count                int               `gorm:"column:count;not null"`
+	WindowStartTimestamp sqltype.DateTime  `gorm:"column:window_start_timestamp;not null"`
+	LockedUntilTimestamp *sqltype.DateTime `gorm:"column:locked_until_timestamp"`
+}
+
+func (authenticationThrottle0001) TableName() string {
+	return "authentication_throttle"
+}
+
+type apiToken0001 struct {
+	ID                  uint64              `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	UserID              uint64              `gorm:"column:user_id;not null;index:idx_api_token_user"`
+	User                *user0001           `gorm:"belongsTo;constraint:fk_api_token_user,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:user_id;references:id"`
+	OrganizationID      uint64              `gorm:"column:organization_id;not null"`
+	Organization        *organization0001   `gorm:"belongsTo;constraint:fk_api_token_organization,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:organization_id;references:id"`
+	Name                string              `gorm:"column:name;not null;size:256;type:varchar(256) collate nocase"`
+	Permissions         sqltype.StringArray `gorm:"column:permissions;type:text"`
+	TokenHash           string              `gorm:"column:token_hash;not null;size:64;type:varchar(64);uniqueIndex:idx_unique_api_token_hash"`
+	CreatedTimestamp    sqltype.DateTime    `gorm:"column:created_timestamp;not null"`
+	ExpirationTimestamp *sqltype.DateTime   `gorm:"column:expiration_timestamp"`
+	RevokedTimestamp    *sqltype.DateTime   `gorm:"column:revoked_timestamp"`
+}
+
+func (apiToken0001) TableName() string {
+	return "api_token"
+}
+
+type filter0001 struct {
+	ID             uint64            `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	OrganizationID uint64            `gorm:"column:organization_id;not null"`
+	Organization   *organization0001 `gorm:"belongsTo;constraint:fk_group_organization,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:organization_id;references:id"`
+	UserID         *uint64           `gorm:"column:user_id"`
+	User           *user0001         `gorm:"belongsTo;constraint:fk_filter_user,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:user_id;references:id"`
+	Name           string            `gorm:"column:name;size:256;type:varchar(256) collate nocase"`
+	Description    string            `gorm:"column:description;type:text collate nocase"`
+	Filter         string            `gorm:"column:filter;type:text collate nocase"`
+}
+
+func (filter0001) TableName() string {
+	return "filter"
+}
+
+type person0001 struct {
+	ID             uint64            `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	OrganizationID uint64            `gorm:"column:organization_id;not null;uniqueIndex:idx_unique_person,priority:1"`
+	Organization   *organization0001 `gorm:"belongsTo;constraint:fk_person_organization,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:organization_id;references:id"`
+	VoterID        string            `gorm:"column:voter_id;not null;size:256;type:varchar(256) collate nocase;uniqueIndex:idx_unique_person,priority:2"`
+}
+
+func (person0001) TableName() string {
+	return "person"
+}
+
+type personField0001 struct {
+	ID                      uint64                     `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	PersonID                uint64                     `gorm:"column:person_id;not null;uniqueIndex:idx_unique_person_field,priority:1;index:idx_person_field,priority:1"`
+	Person                  *person0001                `gorm:"belongsTo;constraint:fk_person_field_person,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:person_id;references:id"`
+	PersonFieldDefinitionID uint64                     `gorm:"column:person_field_definition_id;not null;uniqueIndex:idx_unique_person_field,priority:2;index:idx_person_field,priority:2"`
+	PersonFieldDefinition   *personFieldDefinition0001 `gorm:"belongsTo;constraint:fk_person_field_person_field_definition,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:person_field_definition_id;references:id"`
+	Value                   string                     `gorm:"column:value;not null;size:256;type:varchar(256) collate nocase;index:idx_person_field,priority:3"`
+	BlindIndex              string                     `gorm:"column:blind_index;not null;default:'';size:64;type:varchar(64);index:idx_person_field_blind_index"`
+}
+
+func (personField0001) TableName() string {
+	return "person_field"
+}
+
+type personFieldDefinition0001 struct {
+	ID             uint64              `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	OrganizationID uint64              `gorm:"column:organization_id;not null;uniqueIndex:idx_unique_person_field_definition,priority:1"`
+	Organization   *organization0001   `gorm:"belongsTo;constraint:fk_person_field_definition_organization,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:organization_id;references:id"`
+	Name           string              `gorm:"column:name;not null;size:256;type:varchar(256) collate nocase;uniqueIndex:idx_unique_person_field_definition,priority:2"`
+	Type           string              `gorm:"column:type;not null;size:256;type:varchar(256) collate nocase"`
+	AllowEmpty     bool                `gorm:"column:allow_empty;not null;default:0"`
+	AllowedValues  sqltype.StringArray `gorm:"column:allowed_values;type:text"`
+	AllowedRegex   string              `gorm:"column:allowed_regex;type:text"`
+	Encrypted      bool                `gorm:"column:encrypted;not null;default:0"`
+}
+
+func (personFieldDefinition0001) TableName() string {
+	return "person_field_definition"
+}
+
+type personAudit0001 struct {
+	ID                      uint64                     `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	PersonID                uint64                     `gorm:"column:person_id;not null"`
+	Person                  *person0001                `gorm:"belongsTo;constraint:fk_person_audit_person,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:person_id;references:id"`
+	UserID                  uint64                     `gorm:"column:user_id;not null"`
+	User                    *user0001                  `gorm:"belongsTo;constraint:fk_user_audit_user,OnDelete:RESTRICT,OnUpdate:CASCADE;foreignKey:user_id;references:id"`
+	Timestamp               sqltype.DateTime           `gorm:"column:timestamp;not null"`
+	PersonFieldDefinitionID uint64                     `gorm:"column:person_field_definition_id;not null"`
+	PersonFieldDefinition   *personFieldDefinition0001 `gorm:"belongsTo;constraint:fk_person_audit_person_field_definition,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:person_field_definition_id;references:id"`
+	OldValue                *string                    `gorm:"column:old_value;type:text"`
+	NewValue                *string                    `gorm:"column:new_value;type:text"`
+}
+
+func (personAudit0001) TableName() string {
+	return "person_audit"
+}
+
+type importJob0001 struct {
+	ID                uint64            `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	OrganizationID    uint64            `gorm:"column:organization_id;not null"`
+	Organization      *organization0001 `gorm:"belongsTo;constraint:fk_import_job_organization,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:organization_id;references:id"`
+	UserID            uint64            `gorm:"column:user_id;not null"`
+	User              *user0001         `gorm:"belongsTo;constraint:fk_import_job_user,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:user_id;references:id"`
+	Status            string            `gorm:"column:status;not null;size:32;type:varchar(32)"`
+	DryRun            bool              `gorm:"column:dry_run;not null;default:0"`
+	Message           string            `gorm:"column:message;type:text"`
+	TotalRows         uint64            `gorm:"column:total_rows;not null;default:0"`
+	ProcessedRows     uint64            `gorm:"column:processed_rows;not null;default:0"`
+	CreatedRows       uint64            `gorm:"column:created_rows;not null;default:0"`
+	UpdatedRows       uint64            `gorm:"column:updated_rows;not null;default:0"`
+	UnchangedRows     uint64            `gorm:"column:unchanged_rows;not null;default:0"`
+	FailedRows        uint64            `gorm:"column:failed_rows;not null;default:0"`
+	CreatedTimestamp  sqltype.DateTime  `gorm:"column:created_timestamp;not null"`
+	FinishedTimestamp *sqltype.DateTime `gorm:"column:finished_timestamp"`
+}
+
+func (importJob0001) TableName() string {
+	return "import_job"
+}
+
+type importJobError0001 struct {
+	ID          uint64         `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	ImportJobID uint64         `gorm:"column:import_job_id;not null;index:idx_import_job_error,priority:1"`
+	ImportJob   *importJob0001 `gorm:"belongsTo;constraint:fk_import_job_error_import_job,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:import_job_id;references:id"`
+	RowNumber   uint64         `gorm:"column:row_num;not null;index:idx_import_job_error,priority:2"`
+	VoterID     string         `gorm:"column:voter_id;size:256;type:varchar(256) collate nocase"`
+	Message     string         `gorm:"column:message;type:text"`
+}
+
+func (importJobError0001) TableName() string {
+	return "import_job_error"
+}
+
+type importProfile0001 struct {
+	ID             uint64            `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	OrganizationID uint64            `gorm:"column:organization_id;not null;uniqueIndex:idx_unique_import_profile,priority:1"`
+	Organization   *organization0001 `gorm:"belongsTo;constraint:fk_import_profile_organization,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:organization_id;references:id"`
+	Name           string            `gorm:"column:name;not null;size:256;type:varchar(256) collate nocase;uniqueIndex:idx_unique_import_profile,priority:2"`
+	Description    string            `gorm:"column:description;type:text collate nocase"`
+	Definition     string            `gorm:"column:definition;type:text"`
+}
+
+func (importProfile0001) TableName() string {
+	return "import_profile"
+}
@@ -0,0 +1,35 @@
+package migrator
+
+import "github.com/downballot/downballot/internal/schema/sqltype"
+
+// These are the tables as migration 0002 created them (see models-0001.go); never change them.
+
+type contactResult0002 struct {
+	ID             uint64            `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	OrganizationID uint64            `gorm:"column:organization_id;not null;uniqueIndex:idx_unique_contact_result,priority:1"`
+	Organization   *organization0001 `gorm:"belongsTo;constraint:fk_contact_result_organization,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:organization_id;references:id"`
+	Code           string            `gorm:"column:code;not null;size:64;type:varchar(64);uniqueIndex:idx_unique_contact_result,priority:2"`
+	Name           string            `gorm:"column:name;not null;size:256;type:varchar(256) collate nocase"`
+	Description    string            `gorm:"column:description;type:text collate nocase"`
+}
+
+func (contactResult0002) TableName() string {
+	return "contact_result"
+}
+
+type contactAttempt0002 struct {
+	ID              uint64             `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	PersonID        uint64             `gorm:"column:person_id;not null;index:idx_contact_attempt_person"`
+	Person          *person0001        `gorm:"belongsTo;constraint:fk_contact_attempt_person,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:person_id;references:id"`
+	UserID          uint64             `gorm:"column:user_id;not null;index:idx_contact_attempt_user"`
+	User            *user0001          `gorm:"belongsTo;constraint:fk_contact_attempt_user,OnDelete:RESTRICT,OnUpdate:CASCADE;foreignKey:user_id;references:id"`
+	Timestamp       sqltype.DateTime   `gorm:"column:timestamp;not null"`
+	Channel         string             `gorm:"column:channel;not null;size:16;type:varchar(16)"`
+	ContactResultID uint64             `gorm:"column:contact_result_id;not null"`
+	ContactResult   *contactResult0002 `gorm:"belongsTo;constraint:fk_contact_attempt_contact_result,OnDelete:RESTRICT,OnUpdate:CASCADE;foreignKey:contact_result_id;references:id"`
+	Note            string             `gorm:"column:note;type:text"`
+}
+
+func (contactAttempt0002) TableName() string {
+	return "contact_attempt"
+}
@@ -0,0 +1,27 @@
+package migrator
+
+// These are the tables as migration 0003 created them (see models-0001.go); never change them.
+
+type household0003 struct {
+	ID             uint64            `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	OrganizationID uint64            `gorm:"column:organization_id;not null;uniqueIndex:idx_unique_household,priority:1"`
+	Organization   *organization0001 `gorm:"belongsTo;constraint:fk_household_organization,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:organization_id;references:id"`
+	AddressKey     *string           `gorm:"column:address_key;size:256;type:varchar(256);uniqueIndex:idx_unique_household,priority:2"`
+	Address        string            `gorm:"column:address;not null;type:text"`
+}
+
+func (household0003) TableName() string {
+	return "household"
+}
+
+type householdMember0003 struct {
+	PersonID    uint64         `gorm:"column:person_id;primaryKey;not null;autoIncrement:false"`
+	Person      *person0001    `gorm:"belongsTo;constraint:fk_household_member_person,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:person_id;references:id"`
+	HouseholdID uint64         `gorm:"column:household_id;not null;index:idx_household_member_household"`
+	Household   *household0003 `gorm:"belongsTo;constraint:fk_household_member_household,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:household_id;references:id"`
+	Manual      bool           `gorm:"column:manual;not null;default:false"`
+}
+
+func (householdMember0003) TableName() string {
+	return "household_member"
+}
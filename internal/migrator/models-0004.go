@@ -0,0 +1,31 @@
+package migrator
+
+// These are the tables as migration 0004 created them (see models-0001.go); never change them.
+
+type turf0004 struct {
+	ID             uint64            `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	OrganizationID uint64            `gorm:"column:organization_id;not null;uniqueIndex:idx_unique_turf,priority:1"`
+	Organization   *organization0001 `gorm:"belongsTo;constraint:fk_turf_organization,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:organization_id;references:id"`
+	Name           string            `gorm:"column:name;not null;size:256;type:varchar(256) collate nocase;uniqueIndex:idx_unique_turf,priority:2"`
+	Description    string            `gorm:"column:description;type:text collate nocase"`
+	Geometry       string            `gorm:"column:geometry;not null;type:text"`
+	MinLatitude    float64           `gorm:"column:min_latitude;not null"`
+	MinLongitude   float64           `gorm:"column:min_longitude;not null"`
+	MaxLatitude    float64           `gorm:"column:max_latitude;not null"`
+	MaxLongitude   float64           `gorm:"column:max_longitude;not null"`
+}
+
+func (turf0004) TableName() string {
+	return "turf"
+}
+
+type turfMember0004 struct {
+	TurfID   uint64      `gorm:"column:turf_id;primaryKey;not null;autoIncrement:false"`
+	Turf     *turf0004   `gorm:"belongsTo;constraint:fk_turf_member_turf,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:turf_id;references:id"`
+	PersonID uint64      `gorm:"column:person_id;primaryKey;not null;autoIncrement:false;index:idx_turf_member_person"`
+	Person   *person0001 `gorm:"belongsTo;constraint:fk_turf_member_person,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:person_id;references:id"`
+}
+
+func (turfMember0004) TableName() string {
+	return "turf_member"
+}
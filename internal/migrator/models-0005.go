@@ -0,0 +1,77 @@
+package migrator
+
+import "github.com/downballot/downballot/internal/schema/sqltype"
+
+// These are the tables as migration 0005 created them (see models-0001.go); never change them.
+
+type survey0005 struct {
+	ID             uint64            `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	OrganizationID uint64            `gorm:"column:organization_id;not null;uniqueIndex:idx_unique_survey,priority:1"`
+	Organization   *organization0001 `gorm:"belongsTo;constraint:fk_survey_organization,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:organization_id;references:id"`
+	Name           string            `gorm:"column:name;not null;size:256;type:varchar(256) collate nocase;uniqueIndex:idx_unique_survey,priority:2"`
+	Description    string            `gorm:"column:description;type:text collate nocase"`
+	Active         bool              `gorm:"column:active;not null;default:0"`
+}
+
+func (survey0005) TableName() string {
+	return "survey"
+}
+
+type surveyQuestion0005 struct {
+	ID                      uint64                     `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	SurveyID                uint64                     `gorm:"column:survey_id;not null;index:idx_survey_question_survey"`
+	Survey                  *survey0005                `gorm:"belongsTo;constraint:fk_survey_question_survey,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:survey_id;references:id"`
+	Position                int                        `gorm:"column:position;not null"`
+	Prompt                  string                     `gorm:"column:prompt;not null;type:text"`
+	Type                    string                     `gorm:"column:type;not null;size:16;type:varchar(16)"`
+	PersonFieldDefinitionID *uint64                    `gorm:"column:person_field_definition_id"`
+	PersonFieldDefinition   *personFieldDefinition0001 `gorm:"belongsTo;constraint:fk_survey_question_person_field_definition,OnDelete:SET NULL,OnUpdate:CASCADE;foreignKey:person_field_definition_id;references:id"`
+}
+
+func (surveyQuestion0005) TableName() string {
+	return "survey_question"
+}
+
+type surveyChoice0005 struct {
+	ID               uint64              `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	SurveyQuestionID uint64              `gorm:"column:survey_question_id;not null;uniqueIndex:idx_unique_survey_choice,priority:1"`
+	SurveyQuestion   *surveyQuestion0005 `gorm:"belongsTo;constraint:fk_survey_choice_survey_question,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:survey_question_id;references:id"`
+	Position         int                 `gorm:"column:position;not null"`
+	Code             string              `gorm:"column:code;not null;size:64;type:varchar(64);uniqueIndex:idx_unique_survey_choice,priority:2"`
+	Label            string              `gorm:"column:label;not null;size:256;type:varchar(256)"`
+	Value            string              `gorm:"column:value;type:text"`
+}
+
+func (surveyChoice0005) TableName() string {
+	return "survey_choice"
+}
+
+type surveyResponse0005 struct {
+	ID               uint64              `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	SurveyID         uint64              `gorm:"column:survey_id;not null;index:idx_survey_response_survey"`
+	Survey           *survey0005         `gorm:"belongsTo;constraint:fk_survey_response_survey,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:survey_id;references:id"`
+	PersonID         uint64              `gorm:"column:person_id;not null;index:idx_survey_response_person"`
+	Person           *person0001         `gorm:"belongsTo;constraint:fk_survey_response_person,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:person_id;references:id"`
+	UserID           uint64              `gorm:"column:user_id;not null;index:idx_survey_response_user"`
+	User             *user0001           `gorm:"belongsTo;constraint:fk_survey_response_user,OnDelete:RESTRICT,OnUpdate:CASCADE;foreignKey:user_id;references:id"`
+	Timestamp        sqltype.DateTime    `gorm:"column:timestamp;not null"`
+	ContactAttemptID *uint64             `gorm:"column:contact_attempt_id"`
+	ContactAttempt   *contactAttempt0002 `gorm:"belongsTo;constraint:fk_survey_response_contact_attempt,OnDelete:SET NULL,OnUpdate:CASCADE;foreignKey:contact_attempt_id;references:id"`
+}
+
+func (surveyResponse0005) TableName() string {
+	return "survey_response"
+}
+
+type surveyAnswer0005 struct {
+	ID               uint64              `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	SurveyResponseID uint64              `gorm:"column:survey_response_id;not null;uniqueIndex:idx_unique_survey_answer,priority:1"`
+	SurveyResponse   *surveyResponse0005 `gorm:"belongsTo;constraint:fk_survey_answer_survey_response,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:survey_response_id;references:id"`
+	SurveyQuestionID uint64              `gorm:"column:survey_question_id;not null;uniqueIndex:idx_unique_survey_answer,priority:2"`
+	SurveyQuestion   *surveyQuestion0005 `gorm:"belongsTo;constraint:fk_survey_answer_survey_question,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:survey_question_id;references:id"`
+	Value            string              `gorm:"column:value;not null;type:text"`
+}
+
+func (surveyAnswer0005) TableName() string {
+	return "survey_answer"
+}
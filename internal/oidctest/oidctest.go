@@ -0,0 +1,215 @@
+// Package oidctest provides a stand-in OpenID Connect identity provider for tests.
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// keyID is the ID of the only signing key.
+const keyID = "oidctest"
+
+// Issuer is a stand-in OpenID Connect identity provider.
+//
+// Whoever visits the authorization endpoint is immediately logged in as the current identity (see `SetIdentity`)
+// and sent back with a code.
+type Issuer struct {
+	ClientID     string // This is the only client ID that the issuer accepts.
+	ClientSecret string // This is the client secret for the client ID.
+
+	httpServer *httptest.Server
+	privateKey *rsa.PrivateKey
+
+	mutex         sync.Mutex
+	email         string               // This is the current identity's e-mail address.
+	emailVerified bool                 // Whether the current identity's e-mail address has been verified.
+	codes         map[string]codeGrant // These are the outstanding authorization codes.
+}
+
+// codeGrant is what an authorization code was issued for.
+type codeGrant struct {
+	email         string
+	emailVerified bool
+	nonce         string
+	redirectURI   string
+}
+
+// New starts a new issuer.
+func New(clientID string, clientSecret string) (*Issuer, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("could not generate key: %w", err)
+	}
+
+	i := &Issuer{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		privateKey:   privateKey,
+		codes:        map[string]codeGrant{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", i.handleDiscovery)
+	mux.HandleFunc("/authorize", i.handleAuthorize)
+	mux.HandleFunc("/token", i.handleToken)
+	mux.HandleFunc("/keys", i.handleKeys)
+	i.httpServer = httptest.NewServer(mux)
+
+	return i, nil
+}
+
+// Close shuts down the issuer.
+func (i *Issuer) Close() {
+	i.httpServer.Close()
+}
+
+// URL returns the issuer URL.
+func (i *Issuer) URL() string {
+	return i.httpServer.URL
+}
+
+// SetIdentity sets the identity that the next user to visit the authorization endpoint will be logged in as.
+func (i *Issuer) SetIdentity(email string, emailVerified bool) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	i.email = email
+	i.emailVerified = emailVerified
+}
+
+func (i *Issuer) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                i.URL(),
+		"authorization_endpoint":                i.URL() + "/authorize",
+		"token_endpoint":                        i.URL() + "/token",
+		"jwks_uri":                              i.URL() + "/keys",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	})
+}
+
+func (i *Issuer) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("client_id") != i.ClientID {
+		http.Error(w, "unknown client", http.StatusBadRequest)
+		return
+	}
+	redirectURI, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || query.Get("redirect_uri") == "" {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	code := randomString()
+
+	i.mutex.Lock()
+	i.codes[code] = codeGrant{
+		email:         i.email,
+		emailVerified: i.emailVerified,
+		nonce:         query.Get("nonce"),
+		redirectURI:   query.Get("redirect_uri"),
+	}
+	i.mutex.Unlock()
+
+	values := redirectURI.Query()
+	values.Set("code", code)
+	values.Set("state", query.Get("state"))
+	redirectURI.RawQuery = values.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (i *Issuer) handleToken(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_request"})
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID = r.PostForm.Get("client_id")
+		clientSecret = r.PostForm.Get("client_secret")
+	}
+	if clientID != i.ClientID || clientSecret != i.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]any{"error": "invalid_client"})
+		return
+	}
+
+	// Each code can only be used once.
+	code := r.PostForm.Get("code")
+	i.mutex.Lock()
+	grant, ok := i.codes[code]
+	delete(i.codes, code)
+	i.mutex.Unlock()
+	if !ok || r.PostForm.Get("grant_type") != "authorization_code" || r.PostForm.Get("redirect_uri") != grant.redirectURI {
+		writeJSON(w, http.StatusBadRequest, map[string]any{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            i.URL(),
+		"sub":            grant.email,
+		"aud":            i.ClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          grant.nonce,
+		"email":          grant.email,
+		"email_verified": grant.emailVerified,
+	})
+	token.Header["kid"] = keyID
+	idToken, err := token.SignedString(i.privateKey)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]any{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"expires_in":   300,
+		"id_token":     idToken,
+	})
+}
+
+func (i *Issuer) handleKeys(w http.ResponseWriter, r *http.Request) {
+	publicKey := i.privateKey.PublicKey
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]any{
+			{
+				"kty": "RSA",
+				"use": "sig",
+				"alg": "RS256",
+				"kid": keyID,
+				"n":   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+			},
+		},
+	})
+}
+
+// writeJSON writes the value as JSON.
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+// randomString returns a random string suitable for a code or a token.
+func randomString() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -0,0 +1,166 @@
+// Package reencrypt re-encrypts the encrypted database columns with the active encryption key.
+//
+// Once every value has been re-encrypted, the old key can be removed from the configuration.
+package reencrypt
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+
+	"github.com/downballot/downballot/internal/schema/sqltype"
+	"gorm.io/gorm"
+)
+
+// DefaultBatchSize is the default number of rows to re-encrypt in each transaction.
+const DefaultBatchSize = 100
+
+// Column is an encrypted column.
+type Column struct {
+	Table  string
+	Column string
+	Text   bool   // If set, then the values are encrypted as text (see `sqltype.EncryptText`) instead of being raw bytes.
+	Where  string // If set, then only the rows that match this condition are encrypted.
+}
+
+// encryptedPersonFieldCondition matches the person field values (and audits) that belong to encrypted fields.
+const encryptedPersonFieldCondition = "person_field_definition_id IN (SELECT id FROM person_field_definition WHERE encrypted)"
+
+// Columns are all of the encrypted columns.
+//
+// Every table must have an "id" primary key.
+var Columns = []Column{
+	{Table: "user_totp", Column: "secret"},
+	{Table: "user_authenticator", Column: "secret"},
+	{Table: "person_field", Column: "value", Text: true, Where: encryptedPersonFieldCondition},
+	{Table: "person_audit", Column: "old_value", Text: true, Where: encryptedPersonFieldCondition},
+	{Table: "person_audit", Column: "new_value", Text: true, Where: encryptedPersonFieldCondition},
+}
+
+// Result is the result of re-encrypting a column.
+type Result struct {
+	Column      Column
+	Rows        int // This is the number of rows that were checked.
+	Reencrypted int // This is the number of rows that were re-encrypted.
+}
+
+// Run re-encrypts every encrypted column with the active encryption key.
+func Run(ctx context.Context, db *gorm.DB, batchSize int) ([]Result, error) {
+	var output []Result
+	for _, column := range Columns {
+		result, err := column.Run(ctx, db, batchSize)
+		if err != nil {
+			return output, err
+		}
+		output = append(output, result)
+	}
+	return output, nil
+}
+
+// row is a row of an encrypted column, before it has been decrypted.
+type row struct {
+	ID    uint64
+	Value []byte
+}
+
+// Run re-encrypts the column with the active encryption key.
+//
+// The rows are processed in batches, each in its own transaction, so that a large table doesn't hold a lock for the
+// whole run.  Rows that were already encrypted with the active key are left alone, so this can safely be run again
+// if it is interrupted; a row that changes while it is being re-encrypted is left alone as well.
+func (c Column) Run(ctx context.Context, db *gorm.DB, batchSize int) (Result, error) {
+	result := Result{
+		Column: c,
+	}
+
+	activeKeyID := sqltype.ActiveEncryptionKeyID()
+	if activeKeyID == "" {
+		return result, fmt.Errorf("encryption key not set")
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+
+	var lastID uint64
+	for {
+		var rows []row
+		query := db.Session(&gorm.Session{NewDB: true}).
+			Table(c.Table).
+			Select("id", c.Column+" AS value").
+			Where("id > ?", lastID)
+		if c.Where != "" {
+			query = query.Where(c.Where)
+		}
+		err := query.
+			Order("id ASC").
+			Limit(batchSize).
+			Scan(&rows).
+			Error
+		if err != nil {
+			return result, fmt.Errorf("%s.%s: could not find rows: %w", c.Table, c.Column, err)
+		}
+		if len(rows) == 0 {
+			break
+		}
+		lastID = rows[len(rows)-1].ID
+
+		err = db.Transaction(func(tx *gorm.DB) error {
+			for _, r := range rows {
+				result.Rows++
+				if r.Value == nil {
+					continue
+				}
+				content := r.Value
+				if c.Text {
+					var err error
+					content, err = base64.StdEncoding.DecodeString(string(r.Value))
+					if err != nil {
+						return fmt.Errorf("%s.%s: row %d: could not decode encrypted text: %w", c.Table, c.Column, r.ID, err)
+					}
+				}
+				if sqltype.EncryptionKeyIDOf(content) == activeKeyID {
+					continue
+				}
+
+				var value sqltype.EncryptedString
+				err := value.Scan(content)
+				if err != nil {
+					return fmt.Errorf("%s.%s: row %d: %w", c.Table, c.Column, r.ID, err)
+				}
+				var newValue any = value
+				if c.Text {
+					newValue, err = sqltype.EncryptText(string(value))
+					if err != nil {
+						return fmt.Errorf("%s.%s: row %d: %w", c.Table, c.Column, r.ID, err)
+					}
+				}
+				// The value was read outside of the transaction, so it is only replaced if it hasn't changed since;
+				// otherwise, a new value that was saved in the meantime would be overwritten with the old one.
+				var oldValue any = r.Value
+				if c.Text {
+					oldValue = string(r.Value)
+				}
+				updateResult := tx.Session(&gorm.Session{NewDB: true}).
+					Table(c.Table).
+					Where("id = ?", r.ID).
+					Where(c.Column+" = ?", oldValue).
+					Update(c.Column, newValue)
+				if updateResult.Error != nil {
+					return fmt.Errorf("%s.%s: row %d: could not update row: %w", c.Table, c.Column, r.ID, updateResult.Error)
+				}
+				if updateResult.RowsAffected == 0 {
+					// The new value was saved with the active key, so there is nothing left to do.
+					continue
+				}
+				result.Reencrypted++
+			}
+			return nil
+		})
+		if err != nil {
+			return result, err
+		}
+		slog.InfoContext(ctx, fmt.Sprintf("%s.%s: %d rows checked; %d rows re-encrypted.", c.Table, c.Column, result.Rows, result.Reencrypted))
+	}
+	return result, nil
+}
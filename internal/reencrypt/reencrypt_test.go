@@ -0,0 +1,212 @@
+package reencrypt_test
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/downballot/downballot/internal/databasetest"
+	"github.com/downballot/downballot/internal/reencrypt"
+	"github.com/downballot/downballot/internal/schema"
+	"github.com/downballot/downballot/internal/schema/sqltype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestRun(t *testing.T) {
+	ctx := t.Context()
+
+	db, err := databasetest.New(ctx)
+	require.NoError(t, err)
+
+	legacyKey := strings.Repeat("11", 32)
+	oldKey := strings.Repeat("22", 32)
+	newKey := strings.Repeat("33", 32)
+
+	previousKey := sqltype.GetEncryptionKey()
+	t.Cleanup(func() {
+		sqltype.SetEncryptionKey(fmt.Sprintf("%x", previousKey))
+	})
+
+	// rawSecret returns the secret for the user as it is stored in the database.
+	rawSecret := func(userID uint64) []byte {
+		var output []byte
+		err := db.Table("user_totp").Select("secret").Where("user_id = ?", userID).Row().Scan(&output)
+		require.NoError(t, err)
+		return output
+	}
+
+	var userIDs []uint64
+	for i := range 5 {
+		user := schema.User{
+			Username: fmt.Sprintf("reencrypt-%d@example.com", i),
+		}
+		require.NoError(t, db.Create(&user).Error)
+		userIDs = append(userIDs, user.ID)
+	}
+
+	// The first secret was encrypted before values had key IDs.
+	{
+		block, err := aes.NewCipher([]byte(strings.Repeat("\x11", 32)))
+		require.NoError(t, err)
+		gcm, err := cipher.NewGCM(block)
+		require.NoError(t, err)
+		nonce := make([]byte, gcm.NonceSize())
+		_, err = rand.Read(nonce)
+		require.NoError(t, err)
+		encrypted := gcm.Seal(nonce, nonce, []byte("secret-0"), nil)
+		require.NoError(t, db.Exec("INSERT INTO user_totp (user_id, secret) VALUES (?, ?)", userIDs[0], encrypted).Error)
+	}
+
+	// The rest were encrypted with the old key.
+	require.NoError(t, sqltype.SetEncryptionKeys([]sqltype.EncryptionKey{
+		{ID: sqltype.LegacyEncryptionKeyID, Key: legacyKey},
+		{ID: "old", Key: oldKey},
+	}, "old"))
+	for i, userID := range userIDs[1:] {
+		require.NoError(t, db.Create(&schema.UserTOTP{
+			UserID: userID,
+			Secret: sqltype.EncryptedString(fmt.Sprintf("secret-%d", i+1)),
+		}).Error)
+	}
+	assert.Equal(t, sqltype.LegacyEncryptionKeyID, sqltype.EncryptionKeyIDOf(rawSecret(userIDs[0])))
+	assert.Equal(t, "old", sqltype.EncryptionKeyIDOf(rawSecret(userIDs[1])))
+
+	// Rotate to the new key.
+	require.NoError(t, sqltype.SetEncryptionKeys([]sqltype.EncryptionKey{
+		{ID: sqltype.LegacyEncryptionKeyID, Key: legacyKey},
+		{ID: "old", Key: oldKey},
+		{ID: "new", Key: newKey},
+	}, "new"))
+
+	// The old values can still be read.
+	var userTOTPs []*schema.UserTOTP
+	require.NoError(t, db.Where("user_id IN ?", userIDs).Order("user_id ASC").Find(&userTOTPs).Error)
+	require.Len(t, userTOTPs, 5)
+	for i, userTOTP := range userTOTPs {
+		assert.Equal(t, sqltype.EncryptedString(fmt.Sprintf("secret-%d", i)), userTOTP.Secret)
+	}
+
+	results, err := reencrypt.Run(ctx, db, 2)
+	require.NoError(t, err)
+	require.Len(t, results, len(reencrypt.Columns))
+	assert.Equal(t, reencrypt.Column{Table: "user_totp", Column: "secret"}, results[0].Column)
+	assert.Equal(t, 5, results[0].Rows)
+	assert.Equal(t, 5, results[0].Reencrypted)
+	for _, userID := range userIDs {
+		assert.Equal(t, "new", sqltype.EncryptionKeyIDOf(rawSecret(userID)))
+	}
+
+	// Once everything has been re-encrypted, the old keys are no longer needed.
+	require.NoError(t, sqltype.SetEncryptionKeys([]sqltype.EncryptionKey{
+		{ID: "new", Key: newKey},
+	}, ""))
+	userTOTPs = nil
+	require.NoError(t, db.Where("user_id IN ?", userIDs).Order("user_id ASC").Find(&userTOTPs).Error)
+	require.Len(t, userTOTPs, 5)
+	for i, userTOTP := range userTOTPs {
+		assert.Equal(t, sqltype.EncryptedString(fmt.Sprintf("secret-%d", i)), userTOTP.Secret)
+	}
+
+	// Running it again does nothing.
+	results, err = reencrypt.Run(ctx, db, 2)
+	require.NoError(t, err)
+	assert.Equal(t, 5, results[0].Rows)
+	assert.Equal(t, 0, results[0].Reencrypted)
+
+	// A value whose key is gone cannot be read.
+	require.NoError(t, sqltype.SetEncryptionKeys([]sqltype.EncryptionKey{
+		{ID: "old", Key: oldKey},
+	}, ""))
+	err = db.Session(&gorm.Session{}).Where("user_id IN ?", userIDs).Find(&userTOTPs).Error
+	assert.Error(t, err)
+}
+
+func TestRunConcurrentUpdate(t *testing.T) {
+	ctx := t.Context()
+
+	db, err := databasetest.New(ctx)
+	require.NoError(t, err)
+
+	oldKey := strings.Repeat("44", 32)
+	newKey := strings.Repeat("55", 32)
+
+	previousKey := sqltype.GetEncryptionKey()
+	t.Cleanup(func() {
+		sqltype.SetEncryptionKey(fmt.Sprintf("%x", previousKey))
+	})
+
+	organization := schema.Organization{Name: "Concurrent Update"}
+	require.NoError(t, db.Create(&organization).Error)
+	definition := schema.PersonFieldDefinition{
+		OrganizationID: organization.ID,
+		Name:           "ssn",
+		Type:           schema.PersonFieldDefinitionTypeString,
+		Encrypted:      true,
+	}
+	require.NoError(t, db.Create(&definition).Error)
+
+	require.NoError(t, sqltype.SetEncryptionKeys([]sqltype.EncryptionKey{
+		{ID: "old", Key: oldKey},
+	}, "old"))
+	var fieldIDs []uint64
+	for i := range 2 {
+		person := schema.Person{OrganizationID: organization.ID, VoterID: fmt.Sprintf("concurrent-%d", i)}
+		require.NoError(t, db.Create(&person).Error)
+		value, err := sqltype.EncryptText(fmt.Sprintf("value-%d", i))
+		require.NoError(t, err)
+		field := schema.PersonField{PersonID: person.ID, PersonFieldDefinitionID: definition.ID, Value: value}
+		require.NoError(t, db.Create(&field).Error)
+		fieldIDs = append(fieldIDs, field.ID)
+	}
+
+	require.NoError(t, sqltype.SetEncryptionKeys([]sqltype.EncryptionKey{
+		{ID: "old", Key: oldKey},
+		{ID: "new", Key: newKey},
+	}, "new"))
+
+	// Someone saves a new value for the first field after it has been read but before it has been re-encrypted.
+	concurrentValue, err := sqltype.EncryptText("changed")
+	require.NoError(t, err)
+	changed := false
+	err = db.Callback().Update().Before("gorm:update").Register("reencrypt_test:concurrent_update", func(tx *gorm.DB) {
+		if changed || tx.Statement.Table != "person_field" {
+			return
+		}
+		changed = true
+		err := tx.Session(&gorm.Session{NewDB: true}).
+			Exec("UPDATE person_field SET value = ? WHERE id = ?", concurrentValue, fieldIDs[0]).
+			Error
+		require.NoError(t, err)
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = db.Callback().Update().Remove("reencrypt_test:concurrent_update")
+	})
+
+	column := reencrypt.Column{Table: "person_field", Column: "value", Text: true}
+	result, err := column.Run(ctx, db, 10)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.Equal(t, 2, result.Rows)
+	assert.Equal(t, 1, result.Reencrypted)
+
+	// The new value was kept, and the other value was re-encrypted.
+	var fields []*schema.PersonField
+	require.NoError(t, db.Where("id IN ?", fieldIDs).Order("id ASC").Find(&fields).Error)
+	require.Len(t, fields, 2)
+	assert.Equal(t, concurrentValue, fields[0].Value)
+	for i, field := range fields {
+		content, err := base64.StdEncoding.DecodeString(field.Value)
+		require.NoError(t, err)
+		assert.Equal(t, "new", sqltype.EncryptionKeyIDOf(content), "Field %d", i)
+	}
+	value, err := sqltype.DecryptText(fields[1].Value)
+	require.NoError(t, err)
+	assert.Equal(t, "value-1", value)
+}
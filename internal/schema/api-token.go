@@ -0,0 +1,25 @@
+package schema
+
+import "github.com/downballot/downballot/internal/schema/sqltype"
+
+// APIToken is a long-lived personal API token, such as for an integration.
+//
+// The token acts as the user that created it, but only within one organization and only with the given permissions.
+// Only a hash of the token is stored.
+type APIToken struct {
+	ID                  uint64              `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	UserID              uint64              `gorm:"column:user_id;not null;index:idx_api_token_user"`
+	User                *User               `gorm:"belongsTo;constraint:fk_api_token_user,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:user_id;references:id" json:"-"`
+	OrganizationID      uint64              `gorm:"column:organization_id;not null"`
+	Organization        *Organization       `gorm:"belongsTo;constraint:fk_api_token_organization,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:organization_id;references:id" json:"-"`
+	Name                string              `gorm:"column:name;not null;size:256;type:varchar(256) collate nocase"`
+	Permissions         sqltype.StringArray `gorm:"column:permissions;type:text"`
+	TokenHash           string              `gorm:"column:token_hash;not null;size:64;type:varchar(64);uniqueIndex:idx_unique_api_token_hash"`
+	CreatedTimestamp    sqltype.DateTime    `gorm:"column:created_timestamp;not null"`
+	ExpirationTimestamp *sqltype.DateTime   `gorm:"column:expiration_timestamp"` // If not set, then the token never expires.
+	RevokedTimestamp    *sqltype.DateTime   `gorm:"column:revoked_timestamp"`    // Once set, the token no longer works.
+}
+
+func (APIToken) TableName() string {
+	return "api_token"
+}
@@ -0,0 +1,20 @@
+package schema
+
+import "github.com/downballot/downballot/internal/schema/sqltype"
+
+// AuthenticationThrottle tracks how often something has been attempted, so that authentication can be rate limited.
+//
+// The kind says what is being tracked (such as failed logins for a username, or e-mails sent to an address), and the key
+// is the value that it is being tracked for.
+type AuthenticationThrottle struct {
+	ID                   uint64            `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	Kind                 string            `gorm:"column:kind;not null;size:32;type:varchar(32);uniqueIndex:idx_unique_authentication_throttle,priority:1"`
+	Key                  string            `gorm:"column:throttle_key;not null;size:256;type:varchar(256) collate nocase;uniqueIndex:idx_unique_authentication_throttle,priority:2"`
+	Count                int               `gorm:"column:count;not null"`                  // This is the number of attempts in the current window.
+	WindowStartTimestamp sqltype.DateTime  `gorm:"column:window_start_timestamp;not null"` // This is when the first attempt in the current window was made.
+	LockedUntilTimestamp *sqltype.DateTime `gorm:"column:locked_until_timestamp"`          // If set, then no attempts are allowed until this time.
+}
+
+func (AuthenticationThrottle) TableName() string {
+	return "authentication_throttle"
+}
@@ -0,0 +1,37 @@
+package schema
+
+import "github.com/downballot/downballot/internal/schema/sqltype"
+
+// UserAuthenticator is an authenticator app that a user has enrolled as a second factor.
+//
+// Unlike the `UserTOTP` secret, whose codes are e-mailed to the user, this secret is only ever shown to the user while
+// enrolling, so that a compromised e-mail account is not enough to log in.  The authenticator is not used until the
+// user has confirmed it with a code from the app.
+type UserAuthenticator struct {
+	ID                 uint64                  `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	UserID             uint64                  `gorm:"column:user_id;not null;uniqueIndex:idx_unique_user_authenticator"`
+	User               *User                   `gorm:"belongsTo;constraint:fk_user_authenticator_user,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:user_id;references:id" json:"-"`
+	Secret             sqltype.EncryptedString `gorm:"column:secret;not null;size:256;type:varchar(256) collate nocase"`
+	CreatedTimestamp   sqltype.DateTime        `gorm:"column:created_timestamp;not null"`
+	ConfirmedTimestamp *sqltype.DateTime       `gorm:"column:confirmed_timestamp"`                  // Until this is set, the authenticator is not required to log in.
+	LastUsedCounter    uint64                  `gorm:"column:last_used_counter;not null;default:0"` // This is the time step of the last code that was accepted; a code cannot be used twice.
+}
+
+func (UserAuthenticator) TableName() string {
+	return "user_authenticator"
+}
+
+// UserRecoveryCode is a single-use code that can be used in place of a code from the user's authenticator app.
+//
+// Only a hash of the code is stored.
+type UserRecoveryCode struct {
+	ID            uint64            `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	UserID        uint64            `gorm:"column:user_id;not null;index:idx_user_recovery_code_user"`
+	User          *User             `gorm:"belongsTo;constraint:fk_user_recovery_code_user,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:user_id;references:id" json:"-"`
+	CodeHash      string            `gorm:"column:code_hash;not null;size:64;type:varchar(64)"`
+	UsedTimestamp *sqltype.DateTime `gorm:"column:used_timestamp"` // Once set, the code cannot be used again.
+}
+
+func (UserRecoveryCode) TableName() string {
+	return "user_recovery_code"
+}
@@ -0,0 +1,44 @@
+package schema
+
+import "github.com/downballot/downballot/internal/schema/sqltype"
+
+// ContactChannel is how a person was contacted.
+type ContactChannel string
+
+const (
+	ContactChannelDoor  ContactChannel = "door"
+	ContactChannelPhone ContactChannel = "phone"
+	ContactChannelText  ContactChannel = "text"
+)
+
+// ContactResult is one of the outcomes that an organization records for its contact attempts, such as "not_home".
+type ContactResult struct {
+	ID             uint64        `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	OrganizationID uint64        `gorm:"column:organization_id;not null;uniqueIndex:idx_unique_contact_result,priority:1"`
+	Organization   *Organization `gorm:"belongsTo;constraint:fk_contact_result_organization,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:organization_id;references:id" json:"-"`
+	Code           string        `gorm:"column:code;not null;size:64;type:varchar(64);uniqueIndex:idx_unique_contact_result,priority:2"` // This is always lowercase, so that filters can compare it directly.
+	Name           string        `gorm:"column:name;not null;size:256;type:varchar(256) collate nocase"`
+	Description    string        `gorm:"column:description;type:text collate nocase"`
+}
+
+func (ContactResult) TableName() string {
+	return "contact_result"
+}
+
+// ContactAttempt records a single attempt by a user to contact a person, such as knocking on their door.
+type ContactAttempt struct {
+	ID              uint64           `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	PersonID        uint64           `gorm:"column:person_id;not null;index:idx_contact_attempt_person"`
+	Person          *Person          `gorm:"belongsTo;constraint:fk_contact_attempt_person,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:person_id;references:id" json:"-"`
+	UserID          uint64           `gorm:"column:user_id;not null;index:idx_contact_attempt_user"`
+	User            *User            `gorm:"belongsTo;constraint:fk_contact_attempt_user,OnDelete:RESTRICT,OnUpdate:CASCADE;foreignKey:user_id;references:id" json:"-"`
+	Timestamp       sqltype.DateTime `gorm:"column:timestamp;not null"`
+	Channel         ContactChannel   `gorm:"column:channel;not null;size:16;type:varchar(16)"`
+	ContactResultID uint64           `gorm:"column:contact_result_id;not null"`
+	ContactResult   *ContactResult   `gorm:"belongsTo;constraint:fk_contact_attempt_contact_result,OnDelete:RESTRICT,OnUpdate:CASCADE;foreignKey:contact_result_id;references:id" json:"-"`
+	Note            string           `gorm:"column:note;type:text"`
+}
+
+func (ContactAttempt) TableName() string {
+	return "contact_attempt"
+}
@@ -0,0 +1,31 @@
+package schema
+
+// Household is a group of persons who live at the same address.
+//
+// Canvassers knock on each door once, so a household is the unit of a walk list.
+type Household struct {
+	ID             uint64        `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	OrganizationID uint64        `gorm:"column:organization_id;not null;uniqueIndex:idx_unique_household,priority:1"`
+	Organization   *Organization `gorm:"belongsTo;constraint:fk_household_organization,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:organization_id;references:id" json:"-"`
+	AddressKey     *string       `gorm:"column:address_key;size:256;type:varchar(256);uniqueIndex:idx_unique_household,priority:2"` // This is the normalized address (see `address.Key`); if this is nil, then the household was split off by hand.
+	Address        string        `gorm:"column:address;not null;type:text"`                                                         // This is the address as it was given for the first member.
+}
+
+func (Household) TableName() string {
+	return "household"
+}
+
+// HouseholdMember puts a person in a household.
+//
+// A person is in at most one household.
+type HouseholdMember struct {
+	PersonID    uint64     `gorm:"column:person_id;primaryKey;not null;autoIncrement:false"`
+	Person      *Person    `gorm:"belongsTo;constraint:fk_household_member_person,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:person_id;references:id" json:"-"`
+	HouseholdID uint64     `gorm:"column:household_id;not null;index:idx_household_member_household"`
+	Household   *Household `gorm:"belongsTo;constraint:fk_household_member_household,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:household_id;references:id" json:"-"`
+	Manual      bool       `gorm:"column:manual;not null;default:false"` // If true, then the person was merged or split by hand, so changes to their address do not move them.
+}
+
+func (HouseholdMember) TableName() string {
+	return "household_member"
+}
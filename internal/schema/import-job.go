@@ -0,0 +1,55 @@
+package schema
+
+import "github.com/downballot/downballot/internal/schema/sqltype"
+
+// ImportJobStatus is the status of an import job.
+type ImportJobStatus string
+
+// Import job statuses.
+const (
+	ImportJobStatusPending   ImportJobStatus = "pending"   // The job has been created, but it has not started yet.
+	ImportJobStatusRunning   ImportJobStatus = "running"   // The job is being processed.
+	ImportJobStatusSucceeded ImportJobStatus = "succeeded" // The job finished; individual rows may still have failed.
+	ImportJobStatusFailed    ImportJobStatus = "failed"    // The job could not be finished.
+)
+
+// ImportJob is a background import of persons into an organization.
+//
+// A job processes the rows of a voter file in batches so that progress can be checked while it runs.
+// Rows that cannot be imported are recorded as ImportJobError entries; they do not stop the job.
+type ImportJob struct {
+	ID                uint64            `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	OrganizationID    uint64            `gorm:"column:organization_id;not null"`
+	Organization      *Organization     `gorm:"belongsTo;constraint:fk_import_job_organization,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:organization_id;references:id" json:"-"`
+	UserID            uint64            `gorm:"column:user_id;not null"`
+	User              *User             `gorm:"belongsTo;constraint:fk_import_job_user,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:user_id;references:id" json:"-"`
+	Status            ImportJobStatus   `gorm:"column:status;not null;size:32;type:varchar(32)"`
+	DryRun            bool              `gorm:"column:dry_run;not null;default:0"` // If true, then nothing is written; the counts are what *would* have happened.
+	Message           string            `gorm:"column:message;type:text"`          // If the job failed, then this is why.
+	TotalRows         uint64            `gorm:"column:total_rows;not null;default:0"`
+	ProcessedRows     uint64            `gorm:"column:processed_rows;not null;default:0"`
+	CreatedRows       uint64            `gorm:"column:created_rows;not null;default:0"`
+	UpdatedRows       uint64            `gorm:"column:updated_rows;not null;default:0"`
+	UnchangedRows     uint64            `gorm:"column:unchanged_rows;not null;default:0"`
+	FailedRows        uint64            `gorm:"column:failed_rows;not null;default:0"`
+	CreatedTimestamp  sqltype.DateTime  `gorm:"column:created_timestamp;not null"`
+	FinishedTimestamp *sqltype.DateTime `gorm:"column:finished_timestamp"`
+}
+
+func (ImportJob) TableName() string {
+	return "import_job"
+}
+
+// ImportJobError is a row of an import job that could not be imported.
+type ImportJobError struct {
+	ID          uint64     `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	ImportJobID uint64     `gorm:"column:import_job_id;not null;index:idx_import_job_error,priority:1"`
+	ImportJob   *ImportJob `gorm:"belongsTo;constraint:fk_import_job_error_import_job,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:import_job_id;references:id" json:"-"`
+	RowNumber   uint64     `gorm:"column:row_num;not null;index:idx_import_job_error,priority:2"` // This is the 1-based row number in the file, not counting the header.
+	VoterID     string     `gorm:"column:voter_id;size:256;type:varchar(256) collate nocase"`
+	Message     string     `gorm:"column:message;type:text"`
+}
+
+func (ImportJobError) TableName() string {
+	return "import_job_error"
+}
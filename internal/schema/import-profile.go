@@ -0,0 +1,17 @@
+package schema
+
+// ImportProfile is a named voter-file layout for an organization.
+//
+// The definition is the JSON form of an importprofile.Profile.
+type ImportProfile struct {
+	ID             uint64        `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	OrganizationID uint64        `gorm:"column:organization_id;not null;uniqueIndex:idx_unique_import_profile,priority:1"`
+	Organization   *Organization `gorm:"belongsTo;constraint:fk_import_profile_organization,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:organization_id;references:id" json:"-"`
+	Name           string        `gorm:"column:name;not null;size:256;type:varchar(256) collate nocase;uniqueIndex:idx_unique_import_profile,priority:2"`
+	Description    string        `gorm:"column:description;type:text collate nocase"`
+	Definition     string        `gorm:"column:definition;type:text"`
+}
+
+func (ImportProfile) TableName() string {
+	return "import_profile"
+}
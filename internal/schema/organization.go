@@ -1,5 +1,7 @@
 package schema
 
+import "github.com/downballot/downballot/internal/schema/sqltype"
+
 // Organization is an organization using this system.
 //
 // This will be a candidate campaign.
@@ -11,3 +13,23 @@ type Organization struct {
 func (Organization) TableName() string {
 	return "organization"
 }
+
+// OrganizationArchive records that a full archive of an organization was produced.
+//
+// Deleting an organization requires the confirmation token that was handed out with a recent archive,
+// so that nobody can purge an organization's data without having first downloaded a copy of it.
+// Only a hash of the token is stored.
+type OrganizationArchive struct {
+	ID                  uint64           `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	OrganizationID      uint64           `gorm:"column:organization_id;not null"`
+	Organization        *Organization    `gorm:"belongsTo;constraint:fk_organization_archive_organization,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:organization_id;references:id" json:"-"`
+	UserID              uint64           `gorm:"column:user_id;not null"`
+	User                *User            `gorm:"belongsTo;constraint:fk_organization_archive_user,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:user_id;references:id" json:"-"`
+	TokenHash           string           `gorm:"column:token_hash;not null;size:64;type:varchar(64)"`
+	CreatedTimestamp    sqltype.DateTime `gorm:"column:created_timestamp;not null"`
+	ExpirationTimestamp sqltype.DateTime `gorm:"column:expiration_timestamp;not null"`
+}
+
+func (OrganizationArchive) TableName() string {
+	return "organization_archive"
+}
@@ -23,6 +23,7 @@ type PersonFieldDefinition struct {
 	AllowEmpty     bool                      `gorm:"column:allow_empty;not null;default:0"`
 	AllowedValues  sqltype.StringArray       `gorm:"column:allowed_values;type:text"`
 	AllowedRegex   string                    `gorm:"column:allowed_regex;type:text"`
+	Encrypted      bool                      `gorm:"column:encrypted;not null;default:0"` // If set, then the values are encrypted, and they can only be found by equality.
 }
 
 func (PersonFieldDefinition) TableName() string {
@@ -41,11 +42,62 @@ const (
 	PersonFieldDefinitionTypeString      PersonFieldDefinitionType = "string"
 )
 
+// PersonFieldValueMaxLength is the longest value that can be stored in a person field, as it is stored in the database.
+//
+// An encrypted value is much longer than the value itself (see `sqltype.EncryptedTextLength`).
+const PersonFieldValueMaxLength = 256
+
+// EncodeValue returns a value as it is stored in the database, along with its blind index.
+//
+// If the field is not encrypted, then the value is stored as-is and there is no blind index.
+func (t PersonFieldDefinition) EncodeValue(value string) (string, string, error) {
+	if !t.Encrypted {
+		return value, "", nil
+	}
+	encrypted, err := sqltype.EncryptText(value)
+	if err != nil {
+		return "", "", fmt.Errorf("could not encrypt value for field %s: %w", t.Name, err)
+	}
+	if len(encrypted) > PersonFieldValueMaxLength {
+		return "", "", fmt.Errorf("value for field %s is too long to be encrypted", t.Name)
+	}
+	blindIndex, err := t.BlindIndex(value)
+	if err != nil {
+		return "", "", err
+	}
+	return encrypted, blindIndex, nil
+}
+
+// DecodeValue returns a value as it was before it was stored in the database.
+func (t PersonFieldDefinition) DecodeValue(value string) (string, error) {
+	if !t.Encrypted {
+		return value, nil
+	}
+	decrypted, err := sqltype.DecryptText(value)
+	if err != nil {
+		return "", fmt.Errorf("could not decrypt value for field %s: %w", t.Name, err)
+	}
+	return decrypted, nil
+}
+
+// BlindIndex returns the blind index of a value, which is how an encrypted value is found by equality.
+func (t PersonFieldDefinition) BlindIndex(value string) (string, error) {
+	blindIndex, err := sqltype.BlindIndex(fmt.Sprintf("person_field_definition:%d", t.ID), value)
+	if err != nil {
+		return "", fmt.Errorf("could not compute blind index for field %s: %w", t.Name, err)
+	}
+	return blindIndex, nil
+}
+
 func (t PersonFieldDefinition) Validate(input string) error {
 	if t.AllowEmpty && input == "" {
 		return nil
 	}
 
+	if t.Encrypted && sqltype.EncryptedTextLength(len(input)) > PersonFieldValueMaxLength {
+		return fmt.Errorf("value is too long to be encrypted: %d characters", len(input))
+	}
+
 	switch t.Type {
 	case PersonFieldDefinitionTypeBoolean:
 		if input != "true" && input != "false" {
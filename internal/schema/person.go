@@ -28,7 +28,8 @@ type PersonField struct {
 	Person                  *Person                `gorm:"belongsTo;constraint:fk_person_field_person,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:person_id;references:id" json:"-"`
 	PersonFieldDefinitionID uint64                 `gorm:"column:person_field_definition_id;not null;uniqueIndex:idx_unique_person_field,priority:2;index:idx_person_field,priority:2"`
 	PersonFieldDefinition   *PersonFieldDefinition `gorm:"belongsTo;constraint:fk_person_field_person_field_definition,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:person_field_definition_id;references:id" json:"-"`
-	Value                   string                 `gorm:"column:value;not null;size:256;type:varchar(256) collate nocase;index:idx_person_field,priority:3"`
+	Value                   string                 `gorm:"column:value;not null;size:256;type:varchar(256) collate nocase;index:idx_person_field,priority:3"`  // If the field is encrypted, then this is the encrypted value (see `PersonFieldDefinition.EncodeValue`); either way, it can be at most `PersonFieldValueMaxLength` long.
+	BlindIndex              string                 `gorm:"column:blind_index;not null;default:'';size:64;type:varchar(64);index:idx_person_field_blind_index"` // If the field is encrypted, then this is the blind index of the value.
 }
 
 func (PersonField) TableName() string {
@@ -52,3 +53,33 @@ type PersonAudit struct {
 func (PersonAudit) TableName() string {
 	return "person_audit"
 }
+
+// Encode encrypts the old and new values if the field is encrypted.
+func (a *PersonAudit) Encode(personFieldDefinition PersonFieldDefinition) error {
+	for _, value := range []**string{&a.OldValue, &a.NewValue} {
+		if *value == nil {
+			continue
+		}
+		encoded, _, err := personFieldDefinition.EncodeValue(**value)
+		if err != nil {
+			return err
+		}
+		*value = &encoded
+	}
+	return nil
+}
+
+// Decode decrypts the old and new values if the field is encrypted.
+func (a *PersonAudit) Decode(personFieldDefinition PersonFieldDefinition) error {
+	for _, value := range []**string{&a.OldValue, &a.NewValue} {
+		if *value == nil {
+			continue
+		}
+		decoded, err := personFieldDefinition.DecodeValue(**value)
+		if err != nil {
+			return err
+		}
+		*value = &decoded
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package schema
+
+import "github.com/downballot/downballot/internal/schema/sqltype"
+
+// Role is a named set of permissions within an organization.
+//
+// The permissions may use wildcards, such as "person:*".
+type Role struct {
+	ID             uint64              `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	OrganizationID uint64              `gorm:"column:organization_id;not null;uniqueIndex:idx_unique_role,priority:1"`
+	Organization   *Organization       `gorm:"belongsTo;constraint:fk_role_organization,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:organization_id;references:id" json:"-"`
+	Name           string              `gorm:"column:name;not null;size:256;type:varchar(256) collate nocase;uniqueIndex:idx_unique_role,priority:2"`
+	Description    string              `gorm:"column:description;type:text collate nocase"`
+	Permissions    sqltype.StringArray `gorm:"column:permissions;type:text"`
+}
+
+func (Role) TableName() string {
+	return "role"
+}
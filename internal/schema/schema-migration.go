@@ -0,0 +1,13 @@
+package schema
+
+import "github.com/downballot/downballot/internal/schema/sqltype"
+
+// SchemaMigration records a migration that has been applied to the database.
+type SchemaMigration struct {
+	ID               string           `gorm:"column:id;primaryKey;not null;size:128;type:varchar(128)"` // This is the ID of the migration (see `migrator.Migration`).
+	AppliedTimestamp sqltype.DateTime `gorm:"column:applied_timestamp;not null"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
@@ -0,0 +1,22 @@
+package schema
+
+import "github.com/downballot/downballot/internal/schema/sqltype"
+
+// Session records an API token that was issued to a user.
+//
+// A token that refers to a session stops working once the session has been revoked, so that a user can
+// sign out of a single device without signing out everywhere.
+type Session struct {
+	ID                  uint64            `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	UserID              uint64            `gorm:"column:user_id;not null;index:idx_session_user"`
+	User                *User             `gorm:"belongsTo;constraint:fk_session_user,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:user_id;references:id" json:"-"`
+	CreatedTimestamp    sqltype.DateTime  `gorm:"column:created_timestamp;not null"` // This is when the token was issued.
+	ExpirationTimestamp *sqltype.DateTime `gorm:"column:expiration_timestamp"`       // If not set, then the token never expires.
+	RevokedTimestamp    *sqltype.DateTime `gorm:"column:revoked_timestamp"`          // Once set, the token no longer works.
+	UserAgent           string            `gorm:"column:user_agent;not null;size:512;type:varchar(512)"`
+	SourceAddress       string            `gorm:"column:source_address;not null;size:64;type:varchar(64)"`
+}
+
+func (Session) TableName() string {
+	return "session"
+}
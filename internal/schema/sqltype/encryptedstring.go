@@ -1,73 +1,207 @@
 package sqltype
 
 import (
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
 	"database/sql"
 	"database/sql/driver"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"io"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/migrator"
+	"gorm.io/gorm/schema"
 )
 
-// encryptionKey is the global encryption key.
-var encryptionKey string
+// LegacyEncryptionKeyID is the ID of the key for values that do not have a key ID.
+//
+// Values that were encrypted before key IDs were introduced don't have one, so they are decrypted with this key.
+const LegacyEncryptionKeyID = "default"
+
+// encryptedPrefix is the start of every encrypted value that has a key ID.
+//
+// An encrypted value is the prefix, the key ID, a colon, the nonce, and then the ciphertext.  The key ID is also
+// authenticated, so a value cannot be made to look as if another key encrypted it.
+const encryptedPrefix = "enc:v1:"
+
+// EncryptionKey is a key for encrypting and decrypting values.
+type EncryptionKey struct {
+	ID  string // This is the key ID, which is stored with every value that the key encrypts.
+	Key string // This is the key; it must be 32 (raw) or 64 (hexadecimal-encoded) characters long.
+}
+
+// encryptionKeys are the global encryption keys, by ID.
+var encryptionKeys = map[string][]byte{}
+
+// activeEncryptionKeyID is the ID of the key that encrypts new values.
+var activeEncryptionKeyID string
 
-// GetEncryptionKey returns the encryption key.
+// GetEncryptionKey returns the active encryption key.
 func GetEncryptionKey() string {
-	return encryptionKey
+	return string(encryptionKeys[activeEncryptionKeyID])
+}
+
+// ActiveEncryptionKeyID returns the ID of the key that encrypts new values.
+func ActiveEncryptionKeyID() string {
+	return activeEncryptionKeyID
 }
 
 // SetEncryptionKey sets the encryption key.
 // This should be called only one time, as soon as the encryption key is known.
 //
+// The key has the ID "default".  To use more than one key, use `SetEncryptionKeys` instead.
+//
 // This will panic if the key is invalid.
 func SetEncryptionKey(key string) {
-	if len(key) == 0 {
-		encryptionKey = ""
-		return
+	var keys []EncryptionKey
+	if len(key) > 0 {
+		keys = append(keys, EncryptionKey{ID: LegacyEncryptionKeyID, Key: key})
+	}
+	err := SetEncryptionKeys(keys, "")
+	if err != nil {
+		panic(err)
+	}
+}
+
+// SetEncryptionKeys sets the encryption keys.
+// This should be called only one time, as soon as the encryption keys are known.
+//
+// Every key can decrypt values, but only the active key encrypts new ones.  If the active key ID is empty and
+// there is only one key, then that key is the active one.
+func SetEncryptionKeys(keys []EncryptionKey, activeKeyID string) error {
+	newKeys := map[string][]byte{}
+	for _, key := range keys {
+		if key.ID == "" {
+			return fmt.Errorf("missing encryption key ID")
+		}
+		if strings.Contains(key.ID, ":") {
+			return fmt.Errorf("encryption key %q: key ID cannot contain a colon", key.ID)
+		}
+		if _, ok := newKeys[key.ID]; ok {
+			return fmt.Errorf("encryption key %q: duplicate key ID", key.ID)
+		}
+		decodedKey, err := parseEncryptionKey(key.Key)
+		if err != nil {
+			return fmt.Errorf("encryption key %q: %w", key.ID, err)
+		}
+		newKeys[key.ID] = decodedKey
+	}
+
+	if activeKeyID == "" && len(keys) == 1 {
+		activeKeyID = keys[0].ID
+	}
+	if activeKeyID != "" {
+		if _, ok := newKeys[activeKeyID]; !ok {
+			return fmt.Errorf("encryption key %q: unknown key ID", activeKeyID)
+		}
+	} else if len(keys) > 1 {
+		return fmt.Errorf("missing active encryption key ID")
 	}
+
+	encryptionKeys = newKeys
+	activeEncryptionKeyID = activeKeyID
+	return nil
+}
+
+// parseEncryptionKey decodes an encryption key.
+func parseEncryptionKey(key string) ([]byte, error) {
 	if len(key) == 32 {
-		encryptionKey = key
-		return
+		return []byte(key), nil
 	}
 	if len(key) == 64 {
 		decodedKey, err := hex.DecodeString(key)
 		if err != nil {
-			panic(fmt.Errorf("could not decode hexadecimal-encoded encryption key: %w", err))
+			return nil, fmt.Errorf("could not decode hexadecimal-encoded encryption key: %w", err)
 		}
-		encryptionKey = string(decodedKey)
-		return
+		return decodedKey, nil
+	}
+	return nil, fmt.Errorf("encryption key must be 32 (raw) or 64 (hexadecimal-encoded) characters long")
+}
+
+// EncryptionKeyIDOf returns the ID of the key that encrypted the raw value from the database.
+func EncryptionKeyIDOf(content []byte) string {
+	keyID, _, ok := splitEncrypted(content)
+	if !ok {
+		return LegacyEncryptionKeyID
+	}
+	return keyID
+}
+
+// splitEncrypted splits an encrypted value into its key ID and the rest (the nonce and the ciphertext).
+//
+// If the value does not have a key ID, then this returns false.
+func splitEncrypted(content []byte) (string, []byte, bool) {
+	rest, ok := bytes.CutPrefix(content, []byte(encryptedPrefix))
+	if !ok {
+		return "", nil, false
+	}
+	keyID, rest, ok := bytes.Cut(rest, []byte(":"))
+	if !ok {
+		return "", nil, false
+	}
+	return string(keyID), rest, true
+}
+
+// newGCM returns the cipher for a key.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("could not create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("could not create GCM: %w", err)
 	}
-	panic("encryption key must be 32 (raw) or 64 (hexadecimal-encoded) characters long")
+	return gcm, nil
 }
 
 // EncryptedString is a custom type for an encrypted string.
+//
+// New values are encrypted with the active key, and each value records the ID of the key that encrypted it, so that
+// the key can be rotated without losing access to the older values.
 type EncryptedString string
 
 var _ driver.Valuer = (*EncryptedString)(nil)
 var _ sql.Scanner = (*EncryptedString)(nil)
+var _ migrator.GormDataTypeInterface = (*EncryptedString)(nil)
+
+// GormDBDataType returns a binary type, since the encrypted bytes are not valid text.
+//
+// SQLite doesn't care, so the column keeps the type from its tag.
+func (EncryptedString) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	switch db.Dialector.Name() {
+	case "mysql":
+		return "BLOB"
+	case "postgres":
+		return "BYTEA"
+	}
+	return ""
+}
 
 // Value implements driver.Valuer: converts Go slice to JSON for the DB
 func (a EncryptedString) Value() (driver.Value, error) {
-	if encryptionKey == "" {
+	if activeEncryptionKeyID == "" {
 		return nil, fmt.Errorf("encryption key not set; please call SetEncryptionKey() before using this type")
 	}
 
-	block, err := aes.NewCipher([]byte(encryptionKey))
-	if err != nil {
-		return nil, fmt.Errorf("could not create cipher: %w", err)
-	}
-	gcm, err := cipher.NewGCM(block)
+	gcm, err := newGCM(encryptionKeys[activeEncryptionKeyID])
 	if err != nil {
-		return nil, fmt.Errorf("could not create GCM: %w", err)
+		return nil, err
 	}
 	nonce := make([]byte, gcm.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return nil, fmt.Errorf("could not create nonce: %w", err)
 	}
-	encrypted := gcm.Seal(nonce, nonce, []byte(a), nil)
+	header := []byte(encryptedPrefix + activeEncryptionKeyID + ":")
+	encrypted := append(header, nonce...)
+	encrypted = gcm.Seal(encrypted, nonce, []byte(a), header)
 	return encrypted, nil
 }
 
@@ -86,23 +220,112 @@ func (a *EncryptedString) Scan(src any) error {
 		return fmt.Errorf("invalid underlying type: %T", src)
 	}
 
-	if encryptionKey == "" {
+	if len(encryptionKeys) == 0 {
 		return fmt.Errorf("encryption key not set; please call SetEncryptionKey() before using this type")
 	}
 
-	block, err := aes.NewCipher([]byte(encryptionKey))
-	if err != nil {
-		return fmt.Errorf("could not create cipher: %w", err)
+	keyID := LegacyEncryptionKeyID
+	var additionalData []byte
+	if splitKeyID, rest, ok := splitEncrypted(content); ok {
+		keyID = splitKeyID
+		additionalData = content[:len(content)-len(rest)]
+		content = rest
 	}
-	gcm, err := cipher.NewGCM(block)
+	key, ok := encryptionKeys[keyID]
+	if !ok {
+		return fmt.Errorf("unknown encryption key ID: %q", keyID)
+	}
+
+	gcm, err := newGCM(key)
 	if err != nil {
-		return fmt.Errorf("could not create GCM: %w", err)
+		return err
+	}
+	if len(content) < gcm.NonceSize() {
+		return fmt.Errorf("could not decrypt content: too short")
 	}
 	nonce := content[:gcm.NonceSize()]
-	decrypted, err := gcm.Open(nil, nonce, content[gcm.NonceSize():], nil)
+	decrypted, err := gcm.Open(nil, nonce, content[gcm.NonceSize():], additionalData)
 	if err != nil {
 		return fmt.Errorf("could not decrypt content: %w", err)
 	}
 	*a = EncryptedString(decrypted)
 	return nil
 }
+
+// EncryptText encrypts a string with the active key and returns it as text.
+//
+// This is for columns that hold both plain and encrypted values, so they can't use `EncryptedString`.
+func EncryptText(plaintext string) (string, error) {
+	value, err := EncryptedString(plaintext).Value()
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(value.([]byte)), nil
+}
+
+// EncryptedTextLength returns the length of the text that `EncryptText` returns for a plaintext of the given length.
+func EncryptedTextLength(plaintextLength int) int {
+	const nonceSize = 12 // This is the nonce size of the standard GCM cipher.
+	const tagSize = 16   // This is the tag size of the standard GCM cipher.
+	return base64.StdEncoding.EncodedLen(len(encryptedPrefix) + len(activeEncryptionKeyID) + len(":") + nonceSize + plaintextLength + tagSize)
+}
+
+// DecryptText decrypts a string that was encrypted by `EncryptText`.
+func DecryptText(text string) (string, error) {
+	content, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return "", fmt.Errorf("could not decode encrypted text: %w", err)
+	}
+	var output EncryptedString
+	err = output.Scan(content)
+	if err != nil {
+		return "", err
+	}
+	return string(output), nil
+}
+
+// blindIndexKey is the global blind-index key.
+var blindIndexKey []byte
+
+// SetBlindIndexKey sets the blind-index key.
+// This should be called only one time, as soon as the blind-index key is known.
+//
+// Unlike the encryption keys, this key can never be changed, since every blind index would have to be recomputed.
+func SetBlindIndexKey(key string) error {
+	if len(key) == 0 {
+		blindIndexKey = nil
+		return nil
+	}
+	decodedKey, err := parseEncryptionKey(key)
+	if err != nil {
+		return fmt.Errorf("blind-index key: %w", err)
+	}
+	blindIndexKey = decodedKey
+	return nil
+}
+
+// CheckEncryption fails if values cannot be both encrypted and found by their blind indexes.
+func CheckEncryption() error {
+	if activeEncryptionKeyID == "" {
+		return fmt.Errorf("encryption key not set")
+	}
+	if len(blindIndexKey) == 0 {
+		return fmt.Errorf("blind-index key not set")
+	}
+	return nil
+}
+
+// BlindIndex returns the blind index for a value, which can be used to find an encrypted value by equality.
+//
+// The scope keeps the same value from having the same blind index everywhere (such as in two different fields).
+// The value is case-insensitive.
+func BlindIndex(scope string, value string) (string, error) {
+	if len(blindIndexKey) == 0 {
+		return "", fmt.Errorf("blind-index key not set; please call SetBlindIndexKey() before using blind indexes")
+	}
+	mac := hmac.New(sha256.New, blindIndexKey)
+	mac.Write([]byte(scope))
+	mac.Write([]byte{0})
+	mac.Write([]byte(strings.ToLower(value)))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
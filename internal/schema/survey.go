@@ -0,0 +1,94 @@
+package schema
+
+import "github.com/downballot/downballot/internal/schema/sqltype"
+
+// SurveyQuestionType is the kind of answer that a survey question takes.
+type SurveyQuestionType string
+
+const (
+	SurveyQuestionTypeChoice SurveyQuestionType = "choice" // The answer is the code of exactly one of the choices.
+	SurveyQuestionTypeText   SurveyQuestionType = "text"   // The answer is free-form.
+)
+
+// Survey is a script of questions that canvassers ask the persons that they contact.
+//
+// At most one survey in an organization is active at a time; that is the one that canvassers are given.
+type Survey struct {
+	ID             uint64        `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	OrganizationID uint64        `gorm:"column:organization_id;not null;uniqueIndex:idx_unique_survey,priority:1"`
+	Organization   *Organization `gorm:"belongsTo;constraint:fk_survey_organization,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:organization_id;references:id" json:"-"`
+	Name           string        `gorm:"column:name;not null;size:256;type:varchar(256) collate nocase;uniqueIndex:idx_unique_survey,priority:2"`
+	Description    string        `gorm:"column:description;type:text collate nocase"`
+	Active         bool          `gorm:"column:active;not null;default:0"`
+}
+
+func (Survey) TableName() string {
+	return "survey"
+}
+
+// SurveyQuestion is a single question in a survey.
+//
+// If the question is linked to a person field, then answering it also sets that field on the person.
+type SurveyQuestion struct {
+	ID                      uint64                 `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	SurveyID                uint64                 `gorm:"column:survey_id;not null;index:idx_survey_question_survey"`
+	Survey                  *Survey                `gorm:"belongsTo;constraint:fk_survey_question_survey,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:survey_id;references:id" json:"-"`
+	Position                int                    `gorm:"column:position;not null"` // The questions are asked in this order.
+	Prompt                  string                 `gorm:"column:prompt;not null;type:text"`
+	Type                    SurveyQuestionType     `gorm:"column:type;not null;size:16;type:varchar(16)"`
+	PersonFieldDefinitionID *uint64                `gorm:"column:person_field_definition_id"`
+	PersonFieldDefinition   *PersonFieldDefinition `gorm:"belongsTo;constraint:fk_survey_question_person_field_definition,OnDelete:SET NULL,OnUpdate:CASCADE;foreignKey:person_field_definition_id;references:id" json:"-"`
+}
+
+func (SurveyQuestion) TableName() string {
+	return "survey_question"
+}
+
+// SurveyChoice is one of the answers to a "choice" question.
+type SurveyChoice struct {
+	ID               uint64          `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	SurveyQuestionID uint64          `gorm:"column:survey_question_id;not null;uniqueIndex:idx_unique_survey_choice,priority:1"`
+	SurveyQuestion   *SurveyQuestion `gorm:"belongsTo;constraint:fk_survey_choice_survey_question,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:survey_question_id;references:id" json:"-"`
+	Position         int             `gorm:"column:position;not null"`
+	Code             string          `gorm:"column:code;not null;size:64;type:varchar(64);uniqueIndex:idx_unique_survey_choice,priority:2"` // This is always lowercase.
+	Label            string          `gorm:"column:label;not null;size:256;type:varchar(256)"`
+	Value            string          `gorm:"column:value;type:text"` // If the question is linked to a person field, then this is the value that the field is set to.
+}
+
+func (SurveyChoice) TableName() string {
+	return "survey_choice"
+}
+
+// SurveyResponse records the answers that a person gave to a survey.
+//
+// A person may respond to the same survey more than once; only their most recent response counts toward the results.
+type SurveyResponse struct {
+	ID               uint64           `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	SurveyID         uint64           `gorm:"column:survey_id;not null;index:idx_survey_response_survey"`
+	Survey           *Survey          `gorm:"belongsTo;constraint:fk_survey_response_survey,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:survey_id;references:id" json:"-"`
+	PersonID         uint64           `gorm:"column:person_id;not null;index:idx_survey_response_person"`
+	Person           *Person          `gorm:"belongsTo;constraint:fk_survey_response_person,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:person_id;references:id" json:"-"`
+	UserID           uint64           `gorm:"column:user_id;not null;index:idx_survey_response_user"`
+	User             *User            `gorm:"belongsTo;constraint:fk_survey_response_user,OnDelete:RESTRICT,OnUpdate:CASCADE;foreignKey:user_id;references:id" json:"-"`
+	Timestamp        sqltype.DateTime `gorm:"column:timestamp;not null"`
+	ContactAttemptID *uint64          `gorm:"column:contact_attempt_id"` // This is the contact attempt during which the person responded, if any.
+	ContactAttempt   *ContactAttempt  `gorm:"belongsTo;constraint:fk_survey_response_contact_attempt,OnDelete:SET NULL,OnUpdate:CASCADE;foreignKey:contact_attempt_id;references:id" json:"-"`
+}
+
+func (SurveyResponse) TableName() string {
+	return "survey_response"
+}
+
+// SurveyAnswer is the answer to a single question in a survey response.
+type SurveyAnswer struct {
+	ID               uint64          `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	SurveyResponseID uint64          `gorm:"column:survey_response_id;not null;uniqueIndex:idx_unique_survey_answer,priority:1"`
+	SurveyResponse   *SurveyResponse `gorm:"belongsTo;constraint:fk_survey_answer_survey_response,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:survey_response_id;references:id" json:"-"`
+	SurveyQuestionID uint64          `gorm:"column:survey_question_id;not null;uniqueIndex:idx_unique_survey_answer,priority:2"`
+	SurveyQuestion   *SurveyQuestion `gorm:"belongsTo;constraint:fk_survey_answer_survey_question,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:survey_question_id;references:id" json:"-"`
+	Value            string          `gorm:"column:value;not null;type:text"` // For a "choice" question, this is the code of the choice.
+}
+
+func (SurveyAnswer) TableName() string {
+	return "survey_answer"
+}
@@ -0,0 +1,35 @@
+package schema
+
+// Turf is an area that canvassers walk.
+//
+// The persons in a turf are kept in `TurfMember` so that "within_turf" filters don't have to do any geometry.
+type Turf struct {
+	ID             uint64        `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	OrganizationID uint64        `gorm:"column:organization_id;not null;uniqueIndex:idx_unique_turf,priority:1"`
+	Organization   *Organization `gorm:"belongsTo;constraint:fk_turf_organization,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:organization_id;references:id" json:"-"`
+	Name           string        `gorm:"column:name;not null;size:256;type:varchar(256) collate nocase;uniqueIndex:idx_unique_turf,priority:2"`
+	Description    string        `gorm:"column:description;type:text collate nocase"`
+	Geometry       string        `gorm:"column:geometry;not null;type:text"` // This is the GeoJSON "Polygon" or "MultiPolygon".
+	MinLatitude    float64       `gorm:"column:min_latitude;not null"`       // This is the bounding box of the geometry.
+	MinLongitude   float64       `gorm:"column:min_longitude;not null"`
+	MaxLatitude    float64       `gorm:"column:max_latitude;not null"`
+	MaxLongitude   float64       `gorm:"column:max_longitude;not null"`
+}
+
+func (Turf) TableName() string {
+	return "turf"
+}
+
+// TurfMember puts a person in a turf.
+//
+// A person may be in any number of turfs, since turfs may overlap.
+type TurfMember struct {
+	TurfID   uint64  `gorm:"column:turf_id;primaryKey;not null;autoIncrement:false"`
+	Turf     *Turf   `gorm:"belongsTo;constraint:fk_turf_member_turf,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:turf_id;references:id" json:"-"`
+	PersonID uint64  `gorm:"column:person_id;primaryKey;not null;autoIncrement:false;index:idx_turf_member_person"`
+	Person   *Person `gorm:"belongsTo;constraint:fk_turf_member_person,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:person_id;references:id" json:"-"`
+}
+
+func (TurfMember) TableName() string {
+	return "turf_member"
+}
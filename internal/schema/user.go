@@ -12,6 +12,7 @@ type User struct {
 	Username          string `gorm:"column:username;size:256;unique;type:varchar(256) collate nocase"`
 	Name              string `gorm:"column:name;size:256;type:varchar(256) collate nocase"`
 	SessionIdentifier uint64 `gorm:"column:session_identifier;not null;default:0"`
+	Verified          bool   `gorm:"column:verified;not null;default:0"` // Whether the user has proven that they own the e-mail address (username).
 }
 
 func (User) TableName() string {
@@ -40,6 +41,8 @@ type UserOrganizationMap struct {
 	OrganizationID uint64        `gorm:"column:organization_id;not null;uniqueIndex:idx_unique_user_organization,priority:2"`
 	Organization   *Organization `gorm:"belongsTo;constraint:fk_user_organization_map_organization,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:organization_id;references:id" json:"-"`
 	Owner          bool          `gorm:"column:owner;not null;default:0"`
+	RoleID         *uint64       `gorm:"column:role_id"` // If set, then the user has the role's permissions in addition to the read-only ones.
+	Role           *Role         `gorm:"belongsTo;constraint:fk_user_organization_map_role,OnDelete:SET NULL,OnUpdate:CASCADE;foreignKey:role_id;references:id" json:"-"`
 }
 
 func (UserOrganizationMap) TableName() string {
@@ -60,3 +63,21 @@ type UserTOTP struct {
 func (UserTOTP) TableName() string {
 	return "user_totp"
 }
+
+// UserLink is a single-use link that was e-mailed to a user, such as to verify their e-mail address.
+//
+// The link itself carries a signed token whose nonce refers to this record.  Only a hash of the nonce is stored.
+type UserLink struct {
+	ID                  uint64            `gorm:"column:id;primaryKey;not null;autoIncrement"`
+	UserID              uint64            `gorm:"column:user_id;not null"`
+	User                *User             `gorm:"belongsTo;constraint:fk_user_link_user,OnDelete:CASCADE,OnUpdate:CASCADE;foreignKey:user_id;references:id" json:"-"`
+	Purpose             string            `gorm:"column:purpose;not null;size:32;type:varchar(32)"`
+	NonceHash           string            `gorm:"column:nonce_hash;not null;size:64;type:varchar(64);uniqueIndex:idx_unique_user_link_nonce_hash"`
+	CreatedTimestamp    sqltype.DateTime  `gorm:"column:created_timestamp;not null"`
+	ExpirationTimestamp sqltype.DateTime  `gorm:"column:expiration_timestamp;not null"`
+	UsedTimestamp       *sqltype.DateTime `gorm:"column:used_timestamp"` // Once set, the link cannot be used again.
+}
+
+func (UserLink) TableName() string {
+	return "user_link"
+}